@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 	"github.com/joho/godotenv"
@@ -24,17 +25,73 @@ type Config struct {
 	}
 
 	HttpServer struct {
-		Host string `long:"http_host" description:"Host HTTP server" env:"HTTP_HOST" required:"true" default:"0.0.0.0"`
-		Port int    `long:"http_port" description:"Post HTTP sever" env:"HTTP_PORT" required:"true" default:"80"`
+		Host          string `long:"http_host" description:"Host HTTP server" env:"HTTP_HOST" required:"true" default:"0.0.0.0"`
+		Port          int    `long:"http_port" description:"Post HTTP sever" env:"HTTP_PORT" required:"true" default:"80"`
+		MaxBody       int64  `long:"http_max_body" description:"Size limit of request bodies in bytes, 0 disables" env:"HTTP_MAX_BODY" default:"1048576"`
+		MaxImportBody int64  `long:"http_max_import_body" description:"Size limit of imported calendars in bytes, 0 disables" env:"HTTP_MAX_IMPORT_BODY" default:"10485760"`
+	}
+
+	RateLimit struct {
+		ReadRate   float64 `long:"rate_limit_read" description:"Reads per second of a user, 0 disables" env:"RATE_LIMIT_READ" default:"20"`
+		ReadBurst  int     `long:"rate_limit_read_burst" description:"Burst of reads of a user" env:"RATE_LIMIT_READ_BURST" default:"40"`
+		WriteRate  float64 `long:"rate_limit_write" description:"Writes per second of a user, 0 disables" env:"RATE_LIMIT_WRITE" default:"5"`
+		WriteBurst int     `long:"rate_limit_write_burst" description:"Burst of writes of a user" env:"RATE_LIMIT_WRITE_BURST" default:"10"`
+		AuthRate   float64 `long:"rate_limit_auth" description:"Token refreshes per second of an IP, 0 disables" env:"RATE_LIMIT_AUTH" default:"1"`
+		AuthBurst  int     `long:"rate_limit_auth_burst" description:"Burst of token refreshes of an IP" env:"RATE_LIMIT_AUTH_BURST" default:"5"`
 	}
 
 	DB struct {
-		Host     string `long:"db_host" description:"Host DB" env:"DB_HOST" required:"true" default:"127.0.0.1"`
-		Port     int    `long:"db_port" description:"Port DB" env:"DB_PORT" required:"true" default:"5432"`
-		Name     string `long:"db_name" description:"Name DB" env:"DB_NAME" required:"true" default:"db"`
-		Username string `long:"db_username" description:"Username DB" env:"DB_USER" required:"true" default:"dbuser"`
-		Password string `long:"db_password" description:"Password DB" env:"DB_PASS" required:"true" default:"dbpass"`
-		SSLMode  string `long:"db_sslmode" description:"SSLMode DB" env:"DB_SSLMODE" required:"true" default:"disable"`
+		Driver    string        `long:"db_driver" description:"DB driver: postgres, sqlite, memory" env:"DB_DRIVER" required:"true" default:"postgres"`
+		Path      string        `long:"db_path" description:"Path to SQLite DB file" env:"DB_PATH" default:"calendar.db"`
+		Host      string        `long:"db_host" description:"Host DB" env:"DB_HOST" required:"true" default:"127.0.0.1"`
+		Port      int           `long:"db_port" description:"Port DB" env:"DB_PORT" required:"true" default:"5432"`
+		Name      string        `long:"db_name" description:"Name DB" env:"DB_NAME" required:"true" default:"db"`
+		Username  string        `long:"db_username" description:"Username DB" env:"DB_USER" required:"true" default:"dbuser"`
+		Password  string        `long:"db_password" description:"Password DB" env:"DB_PASS" required:"true" default:"dbpass"`
+		SSLMode   string        `long:"db_sslmode" description:"SSLMode DB" env:"DB_SSLMODE" required:"true" default:"disable"`
+		SlowQuery time.Duration `long:"db_slow_query" description:"Duration after which a query is logged as slow, 0 disables" env:"DB_SLOW_QUERY" default:"200ms"`
+	}
+
+	Events struct {
+		ConflictMode string `long:"conflict_mode" description:"Overlapping events: flag or reject" env:"CONFLICT_MODE" choice:"flag" choice:"reject" default:"flag"`
+	}
+
+	Cache struct {
+		Size int           `long:"event_cache_size" description:"Cached window queries of events, 0 disables the cache" env:"EVENT_CACHE_SIZE" default:"1000"`
+		TTL  time.Duration `long:"event_cache_ttl" description:"How long a cached window query is served" env:"EVENT_CACHE_TTL" default:"1m"`
+	}
+
+	Auth struct {
+		Keys       []string      `long:"auth_keys" description:"Token keys id:secret, the first one signs new tokens" env:"AUTH_KEYS" env-delim:","`
+		Issuer     string        `long:"auth_issuer" description:"Issuer of the tokens" env:"AUTH_ISSUER" default:"calendar"`
+		AccessTTL  time.Duration `long:"auth_access_ttl" description:"Lifetime of access tokens" env:"AUTH_ACCESS_TTL" default:"15m"`
+		RefreshTTL time.Duration `long:"auth_refresh_ttl" description:"Lifetime of refresh tokens" env:"AUTH_REFRESH_TTL" default:"720h"`
+		IssueToken string        `long:"issue_token" description:"Print a token pair of the user ID and exit"`
+	}
+
+	Reminders struct {
+		Interval     time.Duration `long:"reminder_interval" description:"Interval of due reminders checks" env:"REMINDER_INTERVAL" default:"30s"`
+		Lookback     time.Duration `long:"reminder_lookback" description:"How late missed reminders are still delivered" env:"REMINDER_LOOKBACK" default:"24h"`
+		Notifiers    []string      `long:"notifiers" description:"Notifiers of reminders and invitations: log, webhook, smtp" env:"NOTIFIERS" env-delim:"," default:"log"`
+		WebhookURL   string        `long:"notify_webhook_url" description:"URL reminders and invitations are posted to" env:"NOTIFY_WEBHOOK_URL"`
+		SMTPAddr     string        `long:"smtp_addr" description:"SMTP server host:port" env:"SMTP_ADDR" default:"127.0.0.1:25"`
+		SMTPUsername string        `long:"smtp_username" description:"SMTP username" env:"SMTP_USER"`
+		SMTPPassword string        `long:"smtp_password" description:"SMTP password" env:"SMTP_PASS"`
+		SMTPFrom     string        `long:"smtp_from" description:"Reminder sender address" env:"SMTP_FROM" default:"calendar@localhost"`
+		SMTPTo       string        `long:"smtp_to" description:"Reminder recipient, {user_id} is replaced" env:"SMTP_TO" default:"{user_id}@localhost"`
+	}
+
+	Webhooks struct {
+		Interval    time.Duration `long:"webhook_interval" description:"Interval of undelivered changes checks of webhooks" env:"WEBHOOK_INTERVAL" default:"10s"`
+		MaxAttempts int           `long:"webhook_max_attempts" description:"Deliveries of a change before it is dropped" env:"WEBHOOK_MAX_ATTEMPTS" default:"10"`
+		MaxBackoff  time.Duration `long:"webhook_max_backoff" description:"Longest wait between deliveries of a change" env:"WEBHOOK_MAX_BACKOFF" default:"1h"`
+		Timeout     time.Duration `long:"webhook_timeout" description:"Timeout of a delivery" env:"WEBHOOK_TIMEOUT" default:"10s"`
+		Retention   time.Duration `long:"change_retention" description:"How long the change log is kept, 0 keeps it forever" env:"CHANGE_RETENTION" default:"168h"`
+	}
+
+	Trash struct {
+		PurgeInterval time.Duration `long:"trash_purge_interval" description:"Interval of purges of the trash" env:"TRASH_PURGE_INTERVAL" default:"1h"`
+		Retention     time.Duration `long:"trash_retention" description:"How long deleted events are kept in the trash" env:"TRASH_RETENTION" default:"720h"`
 	}
 }
 
@@ -4,13 +4,18 @@ import (
 	"L2/develop/dev11/internal/app"
 	"L2/develop/dev11/cmd/L2/config"
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"os"
 	"sync"
+	_ "time/tzdata"
 
+	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	_ "modernc.org/sqlite"
 )
 
 // appVersion represents the version information of the application.
@@ -49,6 +54,14 @@ func main() {
 		log.Fatalf("can't parse app config: %v", err)
 	}
 
+	if cfg.Auth.IssueToken != "" {
+		err = issueToken(cfg, cfg.Auth.IssueToken)
+		if err != nil {
+			log.Fatalf("can't issue token: %v", err)
+		}
+		return
+	}
+
 	AppVersion = &appVersion{}
 	AppVersion.LoadFromConfig(cfg)
 
@@ -112,3 +125,24 @@ func main() {
 
 	logger.Warn("application is shutdown")
 }
+
+// issueToken prints a token pair of the user, tokens are then renewed through /refresh_token.
+func issueToken(cfg *config.Config, user string) error {
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	tokens, err := app.NewTokens(cfg)
+	if err != nil {
+		return err
+	}
+	pair, err := tokens.Issue(userID)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(pair)
+}
@@ -0,0 +1,299 @@
+// Package caldav provides a CalDAV (RFC 4791) subset on top of the event interactor:
+// PROPFIND, REPORT calendar-query and calendar-multiget, GET, PUT and DELETE of .ics resources.
+//
+// The route tree is
+//
+//	/caldav/{user_id}/                    principal and calendar home
+//	/caldav/{user_id}/calendar/           calendar collection
+//	/caldav/{user_id}/calendar/{uid}.ics  event resource
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/ical"
+	"L2/develop/dev11/internal/usecase"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Prefix is the root of the CalDAV route tree.
+	Prefix = "/caldav/"
+
+	calendarName = "calendar"
+	resourceExt  = ".ics"
+
+	allowedMethods = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT"
+)
+
+// handler serves the CalDAV route tree.
+type handler struct {
+	interactor usecase.EventInteractor
+}
+
+// NewHandler creates a new CalDAV handler backed by the interactor.
+func NewHandler(interactor usecase.EventInteractor) *handler {
+	return &handler{
+		interactor: interactor,
+	}
+}
+
+// target is a parsed CalDAV request path.
+type target struct {
+	userID uuid.UUID
+	// calendar is set for the collection and its resources.
+	calendar bool
+	// resource is the file name of an event resource.
+	resource string
+}
+
+// ServeHTTP implements http.Handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	t, err := parsePath(req.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	// The collection of a user shows every calendar visible to the user, new events go to the default one,
+	// so only the authenticated user may access it.
+	callerID, _ := usecase.CallerFromContext(req.Context())
+	if t.userID != callerID {
+		http.Error(w, usecase.ErrForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodOptions:
+		w.Header().Set("Allow", allowedMethods)
+		w.WriteHeader(http.StatusOK)
+	case "PROPFIND":
+		h.propfind(w, req, t)
+	case "REPORT":
+		h.report(w, req, t)
+	case http.MethodGet, http.MethodHead:
+		h.get(w, req, t)
+	case http.MethodPut:
+		h.put(w, req, t)
+	case http.MethodDelete:
+		h.delete(w, req, t)
+	default:
+		w.Header().Set("Allow", allowedMethods)
+		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
+	}
+}
+
+// parsePath splits a path of the CalDAV route tree.
+func parsePath(path string) (target, error) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(path, Prefix), "/"), "/")
+
+	userID, err := uuid.Parse(parts[0])
+	if err != nil {
+		return target{}, fmt.Errorf("unknown principal")
+	}
+	t := target{userID: userID}
+
+	if len(parts) > 1 {
+		if parts[1] != calendarName || len(parts) > 3 {
+			return target{}, fmt.Errorf("unknown collection")
+		}
+		t.calendar = true
+	}
+	if len(parts) == 3 {
+		if !strings.HasSuffix(parts[2], resourceExt) || len(parts[2]) == len(resourceExt) {
+			return target{}, fmt.Errorf("unknown resource")
+		}
+		t.resource = parts[2]
+	}
+
+	return t, nil
+}
+
+func principalHref(userID uuid.UUID) string {
+	return Prefix + userID.String() + "/"
+}
+
+func calendarHref(userID uuid.UUID) string {
+	return principalHref(userID) + calendarName + "/"
+}
+
+// resource is an event resource: a series or a single event with the occurrences overriding it.
+type resource struct {
+	name   string
+	events entity.Events
+}
+
+func (r *resource) href(userID uuid.UUID) string {
+	return calendarHref(userID) + r.name
+}
+
+// master returns the event the resource is named after.
+func (r *resource) master() *entity.Event {
+	return &r.events[0]
+}
+
+// sortOverrides orders the overrides following the event the resource is named after by their occurrences,
+// so that the content of the resource doesn't depend on how it was loaded.
+func (r *resource) sortOverrides() {
+	overrides := r.events[1:]
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].RecurrenceID.Before(*overrides[j].RecurrenceID)
+	})
+}
+
+// etag returns a strong entity tag of the resource content.
+func (r *resource) etag() string {
+	data, _ := json.Marshal(r.events)
+	sum := sha1.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// calendarData returns the resource as iCalendar.
+func (r *resource) calendarData() (string, error) {
+	buf := &bytes.Buffer{}
+	err := ical.Encode(buf, r.events)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// resourceName returns the file name of the resource of the event.
+func resourceName(event *entity.Event) string {
+	return ical.UID(event) + resourceExt
+}
+
+// loadResources returns the event resources visible to the caller ordered by name.
+func (h *handler) loadResources(req *http.Request) ([]*resource, error) {
+	events, err := h.interactor.GetAll(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[uuid.UUID]*resource{}
+	var overrides entity.Events
+	for _, event := range *events {
+		if event.SeriesID != nil {
+			overrides = append(overrides, event)
+			continue
+		}
+		byID[event.ID] = &resource{name: resourceName(&event), events: entity.Events{event}}
+	}
+	for _, event := range overrides {
+		r, ok := byID[*event.SeriesID]
+		if !ok {
+			// The series is gone, the override is served on its own.
+			r = &resource{name: resourceName(&event)}
+			byID[*event.SeriesID] = r
+		}
+		r.events = append(r.events, event)
+	}
+
+	resources := make([]*resource, 0, len(byID))
+	for _, r := range byID {
+		r.sortOverrides()
+		resources = append(resources, r)
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].name < resources[j].name })
+
+	return resources, nil
+}
+
+// loadResource returns the resource with the file name or nil if it doesn't exist. The event the resource
+// is named after is found by the UID in the name, whatever name the client used, and its overrides
+// by the occurrences it excludes.
+func (h *handler) loadResource(ctx context.Context, name string) (*resource, error) {
+	event, err := h.getEvent(ctx, ical.EventID(strings.TrimSuffix(name, resourceExt)))
+	if event == nil || err != nil {
+		return nil, err
+	}
+	if event.SeriesID != nil {
+		// An override is served with its series, on its own only if the series is gone.
+		series, err := h.getEvent(ctx, *event.SeriesID)
+		if series != nil || err != nil {
+			return nil, err
+		}
+	}
+
+	r := &resource{name: resourceName(event), events: entity.Events{*event}}
+	for _, date := range event.ExDates {
+		override, err := h.getEvent(ctx, event.OverrideID(date))
+		if err != nil {
+			return nil, err
+		}
+		if override != nil {
+			r.events = append(r.events, *override)
+		}
+	}
+	r.sortOverrides()
+
+	return r, nil
+}
+
+// getEvent returns the event or nil if it doesn't exist.
+func (h *handler) getEvent(ctx context.Context, eventID uuid.UUID) (*entity.Event, error) {
+	event, err := h.interactor.Get(ctx, eventID)
+	if errors.Is(err, usecase.ErrNotFound) {
+		return nil, nil
+	}
+	return event, err
+}
+
+// ctag returns the collection tag which changes whenever any resource changes.
+func ctag(resources []*resource) string {
+	h := sha1.New()
+	for _, r := range resources {
+		h.Write([]byte(r.name))
+		h.Write([]byte(r.etag()))
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+// checkPreconditions evaluates If-Match and If-None-Match against the current resource,
+// which is nil if it doesn't exist.
+func checkPreconditions(req *http.Request, r *resource) bool {
+	if ifMatch := req.Header.Get("If-Match"); ifMatch != "" {
+		if r == nil {
+			return false
+		}
+		if ifMatch != "*" && !matchETag(ifMatch, r.etag(), false) {
+			return false
+		}
+	}
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" && r != nil {
+		if ifNoneMatch == "*" || matchETag(ifNoneMatch, r.etag(), true) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchETag reports whether the etag is in the comma-separated list of a conditional header.
+// Weak tags W/"..." match only with the weak comparison of If-None-Match, If-Match compares strongly.
+func matchETag(header string, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package caldav
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/ical"
+	"L2/develop/dev11/internal/repository"
+	"L2/develop/dev11/internal/usecase"
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// testServer serves the CalDAV tree of one user backed by the in-memory source.
+type testServer struct {
+	t        *testing.T
+	handler  *handler
+	events   usecase.EventInteractor
+	userID   uuid.UUID
+	calendar string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	source := db.NewMemorySource()
+	events := usecase.NewEventInteractor(
+		repository.NewEventRepository(source), repository.NewCalendarRepository(source), usecase.ConflictFlag,
+		usecase.EventOptions{Tx: repository.NewTxManager(source)},
+	)
+	userID := uuid.New()
+	return &testServer{t: t, handler: NewHandler(events), events: events, userID: userID, calendar: calendarHref(userID)}
+}
+
+// do serves the request of the user, the headers are given as name and value pairs.
+func (s *testServer) do(method string, path string, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req = req.WithContext(usecase.ContextWithCaller(req.Context(), s.userID))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// put writes the calendar to the resource and checks the status of the response.
+func (s *testServer) put(name string, calendar string, status int, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	rec := s.do(http.MethodPut, s.calendar+name, calendar, headers...)
+	if rec.Code != status {
+		s.t.Fatalf("PUT %s = %d %s, expected %d", name, rec.Code, rec.Body, status)
+	}
+	return rec
+}
+
+// vcalendar wraps the VEVENTs given as their content lines into a calendar.
+func vcalendar(events ...[]string) string {
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//test//EN"}
+	for _, event := range events {
+		lines = append(lines, "BEGIN:VEVENT")
+		lines = append(lines, event...)
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// testMultistatus is the part of a DAV:multistatus response the tests check.
+type testMultistatus struct {
+	Responses []struct {
+		Href   string `xml:"DAV: href"`
+		ETag   string `xml:"DAV: propstat>prop>getetag"`
+		Data   string `xml:"propstat>prop>calendar-data"`
+		Status string `xml:"DAV: status"`
+	} `xml:"DAV: response"`
+}
+
+func parseMultistatus(t *testing.T, rec *httptest.ResponseRecorder) testMultistatus {
+	t.Helper()
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %d %s, expected 207", rec.Code, rec.Body)
+	}
+	var m testMultistatus
+	if err := xml.Unmarshal(rec.Body.Bytes(), &m); err != nil {
+		t.Fatalf("can't parse multistatus: %v", err)
+	}
+	return m
+}
+
+func TestPutPreconditions(t *testing.T) {
+	s := newTestServer(t)
+	dentist := []string{"UID:dentist", "SUMMARY:dentist", "DTSTART:20240305T100000Z", "DTEND:20240305T110000Z"}
+
+	created := s.put("dentist.ics", vcalendar(dentist), http.StatusCreated, "If-None-Match", "*")
+	etag := created.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("PUT didn't return an ETag")
+	}
+
+	// The resource exists now and the client's copy is outdated.
+	s.put("dentist.ics", vcalendar(dentist), http.StatusPreconditionFailed, "If-None-Match", "*")
+	s.put("dentist.ics", vcalendar(dentist), http.StatusPreconditionFailed, "If-Match", `"stale"`)
+	s.put("missing.ics", vcalendar([]string{"UID:missing", "DTSTART:20240305T100000Z", "DURATION:PT1H"}),
+		http.StatusPreconditionFailed, "If-Match", "*")
+	// If-Match compares strongly, If-None-Match weakly, so the weak tag of the current copy fails both.
+	s.put("dentist.ics", vcalendar(dentist), http.StatusPreconditionFailed, "If-Match", "W/"+etag)
+	s.put("dentist.ics", vcalendar(dentist), http.StatusPreconditionFailed, "If-None-Match", "W/"+etag)
+
+	moved := []string{"UID:dentist", "SUMMARY:dentist moved", "DTSTART:20240306T100000Z", "DTEND:20240306T110000Z"}
+	replaced := s.put("dentist.ics", vcalendar(moved), http.StatusNoContent, "If-Match", etag)
+	if replaced.Header().Get("ETag") == etag {
+		t.Errorf("ETag didn't change with the content")
+	}
+
+	rec := s.do(http.MethodGet, s.calendar+"dentist.ics", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "SUMMARY:dentist moved") {
+		t.Fatalf("GET = %d %s, expected the replaced event", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("ETag") != replaced.Header().Get("ETag") {
+		t.Errorf("GET ETag = %s, expected the one of PUT %s", rec.Header().Get("ETag"), replaced.Header().Get("ETag"))
+	}
+
+	// An event is stored under the name of its UID only.
+	s.put("other.ics", vcalendar(moved), http.StatusConflict)
+	if rec := s.do(http.MethodGet, s.calendar+"other.ics", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET of the rejected resource = %d, expected 404", rec.Code)
+	}
+}
+
+func TestPutSeries(t *testing.T) {
+	s := newTestServer(t)
+	series := []string{"UID:standup", "SUMMARY:standup", "DTSTART:20240304T090000Z", "DTEND:20240304T091500Z",
+		"RRULE:FREQ=DAILY;COUNT=5"}
+	override := []string{"UID:standup", "SUMMARY:late standup", "RECURRENCE-ID:20240305T090000Z",
+		"DTSTART:20240305T110000Z", "DTEND:20240305T111500Z"}
+
+	etag := s.put("standup.ics", vcalendar(series, override), http.StatusCreated).Header().Get("ETag")
+
+	rec := s.do(http.MethodGet, s.calendar+"standup.ics", "")
+	if rec.Code != http.StatusOK || strings.Count(rec.Body.String(), "BEGIN:VEVENT") != 2 ||
+		!strings.Contains(rec.Body.String(), "SUMMARY:late standup") {
+		t.Fatalf("GET = %d %s, expected the series with the override", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("ETag") != etag {
+		t.Errorf("GET ETag = %s, expected %s", rec.Header().Get("ETag"), etag)
+	}
+
+	// The collection lists the resource once with the same ETag.
+	m := parseMultistatus(t, s.do("PROPFIND", s.calendar, "", "Depth", "1"))
+	if len(m.Responses) != 2 || m.Responses[1].Href != s.calendar+"standup.ics" || m.Responses[1].ETag != etag {
+		t.Errorf("PROPFIND = %+v, expected the collection and standup.ics with ETag %s", m.Responses, etag)
+	}
+
+	// An override of an occurrence the series doesn't have fails the whole PUT.
+	missing := []string{"UID:standup", "SUMMARY:planning", "RECURRENCE-ID:20240401T090000Z",
+		"DTSTART:20240401T100000Z", "DTEND:20240401T101500Z"}
+	renamed := append([]string{"SUMMARY:renamed standup"}, series[2:]...)
+	renamed = append([]string{"UID:standup"}, renamed...)
+	if rec := s.do(http.MethodPut, s.calendar+"standup.ics", vcalendar(renamed, override, missing)); rec.Code < 400 {
+		t.Fatalf("PUT with an override of a missing occurrence = %d, expected an error", rec.Code)
+	}
+	rec = s.do(http.MethodGet, s.calendar+"standup.ics", "")
+	if rec.Header().Get("ETag") != etag || strings.Contains(rec.Body.String(), "renamed") {
+		t.Errorf("GET after the failed PUT = %s, expected the resource unchanged", rec.Body)
+	}
+}
+
+func TestReport(t *testing.T) {
+	s := newTestServer(t)
+	s.put("monday.ics", vcalendar([]string{"UID:monday", "SUMMARY:monday", "DTSTART:20240304T100000Z", "DURATION:PT1H"}),
+		http.StatusCreated)
+	s.put("friday.ics", vcalendar([]string{"UID:friday", "SUMMARY:friday", "DTSTART:20240308T100000Z", "DURATION:PT1H"}),
+		http.StatusCreated)
+	s.put("daily.ics", vcalendar([]string{"UID:daily", "SUMMARY:daily", "DTSTART:20240301T080000Z", "DURATION:PT1H",
+		"RRULE:FREQ=DAILY;UNTIL=20240303T080000Z"}), http.StatusCreated)
+
+	// Only the resources with an occurrence within the range match, a series by its occurrences.
+	query := `<?xml version="1.0"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/></D:prop>
+  <C:filter><C:comp-filter name="VCALENDAR"><C:comp-filter name="VEVENT">
+    <C:time-range start="20240302T000000Z" end="20240305T000000Z"/>
+  </C:comp-filter></C:comp-filter></C:filter>
+</C:calendar-query>`
+	m := parseMultistatus(t, s.do("REPORT", s.calendar, query, "Depth", "1"))
+	var hrefs []string
+	for _, response := range m.Responses {
+		hrefs = append(hrefs, response.Href)
+	}
+	if strings.Join(hrefs, " ") != s.calendar+"daily.ics "+s.calendar+"monday.ics" {
+		t.Errorf("calendar-query = %v, expected daily.ics and monday.ics", hrefs)
+	}
+
+	// Multiget returns the requested resources with their data and 404 for the missing ones.
+	multiget := `<?xml version="1.0"?>
+<C:calendar-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/><C:calendar-data/></D:prop>
+  <D:href>` + s.calendar + `friday.ics</D:href>
+  <D:href>` + s.calendar + `missing.ics</D:href>
+</C:calendar-multiget>`
+	m = parseMultistatus(t, s.do("REPORT", s.calendar, multiget))
+	if len(m.Responses) != 2 {
+		t.Fatalf("calendar-multiget = %+v, expected 2 responses", m.Responses)
+	}
+	friday := s.do(http.MethodGet, s.calendar+"friday.ics", "")
+	if got := m.Responses[0]; got.ETag != friday.Header().Get("ETag") || !strings.Contains(got.Data, "SUMMARY:friday") {
+		t.Errorf("calendar-multiget of friday.ics = %+v, expected its ETag and data", got)
+	}
+	if got := m.Responses[1]; got.Href != s.calendar+"missing.ics" || !strings.Contains(got.Status, "404") {
+		t.Errorf("calendar-multiget of missing.ics = %+v, expected 404", got)
+	}
+}
+
+func TestDeleteSeries(t *testing.T) {
+	s := newTestServer(t)
+	series := []string{"UID:standup", "SUMMARY:standup", "DTSTART:20240304T090000Z", "DTEND:20240304T091500Z",
+		"RRULE:FREQ=DAILY"}
+	override := []string{"UID:standup", "SUMMARY:late standup", "RECURRENCE-ID:20240305T090000Z",
+		"DTSTART:20240305T110000Z", "DTEND:20240305T111500Z"}
+	etag := s.put("standup.ics", vcalendar(series, override), http.StatusCreated).Header().Get("ETag")
+
+	if rec := s.do(http.MethodDelete, s.calendar+"standup.ics", "", "If-Match", `"stale"`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with a stale ETag = %d, expected 412", rec.Code)
+	}
+	if rec := s.do(http.MethodDelete, s.calendar+"standup.ics", "", "If-Match", etag); rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d %s, expected 204", rec.Code, rec.Body)
+	}
+
+	// The overrides are deleted with their series.
+	if rec := s.do(http.MethodGet, s.calendar+"standup.ics", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET of the deleted resource = %d, expected 404", rec.Code)
+	}
+	ctx := usecase.ContextWithCaller(context.Background(), s.userID)
+	master := entity.Event{ID: ical.EventID("standup")}
+	recurrenceID := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	if _, err := s.events.Get(ctx, master.OverrideID(recurrenceID)); !errors.Is(err, usecase.ErrNotFound) {
+		t.Errorf("Get() of the override error = %v, expected ErrNotFound", err)
+	}
+	if m := parseMultistatus(t, s.do("PROPFIND", s.calendar, "", "Depth", "1")); len(m.Responses) != 1 {
+		t.Errorf("PROPFIND after DELETE = %+v, expected the collection only", m.Responses)
+	}
+}
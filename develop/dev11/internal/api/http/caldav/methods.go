@@ -0,0 +1,381 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/ical"
+	"L2/develop/dev11/internal/usecase"
+)
+
+func (h *handler) propfind(w http.ResponseWriter, req *http.Request, t target) {
+	depth := req.Header.Get("Depth")
+	if depth == "" {
+		depth = "infinity"
+	}
+
+	var body propfindRequest
+	ok, err := decodeBody(req, &body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Can't parse body: %s", err.Error()), bodyStatus(err))
+		return
+	}
+
+	// Without a body or with allprop every live property is returned.
+	var requested []xml.Name
+	if ok && body.Prop != nil {
+		requested = body.Prop.list()
+	}
+	props := func(all []xml.Name) []xml.Name {
+		if requested != nil {
+			return requested
+		}
+		return all
+	}
+
+	m := newMultistatus()
+	if t.resource != "" {
+		r, err := h.loadResource(req.Context(), t.resource)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if r == nil {
+			http.Error(w, "Resource not found", http.StatusNotFound)
+			return
+		}
+		m.addProps(r.href(t.userID), props(resourceProps), h.resourceProp(r))
+		m.write(w)
+		return
+	}
+
+	resources, err := h.loadResources(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case t.calendar:
+		m.addProps(calendarHref(t.userID), props(calendarProps), h.calendarProp(t, resources))
+		if depth != "0" {
+			for _, r := range resources {
+				m.addProps(r.href(t.userID), props(resourceProps), h.resourceProp(r))
+			}
+		}
+	default:
+		m.addProps(principalHref(t.userID), props(principalProps), h.principalProp(t))
+		if depth != "0" {
+			m.addProps(calendarHref(t.userID), props(calendarProps), h.calendarProp(t, resources))
+		}
+	}
+	m.write(w)
+}
+
+func (h *handler) principalProp(t target) func(xml.Name) *string {
+	return func(name xml.Name) *string {
+		switch name {
+		case propResourceType:
+			return fragment(`<principal xmlns="DAV:"/><collection xmlns="DAV:"/>`)
+		case propDisplayName:
+			return text(t.userID.String())
+		case propCurrentPrincipal, propPrincipalURL:
+			return href(principalHref(t.userID))
+		case propCalendarHomeSet:
+			return href(principalHref(t.userID))
+		}
+		return nil
+	}
+}
+
+func (h *handler) calendarProp(t target, resources []*resource) func(xml.Name) *string {
+	return func(name xml.Name) *string {
+		switch name {
+		case propResourceType:
+			return fragment(`<collection xmlns="DAV:"/><calendar xmlns="` + nsCalDAV + `"/>`)
+		case propDisplayName:
+			return text("Calendar")
+		case propCurrentPrincipal:
+			return href(principalHref(t.userID))
+		case propSupportedCompSet:
+			return fragment(`<comp xmlns="` + nsCalDAV + `" name="VEVENT"/>`)
+		case propGetCTag:
+			return text(ctag(resources))
+		}
+		return nil
+	}
+}
+
+func (h *handler) resourceProp(r *resource) func(xml.Name) *string {
+	return func(name xml.Name) *string {
+		switch name {
+		case propResourceType:
+			return fragment("")
+		case propGetETag:
+			return text(r.etag())
+		case propGetContentType:
+			return text(calendarDataMediaType)
+		case propCalendarData:
+			data, err := r.calendarData()
+			if err != nil {
+				return nil
+			}
+			return text(data)
+		}
+		return nil
+	}
+}
+
+func (h *handler) report(w http.ResponseWriter, req *http.Request, t target) {
+	if !t.calendar || t.resource != "" {
+		http.Error(w, "REPORT is supported on the calendar collection only", http.StatusForbidden)
+		return
+	}
+
+	var body reportRequest
+	ok, err := decodeBody(req, &body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Can't parse body: %s", err.Error()), bodyStatus(err))
+		return
+	}
+	if !ok {
+		http.Error(w, "Can't parse body", http.StatusBadRequest)
+		return
+	}
+
+	props := resourceProps
+	if body.Prop != nil {
+		props = body.Prop.list()
+	}
+
+	resources, err := h.loadResources(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	m := newMultistatus()
+	switch body.XMLName {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		start, end, ranged, err := body.Filter.eventTimeRange()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, r := range resources {
+			if ranged && !overlaps(r, start, end) {
+				continue
+			}
+			m.addProps(r.href(t.userID), props, h.resourceProp(r))
+		}
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		byHref := make(map[string]*resource, len(resources))
+		for _, r := range resources {
+			byHref[r.href(t.userID)] = r
+		}
+		for _, requested := range body.Hrefs {
+			path := requested
+			if u, err := url.Parse(requested); err == nil {
+				path = u.Path
+			}
+			if r, ok := byHref[path]; ok {
+				m.addProps(requested, props, h.resourceProp(r))
+			} else {
+				m.addStatus(requested, http.StatusNotFound)
+			}
+		}
+	default:
+		http.Error(w, fmt.Sprintf("Unsupported report %s", body.XMLName.Local), http.StatusForbidden)
+		return
+	}
+	m.write(w)
+}
+
+// overlaps reports whether any occurrence of the resource intersects [start, end).
+// Zero bounds are open.
+func overlaps(r *resource, start time.Time, end time.Time) bool {
+	if start.IsZero() {
+		start = time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if end.IsZero() {
+		end = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+	}
+
+	for _, event := range r.events {
+		occurrences, err := event.Occurrences(start, end)
+		if err == nil && len(occurrences) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *handler) get(w http.ResponseWriter, req *http.Request, t target) {
+	if t.resource == "" {
+		http.Error(w, "Collections can't be fetched with GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r, err := h.loadResource(req.Context(), t.resource)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if r == nil {
+		http.Error(w, "Resource not found", http.StatusNotFound)
+		return
+	}
+
+	data, err := r.calendarData()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", calendarDataMediaType)
+	w.Header().Set("ETag", r.etag())
+	if req.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Write([]byte(data))
+}
+
+// put creates or replaces an event resource. All VEVENTs of the body must share one UID:
+// the series and the occurrences overriding it.
+func (h *handler) put(w http.ResponseWriter, req *http.Request, t target) {
+	if t.resource == "" {
+		http.Error(w, "Only event resources can be written", http.StatusMethodNotAllowed)
+		return
+	}
+
+	items, err := ical.Decode(req.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Can't parse calendar: %s", err.Error()), bodyStatus(err))
+		return
+	}
+	if len(items) == 0 {
+		http.Error(w, "No VEVENT found", http.StatusBadRequest)
+		return
+	}
+
+	var master *entity.Event
+	var overrides entity.Events
+	for _, item := range items {
+		if item.Err != nil {
+			http.Error(w, fmt.Sprintf("Invalid VEVENT %s: %s", item.UID, item.Err.Error()), http.StatusBadRequest)
+			return
+		}
+		if item.UID != items[0].UID {
+			http.Error(w, "All VEVENTs of a resource must have the same UID", http.StatusBadRequest)
+			return
+		}
+		item.Event.UserID = t.userID
+		if item.Event.IsOccurrence() {
+			overrides = append(overrides, *item.Event)
+		} else if master == nil {
+			master = item.Event
+		} else {
+			http.Error(w, "Resource has more than one series", http.StatusBadRequest)
+			return
+		}
+	}
+	if master == nil {
+		http.Error(w, "Resource has no series", http.StatusBadRequest)
+		return
+	}
+	// Resources are found by the UID in their names, so an event with another UID couldn't be found at this one.
+	if ical.EventID(strings.TrimSuffix(t.resource, resourceExt)) != master.ID {
+		http.Error(w, fmt.Sprintf("Resource %s must be named after the UID of its event", t.resource), http.StatusConflict)
+		return
+	}
+
+	current, err := h.loadResource(req.Context(), t.resource)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !checkPreconditions(req, current) {
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return
+	}
+
+	// The series and its overrides are saved at once, so a failed override leaves the resource as it was.
+	_, err = h.interactor.Save(req.Context(), master, overrides)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	stored, err := h.loadResource(req.Context(), t.resource)
+	if err == nil && stored != nil {
+		w.Header().Set("ETag", stored.etag())
+	}
+	if current == nil {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError writes the error of reading, saving or deleting a resource with the status of its kind.
+// Overlapping other events in the reject mode and a concurrent change of the event are conflicts,
+// writing to a calendar without the write role is forbidden.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrValidation):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, usecase.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, usecase.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, usecase.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, usecase.ErrBusinessRule):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *handler) delete(w http.ResponseWriter, req *http.Request, t target) {
+	if t.resource == "" {
+		http.Error(w, "Collections can't be deleted", http.StatusForbidden)
+		return
+	}
+
+	r, err := h.loadResource(req.Context(), t.resource)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if r == nil {
+		http.Error(w, "Resource not found", http.StatusNotFound)
+		return
+	}
+	if !checkPreconditions(req, r) {
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return
+	}
+
+	// Overrides are deleted together with their series, orphaned ones one by one.
+	toDelete := r.events[:1]
+	if r.master().SeriesID != nil {
+		toDelete = r.events
+	}
+	for _, event := range toDelete {
+		err = h.interactor.Delete(req.Context(), event.ID, 0)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,201 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	nsDAV            = "DAV:"
+	nsCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	nsCalendarServer = "http://calendarserver.org/ns/"
+)
+
+var (
+	propResourceType      = xml.Name{Space: nsDAV, Local: "resourcetype"}
+	propDisplayName       = xml.Name{Space: nsDAV, Local: "displayname"}
+	propGetETag           = xml.Name{Space: nsDAV, Local: "getetag"}
+	propGetContentType    = xml.Name{Space: nsDAV, Local: "getcontenttype"}
+	propCurrentPrincipal  = xml.Name{Space: nsDAV, Local: "current-user-principal"}
+	propPrincipalURL      = xml.Name{Space: nsDAV, Local: "principal-URL"}
+	propCalendarHomeSet   = xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}
+	propSupportedCompSet  = xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}
+	propCalendarData      = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+	propGetCTag           = xml.Name{Space: nsCalendarServer, Local: "getctag"}
+	principalProps        = []xml.Name{propResourceType, propDisplayName, propCurrentPrincipal, propPrincipalURL, propCalendarHomeSet}
+	calendarProps         = []xml.Name{propResourceType, propDisplayName, propCurrentPrincipal, propSupportedCompSet, propGetCTag}
+	resourceProps         = []xml.Name{propResourceType, propGetETag, propGetContentType}
+	calendarDataMediaType = "text/calendar; charset=utf-8"
+)
+
+// propNames is a DAV:prop element listing property names.
+type propNames struct {
+	Names []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+func (p *propNames) list() []xml.Name {
+	names := make([]xml.Name, 0, len(p.Names))
+	for _, name := range p.Names {
+		names = append(names, name.XMLName)
+	}
+	return names
+}
+
+// propfindRequest is the body of a PROPFIND request.
+type propfindRequest struct {
+	XMLName  xml.Name   `xml:"DAV: propfind"`
+	AllProp  *struct{}  `xml:"DAV: allprop"`
+	PropName *struct{}  `xml:"DAV: propname"`
+	Prop     *propNames `xml:"DAV: prop"`
+}
+
+// reportRequest is the body of a calendar-query or calendar-multiget REPORT.
+type reportRequest struct {
+	XMLName xml.Name
+	Prop    *propNames  `xml:"DAV: prop"`
+	Hrefs   []string    `xml:"DAV: href"`
+	Filter  *compFilter `xml:"urn:ietf:params:xml:ns:caldav filter>comp-filter"`
+}
+
+// compFilter is a CALDAV:comp-filter element.
+type compFilter struct {
+	Name      string       `xml:"name,attr"`
+	TimeRange *timeRange   `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	Filters   []compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+// timeRange is a CALDAV:time-range element.
+type timeRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+// eventTimeRange returns the time range of the VEVENT comp-filter, if any.
+// Open ends of the range are returned as zero times.
+func (f *compFilter) eventTimeRange() (time.Time, time.Time, bool, error) {
+	if f == nil {
+		return time.Time{}, time.Time{}, false, nil
+	}
+	if strings.EqualFold(f.Name, "VEVENT") && f.TimeRange != nil {
+		var start, end time.Time
+		var err error
+		if f.TimeRange.Start != "" {
+			start, err = time.Parse("20060102T150405Z", f.TimeRange.Start)
+			if err != nil {
+				return time.Time{}, time.Time{}, false, fmt.Errorf("invalid time-range start: %w", err)
+			}
+		}
+		if f.TimeRange.End != "" {
+			end, err = time.Parse("20060102T150405Z", f.TimeRange.End)
+			if err != nil {
+				return time.Time{}, time.Time{}, false, fmt.Errorf("invalid time-range end: %w", err)
+			}
+		}
+		return start, end, true, nil
+	}
+	for i := range f.Filters {
+		start, end, ok, err := f.Filters[i].eventTimeRange()
+		if ok || err != nil {
+			return start, end, ok, err
+		}
+	}
+	return time.Time{}, time.Time{}, false, nil
+}
+
+// decodeBody decodes an XML request body, an empty body leaves v untouched.
+func decodeBody(req *http.Request, v any) (bool, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return false, err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return false, nil
+	}
+	return true, xml.Unmarshal(body, v)
+}
+
+// bodyStatus is the status of a failure to read a request body: 413 for a body over
+// the size limit of the server and 400 for the rest.
+func bodyStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// multistatus builds a DAV:multistatus response.
+type multistatus struct {
+	buf bytes.Buffer
+}
+
+func newMultistatus() *multistatus {
+	m := &multistatus{}
+	m.buf.WriteString(xml.Header)
+	m.buf.WriteString(`<D:multistatus xmlns:D="DAV:">`)
+	return m
+}
+
+// addProps adds a response with the found properties and a 404 propstat for the missing ones.
+// Property values are XML fragments, nil means the property is missing.
+func (m *multistatus) addProps(href string, props []xml.Name, value func(xml.Name) *string) {
+	var found, missing strings.Builder
+	for _, name := range props {
+		v := value(name)
+		if v == nil {
+			fmt.Fprintf(&missing, `<%s xmlns="%s"/>`, name.Local, escape(name.Space))
+			continue
+		}
+		fmt.Fprintf(&found, `<%s xmlns="%s">%s</%s>`, name.Local, escape(name.Space), *v, name.Local)
+	}
+
+	m.buf.WriteString("<D:response><D:href>" + escape(href) + "</D:href>")
+	if found.Len() > 0 {
+		m.buf.WriteString("<D:propstat><D:prop>" + found.String() + "</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat>")
+	}
+	if missing.Len() > 0 {
+		m.buf.WriteString("<D:propstat><D:prop>" + missing.String() + "</D:prop><D:status>HTTP/1.1 404 Not Found</D:status></D:propstat>")
+	}
+	m.buf.WriteString("</D:response>")
+}
+
+// addStatus adds a response with a status only.
+func (m *multistatus) addStatus(href string, status int) {
+	fmt.Fprintf(&m.buf, "<D:response><D:href>%s</D:href><D:status>HTTP/1.1 %d %s</D:status></D:response>",
+		escape(href), status, http.StatusText(status))
+}
+
+func (m *multistatus) write(w http.ResponseWriter) {
+	m.buf.WriteString("</D:multistatus>")
+	w.Header().Set("Content-Type", `application/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusMultiStatus)
+	w.Write(m.buf.Bytes())
+}
+
+// escape escapes text for XML character data and attribute values.
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func text(s string) *string {
+	v := escape(s)
+	return &v
+}
+
+func fragment(s string) *string {
+	return &s
+}
+
+func href(s string) *string {
+	return fragment(`<href xmlns="DAV:">` + escape(s) + `</href>`)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"L2/develop/dev11/internal/auth"
+	"net/http"
+)
+
+// TokenRefresher exchanges refresh tokens for new token pairs.
+type TokenRefresher interface {
+	Refresh(token string) (*auth.Pair, error)
+}
+
+type authHandlers struct {
+	tokens TokenRefresher
+}
+
+func NewAuthHandlers(tokens TokenRefresher) *authHandlers {
+	return &authHandlers{
+		tokens: tokens,
+	}
+}
+
+// RefreshHandler exchanges the refresh_token for a new access and refresh token pair.
+func (h *authHandlers) RefreshHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	token := req.FormValue("refresh_token")
+	if token == "" {
+		jsonError(w, "Empty refresh_token", http.StatusBadRequest)
+		return
+	}
+
+	pair, err := h.tokens.Refresh(token)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeResult(w, http.StatusOK, pair)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/usecase"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// BatchPath applies a batch of operations on events in one transaction.
+const BatchPath = "/events/batch"
+
+// batchResult is the outcome of an operation of a batch with the status it would have as a request of its own.
+// The operations that succeeded in a failed batch have 424 Failed Dependency, as they aren't applied either.
+type batchResult struct {
+	Op        entity.BatchOp `json:"op"`
+	ID        uuid.UUID      `json:"id"`
+	Status    int            `json:"status"`
+	Version   int64          `json:"version,omitempty"`
+	Conflicts entity.Events  `json:"conflicts,omitempty"`
+	Current   *entity.Event  `json:"current,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// batchFailure is the body of a response refusing a batch with the outcome of every operation.
+type batchFailure struct {
+	Error   string        `json:"error"`
+	Results []batchResult `json:"results"`
+}
+
+// BatchHandler applies the operations of a JSON batch at once and returns the outcome of every operation.
+// With dry_run=true the operations are only checked. If an operation fails, nothing is applied and
+// the batch is refused with the status of the first failed operation.
+func (h *eventHandlers) BatchHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	dryRun := false
+	if value := req.URL.Query().Get("dry_run"); value != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			jsonError(w, fmt.Sprintf("Can't parse dry_run: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType != "application/json" {
+		jsonError(w, "Can't parse body: batch must be application/json", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse body: %s", err.Error()), bodyStatus(err))
+		return
+	}
+	operations, err := entity.ParseJSONBatch(body)
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse body: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	// Events created without an ID get a random one, like on create.
+	for _, operation := range operations {
+		if operation.Op == entity.BatchCreate && operation.Event.ID == uuid.Nil {
+			operation.Event.ID = uuid.New()
+		}
+	}
+
+	results, err := h.interactor.Batch(req.Context(), operations, dryRun)
+	var batchErr *usecase.BatchError
+	if errors.As(err, &batchErr) {
+		status, response := 0, batchFailure{Error: batchErr.Error()}
+		for n, result := range batchErr.Results {
+			item := newBatchResult(req, &operations[n], result, false)
+			switch {
+			case result.Err == nil:
+				item.Status = http.StatusFailedDependency
+			case status == 0:
+				status = item.Status
+			}
+			response.Results = append(response.Results, item)
+		}
+		writeJSON(w, status, response)
+		return
+	}
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	response := make([]batchResult, 0, len(results))
+	for n, result := range results {
+		response = append(response, newBatchResult(req, &operations[n], result, !dryRun))
+	}
+	writeResult(w, http.StatusOK, response)
+}
+
+// newBatchResult returns the outcome of the operation, the new version is known once it is applied.
+func newBatchResult(req *http.Request, operation *entity.BatchOperation, result usecase.BatchResult, applied bool) batchResult {
+	response := batchResult{Op: operation.Op, ID: operation.EventID(), Conflicts: result.Conflicts}
+	switch {
+	case result.Err != nil:
+		response.Status, response.Error = errorStatus(req, result.Err), result.Err.Error()
+		var overlapErr *usecase.ConflictError
+		var versionErr *usecase.VersionConflictError
+		switch {
+		case errors.As(result.Err, &overlapErr):
+			response.Conflicts = overlapErr.Conflicts
+		case errors.As(result.Err, &versionErr):
+			response.Current = versionErr.Current
+		}
+	case operation.Op == entity.BatchCreate:
+		response.Status = http.StatusCreated
+	default:
+		response.Status = http.StatusOK
+	}
+	if applied && operation.Event != nil {
+		response.Version = operation.Event.Version
+	}
+
+	return response
+}
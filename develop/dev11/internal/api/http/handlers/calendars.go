@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/usecase"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type calendarHandlers struct {
+	interactor usecase.CalendarInteractor
+}
+
+func NewCalendarHandlers(interactor usecase.CalendarInteractor) *calendarHandlers {
+	return &calendarHandlers{
+		interactor: interactor,
+	}
+}
+
+// ListHandler returns the calendars visible to the caller with the role of the caller.
+func (h *calendarHandlers) ListHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	calendars, err := h.interactor.List(req.Context())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeResult(w, http.StatusOK, calendars)
+}
+
+func (h *calendarHandlers) CreateHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	calendar, err := parseFormCalendar(req)
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse body: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err = h.interactor.Create(req.Context(), calendar)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeResult(w, http.StatusCreated, calendar)
+}
+
+func (h *calendarHandlers) UpdateHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	calendar, err := parseFormCalendar(req)
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse body: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err = h.interactor.Update(req.Context(), calendar)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *calendarHandlers) DeleteHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodDelete {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	calendarID, err := uuid.Parse(req.URL.Query().Get("id"))
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse id: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err = h.interactor.Delete(req.Context(), calendarID)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// SharesHandler returns the users the calendar is shared with.
+func (h *calendarHandlers) SharesHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	calendarID, err := uuid.Parse(req.URL.Query().Get("calendar_id"))
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse calendar_id: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	shares, err := h.interactor.Shares(req.Context(), calendarID)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeResult(w, http.StatusOK, shares)
+}
+
+// ShareHandler grants the role to the member_id user in the calendar_id calendar.
+func (h *calendarHandlers) ShareHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	calendarID, memberID, err := parseMember(req)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	role, err := entity.ParseRole(req.FormValue("role"))
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse role: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	share := &entity.Share{CalendarID: calendarID, UserID: memberID, Role: role}
+	err = h.interactor.Share(req.Context(), share)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeResult(w, http.StatusOK, share)
+}
+
+// UnshareHandler revokes the role of the member_id user in the calendar_id calendar.
+func (h *calendarHandlers) UnshareHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodDelete {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	calendarID, memberID, err := parseMember(req)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.interactor.Unshare(req.Context(), calendarID, memberID)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// parseFormCalendar parses the id and the name of a calendar.
+func parseFormCalendar(req *http.Request) (*entity.Calendar, error) {
+	id, err := uuid.Parse(req.FormValue("id"))
+	if err != nil {
+		return nil, err
+	}
+
+	name := req.FormValue("name")
+	if name == "" {
+		return nil, fmt.Errorf("empty name")
+	}
+
+	return &entity.Calendar{ID: id, Name: name}, nil
+}
+
+// parseMember parses the calendar_id and member_id parameters of sharing.
+func parseMember(req *http.Request) (uuid.UUID, uuid.UUID, error) {
+	calendarID, err := uuid.Parse(req.FormValue("calendar_id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("Can't parse calendar_id: %s", err.Error())
+	}
+
+	memberID, err := uuid.Parse(req.FormValue("member_id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("Can't parse member_id: %s", err.Error())
+	}
+
+	return calendarID, memberID, nil
+}
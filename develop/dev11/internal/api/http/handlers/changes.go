@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/usecase"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StreamPath is the route of the server-sent events of changes, it isn't limited by the request timeout.
+const StreamPath = "/events/stream"
+
+// pingInterval is how often an idle stream sends a comment, so that proxies keep the connection open.
+const pingInterval = 15 * time.Second
+
+type changeHandlers struct {
+	interactor usecase.ChangeInteractor
+	webhooks   usecase.WebhookInteractor
+}
+
+func NewChangeHandlers(interactor usecase.ChangeInteractor, webhooks usecase.WebhookInteractor) *changeHandlers {
+	return &changeHandlers{
+		interactor: interactor,
+		webhooks:   webhooks,
+	}
+}
+
+// StreamHandler streams the changes of the events the caller can read as server-sent events:
+// the ID of an event is the ID of the change, its type the kind of the change and its data the change.
+// A client reconnecting with Last-Event-ID, or the last_event_id parameter, gets the changes it missed first.
+func (h *changeHandlers) StreamHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	lastEventID := req.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = req.URL.Query().Get("last_event_id")
+	}
+	var afterID int64
+	if lastEventID != "" {
+		var err error
+		afterID, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || afterID < 0 {
+			jsonError(w, fmt.Sprintf("Can't parse last event id: %q", lastEventID), http.StatusBadRequest)
+			return
+		}
+	}
+
+	stream, err := h.interactor.Stream(req.Context(), afterID)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	rc.Flush()
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case change, ok := <-stream.C:
+			if !ok {
+				// The client resumes from the last change it got after a failure.
+				if stream.Err() != nil {
+					fmt.Fprintf(w, "event: error\ndata: %q\n\n", stream.Err().Error())
+					rc.Flush()
+				}
+				return
+			}
+			err = writeChange(w, &change)
+		case <-ping.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// writeChange writes the change as a server-sent event.
+func writeChange(w http.ResponseWriter, change *entity.Change) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", change.ID, change.Kind, data)
+	return err
+}
+
+// WebhooksHandler returns the webhooks of the caller.
+func (h *changeHandlers) WebhooksHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	webhooks, err := h.webhooks.List(req.Context())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeResult(w, http.StatusOK, webhooks)
+}
+
+// CreateWebhookHandler registers the url webhook of the caller signed with the secret,
+// a random one if none is given. The secret is returned only here.
+func (h *changeHandlers) CreateWebhookHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	err := req.ParseForm()
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse body: %s", err.Error()), bodyStatus(err))
+		return
+	}
+	webhook := &entity.Webhook{
+		URL:    req.PostFormValue("url"),
+		Secret: req.PostFormValue("secret"),
+	}
+
+	err = h.webhooks.Create(req.Context(), webhook)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeResult(w, http.StatusCreated, webhook)
+}
+
+// DeleteWebhookHandler deletes the id webhook of the caller.
+func (h *changeHandlers) DeleteWebhookHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodDelete {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	webhookID, err := uuid.Parse(req.URL.Query().Get("id"))
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse id: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	err = h.webhooks.Delete(req.Context(), webhookID)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -2,6 +2,10 @@
 package handlers
 
 import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/usecase"
+	"context"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -11,3 +15,60 @@ import (
 func NotImplementedHandler(c *gin.Context) {
 	c.AbortWithStatus(http.StatusMethodNotAllowed)
 }
+
+// overlapConflict is the body of a response refusing to save an event overlapping other events.
+type overlapConflict struct {
+	Error     string        `json:"error"`
+	Conflicts entity.Events `json:"conflicts"`
+}
+
+// versionConflict is the body of a response refusing a write based on a stale version.
+type versionConflict struct {
+	Error   string        `json:"error"`
+	Current *entity.Event `json:"current"`
+}
+
+// writeError writes an error of an interactor as {"error": ...} with the status of errorStatus.
+// Conflicts of an event carry the overlapping events or, for a stale version, the current event.
+func writeError(w http.ResponseWriter, req *http.Request, err error) {
+	status := errorStatus(req, err)
+	var overlapErr *usecase.ConflictError
+	var versionErr *usecase.VersionConflictError
+	switch {
+	case errors.As(err, &overlapErr):
+		writeJSON(w, status, overlapConflict{Error: overlapErr.Error(), Conflicts: overlapErr.Conflicts})
+	case errors.As(err, &versionErr):
+		setETag(w, versionErr.Current)
+		writeJSON(w, status, versionConflict{Error: versionErr.Error(), Current: versionErr.Current})
+	case status == http.StatusServiceUnavailable && !errors.Is(err, usecase.ErrBusinessRule):
+		jsonError(w, "request timed out", status)
+	default:
+		jsonError(w, err.Error(), status)
+	}
+}
+
+// errorStatus is the status of an error of an interactor by its kind: 400 for invalid input, 401 and 403
+// for access errors, 404 for missing entities, 409 for conflicts, 503 for violated business rules
+// and requests running out of time and 500 for the rest. A stale version is 412 Precondition Failed
+// if the version was required by If-Match.
+func errorStatus(req *http.Request, err error) int {
+	var versionErr *usecase.VersionConflictError
+	switch {
+	case errors.As(err, &versionErr) && req.Header.Get("If-Match") != "":
+		return http.StatusPreconditionFailed
+	case errors.Is(err, usecase.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, usecase.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, usecase.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, usecase.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, usecase.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, usecase.ErrBusinessRule), errors.Is(req.Context().Err(), context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -4,12 +4,22 @@ import (
 	"L2/develop/dev11/internal/entity"
 	"L2/develop/dev11/internal/usecase"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// savedEvent is the result of creating or updating an event.
+// Conflicts lists the events it overlaps when conflicts are flagged.
+type savedEvent struct {
+	ID        uuid.UUID     `json:"id"`
+	Conflicts entity.Events `json:"conflicts,omitempty"`
+}
+
 type eventHandlers struct {
 	interactor usecase.EventInteractor
 }
@@ -22,106 +32,132 @@ func NewEventHandlers(interactor usecase.EventInteractor) *eventHandlers {
 
 func (h *eventHandlers) CreateHandler(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodPost {
-		http.Error(w, "Invalid method", http.StatusBadRequest)
+		jsonError(w, "Invalid method", http.StatusBadRequest)
 		return
 	}
 
-	err := req.ParseForm()
+	event, err := parseEvent(req)
 	if err != nil {
-		http.Error(w, "Can't parse form", http.StatusBadRequest)
+		jsonError(w, fmt.Sprintf("Can't parse body: %s", err.Error()), bodyStatus(err))
 		return
 	}
 
-	event, err := entity.ParseFormEvent(req.Form)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Can't parse body: %s", err.Error()), http.StatusBadRequest)
+	h.create(w, req, event)
+}
+
+func (h *eventHandlers) UpdateHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
 		return
 	}
 
-	err = h.interactor.Create(req.Context(), event)
+	event, err := parseEvent(req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		jsonError(w, fmt.Sprintf("Can't parse body: %s", err.Error()), bodyStatus(err))
+		return
+	}
+	if event.ID == uuid.Nil {
+		jsonError(w, "Can't parse body: empty id", http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	h.update(w, req, event)
 }
 
-func (h *eventHandlers) UpdateHandler(w http.ResponseWriter, req *http.Request) {
-	if req.Method != http.MethodPut {
-		http.Error(w, "Invalid method", http.StatusBadRequest)
+func (h *eventHandlers) DeleteHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodDelete {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
 		return
 	}
 
-	err := req.ParseForm()
+	eventID, err := uuid.Parse(req.URL.Query().Get("id"))
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Can't parse form: %s", err.Error()), http.StatusBadRequest)
+		jsonError(w, fmt.Sprintf("Can't parse id: %s", err.Error()), http.StatusBadRequest)
 		return
 	}
 
-	event, err := entity.ParseFormEvent(req.Form)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Can't parse body: %s", err.Error()), http.StatusBadRequest)
-		return
+	h.delete(w, req, eventID, http.StatusOK)
+}
+
+// create saves a new event, an event without an ID gets a random one.
+func (h *eventHandlers) create(w http.ResponseWriter, req *http.Request, event *entity.Event) {
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
 	}
 
-	err = h.interactor.Update(req.Context(), event)
+	conflicts, err := h.interactor.Create(req.Context(), event)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, req, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	setETag(w, event)
+	w.Header().Set("Location", EventsPath+"/"+event.ID.String())
+	writeResult(w, http.StatusCreated, savedEvent{ID: event.ID, Conflicts: conflicts})
 }
 
-func (h *eventHandlers) DeleteHandler(w http.ResponseWriter, req *http.Request) {
-	if req.Method != http.MethodDelete {
-		http.Error(w, "Invalid method", http.StatusBadRequest)
+// update saves the event if the version required by If-Match still matches.
+func (h *eventHandlers) update(w http.ResponseWriter, req *http.Request, event *entity.Event) {
+	var err error
+	event.Version, err = parseIfMatch(req)
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse If-Match: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	conflicts, err := h.interactor.Update(req.Context(), event)
+	if err != nil {
+		writeError(w, req, err)
 		return
 	}
 
-	userID, err := uuid.Parse(req.URL.Query().Get("user_id"))
+	setETag(w, event)
+	writeResult(w, http.StatusOK, savedEvent{ID: event.ID, Conflicts: conflicts})
+}
+
+// delete deletes the event if the version required by If-Match still matches and responds with status.
+func (h *eventHandlers) delete(w http.ResponseWriter, req *http.Request, eventID uuid.UUID, status int) {
+	version, err := parseIfMatch(req)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Can't parse user_id: %s", err.Error()), http.StatusBadRequest)
+		jsonError(w, fmt.Sprintf("Can't parse If-Match: %s", err.Error()), http.StatusBadRequest)
 		return
 	}
 
-	err = h.interactor.Delete(req.Context(), userID)
+	err = h.interactor.Delete(req.Context(), eventID, version)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, req, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
 }
 
 func (h *eventHandlers) GetForDayHandler(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusBadRequest)
+		jsonError(w, "Invalid method", http.StatusBadRequest)
 		return
 	}
 
-	userID, err := uuid.Parse(req.URL.Query().Get("user_id"))
+	date, err := parseDateQuery(req.URL.Query())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Can't parse user_id: %s", err.Error()), http.StatusBadRequest)
+		jsonError(w, fmt.Sprintf("Can't parse date: %s", err.Error()), http.StatusBadRequest)
 		return
 	}
-
-	date, err := time.Parse("2006-01-02", req.URL.Query().Get("date"))
+	tags, err := entity.ParseTagFilter(req.URL.Query())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Can't parse date: %s", err.Error()), http.StatusBadRequest)
+		jsonError(w, fmt.Sprintf("Can't parse tag: %s", err.Error()), http.StatusBadRequest)
 		return
 	}
 
-	events, err := h.interactor.GetForDay(req.Context(), userID, date)
+	events, err := h.interactor.GetForDay(req.Context(), date, tags...)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, req, err)
 		return
 	}
 
 	body, err := events.ToJSON()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		jsonError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -130,31 +166,30 @@ func (h *eventHandlers) GetForDayHandler(w http.ResponseWriter, req *http.Reques
 
 func (h *eventHandlers) GetForWeekHandler(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusBadRequest)
+		jsonError(w, "Invalid method", http.StatusBadRequest)
 		return
 	}
 
-	userID, err := uuid.Parse(req.URL.Query().Get("user_id"))
+	date, err := parseDateQuery(req.URL.Query())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Can't parse user_id: %s", err.Error()), http.StatusBadRequest)
+		jsonError(w, fmt.Sprintf("Can't parse date: %s", err.Error()), http.StatusBadRequest)
 		return
 	}
-
-	date, err := time.Parse("2006-01-02", req.URL.Query().Get("date"))
+	tags, err := entity.ParseTagFilter(req.URL.Query())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Can't parse date: %s", err.Error()), http.StatusBadRequest)
+		jsonError(w, fmt.Sprintf("Can't parse tag: %s", err.Error()), http.StatusBadRequest)
 		return
 	}
 
-	events, err := h.interactor.GetForWeek(req.Context(), userID, date)
+	events, err := h.interactor.GetForWeek(req.Context(), date, tags...)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, req, err)
 		return
 	}
 
 	body, err := events.ToJSON()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		jsonError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -163,33 +198,169 @@ func (h *eventHandlers) GetForWeekHandler(w http.ResponseWriter, req *http.Reque
 
 func (h *eventHandlers) GetForMonthHandler(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusBadRequest)
+		jsonError(w, "Invalid method", http.StatusBadRequest)
 		return
 	}
 
-	userID, err := uuid.Parse(req.URL.Query().Get("user_id"))
+	date, err := parseDateQuery(req.URL.Query())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Can't parse user_id: %s", err.Error()), http.StatusBadRequest)
+		jsonError(w, fmt.Sprintf("Can't parse date: %s", err.Error()), http.StatusBadRequest)
 		return
 	}
-
-	date, err := time.Parse("2006-01-02", req.URL.Query().Get("date"))
+	tags, err := entity.ParseTagFilter(req.URL.Query())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Can't parse date: %s", err.Error()), http.StatusBadRequest)
+		jsonError(w, fmt.Sprintf("Can't parse tag: %s", err.Error()), http.StatusBadRequest)
 		return
 	}
 
-	events, err := h.interactor.GetForMonth(req.Context(), userID, date)
+	events, err := h.interactor.GetForMonth(req.Context(), date, tags...)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, req, err)
 		return
 	}
 
 	body, err := events.ToJSON()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		jsonError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Write(body)
 }
+
+// FreeBusyHandler returns the busy intervals of the user within [from, to) and the free slots
+// lasting at least duration. from and to are dates or times in the time zone given by tz.
+func (h *eventHandlers) FreeBusyHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	// The busy time of another user is computed from the calendars of the user shared with the caller.
+	query := req.URL.Query()
+	userID, _ := usecase.CallerFromContext(req.Context())
+	if value := query.Get("user_id"); value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			jsonError(w, fmt.Sprintf("Can't parse user_id: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		userID = parsed
+	}
+
+	loc, err := time.LoadLocation(query.Get("tz"))
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse tz: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	from, err := entity.ParseLocalTime(query.Get("from"), loc)
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse from: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	to, err := entity.ParseLocalTime(query.Get("to"), loc)
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse to: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	if !to.After(from) {
+		jsonError(w, "to must be after from", http.StatusBadRequest)
+		return
+	}
+
+	var minFree time.Duration
+	if query.Get("duration") != "" {
+		minFree, err = time.ParseDuration(query.Get("duration"))
+		if err != nil {
+			jsonError(w, fmt.Sprintf("Can't parse duration: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+	}
+
+	freeBusy, err := h.interactor.FreeBusy(req.Context(), userID, from, to, minFree)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeResult(w, http.StatusOK, freeBusy)
+}
+
+// SearchHandler returns a page of the user's events within [from, to) filtered by title, q or tag,
+// sorted by sort. The next page is requested with the returned next_cursor.
+func (h *eventHandlers) SearchHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	query, err := entity.ParseEventQuery(req.URL.Query())
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse query: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.interactor.Search(req.Context(), query)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeResult(w, http.StatusOK, page)
+}
+
+// TagsHandler returns the tags of the events of the user with the number of events carrying each of them.
+// The tags of another user are counted over the calendars of the user shared with the caller.
+func (h *eventHandlers) TagsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := usecase.CallerFromContext(req.Context())
+	if value := req.URL.Query().Get("user_id"); value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			jsonError(w, fmt.Sprintf("Can't parse user_id: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		userID = parsed
+	}
+
+	tags, err := h.interactor.GetTags(req.Context(), userID)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeResult(w, http.StatusOK, tags)
+}
+
+// parseEvent parses the event of a JSON or form-encoded body, chosen by the Content-Type of the request.
+func parseEvent(req *http.Request) (*entity.Event, error) {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		return entity.ParseJSONEvent(body)
+	}
+
+	err := req.ParseForm()
+	if err != nil {
+		return nil, err
+	}
+	return entity.ParseFormEvent(req.Form)
+}
+
+// parseDateQuery parses the date query parameter in the time zone given by tz, UTC by default,
+// so that day, week and month windows are computed in the user's zone.
+func parseDateQuery(query url.Values) (time.Time, error) {
+	loc, err := time.LoadLocation(query.Get("tz"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid tz: %w", err)
+	}
+
+	return time.ParseInLocation("2006-01-02", query.Get("date"), loc)
+}
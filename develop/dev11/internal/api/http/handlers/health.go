@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// checkTimeout limits a single readiness check.
+const checkTimeout = 2 * time.Second
+
+// Check reports whether a dependency the service needs, like the database, is ready.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type healthHandlers struct {
+	checks []Check
+}
+
+func NewHealthHandlers(checks []Check) *healthHandlers {
+	return &healthHandlers{
+		checks: checks,
+	}
+}
+
+// LiveHandler reports that the process serves requests.
+func (h *healthHandlers) LiveHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
+
+// ReadyHandler runs the checks and reports 503 with the failed ones unless all of them pass.
+func (h *healthHandlers) ReadyHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	status, code := "ready", http.StatusOK
+	results := make(map[string]string, len(h.checks))
+	for _, check := range h.checks {
+		ctx, cancel := context.WithTimeout(req.Context(), checkTimeout)
+		err := check.Check(ctx)
+		cancel()
+
+		results[check.Name] = "ok"
+		if err != nil {
+			results[check.Name] = err.Error()
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+	}
+
+	writeJSON(w, code, map[string]any{"status": status, "checks": results})
+}
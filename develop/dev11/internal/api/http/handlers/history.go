@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"L2/develop/dev11/internal/usecase"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// HistoryPrefix is the subtree of the history of events: HistoryPrefix{id}/history lists the revisions
+// of an event and HistoryPrefix{id}/revert reverts it to one of them.
+const HistoryPrefix = "/events/"
+
+type historyHandlers struct {
+	interactor usecase.HistoryInteractor
+}
+
+func NewHistoryHandlers(interactor usecase.HistoryInteractor) *historyHandlers {
+	return &historyHandlers{
+		interactor: interactor,
+	}
+}
+
+// HistoryHandler serves the subtree of the history of events.
+func (h *historyHandlers) HistoryHandler(w http.ResponseWriter, req *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, HistoryPrefix), "/")
+	eventID, err := uuid.Parse(id)
+	if err != nil {
+		jsonError(w, "Unknown event", http.StatusNotFound)
+		return
+	}
+
+	switch action {
+	case "history":
+		h.history(w, req, eventID)
+	case "revert":
+		h.revert(w, req, eventID)
+	default:
+		jsonError(w, "Unknown resource", http.StatusNotFound)
+	}
+}
+
+// history returns the revisions of the event, oldest first.
+func (h *historyHandlers) history(w http.ResponseWriter, req *http.Request, eventID uuid.UUID) {
+	if req.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	revisions, err := h.interactor.List(req.Context(), eventID)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeResult(w, http.StatusOK, revisions)
+}
+
+// revert updates the event to how it was after the revision, If-Match makes it conditional like /update_event.
+func (h *historyHandlers) revert(w http.ResponseWriter, req *http.Request, eventID uuid.UUID) {
+	if req.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	err := req.ParseForm()
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse body: %s", err.Error()), bodyStatus(err))
+		return
+	}
+	revisionID, err := strconv.ParseInt(req.PostFormValue("revision"), 10, 64)
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse revision: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	version, err := parseIfMatch(req)
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse If-Match: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	event, conflicts, err := h.interactor.Revert(req.Context(), eventID, revisionID, version)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	setETag(w, event)
+	writeResult(w, http.StatusOK, savedEvent{ID: event.ID, Conflicts: conflicts})
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/ical"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// importResult is the outcome of importing a single VEVENT.
+type importResult struct {
+	UID       string        `json:"uid,omitempty"`
+	ID        uuid.UUID     `json:"id,omitempty"`
+	Conflicts entity.Events `json:"conflicts,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+func (h *eventHandlers) ExportICSHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	// Every calendar visible to the caller is exported unless calendars are given.
+	var calendarIDs []uuid.UUID
+	for _, value := range req.URL.Query()["calendar_id"] {
+		calendarID, err := uuid.Parse(value)
+		if err != nil {
+			jsonError(w, fmt.Sprintf("Can't parse calendar_id: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		calendarIDs = append(calendarIDs, calendarID)
+	}
+
+	events, err := h.interactor.GetAll(req.Context(), calendarIDs...)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="calendar.ics"`)
+	err = ical.Encode(w, *events)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// ImportICSHandler imports the events of an .ics file, uploaded either as the file field
+// of a multipart form or as the request body. Every VEVENT is reported separately.
+func (h *eventHandlers) ImportICSHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	// Events are imported into the default calendar of the caller unless a calendar is given.
+	var calendarID uuid.UUID
+	if value := req.FormValue("calendar_id"); value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			jsonError(w, fmt.Sprintf("Can't parse calendar_id: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		calendarID = parsed
+	}
+
+	var body io.Reader = req.Body
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := req.FormFile("file")
+		if err != nil {
+			jsonError(w, fmt.Sprintf("Can't read file: %s", err.Error()), bodyStatus(err))
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	items, err := ical.Decode(body)
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse calendar: %s", err.Error()), bodyStatus(err))
+		return
+	}
+
+	// Series go first, so that their overridden occurrences can be applied to them.
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Event != nil && !items[i].Event.IsOccurrence() &&
+			(items[j].Event == nil || items[j].Event.IsOccurrence())
+	})
+
+	results := make([]importResult, 0, len(items))
+	for _, item := range items {
+		result := importResult{UID: item.UID}
+		if item.Err != nil {
+			result.Error = item.Err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		event := item.Event
+		event.CalendarID = calendarID
+		if event.IsOccurrence() {
+			result.Conflicts, err = h.interactor.Update(req.Context(), event)
+		} else {
+			result.Conflicts, err = h.interactor.Create(req.Context(), event)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.ID = event.ID
+		}
+		results = append(results, result)
+	}
+
+	writeResult(w, http.StatusOK, results)
+}
@@ -14,4 +14,51 @@ type EventHandlers interface {
 	GetForDayHandler(http.ResponseWriter, *http.Request)
 	GetForWeekHandler(http.ResponseWriter, *http.Request)
 	GetForMonthHandler(http.ResponseWriter, *http.Request)
+	FreeBusyHandler(http.ResponseWriter, *http.Request)
+	SearchHandler(http.ResponseWriter, *http.Request)
+	TagsHandler(http.ResponseWriter, *http.Request)
+	ExportICSHandler(http.ResponseWriter, *http.Request)
+	ImportICSHandler(http.ResponseWriter, *http.Request)
+	CollectionHandler(http.ResponseWriter, *http.Request)
+	ResourceHandler(http.ResponseWriter, *http.Request)
+	BatchHandler(http.ResponseWriter, *http.Request)
+}
+
+type CalendarHandlers interface {
+	ListHandler(http.ResponseWriter, *http.Request)
+	CreateHandler(http.ResponseWriter, *http.Request)
+	UpdateHandler(http.ResponseWriter, *http.Request)
+	DeleteHandler(http.ResponseWriter, *http.Request)
+	SharesHandler(http.ResponseWriter, *http.Request)
+	ShareHandler(http.ResponseWriter, *http.Request)
+	UnshareHandler(http.ResponseWriter, *http.Request)
+}
+
+type AuthHandlers interface {
+	RefreshHandler(http.ResponseWriter, *http.Request)
+}
+
+type HealthHandlers interface {
+	LiveHandler(http.ResponseWriter, *http.Request)
+	ReadyHandler(http.ResponseWriter, *http.Request)
+}
+
+type ChangeHandlers interface {
+	StreamHandler(http.ResponseWriter, *http.Request)
+	WebhooksHandler(http.ResponseWriter, *http.Request)
+	CreateWebhookHandler(http.ResponseWriter, *http.Request)
+	DeleteWebhookHandler(http.ResponseWriter, *http.Request)
+}
+
+type TrashHandlers interface {
+	TrashHandler(http.ResponseWriter, *http.Request)
+	RestoreHandler(http.ResponseWriter, *http.Request)
+}
+
+type HistoryHandlers interface {
+	HistoryHandler(http.ResponseWriter, *http.Request)
+}
+
+type InvitationHandlers interface {
+	RSVPHandler(http.ResponseWriter, *http.Request)
 }
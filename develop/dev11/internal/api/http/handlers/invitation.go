@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/usecase"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type invitationHandlers struct {
+	interactor usecase.InvitationInteractor
+}
+
+func NewInvitationHandlers(interactor usecase.InvitationInteractor) *invitationHandlers {
+	return &invitationHandlers{
+		interactor: interactor,
+	}
+}
+
+// RSVPHandler sets the status of the caller among the attendees of the id event and returns the event.
+func (h *invitationHandlers) RSVPHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	err := req.ParseForm()
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse body: %s", err.Error()), bodyStatus(err))
+		return
+	}
+	eventID, err := uuid.Parse(req.PostFormValue("id"))
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse id: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	status, err := entity.ParseAttendeeStatus(req.PostFormValue("status"))
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse status: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.interactor.Respond(req.Context(), eventID, status)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	setETag(w, event)
+	writeResult(w, http.StatusOK, event)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// setETag sets the entity tag of the event, which is its version.
+func setETag(w http.ResponseWriter, event *entity.Event) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(event.Version, 10)))
+}
+
+// unmatchedVersion is the version required by an entity tag no event can match.
+const unmatchedVersion = -1
+
+// parseIfMatch returns the version required by the If-Match header, or zero if any version matches.
+// If-Match uses the strong comparison, so a weak tag W/"N" matches no version and the write fails with 412.
+func parseIfMatch(req *http.Request) (int64, error) {
+	header := strings.TrimSpace(req.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, errors.New("only a single entity tag is supported")
+	}
+
+	weak := strings.HasPrefix(header, "W/")
+	tag, err := strconv.Unquote(strings.TrimPrefix(header, "W/"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid entity tag %s", header)
+	}
+	if weak {
+		return unmatchedVersion, nil
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("unknown entity tag %s", header)
+	}
+
+	return version, nil
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// EventsPath is the collection of the versioned REST resource tree of events,
+// an event is the resource EventsPath/{id}.
+const EventsPath = "/api/v1/events"
+
+// CollectionHandler serves the collection of events: POST creates an event, GET searches
+// the events with the parameters of SearchHandler.
+func (h *eventHandlers) CollectionHandler(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodPost:
+		event, err := parseEvent(req)
+		if err != nil {
+			jsonError(w, fmt.Sprintf("Can't parse body: %s", err.Error()), bodyStatus(err))
+			return
+		}
+		h.create(w, req, event)
+	case http.MethodGet:
+		h.SearchHandler(w, req)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// ResourceHandler serves a single event: GET returns it with its ETag, PUT replaces it
+// and DELETE deletes it. PUT and DELETE accept If-Match like /update_event and /delete_event.
+func (h *eventHandlers) ResourceHandler(w http.ResponseWriter, req *http.Request) {
+	eventID, err := uuid.Parse(strings.TrimPrefix(req.URL.Path, EventsPath+"/"))
+	if err != nil {
+		jsonError(w, "Unknown event", http.StatusNotFound)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		event, err := h.interactor.Get(req.Context(), eventID)
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+		setETag(w, event)
+		writeResult(w, http.StatusOK, event)
+	case http.MethodPut:
+		event, err := parseEvent(req)
+		if err != nil {
+			jsonError(w, fmt.Sprintf("Can't parse body: %s", err.Error()), bodyStatus(err))
+			return
+		}
+		if event.ID != uuid.Nil && event.ID != eventID {
+			jsonError(w, "Can't parse body: id doesn't match the path", http.StatusBadRequest)
+			return
+		}
+		event.ID = eventID
+		h.update(w, req, event)
+	case http.MethodDelete:
+		h.delete(w, req, eventID, http.StatusNoContent)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+	}
+}
+
+// methodNotAllowed rejects a method the resource doesn't support.
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	jsonError(w, "Invalid method", http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// writeResult writes {"result": result} as a JSON response with the given status.
+func writeResult(w http.ResponseWriter, status int, result any) {
+	writeJSON(w, status, map[string]any{"result": result})
+}
+
+// jsonError writes {"error": message} with the given status, the JSON counterpart of http.Error.
+func jsonError(w http.ResponseWriter, message string, status int) {
+	writeJSON(w, status, map[string]any{"error": message})
+}
+
+// bodyStatus is the status of a failure to read or parse a request body: 413 for a body over
+// the size limit of the server and 400 for the rest.
+func bodyStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// writeJSON writes body as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"can't marshal response"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
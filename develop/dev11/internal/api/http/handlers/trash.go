@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"L2/develop/dev11/internal/usecase"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type trashHandlers struct {
+	interactor usecase.TrashInteractor
+}
+
+func NewTrashHandlers(interactor usecase.TrashInteractor) *trashHandlers {
+	return &trashHandlers{
+		interactor: interactor,
+	}
+}
+
+// TrashHandler returns the deleted events of the calendar_id calendars, of every calendar visible to the caller
+// if none are given, the last deleted first.
+func (h *trashHandlers) TrashHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	var calendarIDs []uuid.UUID
+	for _, value := range req.URL.Query()["calendar_id"] {
+		calendarID, err := uuid.Parse(value)
+		if err != nil {
+			jsonError(w, fmt.Sprintf("Can't parse calendar_id: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		calendarIDs = append(calendarIDs, calendarID)
+	}
+
+	events, err := h.interactor.List(req.Context(), calendarIDs...)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeResult(w, http.StatusOK, events)
+}
+
+// RestoreHandler takes the id event out of the trash and returns it.
+func (h *trashHandlers) RestoreHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		jsonError(w, "Invalid method", http.StatusBadRequest)
+		return
+	}
+
+	err := req.ParseForm()
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse body: %s", err.Error()), bodyStatus(err))
+		return
+	}
+	eventID, err := uuid.Parse(req.PostFormValue("id"))
+	if err != nil {
+		jsonError(w, fmt.Sprintf("Can't parse id: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.interactor.Restore(req.Context(), eventID)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	setETag(w, event)
+	writeResult(w, http.StatusOK, event)
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"L2/develop/dev11/internal/auth"
+	"L2/develop/dev11/internal/usecase"
+
+	"github.com/google/uuid"
+)
+
+// TokenVerifier verifies the tokens of the callers.
+type TokenVerifier interface {
+	Verify(token string, use auth.Use) (uuid.UUID, error)
+}
+
+// Authenticate passes requests with a valid access token to next with the user of the token
+// as the caller of the interactors and rejects the rest with 401.
+// The token is sent as a bearer token or, for CalDAV clients, as the password of basic authentication.
+func Authenticate(verifier TokenVerifier, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			_, token, ok = r.BasicAuth()
+		}
+		if !ok || token == "" {
+			challenge(w, "missing token")
+			return
+		}
+
+		userID, err := verifier.Verify(token, auth.UseAccess)
+		if err != nil {
+			challenge(w, err.Error())
+			return
+		}
+
+		logCaller(r.Context(), userID)
+		next.ServeHTTP(w, r.WithContext(usecase.ContextWithCaller(r.Context(), userID)))
+	})
+}
+
+// bearerToken returns the token of the Authorization: Bearer header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// challenge rejects an unauthenticated request with the {"error": reason} body of the API.
+func challenge(w http.ResponseWriter, reason string) {
+	body, _ := json.Marshal(map[string]string{"error": reason})
+
+	w.Header().Add("WWW-Authenticate", `Bearer realm="calendar"`)
+	w.Header().Add("WWW-Authenticate", `Basic realm="calendar"`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write(body)
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"L2/develop/dev11/internal/usecase"
+)
+
+// RateLimiter decides whether the client of key may make another request and,
+// if not, how long it has to wait.
+type RateLimiter interface {
+	Allow(key string) (bool, time.Duration)
+}
+
+// RateLimit passes the requests allowed by limiter to next and refuses the rest with 429 and Retry-After.
+// Requests are counted per authenticated user, so it goes after Authenticate, or else per remote IP.
+func RateLimit(limiter RateLimiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := limiter.Allow(clientKey(r))
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			jsonError(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RateLimitMethods limits the requests of safe methods, which only read, by reads and the rest by writes.
+func RateLimitMethods(reads RateLimiter, writes RateLimiter, next http.Handler) http.Handler {
+	readLimited := RateLimit(reads, next)
+	writeLimited := RateLimit(writes, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, "PROPFIND", "REPORT":
+			readLimited.ServeHTTP(w, r)
+		default:
+			writeLimited.ServeHTTP(w, r)
+		}
+	})
+}
+
+// clientKey identifies the client of a request: the caller or the remote IP.
+func clientKey(r *http.Request) string {
+	if userID, ok := usecase.CallerFromContext(r.Context()); ok {
+		return "user:" + userID.String()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// LimitBody refuses bodies longer than maxBytes with 413, zero means no limit. Bodies of an unknown
+// length are cut at maxBytes, so that reading them fails with *http.MaxBytesError.
+func LimitBody(maxBytes int64, next http.Handler) http.Handler {
+	if maxBytes <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBytes {
+			jsonError(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Timeout cancels the context of a request after timeout, so that the queries it runs are aborted.
+func Timeout(timeout time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -1,13 +1,98 @@
 package middleware
 
 import (
-	"log"
+	"context"
 	"net/http"
+	"time"
+	"unicode"
+
+	"L2/develop/dev11/internal/trace"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// RequestIDHeader carries the ID of a request, taken from the client or generated.
+	RequestIDHeader = "X-Request-ID"
+	// TraceparentHeader carries the W3C trace context of a request.
+	TraceparentHeader = "traceparent"
+
+	// maxRequestIDLength bounds request IDs taken from clients.
+	maxRequestIDLength = 128
 )
 
-func Logging(next http.Handler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		next.ServeHTTP(w, r)
-		log.Printf("%s | %s | %s", r.RemoteAddr, r.Method, r.URL)
+// requestLog collects what the handlers learn about a request for its log line.
+type requestLog struct {
+	userID uuid.UUID
+}
+
+type requestLogKey struct{}
+
+// Logging logs every request with its status, size, latency and caller once it is served.
+// The request ID of X-Request-ID, or a generated one, is returned in the response, and the trace
+// context of traceparent, or a new trace, is continued by a span of the server. Both are carried
+// by the context of the request, so that the log lines of other layers can be correlated with it.
+func Logging(logger *zap.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		span := trace.New()
+		if parent, err := trace.ParseTraceparent(r.Header.Get(TraceparentHeader)); err == nil {
+			span = parent.Child()
+		}
+
+		entry := &requestLog{}
+		ctx := trace.ContextWithRequestID(r.Context(), requestID)
+		ctx = trace.ContextWithTraceparent(ctx, span)
+		ctx = context.WithValue(ctx, requestLogKey{}, entry)
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		level := zapcore.InfoLevel
+		if recorder.status >= http.StatusInternalServerError {
+			level = zapcore.ErrorLevel
+		}
+		fields := append([]zap.Field{
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.Int("status", recorder.status),
+			zap.Int("bytes", recorder.bytes),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("remote_addr", r.RemoteAddr),
+		}, trace.Fields(ctx)...)
+		if entry.userID != uuid.Nil {
+			fields = append(fields, zap.Stringer("user_id", entry.userID))
+		}
+		logger.Log(level, "http request", fields...)
+	})
+}
+
+// logCaller records the authenticated caller for the log line of the request.
+func logCaller(ctx context.Context, userID uuid.UUID) {
+	if entry, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		entry.userID = userID
+	}
+}
+
+// validRequestID reports whether a request ID of a client is short printable ASCII,
+// so that it can't forge log lines or bloat them.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return false
+		}
 	}
+	return true
 }
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"L2/develop/dev11/internal/metrics"
+)
+
+// RouteMatcher returns the pattern of the route serving a request, like *http.ServeMux.
+type RouteMatcher interface {
+	Handler(r *http.Request) (h http.Handler, pattern string)
+}
+
+var (
+	requestsTotal = metrics.Default.Counter(
+		"calendar_http_requests_total",
+		"HTTP requests by route, method and status.",
+		"route", "method", "status",
+	)
+	requestDuration = metrics.Default.Histogram(
+		"calendar_http_request_duration_seconds",
+		"Duration of HTTP requests by route, method and status.",
+		metrics.DefaultBuckets,
+		"route", "method", "status",
+	)
+	requestsInFlight = metrics.Default.Gauge(
+		"calendar_http_requests_in_flight",
+		"HTTP requests being served by route.",
+		"route",
+	)
+)
+
+// knownMethods bounds the method label, the methods of CalDAV included.
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true, "PROPFIND": true, "REPORT": true,
+}
+
+// Metrics counts and measures the requests passed to next by the pattern of the route matching them,
+// so that the paths of events and CalDAV resources don't make a series each.
+func Metrics(routes RouteMatcher, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := routes.Handler(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		method := r.Method
+		if !knownMethods[method] {
+			method = "OTHER"
+		}
+
+		requestsInFlight.Inc(route)
+		defer requestsInFlight.Dec(route)
+
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		status := strconv.Itoa(recorder.status)
+		requestsTotal.Inc(route, method, status)
+		requestDuration.Observe(time.Since(start).Seconds(), route, method, status)
+	})
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"L2/develop/dev11/internal/api/http/openapi"
+)
+
+// RequestValidator checks requests against the API specification.
+type RequestValidator interface {
+	ValidateRequest(r *http.Request) error
+}
+
+// Validate passes the requests matching the specification to next and rejects the rest with 400
+// and the list of the mismatches: {"error": "...", "details": [{"in": "query", "name": "date", "reason": "..."}]}.
+// Bodies over the limit of LimitBody are rejected with 413.
+func Validate(validator RequestValidator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := validator.ValidateRequest(r)
+		if err == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			jsonError(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		response := map[string]any{"error": err.Error()}
+		var validationErr *openapi.ValidationError
+		if errors.As(err, &validationErr) {
+			response["details"] = validationErr.Problems
+		}
+		body, _ := json.Marshal(response)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(body)
+	})
+}
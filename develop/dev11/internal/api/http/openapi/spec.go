@@ -0,0 +1,313 @@
+// Package openapi provides the OpenAPI document of the HTTP API and validates requests against it.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//go:embed openapi.json
+var document []byte
+
+// Spec is the OpenAPI document of the HTTP API.
+type Spec struct {
+	raw   []byte
+	paths []*pathItem
+}
+
+// pathItem holds the operations of a path template like /api/v1/events/{id}.
+type pathItem struct {
+	path       string
+	segments   []string
+	Parameters []*Parameter `json:"parameters"`
+	Get        *Operation   `json:"get"`
+	Put        *Operation   `json:"put"`
+	Post       *Operation   `json:"post"`
+	Delete     *Operation   `json:"delete"`
+	Options    *Operation   `json:"options"`
+	Head       *Operation   `json:"head"`
+	Patch      *Operation   `json:"patch"`
+}
+
+// Operation is a method of a path.
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Parameters  []*Parameter `json:"parameters"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+// Parameter is a path, query or header parameter of an operation.
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody lists the media types of the body an operation accepts.
+type RequestBody struct {
+	Ref      string                `json:"$ref"`
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// MediaType is the schema of a body of a media type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema the validator checks.
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Enum       []string           `json:"enum"`
+	Items      *Schema            `json:"items"`
+	Properties map[string]*Schema `json:"properties"`
+	Required   []string           `json:"required"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+	MinLength  *int               `json:"minLength"`
+}
+
+// components holds the reusable objects referenced with $ref.
+type components struct {
+	Parameters    map[string]*Parameter   `json:"parameters"`
+	RequestBodies map[string]*RequestBody `json:"requestBodies"`
+	Schemas       map[string]*Schema      `json:"schemas"`
+}
+
+// Load parses the embedded document and resolves its references.
+func Load() (*Spec, error) {
+	var tree any
+	err := json.Unmarshal(document, &tree)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse document: %w", err)
+	}
+	err = checkRefs(tree, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		Paths      map[string]*pathItem `json:"paths"`
+		Components components           `json:"components"`
+	}
+	err = json.Unmarshal(document, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse document: %w", err)
+	}
+
+	r := resolver{components: doc.Components, seen: map[*Schema]bool{}}
+	spec := &Spec{raw: document}
+	for path, item := range doc.Paths {
+		item.path = path
+		item.segments = strings.Split(path, "/")
+		item.Parameters = r.parameters(item.Parameters)
+		for _, op := range item.operations() {
+			op.Parameters = r.parameters(op.Parameters)
+			op.RequestBody = r.requestBody(op.RequestBody)
+		}
+		spec.paths = append(spec.paths, item)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	sort.Slice(spec.paths, func(i, j int) bool { return spec.paths[i].path < spec.paths[j].path })
+
+	return spec, nil
+}
+
+// Paths returns the documented path templates.
+func (s *Spec) Paths() []string {
+	paths := make([]string, 0, len(s.paths))
+	for _, item := range s.paths {
+		paths = append(paths, item.path)
+	}
+	return paths
+}
+
+// ServeHTTP serves the document.
+func (s *Spec) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte(`{"error":"Invalid method"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(s.raw)
+}
+
+// match returns the path item of the path with the values of its template parameters.
+func (s *Spec) match(path string) (*pathItem, map[string]string) {
+	segments := strings.Split(path, "/")
+	for _, item := range s.paths {
+		if len(item.segments) != len(segments) {
+			continue
+		}
+
+		params := map[string]string{}
+		for i, segment := range item.segments {
+			if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") && segments[i] != "" {
+				params[segment[1:len(segment)-1]] = segments[i]
+			} else if segment != segments[i] {
+				params = nil
+				break
+			}
+		}
+		if params != nil {
+			return item, params
+		}
+	}
+
+	return nil, nil
+}
+
+// operation returns the operation of the method or nil when it isn't documented.
+func (p *pathItem) operation(method string) *Operation {
+	switch method {
+	case http.MethodGet:
+		return p.Get
+	case http.MethodPut:
+		return p.Put
+	case http.MethodPost:
+		return p.Post
+	case http.MethodDelete:
+		return p.Delete
+	case http.MethodOptions:
+		return p.Options
+	case http.MethodHead:
+		return p.Head
+	case http.MethodPatch:
+		return p.Patch
+	}
+	return nil
+}
+
+// operations returns the documented operations.
+func (p *pathItem) operations() []*Operation {
+	var ops []*Operation
+	for _, op := range []*Operation{p.Get, p.Put, p.Post, p.Delete, p.Options, p.Head, p.Patch} {
+		if op != nil {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// resolver replaces the references to the components with the components themselves.
+type resolver struct {
+	components components
+	seen       map[*Schema]bool
+	err        error
+}
+
+func (r *resolver) parameters(params []*Parameter) []*Parameter {
+	resolved := make([]*Parameter, 0, len(params))
+	for _, param := range params {
+		if param.Ref != "" {
+			param = r.components.Parameters[r.name(param.Ref, "parameters")]
+			if param == nil {
+				continue
+			}
+		}
+		param.Schema = r.schema(param.Schema)
+		resolved = append(resolved, param)
+	}
+	return resolved
+}
+
+func (r *resolver) requestBody(body *RequestBody) *RequestBody {
+	if body != nil && body.Ref != "" {
+		body = r.components.RequestBodies[r.name(body.Ref, "requestBodies")]
+	}
+	if body == nil {
+		return nil
+	}
+
+	for _, media := range body.Content {
+		media.Schema = r.schema(media.Schema)
+	}
+	return body
+}
+
+func (r *resolver) schema(schema *Schema) *Schema {
+	if schema != nil && schema.Ref != "" {
+		schema = r.components.Schemas[r.name(schema.Ref, "schemas")]
+	}
+	if schema == nil || r.seen[schema] {
+		return schema
+	}
+	r.seen[schema] = true
+
+	schema.Items = r.schema(schema.Items)
+	for name, property := range schema.Properties {
+		schema.Properties[name] = r.schema(property)
+	}
+	return schema
+}
+
+// name returns the name of the component ref points to and records refs to other kinds of objects.
+func (r *resolver) name(ref string, kind string) string {
+	name, ok := strings.CutPrefix(ref, "#/components/"+kind+"/")
+	if !ok && r.err == nil {
+		r.err = fmt.Errorf("reference %s isn't one of %s", ref, kind)
+	}
+	return name
+}
+
+// checkRefs checks that every $ref of node points into the document root.
+func checkRefs(root any, node any) error {
+	switch node := node.(type) {
+	case map[string]any:
+		if ref, ok := node["$ref"].(string); ok {
+			err := checkRef(root, ref)
+			if err != nil {
+				return err
+			}
+		}
+		for _, child := range node {
+			err := checkRefs(root, child)
+			if err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, child := range node {
+			err := checkRefs(root, child)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkRef(root any, ref string) error {
+	pointer, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return fmt.Errorf("reference %s isn't local", ref)
+	}
+
+	node := root
+	for _, key := range strings.Split(pointer, "/") {
+		object, ok := node.(map[string]any)
+		if !ok {
+			return fmt.Errorf("unresolved reference %s", ref)
+		}
+		node, ok = object[key]
+		if !ok {
+			return fmt.Errorf("unresolved reference %s", ref)
+		}
+	}
+	return nil
+}
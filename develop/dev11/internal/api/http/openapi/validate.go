@@ -0,0 +1,424 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"L2/develop/dev11/internal/entity"
+
+	"github.com/google/uuid"
+)
+
+// Problem is a mismatch of a request with the document.
+type Problem struct {
+	// In is the part of the request: path, query, header or body.
+	In     string `json:"in"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s %s: %s", p.In, p.Name, p.Reason)
+}
+
+// ValidationError lists the mismatches of a request with the document.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	reasons := make([]string, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		reasons = append(reasons, problem.String())
+	}
+	return "invalid request: " + strings.Join(reasons, "; ")
+}
+
+// formats checks the string formats of the document with the parsers of the handlers.
+// Values of other formats are accepted as is.
+var formats = map[string]func(string) error{
+	"uuid": func(value string) error {
+		_, err := uuid.Parse(value)
+		return err
+	},
+	"date": func(value string) error {
+		_, err := time.Parse("2006-01-02", value)
+		return err
+	},
+	"date-time": func(value string) error {
+		_, err := time.Parse(time.RFC3339, value)
+		return err
+	},
+	"local-date-time": func(value string) error {
+		_, err := entity.ParseLocalTime(value, time.UTC)
+		return err
+	},
+	"time-zone": func(value string) error {
+		_, err := time.LoadLocation(value)
+		return err
+	},
+	"duration": func(value string) error {
+		_, err := time.ParseDuration(value)
+		return err
+	},
+	"rrule": func(value string) error {
+		_, err := entity.ParseRRule(value, time.UTC)
+		return err
+	},
+	"date-list": func(value string) error {
+		_, err := entity.ParseDates(value)
+		return err
+	},
+	"duration-list": func(value string) error {
+		_, err := entity.ParseOffsets(value)
+		return err
+	},
+	"attendee-list": func(value string) error {
+		_, err := entity.ParseAttendees(value)
+		return err
+	},
+}
+
+// ValidateRequest checks the parameters and the body of the request against its operation
+// and returns a *ValidationError listing the mismatches. Undocumented paths and methods are
+// left to the handlers. A form or JSON body is read and replaced, so the handlers can read it again.
+func (s *Spec) ValidateRequest(req *http.Request) error {
+	item, pathParams := s.match(req.URL.Path)
+	if item == nil {
+		return nil
+	}
+	op := item.operation(req.Method)
+	if op == nil {
+		return nil
+	}
+
+	var problems []Problem
+	query := req.URL.Query()
+	params := append(append([]*Parameter{}, item.Parameters...), op.Parameters...)
+	for _, param := range params {
+		var values []string
+		switch param.In {
+		case "path":
+			values = []string{pathParams[param.Name]}
+		case "query":
+			values = query[param.Name]
+		case "header":
+			values = req.Header.Values(param.Name)
+		}
+		if reason := checkValues(param.Schema, values, param.Required); reason != "" {
+			problems = append(problems, Problem{In: param.In, Name: param.Name, Reason: reason})
+		}
+	}
+
+	if op.RequestBody != nil {
+		bodyProblems, err := checkBody(req, op.RequestBody)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, bodyProblems...)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// checkBody checks the media type of the body and the fields of form and JSON bodies.
+func checkBody(req *http.Request, body *RequestBody) ([]Problem, error) {
+	contentType := req.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if contentType == "" {
+		// Without a media type the body isn't parsed and a form is taken from the query.
+		if media := body.Content["application/x-www-form-urlencoded"]; media != nil {
+			return checkForm(media.Schema, req.URL.Query()), nil
+		}
+	}
+	media := mediaTypeOf(body, mediaType)
+	if err != nil || media == nil {
+		return []Problem{{In: "header", Name: "Content-Type", Reason: fmt.Sprintf("must be one of %s", strings.Join(mediaTypes(body), ", "))}}, nil
+	}
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		data, err := readBody(req)
+		if err != nil {
+			return nil, err
+		}
+		form, err := url.ParseQuery(string(data))
+		if err != nil {
+			return []Problem{{In: "body", Name: "form", Reason: err.Error()}}, nil
+		}
+		// The form values of the handlers merge the body with the query.
+		for name, values := range req.URL.Query() {
+			form[name] = append(form[name], values...)
+		}
+		return checkForm(media.Schema, form), nil
+	case "application/json":
+		data, err := readBody(req)
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(data)) == 0 && !body.Required {
+			return nil, nil
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var value any
+		err = decoder.Decode(&value)
+		if err != nil {
+			return []Problem{{In: "body", Name: "body", Reason: err.Error()}}, nil
+		}
+		return checkJSON(media.Schema, value, ""), nil
+	}
+
+	return nil, nil
+}
+
+// mediaTypeOf returns the media type object matching mediaType exactly or by a range like text/* or */*.
+func mediaTypeOf(body *RequestBody, mediaType string) *MediaType {
+	if media := body.Content[mediaType]; media != nil {
+		return media
+	}
+	if kind, _, ok := strings.Cut(mediaType, "/"); ok {
+		if media := body.Content[kind+"/*"]; media != nil {
+			return media
+		}
+	}
+	return body.Content["*/*"]
+}
+
+func mediaTypes(body *RequestBody) []string {
+	types := make([]string, 0, len(body.Content))
+	for mediaType := range body.Content {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// readBody reads the body and replaces it with a reader of the same bytes.
+func readBody(req *http.Request) ([]byte, error) {
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("can't read body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// checkForm checks the fields of a form against an object schema.
+func checkForm(schema *Schema, form url.Values) []Problem {
+	if schema == nil {
+		return nil
+	}
+
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	var problems []Problem
+	for _, name := range sortedKeys(schema.Properties) {
+		if reason := checkValues(schema.Properties[name], form[name], required[name]); reason != "" {
+			problems = append(problems, Problem{In: "body", Name: name, Reason: reason})
+		}
+	}
+	return problems
+}
+
+// checkValues checks the values of a parameter or a form field and returns the reason of a mismatch.
+// An empty value is missing like in the handlers. Only arrays may be repeated,
+// the handlers take the first value of the rest.
+func checkValues(schema *Schema, values []string, required bool) string {
+	if len(values) == 0 || values[0] == "" {
+		if required {
+			return "is required"
+		}
+		return ""
+	}
+	if schema == nil {
+		return ""
+	}
+
+	if schema.Type == "array" {
+		for _, value := range values {
+			if reason := checkValue(schema.Items, value); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	}
+	return checkValue(schema, values[0])
+}
+
+// checkValue checks a string value of a parameter or a form field.
+func checkValue(schema *Schema, value string) string {
+	if schema == nil {
+		return ""
+	}
+
+	switch schema.Type {
+	case "integer":
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "must be an integer"
+		}
+		return checkRange(schema, float64(number))
+	case "number":
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "must be a number"
+		}
+		return checkRange(schema, number)
+	case "boolean":
+		switch value {
+		case "true", "false", "1", "0":
+			return ""
+		}
+		return "must be true or false"
+	}
+	return checkString(schema, value)
+}
+
+// checkJSON checks a decoded JSON value. A null is treated like a missing value, as json.Unmarshal does.
+func checkJSON(schema *Schema, value any, name string) []Problem {
+	if schema == nil || value == nil {
+		return nil
+	}
+
+	problem := func(reason string) []Problem {
+		if name == "" {
+			name = "body"
+		}
+		return []Problem{{In: "body", Name: name, Reason: reason}}
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return problem("must be an object")
+		}
+		var problems []Problem
+		for _, property := range schema.Required {
+			if object[property] == nil {
+				problems = append(problems, Problem{In: "body", Name: join(name, property), Reason: "is required"})
+			}
+		}
+		for _, property := range sortedKeys(schema.Properties) {
+			problems = append(problems, checkJSON(schema.Properties[property], object[property], join(name, property))...)
+		}
+		return problems
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return problem("must be an array")
+		}
+		var problems []Problem
+		for i, item := range array {
+			problems = append(problems, checkJSON(schema.Items, item, fmt.Sprintf("%s[%d]", name, i))...)
+		}
+		return problems
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return problem("must be an integer")
+		}
+		integer, err := number.Int64()
+		if err != nil {
+			return problem("must be an integer")
+		}
+		if reason := checkRange(schema, float64(integer)); reason != "" {
+			return problem(reason)
+		}
+	case "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return problem("must be a number")
+		}
+		float, err := number.Float64()
+		if err != nil {
+			return problem("must be a number")
+		}
+		if reason := checkRange(schema, float); reason != "" {
+			return problem(reason)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return problem("must be true or false")
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return problem("must be a string")
+		}
+		if reason := checkString(schema, s); reason != "" {
+			return problem(reason)
+		}
+	}
+	return nil
+}
+
+// checkString checks the length, the enum and the format of a string.
+func checkString(schema *Schema, value string) string {
+	if schema.MinLength != nil && utf8.RuneCountInString(value) < *schema.MinLength {
+		if *schema.MinLength == 1 {
+			return "must not be empty"
+		}
+		return fmt.Sprintf("must be at least %d characters long", *schema.MinLength)
+	}
+
+	if len(schema.Enum) > 0 {
+		for _, allowed := range schema.Enum {
+			if value == allowed {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(schema.Enum, ", "))
+	}
+
+	if check := formats[schema.Format]; check != nil {
+		if err := check(value); err != nil {
+			return fmt.Sprintf("must be a valid %s: %s", schema.Format, err.Error())
+		}
+	}
+	return ""
+}
+
+func checkRange(schema *Schema, number float64) string {
+	switch {
+	case schema.Minimum != nil && number < *schema.Minimum:
+		return fmt.Sprintf("must be at least %v", *schema.Minimum)
+	case schema.Maximum != nil && number > *schema.Maximum:
+		return fmt.Sprintf("must be at most %v", *schema.Maximum)
+	}
+	return ""
+}
+
+// join returns the dotted name of a property of a JSON object.
+func join(name string, property string) string {
+	if name == "" {
+		return property
+	}
+	return name + "." + property
+}
+
+func sortedKeys(properties map[string]*Schema) []string {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,116 @@
+package openapi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateRequest(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	const id = "0e4b5e4f-5a1c-4a0e-9d0a-3f6c1f5b2a10"
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		expected    []string
+	}{
+		{name: "valid query", method: http.MethodGet, target: "/events_for_day?date=2024-03-05&tz=Europe/Moscow"},
+		{name: "missing query", method: http.MethodGet, target: "/events_for_day", expected: []string{"query date: is required"}},
+		{name: "invalid query", method: http.MethodGet, target: "/events?from=2024-03-01&to=tomorrow&sort=size&limit=500&calendar_id=" + id + "&calendar_id=1",
+			expected: []string{"query to", "query calendar_id", "query sort: must be one of start, -start, title, -title", "query limit: must be at most 200"}},
+		{name: "invalid path", method: http.MethodGet, target: "/api/v1/events/1", expected: []string{"path id"}},
+		{name: "undocumented method", method: http.MethodPatch, target: "/api/v1/events/" + id},
+		{name: "undocumented path", method: http.MethodGet, target: "/unknown"},
+		{name: "valid form", method: http.MethodPost, target: "/create_event", contentType: "application/x-www-form-urlencoded",
+			body: "title=planning&start=2024-03-05T10:00&duration=1h&reminders=15m,1h&all_day=0"},
+		{name: "form with query", method: http.MethodPost, target: "/create_event?title=planning", contentType: "application/x-www-form-urlencoded",
+			body: "start=2024-03-05T10:00"},
+		{name: "invalid form", method: http.MethodPost, target: "/create_event", contentType: "application/x-www-form-urlencoded",
+			body:     "title=&tz=Mars/Base&rrule=FREQ=SOMETIMES&all_day=yes",
+			expected: []string{"body all_day: must be true or false", "body rrule", "body title: is required", "body tz"}},
+		{name: "valid JSON", method: http.MethodPost, target: "/api/v1/events", contentType: "application/json; charset=utf-8",
+			body: `{"title": "planning", "start": "2024-03-05T10:00:00Z", "end": "2024-03-05T11:00:00Z", "reminders": ["15m"], "recurrence_id": null}`},
+		{name: "invalid JSON", method: http.MethodPut, target: "/api/v1/events/" + id, contentType: "application/json",
+			body:     `{"title": "", "start": "2024-03-05", "all_day": "true", "exdate": ["2024-03-12", 1], "version": 1.5}`,
+			expected: []string{"body end: is required", "body all_day: must be true or false", "body exdate[1]: must be a string", "body start", "body title: must not be empty", "body version: must be an integer"}},
+		{name: "malformed JSON", method: http.MethodPost, target: "/api/v1/events", contentType: "application/json", body: `{"title":`,
+			expected: []string{"body body"}},
+		{name: "unsupported media type", method: http.MethodPost, target: "/api/v1/events", contentType: "text/plain", body: "planning",
+			expected: []string{"header Content-Type: must be one of application/json, application/x-www-form-urlencoded"}},
+		{name: "media range", method: http.MethodPost, target: "/import_ics", contentType: "text/calendar", body: "BEGIN:VCALENDAR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			err := spec.ValidateRequest(req)
+			var problems []string
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				for _, problem := range validationErr.Problems {
+					problems = append(problems, problem.String())
+				}
+			} else if err != nil {
+				t.Fatalf("ValidateRequest() error = %v", err)
+			}
+
+			if len(problems) != len(tt.expected) {
+				t.Fatalf("ValidateRequest() problems = %q, expected %q", problems, tt.expected)
+			}
+			for i, problem := range problems {
+				if !strings.HasPrefix(problem, tt.expected[i]) {
+					t.Errorf("ValidateRequest() problem %d = %q, expected %q", i, problem, tt.expected[i])
+				}
+			}
+
+			// The handlers read the same body.
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != tt.body {
+				t.Errorf("body after ValidateRequest() = %q, expected %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	ids := map[string]string{}
+	for _, item := range spec.paths {
+		if len(item.operations()) == 0 {
+			t.Errorf("%s has no operations", item.path)
+		}
+		for _, op := range item.operations() {
+			if op.OperationID == "" || ids[op.OperationID] != "" {
+				t.Errorf("%s has an empty or duplicate operationId %q", item.path, op.OperationID)
+			}
+			ids[op.OperationID] = item.path
+		}
+	}
+
+	item, params := spec.match("/caldav/" + "u1" + "/calendar/e1.ics")
+	if item == nil || !reflect.DeepEqual(params, map[string]string{"user_id": "u1", "resource": "e1.ics"}) {
+		t.Errorf("match() = %v, %v", item, params)
+	}
+	if item, _ := spec.match("/api/v1/events/"); item != nil {
+		t.Errorf("match() of an empty template segment = %s", item.path)
+	}
+}
@@ -5,35 +5,98 @@ import (
 	"fmt"
 	"net/http"
 
+	"L2/develop/dev11/internal/api/http/caldav"
 	"L2/develop/dev11/internal/api/http/handlers"
 	"L2/develop/dev11/internal/api/http/middleware"
+	"L2/develop/dev11/internal/api/http/openapi"
+	"L2/develop/dev11/internal/auth"
 	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/metrics"
+	"L2/develop/dev11/internal/ratelimit"
 	"L2/develop/dev11/internal/repository"
 	"L2/develop/dev11/internal/usecase"
 
-	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
 
 // routerHandlers contains handlers for router.
 type routerHandlers struct {
-	eventHandlers handlers.EventHandlers
+	eventHandlers      handlers.EventHandlers
+	calendarHandlers   handlers.CalendarHandlers
+	authHandlers       handlers.AuthHandlers
+	healthHandlers     handlers.HealthHandlers
+	changeHandlers     handlers.ChangeHandlers
+	trashHandlers      handlers.TrashHandlers
+	historyHandlers    handlers.HistoryHandlers
+	invitationHandlers handlers.InvitationHandlers
+}
+
+// Options configures the behavior of the HTTP API.
+type Options struct {
+	// ConflictMode defines how overlapping events are handled on create and update.
+	ConflictMode usecase.ConflictMode
+	// Tokens authenticate the callers of every route but the token refresh, the specification and the probes.
+	Tokens *auth.Tokens
+	// ReadinessChecks are run by /readyz, the service is ready when all of them pass.
+	ReadinessChecks []handlers.Check
+	// RateLimits limit the requests of every client per route group, nil limiters allow everything.
+	RateLimits RateLimits
+	// MaxBody is the size limit of request bodies in bytes and MaxImportBody the one of imported calendars, zero means no limit.
+	MaxBody       int64
+	MaxImportBody int64
+	// Changes records the changes of events and publishes them to the streams, nil disables both.
+	Changes *usecase.ChangeFeed
+	// Invitations delivers the invitations to events and the responses of the attendees, nil delivers none.
+	Invitations *usecase.Invitations
+	// EventCache caches the window queries of events, nil disables caching.
+	EventCache *repository.EventCache
+}
+
+// RateLimits are the rate limiters of the route groups. Reads and writes are counted per user,
+// token refreshes per remote IP.
+type RateLimits struct {
+	Read  *ratelimit.Limiter
+	Write *ratelimit.Limiter
+	Auth  *ratelimit.Limiter
 }
 
 // router represents an HTTP router.
 type router struct {
-	mux      http.Handler
-	db       *sqlx.DB
-	handlers routerHandlers
+	mux            http.Handler
+	eventSource    db.EventSource
+	calendarSource db.CalendarSource
+	changeSource   db.ChangeSource
+	webhookSource  db.WebhookSource
+	trashSource    db.TrashSource
+	revisionSource db.RevisionSource
+	options        Options
+	handlers       routerHandlers
+	// patterns lists the registered routes, they are kept in sync with the OpenAPI document.
+	patterns []string
 	logger   *zap.Logger
 }
 
 // NewRouter creates a new instance of HTTP router.
-func NewRouter(db *sqlx.DB, logger *zap.Logger) *router {
+func NewRouter(
+	eventSource db.EventSource,
+	calendarSource db.CalendarSource,
+	changeSource db.ChangeSource,
+	webhookSource db.WebhookSource,
+	trashSource db.TrashSource,
+	revisionSource db.RevisionSource,
+	options Options,
+	logger *zap.Logger,
+) *router {
 	return &router{
-		mux:    http.NewServeMux(),
-		db:     db,
-		logger: logger,
+		mux:            http.NewServeMux(),
+		eventSource:    eventSource,
+		calendarSource: calendarSource,
+		changeSource:   changeSource,
+		webhookSource:  webhookSource,
+		trashSource:    trashSource,
+		revisionSource: revisionSource,
+		options:        options,
+		logger:         logger,
 	}
 }
 
@@ -51,19 +114,116 @@ func (r *router) Init() error {
 func (r *router) registerRoutes() error {
 	mux := &http.ServeMux{}
 	handler := middleware.Recovery(mux)
-	handler = middleware.Logging(handler)
+	handler = middleware.Logging(r.logger, handler)
+	handler = middleware.Metrics(mux, handler)
 
-	pgSource := db.NewSource(r.db)
-	eventRepository := repository.NewEventRepository(pgSource)
-	eventInteractor := usecase.NewEventInteractor(eventRepository)
+	spec, err := openapi.Load()
+	if err != nil {
+		return fmt.Errorf("can't load API specification: %w", err)
+	}
+
+	var eventRepository repository.EventRepository = repository.NewEventRepository(r.eventSource)
+	var calendarRepository repository.CalendarRepository = repository.NewCalendarRepository(r.calendarSource)
+	var trashRepository repository.TrashRepository = repository.NewTrashRepository(r.trashSource)
+	var txManager repository.TxManager = repository.NewTxManager(r.eventSource)
+	// The cache decorates every repository writing events, so that it sees all of their changes.
+	if cache := r.options.EventCache; cache != nil {
+		eventRepository = cache.Events(eventRepository)
+		calendarRepository = cache.Calendars(calendarRepository)
+		trashRepository = cache.Trash(trashRepository)
+		txManager = cache.TxManager(txManager)
+	}
+	changeRepository := repository.NewChangeRepository(r.changeSource)
+	revisionRepository := repository.NewRevisionRepository(r.revisionSource)
+	audit := usecase.NewAudit(revisionRepository)
+	eventInteractor := usecase.NewEventInteractor(eventRepository, calendarRepository, r.options.ConflictMode, usecase.EventOptions{
+		Changes:     r.options.Changes,
+		Audit:       audit,
+		Invitations: r.options.Invitations,
+		Tx:          txManager,
+	})
+	calendarInteractor := usecase.NewCalendarInteractor(calendarRepository)
+	changeInteractor := usecase.NewChangeInteractor(changeRepository, calendarRepository, r.options.Changes)
+	webhookInteractor := usecase.NewWebhookInteractor(repository.NewWebhookRepository(r.webhookSource), changeRepository)
+	trashInteractor := usecase.NewTrashInteractor(trashRepository, eventRepository, calendarRepository, r.options.Changes, audit, txManager)
+	historyInteractor := usecase.NewHistoryInteractor(revisionRepository, calendarRepository, eventInteractor)
+	invitationInteractor := usecase.NewInvitationInteractor(eventRepository, r.options.Changes, audit, r.options.Invitations, txManager)
 	r.handlers.eventHandlers = handlers.NewEventHandlers(eventInteractor)
+	r.handlers.calendarHandlers = handlers.NewCalendarHandlers(calendarInteractor)
+	r.handlers.authHandlers = handlers.NewAuthHandlers(r.options.Tokens)
+	r.handlers.healthHandlers = handlers.NewHealthHandlers(r.options.ReadinessChecks)
+	r.handlers.changeHandlers = handlers.NewChangeHandlers(changeInteractor, webhookInteractor)
+	r.handlers.trashHandlers = handlers.NewTrashHandlers(trashInteractor)
+	r.handlers.historyHandlers = handlers.NewHistoryHandlers(historyInteractor)
+	r.handlers.invitationHandlers = handlers.NewInvitationHandlers(invitationInteractor)
+
+	// Requests are rate limited and validated against the specification once the caller is authenticated.
+	validate := func(next http.HandlerFunc) http.Handler {
+		return middleware.Validate(spec, next)
+	}
+	limit := func(next http.Handler) http.Handler {
+		return middleware.RateLimitMethods(r.options.RateLimits.Read, r.options.RateLimits.Write, next)
+	}
+	authenticate := func(next http.HandlerFunc) http.Handler {
+		return middleware.Authenticate(r.options.Tokens, limit(validate(next)))
+	}
+	// Imported calendars may be larger than the other bodies.
+	bodyLimits := map[string]int64{"/import_ics": r.options.MaxImportBody}
+	handle := func(pattern string, handler http.Handler) {
+		maxBody, ok := bodyLimits[pattern]
+		if !ok {
+			maxBody = r.options.MaxBody
+		}
+		handler = middleware.LimitBody(maxBody, handler)
+		// Streams stay open as long as their clients do.
+		if pattern != handlers.StreamPath {
+			handler = middleware.Timeout(RequestTimeOut, handler)
+		}
+		mux.Handle(pattern, handler)
+		r.patterns = append(r.patterns, pattern)
+	}
+
+	handle("/create_event", authenticate(r.handlers.eventHandlers.CreateHandler))
+	handle("/update_event", authenticate(r.handlers.eventHandlers.UpdateHandler))
+	handle("/delete_event", authenticate(r.handlers.eventHandlers.DeleteHandler))
+	handle("/events_for_day", authenticate(r.handlers.eventHandlers.GetForDayHandler))
+	handle("/events_for_week", authenticate(r.handlers.eventHandlers.GetForWeekHandler))
+	handle("/events_for_month", authenticate(r.handlers.eventHandlers.GetForMonthHandler))
+	handle("/free_busy", authenticate(r.handlers.eventHandlers.FreeBusyHandler))
+	handle("/events", authenticate(r.handlers.eventHandlers.SearchHandler))
+	handle("/tags", authenticate(r.handlers.eventHandlers.TagsHandler))
+	handle("/export.ics", authenticate(r.handlers.eventHandlers.ExportICSHandler))
+	handle("/import_ics", authenticate(r.handlers.eventHandlers.ImportICSHandler))
+	handle("/trash", authenticate(r.handlers.trashHandlers.TrashHandler))
+	handle("/restore_event", authenticate(r.handlers.trashHandlers.RestoreHandler))
+	handle("/rsvp", authenticate(r.handlers.invitationHandlers.RSVPHandler))
+
+	handle(handlers.StreamPath, authenticate(r.handlers.changeHandlers.StreamHandler))
+	handle(handlers.BatchPath, authenticate(r.handlers.eventHandlers.BatchHandler))
+	handle(handlers.HistoryPrefix, authenticate(r.handlers.historyHandlers.HistoryHandler))
+	handle(handlers.EventsPath, authenticate(r.handlers.eventHandlers.CollectionHandler))
+	handle(handlers.EventsPath+"/", authenticate(r.handlers.eventHandlers.ResourceHandler))
+
+	handle("/calendars", authenticate(r.handlers.calendarHandlers.ListHandler))
+	handle("/create_calendar", authenticate(r.handlers.calendarHandlers.CreateHandler))
+	handle("/update_calendar", authenticate(r.handlers.calendarHandlers.UpdateHandler))
+	handle("/delete_calendar", authenticate(r.handlers.calendarHandlers.DeleteHandler))
+	handle("/calendar_shares", authenticate(r.handlers.calendarHandlers.SharesHandler))
+	handle("/share_calendar", authenticate(r.handlers.calendarHandlers.ShareHandler))
+	handle("/unshare_calendar", authenticate(r.handlers.calendarHandlers.UnshareHandler))
+
+	handle("/webhooks", authenticate(r.handlers.changeHandlers.WebhooksHandler))
+	handle("/create_webhook", authenticate(r.handlers.changeHandlers.CreateWebhookHandler))
+	handle("/delete_webhook", authenticate(r.handlers.changeHandlers.DeleteWebhookHandler))
+
+	// CalDAV clients follow RFC 4791, so their requests aren't validated.
+	handle(caldav.Prefix, middleware.Authenticate(r.options.Tokens, limit(caldav.NewHandler(eventInteractor))))
 
-	mux.HandleFunc("/create_event", r.handlers.eventHandlers.CreateHandler)
-	mux.HandleFunc("/update_event", r.handlers.eventHandlers.UpdateHandler)
-	mux.HandleFunc("/delete_event", r.handlers.eventHandlers.DeleteHandler)
-	mux.HandleFunc("/events_for_day", r.handlers.eventHandlers.GetForDayHandler)
-	mux.HandleFunc("/events_for_week", r.handlers.eventHandlers.GetForWeekHandler)
-	mux.HandleFunc("/events_for_month", r.handlers.eventHandlers.GetForMonthHandler)
+	handle("/refresh_token", middleware.RateLimit(r.options.RateLimits.Auth, validate(r.handlers.authHandlers.RefreshHandler)))
+	handle("/openapi.json", spec)
+	handle("/healthz", http.HandlerFunc(r.handlers.healthHandlers.LiveHandler))
+	handle("/readyz", http.HandlerFunc(r.handlers.healthHandlers.ReadyHandler))
+	handle("/metrics", metrics.Default)
 
 	r.mux = handler
 
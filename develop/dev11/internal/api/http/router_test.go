@@ -0,0 +1,739 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"L2/develop/dev11/internal/api/http/handlers"
+	"L2/develop/dev11/internal/api/http/openapi"
+	"L2/develop/dev11/internal/auth"
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/ratelimit"
+	"L2/develop/dev11/internal/repository"
+	"L2/develop/dev11/internal/trace"
+	"L2/develop/dev11/internal/usecase"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newTestRouter creates a router on the in-memory source with test signing keys and the event cache,
+// like the service runs by default.
+func newTestRouter(t *testing.T) (*router, *auth.Tokens) {
+	options := Options{ConflictMode: usecase.ConflictFlag, EventCache: repository.NewEventCache(100, time.Minute)}
+	return newConfiguredTestRouter(t, options, zap.NewNop())
+}
+
+// newConfiguredTestRouter creates a router on the in-memory source with the options and test tokens,
+// which logs to logger.
+func newConfiguredTestRouter(t *testing.T, options Options, logger *zap.Logger) (*router, *auth.Tokens) {
+	tokens, err := auth.NewTokens(auth.Config{
+		Keys:       []auth.Key{{ID: "test", Secret: []byte(strings.Repeat("s", 32))}},
+		Issuer:     "calendar",
+		AccessTTL:  time.Hour,
+		RefreshTTL: 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewTokens() error = %v", err)
+	}
+
+	source := db.NewMemorySource()
+	options.Tokens = tokens
+	if options.Changes == nil {
+		options.Changes = usecase.NewChangeFeed(repository.NewChangeRepository(source))
+	}
+	r := NewRouter(source, source, source, source, source, source, options, logger)
+	if err := r.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	return r, tokens
+}
+
+// accessToken returns an access token of the user.
+func accessToken(t *testing.T, tokens *auth.Tokens, userID uuid.UUID) string {
+	pair, err := tokens.Issue(userID)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	return pair.AccessToken
+}
+
+// TestRouterMemorySource runs the main routes end to end on the in-memory source.
+func TestRouterMemorySource(t *testing.T) {
+	r, tokens := newTestRouter(t)
+
+	userID := uuid.New()
+	token := accessToken(t, tokens, userID)
+	do := func(method string, target string, body string, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	eventID := uuid.New()
+	form := url.Values{
+		"id":    {eventID.String()},
+		"title": {"planning"},
+		"start": {"2024-03-05T10:00"},
+		"end":   {"2024-03-05T11:00"},
+		"tz":    {"UTC"},
+		"tags":  {"Meeting, planning"},
+	}
+	rec := do(http.MethodPost, "/create_event", form.Encode(), "application/x-www-form-urlencoded")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create_event = %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do(http.MethodGet, "/events_for_day?date=2024-03-05", "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "planning") {
+		t.Errorf("events_for_day = %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do(http.MethodGet, "/events?from=2024-03-01&to=2024-04-01&q=Planning", "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"events":[{"id":"`+eventID.String()) {
+		t.Errorf("events = %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do(http.MethodGet, "/events_for_day?date=2024-03-05&tag=meeting&tag=planning", "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"tags":["meeting","planning"]`) {
+		t.Errorf("events_for_day with tags = %d %s", rec.Code, rec.Body)
+	}
+	rec = do(http.MethodGet, "/events_for_week?date=2024-03-04&tag=on-call", "", "")
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "planning") {
+		t.Errorf("events_for_week with another tag = %d %s", rec.Code, rec.Body)
+	}
+	rec = do(http.MethodGet, "/events_for_month?date=2024-03-01&tag=on+call", "", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("events_for_month with an invalid tag = %d %s", rec.Code, rec.Body)
+	}
+	rec = do(http.MethodGet, "/tags?user_id="+userID.String(), "", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"result":[{"name":"meeting","count":1},{"name":"planning","count":1}]}` {
+		t.Errorf("tags = %d %q", rec.Code, rec.Body)
+	}
+
+	rec = do(http.MethodGet, "/export.ics", "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "SUMMARY:planning") {
+		t.Errorf("export.ics = %d %s", rec.Code, rec.Body)
+	}
+
+	resource := "/caldav/" + userID.String() + "/calendar/" + eventID.String() + ".ics"
+	rec = do(http.MethodGet, resource, "", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == "" {
+		t.Errorf("caldav GET = %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do(http.MethodDelete, resource, "", "")
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("caldav DELETE = %d %s", rec.Code, rec.Body)
+	}
+	rec = do(http.MethodGet, "/events_for_day?date=2024-03-05", "", "")
+	if strings.Contains(rec.Body.String(), "planning") {
+		t.Errorf("events_for_day after delete = %s", rec.Body)
+	}
+
+	rec = do(http.MethodGet, "/trash", "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"deleted_at":`) {
+		t.Errorf("trash = %d %s", rec.Code, rec.Body)
+	}
+	rec = do(http.MethodPost, "/restore_event", url.Values{"id": {eventID.String()}}.Encode(), "application/x-www-form-urlencoded")
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"deleted_at":`) {
+		t.Errorf("restore_event = %d %s", rec.Code, rec.Body)
+	}
+	rec = do(http.MethodGet, "/events_for_day?date=2024-03-05", "", "")
+	if !strings.Contains(rec.Body.String(), "planning") {
+		t.Errorf("events_for_day after restore = %s", rec.Body)
+	}
+	rec = do(http.MethodGet, "/trash", "", "")
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "planning") {
+		t.Errorf("trash after restore = %d %s", rec.Code, rec.Body)
+	}
+
+	// An attendee sees the event the organizer invited them to and answers the invitation.
+	attendeeID, invitationID := uuid.New(), uuid.New()
+	form = url.Values{
+		"id":        {invitationID.String()},
+		"title":     {"review"},
+		"start":     {"2024-03-05T12:00"},
+		"duration":  {"30m"},
+		"attendees": {attendeeID.String() + ", guest@example.com"},
+	}
+	rec = do(http.MethodPost, "/create_event", form.Encode(), "application/x-www-form-urlencoded")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create_event with attendees = %d %s", rec.Code, rec.Body)
+	}
+	token = accessToken(t, tokens, attendeeID)
+	rec = do(http.MethodGet, "/events_for_day?date=2024-03-05", "", "")
+	if !strings.Contains(rec.Body.String(), "review") || strings.Contains(rec.Body.String(), "planning") {
+		t.Errorf("events_for_day of the attendee = %s", rec.Body)
+	}
+	rsvp := url.Values{"id": {invitationID.String()}, "status": {"accepted"}}
+	rec = do(http.MethodPost, "/rsvp", rsvp.Encode(), "application/x-www-form-urlencoded")
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` ||
+		!strings.Contains(rec.Body.String(), `{"user_id":"`+attendeeID.String()+`","status":"accepted"}`) {
+		t.Errorf("rsvp = %d %s", rec.Code, rec.Body)
+	}
+	rsvp.Set("status", "maybe")
+	rec = do(http.MethodPost, "/rsvp", rsvp.Encode(), "application/x-www-form-urlencoded")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("rsvp with an unknown status = %d %s", rec.Code, rec.Body)
+	}
+}
+
+// TestRouterAuthentication checks that the caller is taken from the token and never from user_id.
+func TestRouterAuthentication(t *testing.T) {
+	r, tokens := newTestRouter(t)
+
+	do := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	userID, otherID := uuid.New(), uuid.New()
+	req := httptest.NewRequest(http.MethodGet, "/events_for_day?date=2024-03-05&user_id="+userID.String(), nil)
+	rec := do(req)
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("events_for_day without a token = %d %s", rec.Code, rec.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/events_for_day?date=2024-03-05", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, tokens, userID)+"x")
+	if rec := do(req); rec.Code != http.StatusUnauthorized {
+		t.Errorf("events_for_day with a forged token = %d %s", rec.Code, rec.Body)
+	}
+
+	// CalDAV clients send the token as the password of basic authentication.
+	req = httptest.NewRequest("PROPFIND", "/caldav/"+userID.String()+"/", nil)
+	req.SetBasicAuth("anyone", accessToken(t, tokens, userID))
+	if rec := do(req); rec.Code != http.StatusMultiStatus {
+		t.Errorf("caldav PROPFIND = %d %s", rec.Code, rec.Body)
+	}
+	req = httptest.NewRequest("PROPFIND", "/caldav/"+userID.String()+"/", nil)
+	req.SetBasicAuth("anyone", accessToken(t, tokens, otherID))
+	if rec := do(req); rec.Code != http.StatusForbidden {
+		t.Errorf("caldav PROPFIND of another user = %d %s", rec.Code, rec.Body)
+	}
+
+	pair, err := tokens.Issue(userID)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/refresh_token", strings.NewReader(url.Values{"refresh_token": {pair.AccessToken}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if rec := do(req); rec.Code != http.StatusUnauthorized {
+		t.Errorf("refresh_token with an access token = %d %s", rec.Code, rec.Body)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/refresh_token", strings.NewReader(url.Values{"refresh_token": {pair.RefreshToken}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if rec := do(req); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"access_token":"`) {
+		t.Errorf("refresh_token = %d %s", rec.Code, rec.Body)
+	}
+}
+
+// TestRouterPreconditions checks that writes based on a stale ETag are refused with the current event.
+func TestRouterPreconditions(t *testing.T) {
+	r, tokens := newTestRouter(t)
+
+	token := accessToken(t, tokens, uuid.New())
+	do := func(method string, target string, body string, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer "+token)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		r.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	eventID := uuid.New()
+	form := url.Values{
+		"id":    {eventID.String()},
+		"title": {"planning"},
+		"start": {"2024-03-05T10:00"},
+		"end":   {"2024-03-05T11:00"},
+		"tz":    {"UTC"},
+		// The description is kept by the updates leaving it out.
+		"description": {"weekly"},
+	}
+	rec := do(http.MethodPost, "/create_event", form.Encode(), "")
+	if rec.Code != http.StatusCreated || rec.Header().Get("ETag") != `"1"` {
+		t.Fatalf("create_event = %d %s, ETag %s", rec.Code, rec.Body, rec.Header().Get("ETag"))
+	}
+
+	form.Del("description")
+	form.Set("title", "review")
+	rec = do(http.MethodPut, "/update_event", form.Encode(), `"1"`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("update_event = %d %s, ETag %s", rec.Code, rec.Body, rec.Header().Get("ETag"))
+	}
+
+	form.Set("title", "lost update")
+	rec = do(http.MethodPut, "/update_event", form.Encode(), `"1"`)
+	if rec.Code != http.StatusPreconditionFailed || rec.Header().Get("ETag") != `"2"` ||
+		!strings.Contains(rec.Body.String(), `"title":"review"`) || !strings.Contains(rec.Body.String(), `"description":"weekly"`) {
+		t.Errorf("update_event with a stale ETag = %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do(http.MethodPut, "/update_event", form.Encode(), `"1", "2"`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("update_event with several ETags = %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do(http.MethodDelete, "/delete_event?id="+eventID.String(), "", `"1"`)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("delete_event with a stale ETag = %d %s", rec.Code, rec.Body)
+	}
+	// If-Match compares strongly, so a weak tag fails even with the current version.
+	rec = do(http.MethodDelete, "/delete_event?id="+eventID.String(), "", `W/"2"`)
+	if rec.Code != http.StatusPreconditionFailed || rec.Header().Get("ETag") != `"2"` {
+		t.Errorf("delete_event with a weak ETag = %d %s", rec.Code, rec.Body)
+	}
+	rec = do(http.MethodDelete, "/delete_event?id="+eventID.String(), "", `"2"`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("delete_event = %d %s", rec.Code, rec.Body)
+	}
+}
+
+// TestRouterREST runs the versioned REST resource tree of events with JSON bodies.
+func TestRouterREST(t *testing.T) {
+	r, tokens := newTestRouter(t)
+
+	token := accessToken(t, tokens, uuid.New())
+	do := func(method string, target string, body string, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		req.Header.Set("Authorization", "Bearer "+token)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		r.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/v1/events", `{"title": "planning", "start": "2024-03-05T10:00:00+03:00", "end": "2024-03-05T11:00:00+03:00", "time_zone": "Europe/Moscow"}`, "")
+	location := rec.Header().Get("Location")
+	if rec.Code != http.StatusCreated || !strings.HasPrefix(location, "/api/v1/events/") {
+		t.Fatalf("POST /api/v1/events = %d %s, Location %s", rec.Code, rec.Body, location)
+	}
+
+	rec = do(http.MethodGet, location, "", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"1"` || !strings.Contains(rec.Body.String(), `"start":"2024-03-05T10:00:00+03:00"`) {
+		t.Fatalf("GET %s = %d %s", location, rec.Code, rec.Body)
+	}
+
+	rec = do(http.MethodPut, location, `{"title": "review", "start": "2024-03-05T12:00:00Z", "end": "2024-03-05T13:00:00Z"}`, `"1"`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Errorf("PUT %s = %d %s", location, rec.Code, rec.Body)
+	}
+	rec = do(http.MethodPut, location, `{"id": "`+uuid.New().String()+`", "title": "review", "start": "2024-03-05T12:00:00Z", "end": "2024-03-05T13:00:00Z"}`, "")
+	if rec.Code != http.StatusBadRequest || !strings.HasPrefix(rec.Body.String(), `{"error":`) {
+		t.Errorf("PUT %s with another id = %d %s", location, rec.Code, rec.Body)
+	}
+
+	// The form routes accept JSON bodies too.
+	rec = do(http.MethodPost, "/create_event", `{"title": "lunch", "start": "2024-03-05T12:30:00Z", "end": "2024-03-05T13:30:00Z"}`, "")
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"conflicts":[{`) {
+		t.Errorf("create_event with JSON = %d %s", rec.Code, rec.Body)
+	}
+	rec = do(http.MethodGet, "/api/v1/events?from=2024-03-05&to=2024-03-06&sort=title", "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"title":"lunch"`) {
+		t.Errorf("GET /api/v1/events = %d %s", rec.Code, rec.Body)
+	}
+
+	// The history of the event lists its revisions with the request IDs, and it can be reverted to any of them.
+	history := "/events/" + strings.TrimPrefix(location, "/api/v1/events/") + "/history"
+	rec = do(http.MethodGet, history, "", "")
+	if rec.Code != http.StatusOK || strings.Count(rec.Body.String(), `"kind":`) != 2 ||
+		!strings.Contains(rec.Body.String(), `"title":{"before":"planning","after":"review"}`) {
+		t.Fatalf("GET %s = %d %s", history, rec.Code, rec.Body)
+	}
+	var revisions struct {
+		Result []entity.Revision `json:"result"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &revisions); err != nil || revisions.Result[0].RequestID == "" {
+		t.Fatalf("GET %s = %s, %v", history, rec.Body, err)
+	}
+	revert := strings.TrimSuffix(history, "/history") + "/revert"
+	req := httptest.NewRequest(http.MethodPost, revert, strings.NewReader(fmt.Sprintf("revision=%d", revisions.Result[0].ID)))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("If-Match", `"2"`)
+	rec = httptest.NewRecorder()
+	r.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"3"` {
+		t.Errorf("POST %s = %d %s", revert, rec.Code, rec.Body)
+	}
+	rec = do(http.MethodGet, location, "", "")
+	if !strings.Contains(rec.Body.String(), `"title":"planning"`) {
+		t.Errorf("GET %s after revert = %d %s", location, rec.Code, rec.Body)
+	}
+
+	rec = do(http.MethodPatch, location, "{}", "")
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") == "" {
+		t.Errorf("PATCH %s = %d %s", location, rec.Code, rec.Body)
+	}
+	rec = do(http.MethodDelete, location, "", `"3"`)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE %s = %d %s", location, rec.Code, rec.Body)
+	}
+	rec = do(http.MethodGet, location, "", "")
+	if rec.Code != http.StatusNotFound || !strings.HasPrefix(rec.Body.String(), `{"error":`) {
+		t.Errorf("GET %s after delete = %d %s", location, rec.Code, rec.Body)
+	}
+	rec = do(http.MethodPut, location, `{"title": "review", "start": "2024-03-05T12:00:00Z", "end": "2024-03-05T13:00:00Z"}`, "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("PUT %s after delete = %d %s", location, rec.Code, rec.Body)
+	}
+	rec = do(http.MethodDelete, location, "", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("DELETE %s after delete = %d %s", location, rec.Code, rec.Body)
+	}
+
+	// A batch is applied at once: the deletion of the deleted event refuses it with the status of the failure.
+	created := uuid.New()
+	operations := `{"op": "create", "event": {"id": "` + created.String() + `", "title": "retro", "start": "2024-03-06T10:00:00Z", "end": "2024-03-06T11:00:00Z"}}`
+	deletion := `{"op": "delete", "id": "` + strings.TrimPrefix(location, "/api/v1/events/") + `"}`
+	rec = do(http.MethodPost, "/events/batch", `{"operations": [`+operations+`, `+deletion+`]}`, "")
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"status":424`) {
+		t.Errorf("POST /events/batch with a failed operation = %d %s", rec.Code, rec.Body)
+	}
+	rec = do(http.MethodPost, "/events/batch?dry_run=true", `{"operations": [`+operations+`]}`, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":201`) || strings.Contains(rec.Body.String(), `"version"`) {
+		t.Errorf("POST /events/batch?dry_run=true = %d %s", rec.Code, rec.Body)
+	}
+	rec = do(http.MethodGet, "/api/v1/events/"+created.String(), "", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET of an event of a failed or dry run batch = %d %s", rec.Code, rec.Body)
+	}
+	rec = do(http.MethodPost, "/events/batch", `{"operations": [`+operations+`]}`, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":201,"version":1`) {
+		t.Errorf("POST /events/batch = %d %s", rec.Code, rec.Body)
+	}
+	rec = do(http.MethodPost, "/events/batch", `{"operations": [{"op": "move"}]}`, "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("POST /events/batch with an unknown op = %d %s", rec.Code, rec.Body)
+	}
+}
+
+// TestRouterSpecification checks that the OpenAPI document describes exactly the registered routes
+// and that mismatching requests are rejected with the list of the mismatches.
+func TestRouterSpecification(t *testing.T) {
+	r, tokens := newTestRouter(t)
+
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// A templated path is served by the subtree pattern up to its first parameter.
+	documented := map[string]bool{}
+	for _, path := range spec.Paths() {
+		pattern, _, _ := strings.Cut(path, "{")
+		documented[pattern] = true
+
+		target := path
+		for strings.Contains(target, "{") {
+			start, end := strings.Index(target, "{"), strings.Index(target, "}")
+			target = target[:start] + uuid.NewString() + target[end+1:]
+		}
+		rec := httptest.NewRecorder()
+		r.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code == http.StatusNotFound {
+			t.Errorf("documented path %s isn't routed", path)
+		}
+	}
+
+	registered := map[string]bool{}
+	for _, pattern := range r.patterns {
+		registered[pattern] = true
+		if !documented[pattern] {
+			t.Errorf("route %s isn't documented", pattern)
+		}
+	}
+	for pattern := range documented {
+		if !registered[pattern] {
+			t.Errorf("documented route %s isn't registered", pattern)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	r.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"openapi": "3.0.3"`) {
+		t.Errorf("openapi.json = %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/events_for_week?date=05.03.2024", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, tokens, uuid.New()))
+	rec = httptest.NewRecorder()
+	r.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"details":[{"in":"query","name":"date","reason":`) {
+		t.Errorf("events_for_week with an invalid date = %d %s", rec.Code, rec.Body)
+	}
+}
+
+// TestRouterOperations checks the probes and that served requests show up in the metrics.
+func TestRouterOperations(t *testing.T) {
+	var migrationErr error
+	source := db.NewMemorySource()
+	r := NewRouter(source, source, source, source, source, source, Options{ReadinessChecks: []handlers.Check{
+		{Name: "db", Check: func(context.Context) error { return nil }},
+		{Name: "migrations", Check: func(context.Context) error { return migrationErr }},
+	}}, zap.NewNop())
+	if err := r.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	if rec := get("/healthz"); rec.Code != http.StatusOK {
+		t.Errorf("healthz = %d %s", rec.Code, rec.Body)
+	}
+	if rec := get("/readyz"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"ready"`) {
+		t.Errorf("readyz = %d %s", rec.Code, rec.Body)
+	}
+	migrationErr = errors.New("dirty database version 3")
+	if rec := get("/readyz"); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"migrations":"dirty database version 3"`) {
+		t.Errorf("readyz with failed migrations = %d %s", rec.Code, rec.Body)
+	}
+	get("/api/v1/events/" + uuid.NewString())
+
+	rec := get("/metrics")
+	for _, line := range []string{
+		`calendar_http_requests_total{route="/readyz",method="GET",status="503"} `,
+		`calendar_http_requests_total{route="/api/v1/events/",method="GET",status="401"} `,
+		`calendar_http_request_duration_seconds_bucket{route="/healthz",method="GET",status="200",le="+Inf"} `,
+		`calendar_http_requests_in_flight{route="/metrics"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), line) {
+			t.Errorf("metrics don't contain %s:\n%s", line, rec.Body)
+		}
+	}
+}
+
+// TestRouterLogging checks that requests are logged with their status, caller, request ID and trace context.
+func TestRouterLogging(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	r, tokens := newConfiguredTestRouter(t, Options{}, zap.New(core))
+
+	userID := uuid.New()
+	parent := trace.New()
+	req := httptest.NewRequest(http.MethodGet, "/events_for_day?date=2024-03-05", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, tokens, userID))
+	req.Header.Set("X-Request-ID", "req-1")
+	req.Header.Set("traceparent", parent.String())
+	rec := httptest.NewRecorder()
+	r.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Request-ID") != "req-1" {
+		t.Fatalf("events_for_day = %d, X-Request-ID %q", rec.Code, rec.Header().Get("X-Request-ID"))
+	}
+
+	entries := logs.FilterMessage("http request").All()
+	if len(entries) != 1 {
+		t.Fatalf("request log entries = %d, expected 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	for key, expected := range map[string]any{
+		"method":     http.MethodGet,
+		"path":       "/events_for_day",
+		"status":     int64(http.StatusOK),
+		"bytes":      int64(rec.Body.Len()),
+		"user_id":    userID.String(),
+		"request_id": "req-1",
+		"trace_id":   parent.String()[3:35],
+	} {
+		if fields[key] != expected {
+			t.Errorf("request log %s = %v, expected %v", key, fields[key], expected)
+		}
+	}
+	if fields["span_id"] == parent.String()[36:52] {
+		t.Errorf("request log span_id = %v, expected a span of the server", fields["span_id"])
+	}
+
+	// Malformed request IDs and trace contexts are replaced.
+	req = httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set("X-Request-ID", "forged\nline")
+	req.Header.Set("traceparent", "00-00000000000000000000000000000000-0000000000000000-01")
+	rec = httptest.NewRecorder()
+	r.mux.ServeHTTP(rec, req)
+	if requestID := rec.Header().Get("X-Request-ID"); uuid.Validate(requestID) != nil {
+		t.Errorf("X-Request-ID of a malformed request ID = %q, expected a generated one", requestID)
+	}
+	entry := logs.All()[logs.Len()-1]
+	if entry.Level != zapcore.InfoLevel || entry.ContextMap()["trace_id"] == "00000000000000000000000000000000" {
+		t.Errorf("request log of a malformed traceparent = %v %v", entry.Level, entry.ContextMap())
+	}
+}
+
+// TestRouterLimits checks the rate limits of the route groups and the size limits of bodies.
+func TestRouterLimits(t *testing.T) {
+	r, tokens := newConfiguredTestRouter(t, Options{
+		RateLimits: RateLimits{
+			Write: ratelimit.New(ratelimit.Limit{Rate: 0.1, Burst: 2}),
+			Auth:  ratelimit.New(ratelimit.Limit{Rate: 0.1, Burst: 1}),
+		},
+		MaxBody:       256,
+		MaxImportBody: 1024,
+	}, zap.NewNop())
+
+	userID, otherID := uuid.New(), uuid.New()
+	do := func(userID uuid.UUID, method string, target string, contentType string, body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, body)
+		req.Header.Set("Content-Type", contentType)
+		if userID != uuid.Nil {
+			req.Header.Set("Authorization", "Bearer "+accessToken(t, tokens, userID))
+		}
+		rec := httptest.NewRecorder()
+		r.mux.ServeHTTP(rec, req)
+		return rec
+	}
+	form := func(title string) io.Reader {
+		return strings.NewReader(url.Values{"title": {title}, "start": {"2024-03-05T10:00"}, "duration": {"1h"}}.Encode())
+	}
+	const formType = "application/x-www-form-urlencoded"
+
+	for i := 0; i < 2; i++ {
+		if rec := do(userID, http.MethodPost, "/create_event", formType, form("planning")); rec.Code != http.StatusCreated {
+			t.Fatalf("create_event %d within the burst = %d %s", i, rec.Code, rec.Body)
+		}
+	}
+	rec := do(userID, http.MethodPost, "/create_event", formType, form("planning"))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "10" {
+		t.Errorf("create_event over the limit = %d %s, Retry-After %q", rec.Code, rec.Body, rec.Header().Get("Retry-After"))
+	}
+	// Reads and other users have limits of their own.
+	if rec := do(userID, http.MethodGet, "/events_for_day?date=2024-03-05", "", nil); rec.Code != http.StatusOK {
+		t.Errorf("events_for_day over the write limit = %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(otherID, http.MethodPost, "/create_event", formType, form("planning")); rec.Code != http.StatusCreated {
+		t.Errorf("create_event of another user = %d %s", rec.Code, rec.Body)
+	}
+
+	// Token refreshes are counted per remote IP.
+	for i, expected := range []int{http.StatusUnauthorized, http.StatusTooManyRequests} {
+		rec := do(uuid.Nil, http.MethodPost, "/refresh_token", formType, strings.NewReader("refresh_token=forged"))
+		if rec.Code != expected {
+			t.Errorf("refresh_token %d = %d %s, expected %d", i, rec.Code, rec.Body, expected)
+		}
+	}
+
+	rec = do(otherID, http.MethodPost, "/create_event", formType, form(strings.Repeat("x", 300)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("create_event with a large body = %d %s", rec.Code, rec.Body)
+	}
+	// A body of an unknown length is cut at the limit.
+	rec = do(otherID, http.MethodPost, "/import_ics", "text/calendar", io.MultiReader(strings.NewReader(strings.Repeat("X-PAD:x\r\n", 200))))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("import_ics with a large body = %d %s", rec.Code, rec.Body)
+	}
+}
+
+// TestRouterChanges checks that the stream of changes resumes after Last-Event-ID,
+// carries the live changes of the caller only and that webhooks keep their secrets.
+func TestRouterChanges(t *testing.T) {
+	r, tokens := newTestRouter(t)
+	server := httptest.NewServer(r.mux)
+	defer server.Close()
+
+	userID, otherID := uuid.New(), uuid.New()
+	do := func(userID uuid.UUID, method string, target string, form url.Values) *http.Response {
+		req, err := http.NewRequest(method, server.URL+target, strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer "+accessToken(t, tokens, userID))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s error = %v", method, target, err)
+		}
+		return resp
+	}
+	create := func(userID uuid.UUID, title string) {
+		resp := do(userID, http.MethodPost, "/create_event", url.Values{"title": {title}, "start": {"2024-03-05T10:00"}, "duration": {"1h"}})
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create_event = %d", resp.StatusCode)
+		}
+	}
+
+	create(userID, "first")
+	create(userID, "second")
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+handlers.StreamPath, nil)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, tokens, userID))
+	req.Header.Set("Last-Event-ID", "1")
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream error = %v", err)
+	}
+	defer stream.Body.Close()
+	if stream.StatusCode != http.StatusOK || stream.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream = %d %s", stream.StatusCode, stream.Header.Get("Content-Type"))
+	}
+
+	create(otherID, "private")
+	create(userID, "third")
+
+	lines := bufio.NewScanner(stream.Body)
+	var events []string
+	for len(events) < 2 && lines.Scan() {
+		line := lines.Text()
+		if strings.HasPrefix(line, "data: ") {
+			var change entity.Change
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &change); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			events = append(events, fmt.Sprintf("%d %s %s", change.ID, change.Kind, change.Event.Title))
+		}
+	}
+	if strings.Join(events, ",") != "2 created second,4 created third" {
+		t.Errorf("streamed %v, want the second change and the live change of the caller", events)
+	}
+
+	resp := do(userID, http.MethodPost, "/create_webhook", url.Values{"url": {"https://example.com/hook"}})
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || !strings.Contains(string(body), `"secret":"`) {
+		t.Errorf("create_webhook = %d %s", resp.StatusCode, body)
+	}
+	resp = do(userID, http.MethodPost, "/create_webhook", url.Values{"url": {"ftp://example.com"}})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("create_webhook with an ftp url = %d", resp.StatusCode)
+	}
+
+	resp = do(userID, http.MethodGet, "/webhooks", nil)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "https://example.com/hook") || strings.Contains(string(body), "secret") {
+		t.Errorf("webhooks = %d %s", resp.StatusCode, body)
+	}
+}
@@ -7,11 +7,12 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/jmoiron/sqlx"
+	"L2/develop/dev11/internal/db"
+
 	"go.uber.org/zap"
 )
 
-// RequestTimeOut defines the timeout duration for HTTP requests.
+// RequestTimeOut defines the timeout duration for HTTP requests: reading them and handling them.
 const RequestTimeOut = 30 * time.Second
 
 // Server represents an HTTP server.
@@ -30,24 +31,28 @@ type Server interface {
 // server represents an HTTP server instance.
 type server struct {
 	server *http.Server
-	db     *sqlx.DB
 	logger *zap.Logger
 }
 
 // NewServer creates a new instance of the HTTP server.
-// It takes the server address, database connection, logger, and cache as input parameters.
+// It takes the server address, event, calendar, change, webhook, trash and revision data sources, API options and logger as input parameters.
 // Returns the HTTP server instance.
 func NewServer(
 	addr string,
-	db *sqlx.DB,
+	eventSource db.EventSource,
+	calendarSource db.CalendarSource,
+	changeSource db.ChangeSource,
+	webhookSource db.WebhookSource,
+	trashSource db.TrashSource,
+	revisionSource db.RevisionSource,
+	options Options,
 	logger *zap.Logger,
 ) *server {
 	s := &server{
-		db:     db,
 		logger: logger,
 	}
 
-	r := NewRouter(db, logger)
+	r := NewRouter(eventSource, calendarSource, changeSource, webhookSource, trashSource, revisionSource, options, logger)
 	err := r.Init()
 	if err != nil {
 		s.logger.Error("can't init router:", zap.Error(err))
@@ -58,6 +63,12 @@ func NewServer(
 		Addr:              addr,
 		Handler:           r.mux,
 		ReadHeaderTimeout: RequestTimeOut,
+		ReadTimeout:       RequestTimeOut,
+		IdleTimeout:       2 * RequestTimeOut,
+	}
+	// Shutdown waits for the open streams of changes, closing the feed ends them.
+	if options.Changes != nil {
+		httpServer.RegisterOnShutdown(options.Changes.Close)
 	}
 	s.server = httpServer
 
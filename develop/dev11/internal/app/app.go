@@ -3,20 +3,49 @@ package app
 import (
 	"L2/develop/dev11/cmd/L2/config"
 	"L2/develop/dev11/internal/api/http"
+	"L2/develop/dev11/internal/api/http/handlers"
+	"L2/develop/dev11/internal/auth"
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/notifier"
+	"L2/develop/dev11/internal/ratelimit"
+	"L2/develop/dev11/internal/repository"
+	"L2/develop/dev11/internal/scheduler"
+	"L2/develop/dev11/internal/trash"
+	"L2/develop/dev11/internal/usecase"
+	"L2/develop/dev11/internal/webhook"
 	"context"
+	"errors"
 	"fmt"
+	nethttp "net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
 
+// notifyTimeout limits a single webhook delivery of a reminder.
+const notifyTimeout = 10 * time.Second
+
 // App represents the main application.
 type App struct {
-	config     *config.Config
-	dbConn     *sqlx.DB
-	logger     *zap.Logger
-	httpServer http.Server
+	config         *config.Config
+	dbConn         *sqlx.DB
+	eventSource    db.EventSource
+	reminderSource db.ReminderSource
+	calendarSource db.CalendarSource
+	changeSource   db.ChangeSource
+	webhookSource  db.WebhookSource
+	trashSource    db.TrashSource
+	revisionSource db.RevisionSource
+	logger         *zap.Logger
+	httpServer     http.Server
+	scheduler      *scheduler.Scheduler
+	dispatcher     *webhook.Dispatcher
+	purger         *trash.Purger
+	// migrationErr is the outcome of the migrations run on start, reported by the readiness probe.
+	migrationErr error
 }
 
 // NewApp creates a new instance of the application.
@@ -38,28 +67,130 @@ func (a *App) Start(ctx context.Context) {
 		}
 	}()
 
-	// Initialize the database
-	dbConn, err := a.initDb(appCtx,
-		a.config.DB.Host,
-		a.config.DB.Port,
-		a.config.DB.Name,
-		a.config.DB.Username,
-		a.config.DB.Password,
-		a.config.DB.SSLMode,
-	)
+	if a.config.DB.Driver == db.DriverMemory {
+		source := db.NewMemorySource()
+		a.eventSource = source
+		a.reminderSource = source
+		a.calendarSource = source
+		a.changeSource = source
+		a.webhookSource = source
+		a.trashSource = source
+		a.revisionSource = source
+	} else {
+		// Initialize the database
+		dbConn, err := a.initDb(appCtx, a.config.DB.Driver, a.dataSourceName())
+		if err != nil {
+			logger.Fatal("init db error", zap.Error(err))
+		}
+		a.dbConn = dbConn
+
+		// Start database migrations
+		err = a.startMigrate(appCtx, migrationsPath, a.config.DB.Driver, a.config.DB.Name, a.dbConn)
+		if err != nil {
+			logger.Error("db migration error", zap.Error(err))
+		}
+		a.migrationErr = err
+
+		source := db.NewSource(a.dbConn, logger, a.config.DB.SlowQuery)
+		a.eventSource = source
+		a.reminderSource = source
+		a.calendarSource = source
+		a.changeSource = source
+		a.webhookSource = source
+		a.trashSource = source
+		a.revisionSource = source
+	}
+
+	tokens, err := NewTokens(a.config)
 	if err != nil {
-		logger.Fatal("init db error", zap.Error(err))
+		logger.Fatal("init auth error", zap.Error(err))
 	}
-	a.dbConn = dbConn
 
-	// Start database migrations
-	err = a.startMigrate(appCtx, migrationsPath, a.config.DB.Name, a.dbConn)
+	reminderNotifier, invitationNotifier, err := a.newNotifier()
 	if err != nil {
-		logger.Error("db migration error", zap.Error(err))
+		logger.Fatal("init notifier error", zap.Error(err))
 	}
+	a.scheduler = scheduler.NewScheduler(
+		repository.NewReminderRepository(a.reminderSource),
+		reminderNotifier,
+		a.config.Reminders.Interval,
+		a.config.Reminders.Lookback,
+		logger,
+	)
+
+	changeRepository := repository.NewChangeRepository(a.changeSource)
+	changes := usecase.NewChangeFeed(changeRepository)
+	a.dispatcher = webhook.NewDispatcher(
+		repository.NewWebhookRepository(a.webhookSource),
+		changeRepository,
+		repository.NewCalendarRepository(a.calendarSource),
+		changes,
+		&nethttp.Client{Timeout: a.config.Webhooks.Timeout},
+		webhook.Config{
+			Interval:    a.config.Webhooks.Interval,
+			MaxAttempts: a.config.Webhooks.MaxAttempts,
+			MaxBackoff:  a.config.Webhooks.MaxBackoff,
+			Retention:   a.config.Webhooks.Retention,
+		},
+		logger,
+	)
+	a.purger = trash.NewPurger(
+		repository.NewTrashRepository(a.trashSource),
+		a.config.Trash.PurgeInterval,
+		a.config.Trash.Retention,
+		logger,
+	)
 
 	wg := &sync.WaitGroup{}
 
+	// Start reminder scheduler
+	wg.Add(1)
+	go func() {
+		defer func() {
+			if e := recover(); e != nil {
+				logger.Panic("scheduler panic", zap.Error(fmt.Errorf("%s", e)))
+			}
+			wg.Done()
+		}()
+
+		err := a.scheduler.Run(appCtx)
+		if err != nil {
+			logger.Error("can't run scheduler", zap.Error(err))
+		}
+	}()
+
+	// Start webhook dispatcher
+	wg.Add(1)
+	go func() {
+		defer func() {
+			if e := recover(); e != nil {
+				logger.Panic("webhook dispatcher panic", zap.Error(fmt.Errorf("%s", e)))
+			}
+			wg.Done()
+		}()
+
+		err := a.dispatcher.Run(appCtx)
+		if err != nil {
+			logger.Error("can't run webhook dispatcher", zap.Error(err))
+		}
+	}()
+
+	// Start trash purger
+	wg.Add(1)
+	go func() {
+		defer func() {
+			if e := recover(); e != nil {
+				logger.Panic("trash purger panic", zap.Error(fmt.Errorf("%s", e)))
+			}
+			wg.Done()
+		}()
+
+		err := a.purger.Run(appCtx)
+		if err != nil {
+			logger.Error("can't run trash purger", zap.Error(err))
+		}
+	}()
+
 	// Start HTTP server
 	wg.Add(1)
 	go func() {
@@ -72,14 +203,28 @@ func (a *App) Start(ctx context.Context) {
 
 		addr := fmt.Sprintf("%s:%d", a.config.HttpServer.Host, a.config.HttpServer.Port)
 
-		a.httpServer = http.NewServer(addr, a.dbConn, logger)
+		options := http.Options{
+			ConflictMode:    usecase.ConflictMode(a.config.Events.ConflictMode),
+			Tokens:          tokens,
+			ReadinessChecks: a.readinessChecks(),
+			RateLimits:      a.rateLimits(),
+			MaxBody:         a.config.HttpServer.MaxBody,
+			MaxImportBody:   a.config.HttpServer.MaxImportBody,
+			Changes:         changes,
+			Invitations:     usecase.NewInvitations(invitationNotifier),
+		}
+		if a.config.Cache.Size > 0 {
+			options.EventCache = repository.NewEventCache(a.config.Cache.Size, a.config.Cache.TTL)
+		}
+
+		a.httpServer = http.NewServer(addr, a.eventSource, a.calendarSource, a.changeSource, a.webhookSource, a.trashSource, a.revisionSource, options, logger)
 		if a.httpServer == nil {
 			cancelApp()
 			logger.Fatal("can't create http server")
 			return
 		}
 
-		err = a.httpServer.Run(appCtx)
+		err := a.httpServer.Run(appCtx)
 		cancelApp()
 		if err != nil {
 			logger.Error("can't start http server", zap.Error(err))
@@ -90,37 +235,147 @@ func (a *App) Start(ctx context.Context) {
 	wg.Wait()
 }
 
-// GracefulShutdown performs a graceful shutdown of the application.
+// GracefulShutdown performs a graceful shutdown of the application. Every component is shut down
+// even if another one fails, the errors are joined.
 func (a *App) GracefulShutdown(ctx context.Context) error {
-	err := a.httpServer.Shutdown(ctx)
-	if err != nil {
-		return fmt.Errorf("can't shutdown http-server: %w", err)
+	var errs []error
+	if err := a.httpServer.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("can't shutdown http-server: %w", err))
+	}
+	if a.scheduler != nil {
+		if err := a.scheduler.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("can't shutdown scheduler: %w", err))
+		}
+	}
+	if a.dispatcher != nil {
+		if err := a.dispatcher.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("can't shutdown webhook dispatcher: %w", err))
+		}
+	}
+	if a.purger != nil {
+		if err := a.purger.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("can't shutdown trash purger: %w", err))
+		}
+	}
+	if a.dbConn != nil {
+		if err := a.dbConn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("can't shutdown db: %w", err))
+		}
 	}
-	err = a.dbConn.Close()
+	return errors.Join(errs...)
+}
+
+// NewTokens creates the tokens authenticating API callers from the configured keys.
+func NewTokens(cfg *config.Config) (*auth.Tokens, error) {
+	keys, err := auth.ParseKeys(cfg.Auth.Keys)
 	if err != nil {
-		return fmt.Errorf("can't shutdown db: %w", err)
+		return nil, fmt.Errorf("invalid AUTH_KEYS: %w", err)
+	}
+
+	return auth.NewTokens(auth.Config{
+		Keys:       keys,
+		Issuer:     cfg.Auth.Issuer,
+		AccessTTL:  cfg.Auth.AccessTTL,
+		RefreshTTL: cfg.Auth.RefreshTTL,
+	})
+}
+
+// readinessChecks returns the checks of the readiness probe: the database answers a ping
+// and has been migrated. The in-memory source is always ready.
+func (a *App) readinessChecks() []handlers.Check {
+	if a.dbConn == nil {
+		return nil
+	}
+
+	return []handlers.Check{
+		{Name: "db", Check: a.dbConn.PingContext},
+		{Name: "migrations", Check: func(context.Context) error { return a.migrationErr }},
 	}
-	return nil
+}
+
+// rateLimits returns the rate limiters of the route groups of the API.
+func (a *App) rateLimits() http.RateLimits {
+	cfg := a.config.RateLimit
+	return http.RateLimits{
+		Read:  ratelimit.New(ratelimit.Limit{Rate: cfg.ReadRate, Burst: cfg.ReadBurst}),
+		Write: ratelimit.New(ratelimit.Limit{Rate: cfg.WriteRate, Burst: cfg.WriteBurst}),
+		Auth:  ratelimit.New(ratelimit.Limit{Rate: cfg.AuthRate, Burst: cfg.AuthBurst}),
+	}
+}
+
+// channel is a notifier of reminders, invitations and responses.
+type channel interface {
+	notifier.Notifier
+	notifier.InvitationNotifier
+}
+
+// newNotifier creates the notifiers of reminders and of invitations from the configured channels,
+// both are delivered through every channel.
+func (a *App) newNotifier() (notifier.Notifier, notifier.InvitationNotifier, error) {
+	cfg := a.config.Reminders
+
+	var channels []channel
+	for _, name := range cfg.Notifiers {
+		switch strings.TrimSpace(name) {
+		case "log":
+			channels = append(channels, notifier.NewLogNotifier(a.logger))
+		case "webhook":
+			if cfg.WebhookURL == "" {
+				return nil, nil, fmt.Errorf("webhook notifier requires NOTIFY_WEBHOOK_URL")
+			}
+			channels = append(channels, notifier.NewWebhookNotifier(cfg.WebhookURL, &nethttp.Client{Timeout: notifyTimeout}))
+		case "smtp":
+			channels = append(channels, notifier.NewSMTPNotifier(notifier.SMTPConfig{
+				Addr:     cfg.SMTPAddr,
+				Username: cfg.SMTPUsername,
+				Password: cfg.SMTPPassword,
+				From:     cfg.SMTPFrom,
+				To:       cfg.SMTPTo,
+			}))
+		default:
+			return nil, nil, fmt.Errorf("unknown notifier %q", name)
+		}
+	}
+	if len(channels) == 0 {
+		return nil, nil, fmt.Errorf("no notifiers configured")
+	}
+
+	notifiers := make([]notifier.Notifier, 0, len(channels))
+	invitationNotifiers := make([]notifier.InvitationNotifier, 0, len(channels))
+	for _, channel := range channels {
+		notifiers = append(notifiers, channel)
+		invitationNotifiers = append(invitationNotifiers, channel)
+	}
+
+	return notifier.Multi(notifiers...), notifier.MultiInvitation(invitationNotifiers...), nil
+}
+
+// dataSourceName returns the connection string of the configured database driver.
+func (a *App) dataSourceName() string {
+	cfg := a.config.DB
+	if cfg.Driver == db.DriverSQLite {
+		return cfg.Path
+	}
+
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.Name, cfg.SSLMode)
 }
 
 // initDb initializes the database.
-func (a *App) initDb(
-	ctx context.Context,
-	host string,
-	port int,
-	dbName string,
-	user string,
-	password string,
-	sslmode string,
-) (*sqlx.DB, error) {
-	db, err := sqlx.ConnectContext(
-		ctx,
-		"postgres",
-		fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s", host, port, user, password, dbName, sslmode),
-	)
+func (a *App) initDb(ctx context.Context, driver string, dataSourceName string) (*sqlx.DB, error) {
+	if driver != db.DriverPostgres && driver != db.DriverSQLite {
+		return nil, fmt.Errorf("unknown db driver %q", driver)
+	}
+
+	conn, err := sqlx.ConnectContext(ctx, driver, dataSourceName)
 	if err != nil {
 		return nil, err
 	}
 
-	return db, nil
+	if driver == db.DriverSQLite {
+		// SQLite allows a single writer, concurrent connections would fail with SQLITE_BUSY.
+		conn.SetMaxOpenConns(1)
+	}
+
+	return conn, nil
 }
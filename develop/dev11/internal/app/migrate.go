@@ -4,37 +4,50 @@ import (
 	"context"
 	"embed"
 	"fmt"
+	"path"
+
+	"L2/develop/dev11/internal/db"
 
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jmoiron/sqlx"
 )
 
 const migrationsPath = "migrations"
 
-//go:embed migrations/*.sql
+//go:embed migrations/*/*.sql
 var fs embed.FS
 
-// startMigrate executes database migrations.
-func (a *App) startMigrate(ctx context.Context, migratePath string, dbName string, db *sqlx.DB) error {
+// startMigrate executes database migrations of the driver, stored in a directory named after it.
+func (a *App) startMigrate(ctx context.Context, migratePath string, driverName string, dbName string, conn *sqlx.DB) error {
 	// Check if the database connection is alive
-	err := db.PingContext(ctx)
+	err := conn.PingContext(ctx)
 	if err != nil {
 		return fmt.Errorf("db connection not alive: %w", err)
 	}
 
 	// Create the migration database driver
-	driver, err := postgres.WithInstance(db.DB, &postgres.Config{
-		DatabaseName: dbName,
-		SchemaName:   "public",
-	})
+	var driver database.Driver
+	switch driverName {
+	case db.DriverPostgres:
+		driver, err = postgres.WithInstance(conn.DB, &postgres.Config{
+			DatabaseName: dbName,
+			SchemaName:   "public",
+		})
+	case db.DriverSQLite:
+		driver, err = sqlite.WithInstance(conn.DB, &sqlite.Config{})
+	default:
+		err = fmt.Errorf("no migrations for driver %q", driverName)
+	}
 	if err != nil {
 		return fmt.Errorf("db migration database driver error: %w", err)
 	}
 
 	// Create the migration source driver
-	source, err := iofs.New(fs, migratePath)
+	source, err := iofs.New(fs, path.Join(migratePath, driverName))
 	if err != nil {
 		return fmt.Errorf("db migration source driver error: %w", err)
 	}
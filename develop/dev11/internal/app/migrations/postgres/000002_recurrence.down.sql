@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS events_series_id_idx;
+
+ALTER TABLE events
+    DROP COLUMN IF EXISTS rrule,
+    DROP COLUMN IF EXISTS exdate,
+    DROP COLUMN IF EXISTS series_id,
+    DROP COLUMN IF EXISTS recurrence_id;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE events
+    ADD COLUMN rrule         varchar NOT NULL DEFAULT '',
+    ADD COLUMN exdate        varchar NOT NULL DEFAULT '',
+    ADD COLUMN series_id     uuid,
+    ADD COLUMN recurrence_id date;
+
+CREATE INDEX events_series_id_idx ON events (series_id);
\ No newline at end of file
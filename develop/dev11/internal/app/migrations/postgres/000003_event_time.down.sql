@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS events_user_id_start_at_idx;
+
+ALTER TABLE events
+    DROP COLUMN IF EXISTS end_at,
+    DROP COLUMN IF EXISTS all_day,
+    DROP COLUMN IF EXISTS time_zone,
+    ALTER COLUMN start_at TYPE date USING (start_at AT TIME ZONE 'UTC')::date;
+
+ALTER TABLE events RENAME COLUMN start_at TO date;
@@ -0,0 +1,14 @@
+ALTER TABLE events RENAME COLUMN date TO start_at;
+
+ALTER TABLE events
+    ALTER COLUMN start_at TYPE timestamptz USING (start_at::timestamp AT TIME ZONE 'UTC'),
+    ADD COLUMN end_at    timestamptz,
+    ADD COLUMN all_day   boolean NOT NULL DEFAULT false,
+    ADD COLUMN time_zone varchar NOT NULL DEFAULT 'UTC';
+
+-- Events created before this migration only had a date, so they become all-day events.
+UPDATE events SET end_at = start_at + interval '1 day', all_day = true;
+
+ALTER TABLE events ALTER COLUMN end_at SET NOT NULL;
+
+CREATE INDEX events_user_id_start_at_idx ON events (user_id, start_at);
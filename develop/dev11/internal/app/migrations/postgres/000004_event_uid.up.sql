@@ -0,0 +1 @@
+ALTER TABLE events ADD COLUMN uid varchar NOT NULL DEFAULT '';
\ No newline at end of file
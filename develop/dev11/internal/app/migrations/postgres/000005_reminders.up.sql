@@ -0,0 +1,12 @@
+ALTER TABLE events ADD COLUMN reminders varchar NOT NULL DEFAULT '';
+
+CREATE TABLE reminder_deliveries
+(
+    event_id     uuid        NOT NULL,
+    start_at     timestamptz NOT NULL,
+    offset_ns    bigint      NOT NULL,
+    delivered_at timestamptz NOT NULL,
+    PRIMARY KEY (event_id, start_at, offset_ns)
+);
+
+CREATE INDEX reminder_deliveries_delivered_at_idx ON reminder_deliveries (delivered_at);
\ No newline at end of file
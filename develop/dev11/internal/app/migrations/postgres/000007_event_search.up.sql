@@ -0,0 +1 @@
+CREATE INDEX events_title_search_idx ON events USING gin (to_tsvector('simple', title));
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS events_calendar_id_start_at_idx;
+ALTER TABLE events DROP COLUMN IF EXISTS calendar_id;
+DROP TABLE IF EXISTS calendar_shares;
+DROP TABLE IF EXISTS calendars;
\ No newline at end of file
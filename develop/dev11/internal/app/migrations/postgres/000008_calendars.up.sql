@@ -0,0 +1,26 @@
+CREATE TABLE calendars
+(
+    id       uuid PRIMARY KEY,
+    owner_id uuid    NOT NULL,
+    name     varchar NOT NULL
+);
+
+CREATE INDEX calendars_owner_id_idx ON calendars (owner_id);
+
+CREATE TABLE calendar_shares
+(
+    calendar_id uuid    NOT NULL,
+    user_id     uuid    NOT NULL,
+    role        varchar NOT NULL,
+    PRIMARY KEY (calendar_id, user_id)
+);
+
+CREATE INDEX calendar_shares_user_id_idx ON calendar_shares (user_id);
+
+-- Existing events move to the default calendars of their users, which have the IDs of the users.
+ALTER TABLE events ADD COLUMN calendar_id uuid;
+UPDATE events SET calendar_id = user_id;
+INSERT INTO calendars (id, owner_id, name)
+SELECT DISTINCT user_id, user_id, 'Default' FROM events WHERE user_id IS NOT NULL;
+
+CREATE INDEX events_calendar_id_start_at_idx ON events (calendar_id, start_at);
\ No newline at end of file
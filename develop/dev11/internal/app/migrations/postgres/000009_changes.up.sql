@@ -0,0 +1,26 @@
+CREATE TABLE event_changes
+(
+    id          bigserial PRIMARY KEY,
+    kind        varchar     NOT NULL,
+    event_id    uuid        NOT NULL,
+    calendar_id uuid        NOT NULL,
+    user_id     uuid        NOT NULL,
+    version     bigint      NOT NULL,
+    event       text,
+    changed_at  timestamptz NOT NULL
+);
+
+CREATE INDEX event_changes_calendar_id_idx ON event_changes (calendar_id, id);
+CREATE INDEX event_changes_changed_at_idx ON event_changes (changed_at);
+
+CREATE TABLE webhooks
+(
+    id             uuid PRIMARY KEY,
+    user_id        uuid        NOT NULL,
+    url            varchar     NOT NULL,
+    secret         varchar     NOT NULL,
+    last_change_id bigint      NOT NULL,
+    created_at     timestamptz NOT NULL
+);
+
+CREATE INDEX webhooks_user_id_idx ON webhooks (user_id);
\ No newline at end of file
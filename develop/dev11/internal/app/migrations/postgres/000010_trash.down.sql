@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS events_deleted_at_idx;
+ALTER TABLE events DROP COLUMN IF EXISTS deleted_at;
\ No newline at end of file
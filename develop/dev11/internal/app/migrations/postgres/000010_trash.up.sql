@@ -0,0 +1,3 @@
+ALTER TABLE events ADD COLUMN deleted_at timestamptz;
+
+CREATE INDEX events_deleted_at_idx ON events (deleted_at) WHERE deleted_at IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE event_revisions
+(
+    id           bigserial PRIMARY KEY,
+    kind         varchar     NOT NULL,
+    event_id     uuid        NOT NULL,
+    calendar_id  uuid        NOT NULL,
+    actor_id     uuid        NOT NULL,
+    request_id   varchar     NOT NULL DEFAULT '',
+    version      bigint      NOT NULL,
+    before_event text,
+    after_event  text,
+    diff         text        NOT NULL,
+    changed_at   timestamptz NOT NULL
+);
+
+CREATE INDEX event_revisions_event_id_idx ON event_revisions (event_id, id);
\ No newline at end of file
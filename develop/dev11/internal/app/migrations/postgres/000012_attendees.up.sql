@@ -0,0 +1 @@
+ALTER TABLE events ADD COLUMN attendees text NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS event_tags;
+DROP TABLE IF EXISTS tags;
+ALTER TABLE events DROP COLUMN IF EXISTS color;
+ALTER TABLE events DROP COLUMN IF EXISTS location;
+ALTER TABLE events DROP COLUMN IF EXISTS description;
@@ -0,0 +1,18 @@
+ALTER TABLE events ADD COLUMN description text NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN location varchar NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN color varchar NOT NULL DEFAULT '';
+
+CREATE TABLE tags
+(
+    id   bigserial PRIMARY KEY,
+    name varchar NOT NULL UNIQUE
+);
+
+CREATE TABLE event_tags
+(
+    event_id uuid   NOT NULL,
+    tag_id   bigint NOT NULL,
+    PRIMARY KEY (event_id, tag_id)
+);
+
+CREATE INDEX event_tags_tag_id_idx ON event_tags (tag_id);
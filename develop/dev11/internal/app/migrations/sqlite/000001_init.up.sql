@@ -0,0 +1,18 @@
+CREATE TABLE events
+(
+    id            uuid PRIMARY KEY,
+    uid           varchar   NOT NULL DEFAULT '',
+    title         varchar,
+    user_id       uuid,
+    start_at      timestamp NOT NULL,
+    end_at        timestamp NOT NULL,
+    all_day       boolean   NOT NULL DEFAULT false,
+    time_zone     varchar   NOT NULL DEFAULT 'UTC',
+    rrule         varchar   NOT NULL DEFAULT '',
+    exdate        varchar   NOT NULL DEFAULT '',
+    series_id     uuid,
+    recurrence_id date
+);
+
+CREATE INDEX events_series_id_idx ON events (series_id);
+CREATE INDEX events_user_id_start_at_idx ON events (user_id, start_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS reminder_deliveries;
+
+ALTER TABLE events DROP COLUMN reminders;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS event_changes;
\ No newline at end of file
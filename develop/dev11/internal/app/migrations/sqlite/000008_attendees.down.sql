@@ -0,0 +1 @@
+ALTER TABLE events DROP COLUMN attendees;
\ No newline at end of file
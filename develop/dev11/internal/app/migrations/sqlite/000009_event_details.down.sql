@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS event_tags;
+DROP TABLE IF EXISTS tags;
+ALTER TABLE events DROP COLUMN color;
+ALTER TABLE events DROP COLUMN location;
+ALTER TABLE events DROP COLUMN description;
@@ -0,0 +1,232 @@
+// Package auth issues and verifies HMAC-signed JSON Web Tokens (RFC 7519, HS256)
+// identifying the user calling the API.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// leeway tolerates the clock skew between the issuer and the verifier.
+const leeway = time.Minute
+
+var (
+	// ErrInvalidToken is returned for malformed tokens, unknown keys and wrong signatures.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrExpiredToken is returned for tokens past their expiration time.
+	ErrExpiredToken = errors.New("token expired")
+)
+
+// Use tells access tokens, accepted by the API, from refresh tokens, exchanged for new pairs.
+type Use string
+
+const (
+	UseAccess  Use = "access"
+	UseRefresh Use = "refresh"
+)
+
+// Key is a named HMAC secret. The name is sent in the kid header of the tokens it signs.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// ParseKeys parses keys given as id:secret. Secrets must be at least 32 bytes long.
+func ParseKeys(values []string) ([]Key, error) {
+	var keys []Key
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		id, secret, ok := strings.Cut(value, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("key must be id:secret")
+		}
+		if len(secret) < sha256.Size {
+			return nil, fmt.Errorf("secret of key %q is shorter than %d bytes", id, sha256.Size)
+		}
+		keys = append(keys, Key{ID: id, Secret: []byte(secret)})
+	}
+
+	return keys, nil
+}
+
+// Config configures the tokens.
+type Config struct {
+	// Keys verify tokens, the first one also signs new tokens. Older keys are kept
+	// after the first one while tokens signed by them are still valid.
+	Keys       []Key
+	Issuer     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// Pair is the result of a sign-in or a refresh.
+type Pair struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token"`
+}
+
+// header is the JOSE header of a token.
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// claims is the payload of a token.
+type claims struct {
+	Issuer    string    `json:"iss"`
+	Subject   uuid.UUID `json:"sub"`
+	IssuedAt  int64     `json:"iat"`
+	ExpiresAt int64     `json:"exp"`
+	Use       Use       `json:"use"`
+}
+
+// Tokens issues and verifies tokens.
+type Tokens struct {
+	config Config
+	keys   map[string][]byte
+	now    func() time.Time
+}
+
+// NewTokens creates tokens signed by the first configured key.
+func NewTokens(config Config) (*Tokens, error) {
+	if len(config.Keys) == 0 {
+		return nil, fmt.Errorf("no signing keys configured")
+	}
+	if config.AccessTTL <= 0 || config.RefreshTTL <= 0 {
+		return nil, fmt.Errorf("token ttl must be positive")
+	}
+
+	keys := make(map[string][]byte, len(config.Keys))
+	for _, key := range config.Keys {
+		if _, ok := keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key %q", key.ID)
+		}
+		keys[key.ID] = key.Secret
+	}
+
+	return &Tokens{
+		config: config,
+		keys:   keys,
+		now:    time.Now,
+	}, nil
+}
+
+// Issue returns a new access and refresh token pair of the user.
+func (t *Tokens) Issue(userID uuid.UUID) (*Pair, error) {
+	now := t.now()
+
+	access, err := t.sign(userID, UseAccess, now, t.config.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := t.sign(userID, UseRefresh, now, t.config.RefreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pair{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresAt:    now.Add(t.config.AccessTTL).Truncate(time.Second),
+		RefreshToken: refresh,
+	}, nil
+}
+
+// Refresh verifies a refresh token and issues a new pair for its user.
+func (t *Tokens) Refresh(token string) (*Pair, error) {
+	userID, err := t.Verify(token, UseRefresh)
+	if err != nil {
+		return nil, err
+	}
+	return t.Issue(userID)
+}
+
+// Verify checks the signature, the issuer, the expiration time and the use of the token
+// and returns the user it identifies.
+func (t *Tokens) Verify(token string, use Use) (uuid.UUID, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil || h.Alg != "HS256" {
+		return uuid.Nil, ErrInvalidToken
+	}
+	secret, ok := t.keys[h.Kid]
+	if !ok {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, sign(secret, parts[0]+"."+parts[1])) {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+	if c.Issuer != t.config.Issuer || c.Use != use || c.Subject == uuid.Nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+	if !t.now().Before(time.Unix(c.ExpiresAt, 0).Add(leeway)) {
+		return uuid.Nil, ErrExpiredToken
+	}
+
+	return c.Subject, nil
+}
+
+// sign creates a token of the user valid for ttl since now.
+func (t *Tokens) sign(userID uuid.UUID, use Use, now time.Time, ttl time.Duration) (string, error) {
+	key := t.config.Keys[0]
+
+	h, err := json.Marshal(header{Alg: "HS256", Typ: "JWT", Kid: key.ID})
+	if err != nil {
+		return "", fmt.Errorf("can't marshal token header: %v", err)
+	}
+	c, err := json.Marshal(claims{
+		Issuer:    t.config.Issuer,
+		Subject:   userID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+		Use:       use,
+	})
+	if err != nil {
+		return "", fmt.Errorf("can't marshal token claims: %v", err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sign(key.Secret, unsigned)), nil
+}
+
+// sign returns the HMAC-SHA256 of data.
+func sign(secret []byte, data string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// decodeSegment decodes a base64url JSON segment of a token.
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
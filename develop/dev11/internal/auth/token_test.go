@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTokens(t *testing.T) {
+	oldKey := Key{ID: "old", Secret: []byte(strings.Repeat("o", 32))}
+	newKey := Key{ID: "new", Secret: []byte(strings.Repeat("n", 32))}
+	config := Config{Keys: []Key{oldKey}, Issuer: "calendar", AccessTTL: 15 * time.Minute, RefreshTTL: 24 * time.Hour}
+
+	now := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	newTokens := func(config Config) *Tokens {
+		tokens, err := NewTokens(config)
+		if err != nil {
+			t.Fatalf("NewTokens() error = %v", err)
+		}
+		tokens.now = func() time.Time { return now }
+		return tokens
+	}
+
+	userID := uuid.New()
+	tokens := newTokens(config)
+	pair, err := tokens.Issue(userID)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if got, err := tokens.Verify(pair.AccessToken, UseAccess); err != nil || got != userID {
+		t.Errorf("Verify() = %s, %v, expected %s", got, err, userID)
+	}
+	if _, err := tokens.Verify(pair.RefreshToken, UseAccess); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() of refresh token as access error = %v, expected ErrInvalidToken", err)
+	}
+
+	// A tampered payload breaks the signature.
+	parts := strings.Split(pair.AccessToken, ".")
+	forged, _ := newTokens(config).sign(uuid.New(), UseAccess, now, time.Hour)
+	tampered := parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2]
+	if _, err := tokens.Verify(tampered, UseAccess); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() of tampered token error = %v, expected ErrInvalidToken", err)
+	}
+
+	// After the rotation tokens of the old key stay valid while it is configured.
+	rotated := config
+	rotated.Keys = []Key{newKey, oldKey}
+	if _, err := newTokens(rotated).Verify(pair.AccessToken, UseAccess); err != nil {
+		t.Errorf("Verify() after rotation error = %v", err)
+	}
+	rotated.Keys = []Key{newKey}
+	if _, err := newTokens(rotated).Verify(pair.AccessToken, UseAccess); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() with removed key error = %v, expected ErrInvalidToken", err)
+	}
+
+	now = now.Add(config.AccessTTL + leeway)
+	if _, err := tokens.Verify(pair.AccessToken, UseAccess); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("Verify() of expired token error = %v, expected ErrExpiredToken", err)
+	}
+	refreshed, err := tokens.Refresh(pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if got, err := tokens.Verify(refreshed.AccessToken, UseAccess); err != nil || got != userID {
+		t.Errorf("Verify() of refreshed token = %s, %v, expected %s", got, err, userID)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys([]string{"k1:" + strings.Repeat("a", 32), " k2:" + strings.Repeat("b", 40), ""})
+	if err != nil || len(keys) != 2 || keys[1].ID != "k2" {
+		t.Errorf("ParseKeys() = %+v, %v", keys, err)
+	}
+
+	for _, value := range []string{"secret", ":" + strings.Repeat("a", 32), "k1:short"} {
+		if _, err := ParseKeys([]string{value}); err == nil {
+			t.Errorf("ParseKeys(%q) expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *source) CreateCalendar(ctx context.Context, calendar *entity.Calendar) error {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "CreateCalendar", time.Now())
+
+	_, err := s.conn(ctx).ExecContext(
+		dbCtx,
+		"INSERT INTO calendars (id, owner_id, name) VALUES ($1, $2, $3)",
+		calendar.ID, calendar.OwnerID, calendar.Name,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("calendar %s: %w", calendar.ID, ErrAlreadyExists)
+		}
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return nil
+}
+
+// UpdateCalendar renames the calendar, the owner never changes.
+func (s *source) UpdateCalendar(ctx context.Context, calendar *entity.Calendar) error {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "UpdateCalendar", time.Now())
+
+	result, err := s.conn(ctx).ExecContext(dbCtx, "UPDATE calendars SET name = $1 WHERE id = $2", calendar.Name, calendar.ID)
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("calendar %s: %w", calendar.ID, ErrNotFound)
+	}
+
+	return nil
+}
+
+// DeleteCalendar deletes the calendar together with its events, their tags and its shares.
+func (s *source) DeleteCalendar(ctx context.Context, calendarID uuid.UUID) error {
+	defer s.observeQuery(ctx, "DeleteCalendar", time.Now())
+
+	return s.WithinTx(ctx, func(ctx context.Context) error {
+		dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+		defer dbCancel()
+
+		for _, query := range []string{
+			"DELETE FROM event_tags WHERE event_id IN (SELECT id FROM events WHERE calendar_id = $1)",
+			"DELETE FROM events WHERE calendar_id = $1",
+			"DELETE FROM calendar_shares WHERE calendar_id = $1",
+			"DELETE FROM calendars WHERE id = $1",
+		} {
+			_, err := s.conn(ctx).ExecContext(dbCtx, query, calendarID)
+			if err != nil {
+				return fmt.Errorf("can't exec query: %v", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *source) GetCalendar(ctx context.Context, calendarID uuid.UUID) (*entity.Calendar, error) {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetCalendar", time.Now())
+
+	var calendar entity.Calendar
+	err := s.conn(ctx).GetContext(dbCtx, &calendar, "SELECT id, owner_id, name FROM calendars WHERE id = $1", calendarID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("calendar %s: %w", calendarID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return &calendar, nil
+}
+
+// GetUserCalendars returns the calendars the user owns or which are shared with the user,
+// with the role of the user, ordered by name.
+func (s *source) GetUserCalendars(ctx context.Context, userID uuid.UUID) (entity.Calendars, error) {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetUserCalendars", time.Now())
+
+	calendars := entity.Calendars{}
+	err := s.conn(ctx).SelectContext(
+		dbCtx,
+		&calendars,
+		`SELECT id, owner_id, name, 'admin' AS role FROM calendars WHERE owner_id = $1
+		UNION ALL
+		SELECT c.id, c.owner_id, c.name, s.role FROM calendars c
+		JOIN calendar_shares s ON s.calendar_id = c.id
+		WHERE s.user_id = $1
+		ORDER BY name, id`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return calendars, nil
+}
+
+// GetCalendarRole returns the role of the user in the calendar, admin for the owner
+// and an empty role if the calendar isn't shared with the user.
+func (s *source) GetCalendarRole(ctx context.Context, calendarID uuid.UUID, userID uuid.UUID) (entity.Role, error) {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetCalendarRole", time.Now())
+
+	var role entity.Role
+	err := s.conn(ctx).GetContext(
+		dbCtx,
+		&role,
+		`SELECT CASE WHEN c.owner_id = $2 THEN 'admin' ELSE COALESCE(s.role, '') END
+		FROM calendars c
+		LEFT JOIN calendar_shares s ON s.calendar_id = c.id AND s.user_id = $2
+		WHERE c.id = $1`,
+		calendarID, userID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("calendar %s: %w", calendarID, ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return role, nil
+}
+
+func (s *source) GetCalendarShares(ctx context.Context, calendarID uuid.UUID) ([]entity.Share, error) {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetCalendarShares", time.Now())
+
+	shares := []entity.Share{}
+	err := s.conn(ctx).SelectContext(
+		dbCtx,
+		&shares,
+		"SELECT calendar_id, user_id, role FROM calendar_shares WHERE calendar_id = $1 ORDER BY user_id",
+		calendarID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return shares, nil
+}
+
+// SetCalendarShare grants the role to the user, replacing the role the user had.
+func (s *source) SetCalendarShare(ctx context.Context, share *entity.Share) error {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "SetCalendarShare", time.Now())
+
+	_, err := s.conn(ctx).ExecContext(
+		dbCtx,
+		`INSERT INTO calendar_shares (calendar_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (calendar_id, user_id) DO UPDATE SET role = excluded.role`,
+		share.CalendarID, share.UserID, share.Role,
+	)
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return nil
+}
+
+func (s *source) DeleteCalendarShare(ctx context.Context, calendarID uuid.UUID, userID uuid.UUID) error {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "DeleteCalendarShare", time.Now())
+
+	_, err := s.conn(ctx).ExecContext(
+		dbCtx,
+		"DELETE FROM calendar_shares WHERE calendar_id = $1 AND user_id = $2",
+		calendarID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// changeRow is a row of the change log, the event after the change is stored as JSON.
+type changeRow struct {
+	ID         int64          `db:"id"`
+	Kind       string         `db:"kind"`
+	EventID    uuid.UUID      `db:"event_id"`
+	CalendarID uuid.UUID      `db:"calendar_id"`
+	UserID     uuid.UUID      `db:"user_id"`
+	Version    int64          `db:"version"`
+	Event      sql.NullString `db:"event"`
+	ChangedAt  time.Time      `db:"changed_at"`
+}
+
+func (r *changeRow) change() (entity.Change, error) {
+	change := entity.Change{
+		ID:         r.ID,
+		Kind:       entity.ChangeKind(r.Kind),
+		EventID:    r.EventID,
+		CalendarID: r.CalendarID,
+		UserID:     r.UserID,
+		Version:    r.Version,
+		ChangedAt:  r.ChangedAt.UTC(),
+	}
+	if r.Event.Valid {
+		event, err := entity.UnmarshalEvent([]byte(r.Event.String))
+		if err != nil {
+			return change, fmt.Errorf("can't unmarshal event of change %d: %v", r.ID, err)
+		}
+		change.Event = event
+	}
+	return change, nil
+}
+
+// AppendChange appends the change to the log and sets its ID.
+func (s *source) AppendChange(ctx context.Context, change *entity.Change) error {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "AppendChange", time.Now())
+
+	var event sql.NullString
+	if change.Event != nil {
+		data, err := json.Marshal(change.Event)
+		if err != nil {
+			return fmt.Errorf("can't marshal event: %v", err)
+		}
+		event = sql.NullString{String: string(data), Valid: true}
+	}
+
+	err := s.conn(ctx).QueryRowContext(
+		dbCtx,
+		`INSERT INTO event_changes (kind, event_id, calendar_id, user_id, version, event, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		change.Kind, change.EventID, change.CalendarID, change.UserID, change.Version, event, change.ChangedAt.UTC(),
+	).Scan(&change.ID)
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return nil
+}
+
+// GetChanges returns up to limit changes of events of the calendars after the change afterID, oldest first.
+func (s *source) GetChanges(ctx context.Context, afterID int64, calendarIDs []uuid.UUID, limit int) ([]entity.Change, error) {
+	if len(calendarIDs) == 0 {
+		return nil, nil
+	}
+
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetChanges", time.Now())
+
+	args, in := appendIDs([]any{afterID}, calendarIDs)
+	args = append(args, limit)
+	var rows []changeRow
+	err := s.conn(ctx).SelectContext(
+		dbCtx,
+		&rows,
+		fmt.Sprintf("SELECT * FROM event_changes WHERE id > $1 AND calendar_id IN (%s) ORDER BY id LIMIT $%d", in, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+
+	changes := make([]entity.Change, 0, len(rows))
+	for i := range rows {
+		change, err := rows[i].change()
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// GetLastChangeID returns the ID of the last change, zero if the log is empty.
+func (s *source) GetLastChangeID(ctx context.Context) (int64, error) {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetLastChangeID", time.Now())
+
+	var id int64
+	err := s.conn(ctx).GetContext(dbCtx, &id, "SELECT COALESCE(MAX(id), 0) FROM event_changes")
+	if err != nil {
+		return 0, fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return id, nil
+}
+
+// DeleteChanges drops the changes made before the time from the log.
+func (s *source) DeleteChanges(ctx context.Context, changedBefore time.Time) error {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "DeleteChanges", time.Now())
+
+	_, err := s.conn(ctx).ExecContext(dbCtx, "DELETE FROM event_changes WHERE changed_at < $1", changedBefore.UTC())
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return nil
+}
+
+func (s *source) CreateWebhook(ctx context.Context, webhook *entity.Webhook) error {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "CreateWebhook", time.Now())
+
+	_, err := s.conn(ctx).ExecContext(
+		dbCtx,
+		"INSERT INTO webhooks (id, user_id, url, secret, last_change_id, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		webhook.ID, webhook.UserID, webhook.URL, webhook.Secret, webhook.LastChangeID, webhook.CreatedAt.UTC(),
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("webhook %s: %w", webhook.ID, ErrAlreadyExists)
+		}
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return nil
+}
+
+func (s *source) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "DeleteWebhook", time.Now())
+
+	result, err := s.conn(ctx).ExecContext(dbCtx, "DELETE FROM webhooks WHERE id = $1", webhookID)
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("webhook %s: %w", webhookID, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *source) GetWebhook(ctx context.Context, webhookID uuid.UUID) (*entity.Webhook, error) {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetWebhook", time.Now())
+
+	var webhook entity.Webhook
+	err := s.conn(ctx).GetContext(dbCtx, &webhook, "SELECT * FROM webhooks WHERE id = $1", webhookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("webhook %s: %w", webhookID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+	webhook.CreatedAt = webhook.CreatedAt.UTC()
+
+	return &webhook, nil
+}
+
+// GetUserWebhooks returns the webhooks of the user, oldest first.
+func (s *source) GetUserWebhooks(ctx context.Context, userID uuid.UUID) ([]entity.Webhook, error) {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetUserWebhooks", time.Now())
+
+	webhooks := []entity.Webhook{}
+	err := s.conn(ctx).SelectContext(dbCtx, &webhooks, "SELECT * FROM webhooks WHERE user_id = $1 ORDER BY created_at, id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+	for i := range webhooks {
+		webhooks[i].CreatedAt = webhooks[i].CreatedAt.UTC()
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhooks returns the webhooks of every user.
+func (s *source) GetWebhooks(ctx context.Context) ([]entity.Webhook, error) {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetWebhooks", time.Now())
+
+	var webhooks []entity.Webhook
+	err := s.conn(ctx).SelectContext(dbCtx, &webhooks, "SELECT * FROM webhooks ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return webhooks, nil
+}
+
+// SetWebhookCursor records the last change delivered to the webhook.
+func (s *source) SetWebhookCursor(ctx context.Context, webhookID uuid.UUID, lastChangeID int64) error {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "SetWebhookCursor", time.Now())
+
+	_, err := s.conn(ctx).ExecContext(dbCtx, "UPDATE webhooks SET last_change_id = $1 WHERE id = $2", lastChangeID, webhookID)
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return nil
+}
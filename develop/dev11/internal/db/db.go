@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
 )
 
 // QueryTimeout specifies the maximum time allowed for a database query to execute.
@@ -13,14 +14,27 @@ const (
 	QueryTimeout = 10 * time.Second
 )
 
-// source represents the data source for interacting with the database.
+// Supported database drivers.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// source represents the data source for interacting with an SQL database, Postgres or SQLite.
+// Times are stored in UTC, so that SQLite can compare them as text.
 type source struct {
-	db *sqlx.DB
+	db        *sqlx.DB
+	logger    *zap.Logger
+	slowQuery time.Duration
 }
 
 // NewSource creates a new instance of the database source with the provided SQLx database connection.
-func NewSource(db *sqlx.DB) *source {
+// Queries running longer than slowQuery are logged with the request they serve, zero disables the log.
+func NewSource(db *sqlx.DB, logger *zap.Logger, slowQuery time.Duration) *source {
 	return &source{
-		db: db,
+		db:        db,
+		logger:    logger,
+		slowQuery: slowQuery,
 	}
 }
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// ErrNotFound is returned when the requested row doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrVersionConflict is returned when a row was changed since the version the write is based on.
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrAlreadyExists is returned when a row with the same key exists already.
+var ErrAlreadyExists = errors.New("already exists")
+
+// SQLite extended result codes of violated keys.
+const (
+	sqlitePrimaryKey = 1555
+	sqliteUnique     = 2067
+)
+
+// isUniqueViolation reports whether the driver error is a violated primary or unique key.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+
+	// The SQLite driver is registered by the application, its errors only expose the code.
+	var sqliteErr interface{ Code() int }
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlitePrimaryKey || sqliteErr.Code() == sqliteUnique
+	}
+
+	return false
+}
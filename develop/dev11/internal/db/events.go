@@ -3,150 +3,372 @@ package db
 import (
 	"L2/develop/dev11/internal/entity"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// CreateEvent saves the event together with its tags.
 func (s *source) CreateEvent(ctx context.Context, event *entity.Event) error {
-	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
-	defer dbCancel()
+	defer s.observeQuery(ctx, "CreateEvent", time.Now())
 
-	row := s.db.QueryRowContext(
-		dbCtx,
-		"INSERT INTO events (id, title, date, user_id) VALUES ($1, $2, $3, $4);",
-		event.ID, event.Title, event.Date, event.UserID,
-	)
-	if err := row.Err(); err != nil {
-		return fmt.Errorf("can't exec query: %v", err)
-	}
+	return s.WithinTx(ctx, func(ctx context.Context) error {
+		dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+		defer dbCancel()
 
-	return nil
+		_, err := s.conn(ctx).ExecContext(
+			dbCtx,
+			`INSERT INTO events (id, uid, title, user_id, calendar_id, start_at, end_at, all_day, time_zone, rrule, exdate, series_id,
+			recurrence_id, reminders, attendees, description, location, color, version)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, 1);`,
+			event.ID, event.UID, event.Title, event.UserID, event.CalendarID, event.Start.UTC(), event.End.UTC(), event.AllDay,
+			event.TimeZone, event.RRule, event.ExDates, event.SeriesID, event.RecurrenceID, event.Reminders, event.Attendees,
+			event.Description, event.Place, event.Color,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("event %s: %w", event.ID, ErrAlreadyExists)
+			}
+			return fmt.Errorf("can't exec query: %v", err)
+		}
+		if err := s.saveTags(dbCtx, event.ID, event.Tags); err != nil {
+			return err
+		}
+		event.Version = 1
+
+		return nil
+	})
 }
 
+// UpdateEvent updates the event and replaces its tags if its stored version is still event.Version
+// and increments the version.
+// It fails with ErrVersionConflict if the event was changed meanwhile and with ErrNotFound if it doesn't exist.
 func (s *source) UpdateEvent(ctx context.Context, event *entity.Event) error {
-	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
-	defer dbCancel()
+	defer s.observeQuery(ctx, "UpdateEvent", time.Now())
 
-	row := s.db.QueryRowContext(
-		dbCtx,
-		"UPDATE events SET title = $1, date = $2 WHERE id = $3;",
-		event.Title, event.Date, event.ID,
-	)
-	if err := row.Err(); err != nil {
-		return fmt.Errorf("can't exec query: %v", err)
+	return s.WithinTx(ctx, func(ctx context.Context) error {
+		dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+		defer dbCancel()
+
+		result, err := s.conn(ctx).ExecContext(
+			dbCtx,
+			`UPDATE events SET title = $1, start_at = $2, end_at = $3, all_day = $4, time_zone = $5, rrule = $6, exdate = $7,
+			reminders = $8, attendees = $9, description = $10, location = $11, color = $12, version = version + 1
+			WHERE id = $13 AND version = $14 AND deleted_at IS NULL;`,
+			event.Title, event.Start.UTC(), event.End.UTC(), event.AllDay, event.TimeZone, event.RRule, event.ExDates,
+			event.Reminders, event.Attendees, event.Description, event.Place, event.Color, event.ID, event.Version,
+		)
+		if err != nil {
+			return fmt.Errorf("can't exec query: %v", err)
+		}
+		if err := s.checkVersionMatched(dbCtx, result, event.ID); err != nil {
+			return err
+		}
+		if err := s.saveTags(dbCtx, event.ID, event.Tags); err != nil {
+			return err
+		}
+		event.Version++
+
+		return nil
+	})
+}
+
+// DeleteEvent moves the event together with the occurrences overriding it to the trash.
+// A non-zero version makes the deletion conditional like UpdateEvent, a missing event is ErrNotFound.
+func (s *source) DeleteEvent(ctx context.Context, eventID uuid.UUID, version int64) error {
+	defer s.observeQuery(ctx, "DeleteEvent", time.Now())
+
+	return s.WithinTx(ctx, func(ctx context.Context) error {
+		dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+		defer dbCancel()
+
+		deletedAt := time.Now().UTC()
+		query, args := "UPDATE events SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL", []any{deletedAt, eventID}
+		if version != 0 {
+			query, args = query+" AND version = $3", append(args, version)
+		}
+		result, err := s.conn(ctx).ExecContext(dbCtx, query, args...)
+		if err != nil {
+			return fmt.Errorf("can't exec query: %v", err)
+		}
+		if err := s.checkVersionMatched(dbCtx, result, eventID); err != nil {
+			return err
+		}
+
+		// The overrides share the deletion time of the series, so that they are restored with it.
+		_, err = s.conn(ctx).ExecContext(
+			dbCtx, "UPDATE events SET deleted_at = $1 WHERE series_id = $2 AND deleted_at IS NULL", deletedAt, eventID,
+		)
+		if err != nil {
+			return fmt.Errorf("can't exec query: %v", err)
+		}
+
+		return nil
+	})
+}
+
+// Batch applies the operations in one transaction, so either all of them are applied or none.
+// The error of a failed operation tells its index in the batch.
+func (s *source) Batch(ctx context.Context, operations []entity.BatchOperation) error {
+	defer s.observeQuery(ctx, "Batch", time.Now())
+
+	return s.WithinTx(ctx, func(ctx context.Context) error {
+		return applyBatch(ctx, s, operations)
+	})
+}
+
+// applyBatch applies the operations one by one through the source, the caller runs it within a transaction.
+func applyBatch(ctx context.Context, source EventSource, operations []entity.BatchOperation) error {
+	for n := range operations {
+		var err error
+		operation := &operations[n]
+		switch operation.Op {
+		case entity.BatchCreate:
+			err = source.CreateEvent(ctx, operation.Event)
+		case entity.BatchUpdate:
+			err = source.UpdateEvent(ctx, operation.Event)
+		case entity.BatchDelete:
+			err = source.DeleteEvent(ctx, operation.ID, operation.Version)
+		default:
+			err = fmt.Errorf("unknown op %q", operation.Op)
+		}
+		if err != nil {
+			return fmt.Errorf("operation %d: %w", n, err)
+		}
 	}
 
 	return nil
 }
 
-func (s *source) DeleteEvent(ctx context.Context, eventID uuid.UUID) error {
-	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
-	defer dbCancel()
+// checkVersionMatched tells why a conditional write of the event matched no row, if it didn't.
+func (s *source) checkVersionMatched(ctx context.Context, result sql.Result, eventID uuid.UUID) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+	if n > 0 {
+		return nil
+	}
 
-	row := s.db.QueryRowContext(
-		dbCtx,
-		"DELETE events WHERE id = $1",
-		eventID,
-	)
-	if err := row.Err(); err != nil {
+	var version int64
+	err = s.conn(ctx).GetContext(ctx, &version, "SELECT version FROM events WHERE id = $1 AND deleted_at IS NULL", eventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("event %s: %w", eventID, ErrNotFound)
+	}
+	if err != nil {
 		return fmt.Errorf("can't exec query: %v", err)
 	}
 
-	return nil
+	return fmt.Errorf("event %s has version %d: %w", eventID, version, ErrVersionConflict)
 }
 
-func (s *source) GetEventForDay(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error) {
+func (s *source) GetEvent(ctx context.Context, eventID uuid.UUID) (*entity.Event, error) {
 	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
 	defer dbCancel()
+	defer s.observeQuery(ctx, "GetEvent", time.Now())
 
-	// Вычисляем начало и конец указанного дня
-	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
-
-	rows, err := s.db.QueryxContext(
-		dbCtx,
-		"SELECT * FROM events WHERE user_id = $1 AND date >= $2 AND date < $3",
-		userID, startOfDay, endOfDay,
-	)
+	var event entity.Event
+	err := s.conn(ctx).GetContext(dbCtx, &event, "SELECT * FROM events WHERE id = $1 AND deleted_at IS NULL", eventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("event %s: %w", eventID, ErrNotFound)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("can't exec query: %v", err)
 	}
-	defer rows.Close()
+	events := []entity.Event{event}
+	if err := s.loadTags(dbCtx, events); err != nil {
+		return nil, err
+	}
 
+	return &events[0], nil
+}
+
+// GetCalendarEvents returns all live events of the calendars as stored, series masters aren't expanded.
+func (s *source) GetCalendarEvents(ctx context.Context, calendarIDs []uuid.UUID) (*entity.Events, error) {
 	events := &entity.Events{}
+	if len(calendarIDs) == 0 {
+		return events, nil
+	}
 
-	for rows.Next() {
-		var event entity.Event
-		if err := rows.StructScan(&event); err != nil {
-			return nil, fmt.Errorf("can't scan event: %v", err)
-		}
-		events.Add(event)
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetCalendarEvents", time.Now())
+
+	args, in := appendIDs(nil, calendarIDs)
+	err := s.conn(ctx).SelectContext(dbCtx, events, "SELECT * FROM events WHERE deleted_at IS NULL AND calendar_id IN ("+in+") ORDER BY start_at", args...)
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+	if err := s.loadTags(dbCtx, *events); err != nil {
+		return nil, err
 	}
 
 	return events, nil
 }
 
-func (s *source) GetEventForWeek(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error) {
+// GetEventForDay returns the events of the day of date, computed in the location of date.
+func (s *source) GetEventForDay(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error) {
+	from, to := entity.DayWindow(date)
+	return s.GetEventsInWindow(ctx, calendarIDs, from, to)
+}
+
+// GetEventForWeek returns the events of the week starting at date.
+func (s *source) GetEventForWeek(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error) {
+	from, to := entity.WeekWindow(date)
+	return s.GetEventsInWindow(ctx, calendarIDs, from, to)
+}
+
+// GetEventForMonth returns the events of the month of date, computed in the location of date.
+func (s *source) GetEventForMonth(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error) {
+	from, to := entity.MonthWindow(date)
+	return s.GetEventsInWindow(ctx, calendarIDs, from, to)
+}
+
+// GetEventsInWindow returns the live events of the calendars overlapping [from, to).
+// Series masters starting before the end of the window are expanded into their occurrences.
+func (s *source) GetEventsInWindow(ctx context.Context, calendarIDs []uuid.UUID, from time.Time, to time.Time) (*entity.Events, error) {
+	if len(calendarIDs) == 0 {
+		return &entity.Events{}, nil
+	}
+
 	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
 	defer dbCancel()
+	defer s.observeQuery(ctx, "GetEventsInWindow", time.Now())
 
-	// Вычисляем начало и конец недели
-	startOfWeek := date
-	endOfWeek := date.AddDate(0, 0, 7)
-
-	rows, err := s.db.QueryxContext(
-		dbCtx,
-		"SELECT * FROM events WHERE user_id = $1 AND date >= $2 AND date <= $3",
-		userID, startOfWeek, endOfWeek,
+	args, in := appendIDs([]any{from.UTC(), to.UTC()}, calendarIDs)
+	events, err := s.selectOccurrences(
+		dbCtx, from, to, nil,
+		"SELECT * FROM events WHERE (end_at > $1 OR rrule <> '') AND start_at < $2 AND deleted_at IS NULL AND calendar_id IN ("+in+")",
+		args...,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("can't exec query: %v", err)
+		return nil, err
 	}
-	defer rows.Close()
 
-	events := &entity.Events{}
+	events.Sort()
 
-	for rows.Next() {
-		var event entity.Event
-		if err := rows.StructScan(&event); err != nil {
-			return nil, fmt.Errorf("can't scan event: %v", err)
-		}
-		events.Add(event)
+	return events, nil
+}
+
+// GetInvitedEvents returns the live events the user is an attendee of overlapping [from, to),
+// whatever calendar they belong to. Series masters are expanded like in GetEventsInWindow.
+func (s *source) GetInvitedEvents(ctx context.Context, userID uuid.UUID, from time.Time, to time.Time) (*entity.Events, error) {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetInvitedEvents", time.Now())
+
+	events, err := s.selectOccurrences(
+		dbCtx, from, to, func(event *entity.Event) bool { return event.Attendees.Invites(userID) },
+		"SELECT * FROM events WHERE (end_at > $1 OR rrule <> '') AND start_at < $2 AND deleted_at IS NULL AND attendees LIKE $3",
+		from.UTC(), to.UTC(), attendeePattern(userID),
+	)
+	if err != nil {
+		return nil, err
 	}
 
+	events.Sort()
+
 	return events, nil
 }
 
-func (s *source) GetEventForMonth(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error) {
+// SearchEvents returns a page of the occurrences of the user's events matching the query.
+// The tags are matched in the query. Postgres matches the title in the query too, using the full-text index
+// for the text; other databases match it with EventQuery.Matches.
+func (s *source) SearchEvents(ctx context.Context, query *entity.EventQuery) (*entity.EventPage, error) {
+	if len(query.CalendarIDs) == 0 {
+		return query.Page(nil), nil
+	}
+
 	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
 	defer dbCancel()
+	defer s.observeQuery(ctx, "SearchEvents", time.Now())
 
-	// Вычисляем начало и конец месяца
-	startOfMonth := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
-	endOfMonth := startOfMonth.AddDate(0, 1, 0).Add(-time.Nanosecond)
+	args, in := appendIDs([]any{query.From.UTC(), query.To.UTC()}, query.CalendarIDs)
+	conditions := []string{"(end_at > $1 OR rrule <> '')", "start_at < $2", "deleted_at IS NULL", "calendar_id IN (" + in + ")"}
+	for _, tag := range query.Tags {
+		var condition string
+		args, condition = tagCondition(args, tag)
+		conditions = append(conditions, condition)
+	}
+	match := query.Matches
+	if s.db.DriverName() == DriverPostgres {
+		if query.Title != "" {
+			args = append(args, query.Title)
+			conditions = append(conditions, fmt.Sprintf("strpos(lower(title), lower($%d)) > 0", len(args)))
+		}
+		if query.Text != "" {
+			args = append(args, query.Text)
+			conditions = append(conditions, fmt.Sprintf(
+				"to_tsvector('simple', title) @@ plainto_tsquery('simple', $%d)", len(args),
+			))
+		}
+		match = nil
+	}
 
-	// Выполняем запрос к базе данных
-	rows, err := s.db.QueryxContext(
-		dbCtx,
-		"SELECT * FROM events WHERE user_id = $1 AND date >= $2 AND date <= $3",
-		userID, startOfMonth, endOfMonth,
+	events, err := s.selectOccurrences(
+		dbCtx, query.From, query.To, match,
+		"SELECT * FROM events WHERE "+strings.Join(conditions, " AND "), args...,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	return query.Page(*events), nil
+}
+
+// appendIDs appends the IDs to the arguments of a query and returns them with the placeholders of the IDs.
+func appendIDs(args []any, ids []uuid.UUID) ([]any, string) {
+	placeholders := make([]string, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+	return args, strings.Join(placeholders, ", ")
+}
+
+// attendeePattern returns the LIKE pattern of the stored attendees of the events the user is invited to.
+// The match only narrows the rows down, they are checked with Attendees.Invites.
+func attendeePattern(userID uuid.UUID) string {
+	return `%"user_id":"` + userID.String() + `"%`
+}
+
+// selectOccurrences selects events, loads their tags and expands the ones matching match, if it isn't nil,
+// into their occurrences overlapping [from, to).
+func (s *source) selectOccurrences(
+	ctx context.Context,
+	from time.Time,
+	to time.Time,
+	match func(*entity.Event) bool,
+	query string,
+	args ...any,
+) (*entity.Events, error) {
+	var selected []entity.Event
+	// The tags are loaded once the rows are read, the connection of a transaction runs a query at a time.
+	err := s.conn(ctx).SelectContext(ctx, &selected, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("can't exec query: %v", err)
 	}
-	defer rows.Close()
+	if err := s.loadTags(ctx, selected); err != nil {
+		return nil, err
+	}
 
 	events := &entity.Events{}
 
-	// Обрабатываем результаты запроса
-	for rows.Next() {
-		var event entity.Event
-		if err := rows.StructScan(&event); err != nil {
-			return nil, fmt.Errorf("can't scan event: %v", err)
+	for i := range selected {
+		event := &selected[i]
+		if match != nil && !match(event) {
+			continue
+		}
+
+		occurrences, err := event.Occurrences(from, to)
+		if err != nil {
+			return nil, fmt.Errorf("can't expand event: %v", err)
 		}
-		events.Add(event)
+		*events = append(*events, occurrences...)
 	}
 
 	return events, nil
@@ -12,11 +12,78 @@ import (
 
 //go:generate mockgen -source=interfaces.go -destination=source_mock.go -package=db
 
+// Transactor runs a function within one transaction of the source carried by the context passed to it.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 type EventSource interface {
+	Transactor
 	CreateEvent(ctx context.Context, event *entity.Event) error
 	UpdateEvent(ctx context.Context, event *entity.Event) error
-	DeleteEvent(ctx context.Context, eventID uuid.UUID) error
-	GetEventForDay(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error)
-	GetEventForWeek(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error)
-	GetEventForMonth(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error)
+	DeleteEvent(ctx context.Context, eventID uuid.UUID, version int64) error
+	GetEvent(ctx context.Context, eventID uuid.UUID) (*entity.Event, error)
+	GetCalendarEvents(ctx context.Context, calendarIDs []uuid.UUID) (*entity.Events, error)
+	GetEventForDay(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error)
+	GetEventForWeek(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error)
+	GetEventForMonth(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error)
+	GetEventsInWindow(ctx context.Context, calendarIDs []uuid.UUID, from time.Time, to time.Time) (*entity.Events, error)
+	SearchEvents(ctx context.Context, query *entity.EventQuery) (*entity.EventPage, error)
+	GetInvitedEvents(ctx context.Context, userID uuid.UUID, from time.Time, to time.Time) (*entity.Events, error)
+	GetTagCounts(ctx context.Context, calendarIDs []uuid.UUID) ([]entity.TagCount, error)
+	Batch(ctx context.Context, operations []entity.BatchOperation) error
+}
+
+// ReminderSource stores what the reminder scheduler needs across restarts.
+type ReminderSource interface {
+	GetRemindedEvents(ctx context.Context, from time.Time, to time.Time) (*entity.Events, error)
+	GetDeliveredReminders(ctx context.Context, startedAfter time.Time) ([]entity.ReminderKey, error)
+	MarkReminderDelivered(ctx context.Context, key entity.ReminderKey, deliveredAt time.Time) error
+	DeleteDeliveredReminders(ctx context.Context, startedBefore time.Time) error
+}
+
+// CalendarSource stores calendars and the roles of the users they are shared with.
+type CalendarSource interface {
+	CreateCalendar(ctx context.Context, calendar *entity.Calendar) error
+	UpdateCalendar(ctx context.Context, calendar *entity.Calendar) error
+	DeleteCalendar(ctx context.Context, calendarID uuid.UUID) error
+	GetCalendar(ctx context.Context, calendarID uuid.UUID) (*entity.Calendar, error)
+	GetUserCalendars(ctx context.Context, userID uuid.UUID) (entity.Calendars, error)
+	GetCalendarRole(ctx context.Context, calendarID uuid.UUID, userID uuid.UUID) (entity.Role, error)
+	GetCalendarShares(ctx context.Context, calendarID uuid.UUID) ([]entity.Share, error)
+	SetCalendarShare(ctx context.Context, share *entity.Share) error
+	DeleteCalendarShare(ctx context.Context, calendarID uuid.UUID, userID uuid.UUID) error
+}
+
+// ChangeSource stores the change log of events, ordered by the IDs it assigns to the changes.
+type ChangeSource interface {
+	AppendChange(ctx context.Context, change *entity.Change) error
+	GetChanges(ctx context.Context, afterID int64, calendarIDs []uuid.UUID, limit int) ([]entity.Change, error)
+	GetLastChangeID(ctx context.Context) (int64, error)
+	DeleteChanges(ctx context.Context, changedBefore time.Time) error
+}
+
+// WebhookSource stores the webhooks of users and the last change delivered to each of them.
+type WebhookSource interface {
+	CreateWebhook(ctx context.Context, webhook *entity.Webhook) error
+	DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error
+	GetWebhook(ctx context.Context, webhookID uuid.UUID) (*entity.Webhook, error)
+	GetUserWebhooks(ctx context.Context, userID uuid.UUID) ([]entity.Webhook, error)
+	GetWebhooks(ctx context.Context) ([]entity.Webhook, error)
+	SetWebhookCursor(ctx context.Context, webhookID uuid.UUID, lastChangeID int64) error
+}
+
+// TrashSource stores the deleted events until they are restored or purged.
+type TrashSource interface {
+	GetDeletedEvents(ctx context.Context, calendarIDs []uuid.UUID) (*entity.Events, error)
+	GetDeletedEvent(ctx context.Context, eventID uuid.UUID) (*entity.Event, error)
+	RestoreEvent(ctx context.Context, eventID uuid.UUID) error
+	PurgeDeletedEvents(ctx context.Context, deletedBefore time.Time) error
+}
+
+// RevisionSource stores the append-only audit history of events, ordered by the IDs it assigns to the revisions.
+type RevisionSource interface {
+	AppendRevision(ctx context.Context, revision *entity.Revision) error
+	GetRevisions(ctx context.Context, eventID uuid.UUID) ([]entity.Revision, error)
+	GetRevision(ctx context.Context, revisionID int64) (*entity.Revision, error)
 }
@@ -0,0 +1,898 @@
+package db
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// memorySource is a thread-safe in-memory data source. Events are indexed by calendar,
+// every calendar's events are kept ordered by start, so window queries stop at the end of the window.
+type memorySource struct {
+	mu         sync.RWMutex
+	events     map[uuid.UUID]entity.Event
+	byCalendar map[uuid.UUID][]uuid.UUID
+	deliveries map[entity.ReminderKey]time.Time
+	calendars  map[uuid.UUID]entity.Calendar
+	shares     map[uuid.UUID]map[uuid.UUID]entity.Role
+	changes    []entity.Change
+	lastChange int64
+	webhooks   map[uuid.UUID]entity.Webhook
+	revisions  []entity.Revision
+	// undoLog reverts the changes made within the running transaction, in reverse order.
+	undoLog []func()
+}
+
+// NewMemorySource creates a new empty in-memory data source.
+func NewMemorySource() *memorySource {
+	return &memorySource{
+		events:     map[uuid.UUID]entity.Event{},
+		byCalendar: map[uuid.UUID][]uuid.UUID{},
+		deliveries: map[entity.ReminderKey]time.Time{},
+		calendars:  map[uuid.UUID]entity.Calendar{},
+		shares:     map[uuid.UUID]map[uuid.UUID]entity.Role{},
+		webhooks:   map[uuid.UUID]entity.Webhook{},
+	}
+}
+
+// memoryTxKey is the context key of the transaction of the in-memory source, its value is the source.
+type memoryTxKey struct{}
+
+// WithinTx runs fn within one transaction like the SQL source. The source is locked for the whole transaction,
+// its methods called with the context passed to fn don't lock it again. Every change made within the transaction
+// records how to revert it, if fn fails or panics the changes are reverted in reverse order.
+// The error of fn is returned as is.
+func (s *memorySource) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if s.inTx(ctx) {
+		return fn(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	defer func() {
+		p := recover()
+		if p != nil || err != nil {
+			for i := len(s.undoLog) - 1; i >= 0; i-- {
+				s.undoLog[i]()
+			}
+		}
+		s.undoLog = nil
+		if p != nil {
+			panic(p)
+		}
+	}()
+
+	return fn(context.WithValue(ctx, memoryTxKey{}, s))
+}
+
+// inTx tells if the context runs in a transaction of the source.
+func (s *memorySource) inTx(ctx context.Context) bool {
+	source, ok := ctx.Value(memoryTxKey{}).(*memorySource)
+	return ok && source == s
+}
+
+// lock locks the source for writing unless the context runs in its transaction and returns the unlock.
+func (s *memorySource) lock(ctx context.Context) func() {
+	if s.inTx(ctx) {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// rlock locks the source for reading unless the context runs in its transaction and returns the unlock.
+func (s *memorySource) rlock(ctx context.Context) func() {
+	if s.inTx(ctx) {
+		return func() {}
+	}
+	s.mu.RLock()
+	return s.mu.RUnlock
+}
+
+// undo records how to revert a change made within the transaction of the context, it must be called with the lock held.
+// Changes made outside a transaction are never reverted, so nothing is recorded for them.
+func (s *memorySource) undo(ctx context.Context, revert func()) {
+	if s.inTx(ctx) {
+		s.undoLog = append(s.undoLog, revert)
+	}
+}
+
+// keepEvent records how to restore the event and its place in the index before the event is changed.
+// Stored events are replaced rather than changed, so keeping the stored value is enough.
+func (s *memorySource) keepEvent(ctx context.Context, eventID uuid.UUID) {
+	stored, existed := s.events[eventID]
+	s.undo(ctx, func() {
+		if current, ok := s.events[eventID]; ok {
+			s.unindex(current.CalendarID, eventID)
+		}
+		if !existed {
+			delete(s.events, eventID)
+			return
+		}
+		s.events[eventID] = stored
+		if stored.DeletedAt == nil {
+			s.index(stored.CalendarID, eventID)
+		}
+	})
+}
+
+func (s *memorySource) CreateEvent(ctx context.Context, event *entity.Event) error {
+	defer s.lock(ctx)()
+
+	if _, ok := s.events[event.ID]; ok {
+		return fmt.Errorf("event %s: %w", event.ID, ErrAlreadyExists)
+	}
+
+	event.Version = 1
+	event.DeletedAt = nil
+	s.keepEvent(ctx, event.ID)
+	s.events[event.ID] = cloneEvent(event)
+	s.index(event.CalendarID, event.ID)
+
+	return nil
+}
+
+func (s *memorySource) UpdateEvent(ctx context.Context, event *entity.Event) error {
+	defer s.lock(ctx)()
+
+	stored, ok := s.events[event.ID]
+	if !ok || stored.DeletedAt != nil {
+		return fmt.Errorf("event %s: %w", event.ID, ErrNotFound)
+	}
+	if stored.Version != event.Version {
+		return fmt.Errorf("event %s has version %d: %w", event.ID, stored.Version, ErrVersionConflict)
+	}
+
+	// Only the columns updated by the SQL source change.
+	stored.Title = event.Title
+	stored.Start = event.Start
+	stored.End = event.End
+	stored.AllDay = event.AllDay
+	stored.TimeZone = event.TimeZone
+	stored.RRule = event.RRule
+	stored.ExDates = event.ExDates
+	stored.Reminders = event.Reminders
+	stored.Attendees = event.Attendees
+	stored.Description = event.Description
+	stored.Place = event.Place
+	stored.Color = event.Color
+	stored.Tags = event.Tags
+	stored.Version++
+	s.keepEvent(ctx, event.ID)
+	s.events[event.ID] = cloneEvent(&stored)
+	s.index(stored.CalendarID, stored.ID)
+	event.Version = stored.Version
+
+	return nil
+}
+
+func (s *memorySource) DeleteEvent(ctx context.Context, eventID uuid.UUID, version int64) error {
+	defer s.lock(ctx)()
+
+	stored, ok := s.events[eventID]
+	if !ok || stored.DeletedAt != nil {
+		return fmt.Errorf("event %s: %w", eventID, ErrNotFound)
+	}
+	if version != 0 && stored.Version != version {
+		return fmt.Errorf("event %s has version %d: %w", eventID, stored.Version, ErrVersionConflict)
+	}
+
+	// Deleted events leave the index of their calendar, so the queries of live events skip them.
+	deletedAt := time.Now().UTC()
+	for id, event := range s.events {
+		if event.DeletedAt == nil && (id == eventID || (event.SeriesID != nil && *event.SeriesID == eventID)) {
+			event.DeletedAt = &deletedAt
+			s.keepEvent(ctx, id)
+			s.events[id] = event
+			s.unindex(event.CalendarID, id)
+		}
+	}
+
+	return nil
+}
+
+// Batch applies the operations at once like the SQL source: if an operation fails,
+// the source is restored to how it was before the batch.
+func (s *memorySource) Batch(ctx context.Context, operations []entity.BatchOperation) error {
+	return s.WithinTx(ctx, func(ctx context.Context) error {
+		return applyBatch(ctx, s, operations)
+	})
+}
+
+func (s *memorySource) GetEvent(ctx context.Context, eventID uuid.UUID) (*entity.Event, error) {
+	defer s.rlock(ctx)()
+
+	event, ok := s.events[eventID]
+	if !ok || event.DeletedAt != nil {
+		return nil, fmt.Errorf("event %s: %w", eventID, ErrNotFound)
+	}
+
+	clone := cloneEvent(&event)
+	return &clone, nil
+}
+
+func (s *memorySource) GetCalendarEvents(ctx context.Context, calendarIDs []uuid.UUID) (*entity.Events, error) {
+	defer s.rlock(ctx)()
+
+	events := &entity.Events{}
+	for _, calendarID := range calendarIDs {
+		for _, id := range s.byCalendar[calendarID] {
+			event := s.events[id]
+			events.Add(cloneEvent(&event))
+		}
+	}
+	events.Sort()
+
+	return events, nil
+}
+
+func (s *memorySource) GetEventForDay(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error) {
+	from, to := entity.DayWindow(date)
+	return s.GetEventsInWindow(ctx, calendarIDs, from, to)
+}
+
+func (s *memorySource) GetEventForWeek(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error) {
+	from, to := entity.WeekWindow(date)
+	return s.GetEventsInWindow(ctx, calendarIDs, from, to)
+}
+
+func (s *memorySource) GetEventForMonth(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error) {
+	from, to := entity.MonthWindow(date)
+	return s.GetEventsInWindow(ctx, calendarIDs, from, to)
+}
+
+// GetEventsInWindow returns the events of the calendars overlapping [from, to), expanding series.
+func (s *memorySource) GetEventsInWindow(ctx context.Context, calendarIDs []uuid.UUID, from time.Time, to time.Time) (*entity.Events, error) {
+	defer s.rlock(ctx)()
+
+	events, err := s.occurrences(calendarIDs, from, to, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	events.Sort()
+
+	return events, nil
+}
+
+// GetInvitedEvents returns the events the user is an attendee of overlapping [from, to), expanding series.
+func (s *memorySource) GetInvitedEvents(ctx context.Context, userID uuid.UUID, from time.Time, to time.Time) (*entity.Events, error) {
+	defer s.rlock(ctx)()
+
+	events := &entity.Events{}
+	for _, event := range s.events {
+		if event.DeletedAt != nil || !event.Start.Before(to) || !event.Attendees.Invites(userID) {
+			continue
+		}
+		occurrences, err := event.Occurrences(from, to)
+		if err != nil {
+			return nil, fmt.Errorf("can't expand event: %v", err)
+		}
+		for _, occurrence := range occurrences {
+			events.Add(cloneEvent(&occurrence))
+		}
+	}
+
+	events.Sort()
+
+	return events, nil
+}
+
+// SearchEvents returns a page of the occurrences of the events of the calendars matching the query.
+func (s *memorySource) SearchEvents(ctx context.Context, query *entity.EventQuery) (*entity.EventPage, error) {
+	defer s.rlock(ctx)()
+
+	events, err := s.occurrences(query.CalendarIDs, query.From, query.To, query.Matches)
+	if err != nil {
+		return nil, err
+	}
+
+	return query.Page(*events), nil
+}
+
+// GetTagCounts returns the tags of the live events of the calendars with the number of events carrying them.
+func (s *memorySource) GetTagCounts(ctx context.Context, calendarIDs []uuid.UUID) ([]entity.TagCount, error) {
+	defer s.rlock(ctx)()
+
+	counts := map[string]int{}
+	for _, calendarID := range calendarIDs {
+		for _, id := range s.byCalendar[calendarID] {
+			for _, tag := range s.events[id].Tags {
+				counts[tag]++
+			}
+		}
+	}
+
+	tags := make([]entity.TagCount, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, entity.TagCount{Name: name, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+
+	return tags, nil
+}
+
+// occurrences returns the occurrences of the events of the calendars matching match, if it isn't nil,
+// overlapping [from, to). The caller must hold the lock.
+func (s *memorySource) occurrences(
+	calendarIDs []uuid.UUID,
+	from time.Time,
+	to time.Time,
+	match func(*entity.Event) bool,
+) (*entity.Events, error) {
+	events := &entity.Events{}
+	for _, calendarID := range calendarIDs {
+		ids := s.byCalendar[calendarID]
+		// Events starting at or after the end of the window can't overlap it.
+		n := sort.Search(len(ids), func(i int) bool {
+			return !s.events[ids[i]].Start.Before(to)
+		})
+
+		for _, id := range ids[:n] {
+			event := s.events[id]
+			if match != nil && !match(&event) {
+				continue
+			}
+
+			occurrences, err := event.Occurrences(from, to)
+			if err != nil {
+				return nil, fmt.Errorf("can't expand event: %v", err)
+			}
+			for _, occurrence := range occurrences {
+				events.Add(cloneEvent(&occurrence))
+			}
+		}
+	}
+
+	return events, nil
+}
+
+func (s *memorySource) GetRemindedEvents(ctx context.Context, from time.Time, to time.Time) (*entity.Events, error) {
+	defer s.rlock(ctx)()
+
+	events := &entity.Events{}
+	for _, event := range s.events {
+		if event.DeletedAt != nil || len(event.Reminders) == 0 || !event.Start.Before(to) || (!event.End.After(from) && !event.IsRecurring()) {
+			continue
+		}
+		events.Add(cloneEvent(&event))
+	}
+
+	return events, nil
+}
+
+func (s *memorySource) GetDeliveredReminders(ctx context.Context, startedAfter time.Time) ([]entity.ReminderKey, error) {
+	defer s.rlock(ctx)()
+
+	var keys []entity.ReminderKey
+	for key := range s.deliveries {
+		if key.Start.After(startedAfter) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (s *memorySource) MarkReminderDelivered(ctx context.Context, key entity.ReminderKey, deliveredAt time.Time) error {
+	defer s.lock(ctx)()
+
+	key = key.Normalize()
+	if _, ok := s.deliveries[key]; !ok {
+		s.undo(ctx, func() { delete(s.deliveries, key) })
+		s.deliveries[key] = deliveredAt
+	}
+
+	return nil
+}
+
+func (s *memorySource) DeleteDeliveredReminders(ctx context.Context, startedBefore time.Time) error {
+	defer s.lock(ctx)()
+
+	for key, deliveredAt := range s.deliveries {
+		if !key.Start.After(startedBefore) {
+			key, deliveredAt := key, deliveredAt
+			s.undo(ctx, func() { s.deliveries[key] = deliveredAt })
+			delete(s.deliveries, key)
+		}
+	}
+
+	return nil
+}
+
+// index puts the event into the calendar's index, keeping it ordered by start.
+// It must be called with the lock held.
+func (s *memorySource) index(calendarID uuid.UUID, eventID uuid.UUID) {
+	s.unindex(calendarID, eventID)
+
+	ids := s.byCalendar[calendarID]
+	start := s.events[eventID].Start
+	i := sort.Search(len(ids), func(i int) bool {
+		return s.events[ids[i]].Start.After(start)
+	})
+
+	ids = append(ids, uuid.Nil)
+	copy(ids[i+1:], ids[i:])
+	ids[i] = eventID
+	s.byCalendar[calendarID] = ids
+}
+
+// unindex removes the event from the calendar's index.
+// It must be called with the lock held.
+func (s *memorySource) unindex(calendarID uuid.UUID, eventID uuid.UUID) {
+	ids := s.byCalendar[calendarID]
+	for i, id := range ids {
+		if id == eventID {
+			s.byCalendar[calendarID] = append(ids[:i], ids[i+1:]...)
+			return
+		}
+	}
+}
+
+// cloneEvent returns a deep copy of the event, so that callers can't modify the stored one.
+func cloneEvent(event *entity.Event) entity.Event {
+	clone := *event
+	if event.ExDates != nil {
+		clone.ExDates = append(entity.Dates{}, event.ExDates...)
+	}
+	if event.Reminders != nil {
+		clone.Reminders = append(entity.Offsets{}, event.Reminders...)
+	}
+	clone.Attendees = event.Attendees.Clone()
+	if event.Tags != nil {
+		clone.Tags = append(entity.Tags{}, event.Tags...)
+	}
+	if event.SeriesID != nil {
+		seriesID := *event.SeriesID
+		clone.SeriesID = &seriesID
+	}
+	if event.RecurrenceID != nil {
+		recurrenceID := *event.RecurrenceID
+		clone.RecurrenceID = &recurrenceID
+	}
+	if event.DeletedAt != nil {
+		deletedAt := *event.DeletedAt
+		clone.DeletedAt = &deletedAt
+	}
+	return clone
+}
+
+func (s *memorySource) CreateCalendar(ctx context.Context, calendar *entity.Calendar) error {
+	defer s.lock(ctx)()
+
+	if _, ok := s.calendars[calendar.ID]; ok {
+		return fmt.Errorf("calendar %s: %w", calendar.ID, ErrAlreadyExists)
+	}
+
+	stored := *calendar
+	stored.Role = ""
+	s.undo(ctx, func() { delete(s.calendars, stored.ID) })
+	s.calendars[calendar.ID] = stored
+
+	return nil
+}
+
+func (s *memorySource) UpdateCalendar(ctx context.Context, calendar *entity.Calendar) error {
+	defer s.lock(ctx)()
+
+	stored, ok := s.calendars[calendar.ID]
+	if !ok {
+		return fmt.Errorf("calendar %s: %w", calendar.ID, ErrNotFound)
+	}
+
+	previous := stored
+	s.undo(ctx, func() { s.calendars[previous.ID] = previous })
+	stored.Name = calendar.Name
+	s.calendars[calendar.ID] = stored
+
+	return nil
+}
+
+func (s *memorySource) DeleteCalendar(ctx context.Context, calendarID uuid.UUID) error {
+	defer s.lock(ctx)()
+
+	// The deleted events of the calendar aren't indexed.
+	for id, event := range s.events {
+		if event.CalendarID == calendarID {
+			s.keepEvent(ctx, id)
+			delete(s.events, id)
+		}
+	}
+	delete(s.byCalendar, calendarID)
+	if roles, ok := s.shares[calendarID]; ok {
+		s.undo(ctx, func() { s.shares[calendarID] = roles })
+		delete(s.shares, calendarID)
+	}
+	if calendar, ok := s.calendars[calendarID]; ok {
+		s.undo(ctx, func() { s.calendars[calendarID] = calendar })
+		delete(s.calendars, calendarID)
+	}
+
+	return nil
+}
+
+func (s *memorySource) GetCalendar(ctx context.Context, calendarID uuid.UUID) (*entity.Calendar, error) {
+	defer s.rlock(ctx)()
+
+	calendar, ok := s.calendars[calendarID]
+	if !ok {
+		return nil, fmt.Errorf("calendar %s: %w", calendarID, ErrNotFound)
+	}
+
+	return &calendar, nil
+}
+
+func (s *memorySource) GetUserCalendars(ctx context.Context, userID uuid.UUID) (entity.Calendars, error) {
+	defer s.rlock(ctx)()
+
+	calendars := entity.Calendars{}
+	for _, calendar := range s.calendars {
+		calendar.Role = s.role(calendar, userID)
+		if calendar.Role != "" {
+			calendars = append(calendars, calendar)
+		}
+	}
+	sort.Slice(calendars, func(i, j int) bool {
+		if calendars[i].Name != calendars[j].Name {
+			return calendars[i].Name < calendars[j].Name
+		}
+		return calendars[i].ID.String() < calendars[j].ID.String()
+	})
+
+	return calendars, nil
+}
+
+func (s *memorySource) GetCalendarRole(ctx context.Context, calendarID uuid.UUID, userID uuid.UUID) (entity.Role, error) {
+	defer s.rlock(ctx)()
+
+	calendar, ok := s.calendars[calendarID]
+	if !ok {
+		return "", fmt.Errorf("calendar %s: %w", calendarID, ErrNotFound)
+	}
+
+	return s.role(calendar, userID), nil
+}
+
+func (s *memorySource) GetCalendarShares(ctx context.Context, calendarID uuid.UUID) ([]entity.Share, error) {
+	defer s.rlock(ctx)()
+
+	shares := []entity.Share{}
+	for userID, role := range s.shares[calendarID] {
+		shares = append(shares, entity.Share{CalendarID: calendarID, UserID: userID, Role: role})
+	}
+	sort.Slice(shares, func(i, j int) bool { return shares[i].UserID.String() < shares[j].UserID.String() })
+
+	return shares, nil
+}
+
+func (s *memorySource) SetCalendarShare(ctx context.Context, share *entity.Share) error {
+	defer s.lock(ctx)()
+
+	if s.shares[share.CalendarID] == nil {
+		s.shares[share.CalendarID] = map[uuid.UUID]entity.Role{}
+	}
+	s.keepShare(ctx, share.CalendarID, share.UserID)
+	s.shares[share.CalendarID][share.UserID] = share.Role
+
+	return nil
+}
+
+func (s *memorySource) DeleteCalendarShare(ctx context.Context, calendarID uuid.UUID, userID uuid.UUID) error {
+	defer s.lock(ctx)()
+
+	s.keepShare(ctx, calendarID, userID)
+	delete(s.shares[calendarID], userID)
+
+	return nil
+}
+
+// keepShare records how to restore the role of the user in the calendar before it's changed.
+// It must be called with the lock held.
+func (s *memorySource) keepShare(ctx context.Context, calendarID uuid.UUID, userID uuid.UUID) {
+	role, existed := s.shares[calendarID][userID]
+	s.undo(ctx, func() {
+		if !existed {
+			delete(s.shares[calendarID], userID)
+			return
+		}
+		if s.shares[calendarID] == nil {
+			s.shares[calendarID] = map[uuid.UUID]entity.Role{}
+		}
+		s.shares[calendarID][userID] = role
+	})
+}
+
+// role returns the role of the user in the calendar. It must be called with the lock held.
+func (s *memorySource) role(calendar entity.Calendar, userID uuid.UUID) entity.Role {
+	if calendar.OwnerID == userID {
+		return entity.RoleAdmin
+	}
+	return s.shares[calendar.ID][userID]
+}
+
+func (s *memorySource) AppendChange(ctx context.Context, change *entity.Change) error {
+	defer s.lock(ctx)()
+
+	changes, lastChange := s.changes, s.lastChange
+	s.undo(ctx, func() { s.changes, s.lastChange = changes, lastChange })
+	s.lastChange++
+	change.ID = s.lastChange
+	stored := *change
+	if change.Event != nil {
+		event := cloneEvent(change.Event)
+		stored.Event = &event
+	}
+	s.changes = append(s.changes, stored)
+
+	return nil
+}
+
+func (s *memorySource) GetChanges(ctx context.Context, afterID int64, calendarIDs []uuid.UUID, limit int) ([]entity.Change, error) {
+	defer s.rlock(ctx)()
+
+	calendars := make(map[uuid.UUID]bool, len(calendarIDs))
+	for _, id := range calendarIDs {
+		calendars[id] = true
+	}
+
+	// The log is ordered by ID.
+	first := sort.Search(len(s.changes), func(i int) bool { return s.changes[i].ID > afterID })
+	var changes []entity.Change
+	for _, change := range s.changes[first:] {
+		if len(changes) == limit {
+			break
+		}
+		if !calendars[change.CalendarID] {
+			continue
+		}
+		if change.Event != nil {
+			event := cloneEvent(change.Event)
+			change.Event = &event
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+func (s *memorySource) GetLastChangeID(ctx context.Context) (int64, error) {
+	defer s.rlock(ctx)()
+
+	return s.lastChange, nil
+}
+
+func (s *memorySource) DeleteChanges(ctx context.Context, changedBefore time.Time) error {
+	defer s.lock(ctx)()
+
+	// The kept changes are copied, so the log stays intact for the revert of the transaction.
+	changes := s.changes
+	s.undo(ctx, func() { s.changes = changes })
+	var kept []entity.Change
+	for _, change := range s.changes {
+		if !change.ChangedAt.Before(changedBefore) {
+			kept = append(kept, change)
+		}
+	}
+	s.changes = kept
+
+	return nil
+}
+
+func (s *memorySource) CreateWebhook(ctx context.Context, webhook *entity.Webhook) error {
+	defer s.lock(ctx)()
+
+	if _, ok := s.webhooks[webhook.ID]; ok {
+		return fmt.Errorf("webhook %s: %w", webhook.ID, ErrAlreadyExists)
+	}
+	webhookID := webhook.ID
+	s.undo(ctx, func() { delete(s.webhooks, webhookID) })
+	s.webhooks[webhook.ID] = *webhook
+
+	return nil
+}
+
+func (s *memorySource) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error {
+	defer s.lock(ctx)()
+
+	webhook, ok := s.webhooks[webhookID]
+	if !ok {
+		return fmt.Errorf("webhook %s: %w", webhookID, ErrNotFound)
+	}
+	s.undo(ctx, func() { s.webhooks[webhookID] = webhook })
+	delete(s.webhooks, webhookID)
+
+	return nil
+}
+
+func (s *memorySource) GetWebhook(ctx context.Context, webhookID uuid.UUID) (*entity.Webhook, error) {
+	defer s.rlock(ctx)()
+
+	webhook, ok := s.webhooks[webhookID]
+	if !ok {
+		return nil, fmt.Errorf("webhook %s: %w", webhookID, ErrNotFound)
+	}
+
+	return &webhook, nil
+}
+
+func (s *memorySource) GetUserWebhooks(ctx context.Context, userID uuid.UUID) ([]entity.Webhook, error) {
+	webhooks, _ := s.GetWebhooks(ctx)
+
+	owned := []entity.Webhook{}
+	for _, webhook := range webhooks {
+		if webhook.UserID == userID {
+			owned = append(owned, webhook)
+		}
+	}
+
+	return owned, nil
+}
+
+func (s *memorySource) GetWebhooks(ctx context.Context) ([]entity.Webhook, error) {
+	defer s.rlock(ctx)()
+
+	webhooks := make([]entity.Webhook, 0, len(s.webhooks))
+	for _, webhook := range s.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		if !webhooks[i].CreatedAt.Equal(webhooks[j].CreatedAt) {
+			return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+		}
+		return webhooks[i].ID.String() < webhooks[j].ID.String()
+	})
+
+	return webhooks, nil
+}
+
+func (s *memorySource) SetWebhookCursor(ctx context.Context, webhookID uuid.UUID, lastChangeID int64) error {
+	defer s.lock(ctx)()
+
+	webhook, ok := s.webhooks[webhookID]
+	if ok {
+		previous := webhook
+		s.undo(ctx, func() { s.webhooks[webhookID] = previous })
+		webhook.LastChangeID = lastChangeID
+		s.webhooks[webhookID] = webhook
+	}
+
+	return nil
+}
+
+func (s *memorySource) GetDeletedEvents(ctx context.Context, calendarIDs []uuid.UUID) (*entity.Events, error) {
+	defer s.rlock(ctx)()
+
+	inCalendars := make(map[uuid.UUID]bool, len(calendarIDs))
+	for _, calendarID := range calendarIDs {
+		inCalendars[calendarID] = true
+	}
+
+	events := entity.Events{}
+	for _, event := range s.events {
+		if event.DeletedAt == nil || !inCalendars[event.CalendarID] || s.deletedWithSeries(&event) {
+			continue
+		}
+		events.Add(cloneEvent(&event))
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].DeletedAt.Equal(*events[j].DeletedAt) {
+			return events[i].DeletedAt.After(*events[j].DeletedAt)
+		}
+		return events[i].Start.Before(events[j].Start)
+	})
+
+	return &events, nil
+}
+
+// deletedWithSeries reports whether the event is an override deleted together with its series.
+// It must be called with the lock held.
+func (s *memorySource) deletedWithSeries(event *entity.Event) bool {
+	if event.SeriesID == nil {
+		return false
+	}
+	master, ok := s.events[*event.SeriesID]
+	return ok && master.DeletedAt != nil && master.DeletedAt.Equal(*event.DeletedAt)
+}
+
+func (s *memorySource) GetDeletedEvent(ctx context.Context, eventID uuid.UUID) (*entity.Event, error) {
+	defer s.rlock(ctx)()
+
+	event, ok := s.events[eventID]
+	if !ok || event.DeletedAt == nil {
+		return nil, fmt.Errorf("deleted event %s: %w", eventID, ErrNotFound)
+	}
+
+	clone := cloneEvent(&event)
+	return &clone, nil
+}
+
+func (s *memorySource) RestoreEvent(ctx context.Context, eventID uuid.UUID) error {
+	defer s.lock(ctx)()
+
+	stored, ok := s.events[eventID]
+	if !ok || stored.DeletedAt == nil {
+		return fmt.Errorf("deleted event %s: %w", eventID, ErrNotFound)
+	}
+
+	deletedAt := *stored.DeletedAt
+	for id, event := range s.events {
+		if event.DeletedAt != nil && event.DeletedAt.Equal(deletedAt) &&
+			(id == eventID || (event.SeriesID != nil && *event.SeriesID == eventID)) {
+			event.DeletedAt = nil
+			s.keepEvent(ctx, id)
+			s.events[id] = event
+			s.index(event.CalendarID, id)
+		}
+	}
+
+	return nil
+}
+
+func (s *memorySource) PurgeDeletedEvents(ctx context.Context, deletedBefore time.Time) error {
+	defer s.lock(ctx)()
+
+	for id, event := range s.events {
+		if event.DeletedAt != nil && event.DeletedAt.Before(deletedBefore) {
+			s.keepEvent(ctx, id)
+			delete(s.events, id)
+		}
+	}
+
+	return nil
+}
+
+func (s *memorySource) AppendRevision(ctx context.Context, revision *entity.Revision) error {
+	defer s.lock(ctx)()
+
+	revisions := s.revisions
+	s.undo(ctx, func() { s.revisions = revisions })
+	revision.ID = int64(len(s.revisions)) + 1
+	s.revisions = append(s.revisions, cloneRevision(revision))
+
+	return nil
+}
+
+func (s *memorySource) GetRevisions(ctx context.Context, eventID uuid.UUID) ([]entity.Revision, error) {
+	defer s.rlock(ctx)()
+
+	var revisions []entity.Revision
+	for i := range s.revisions {
+		if s.revisions[i].EventID == eventID {
+			revisions = append(revisions, cloneRevision(&s.revisions[i]))
+		}
+	}
+
+	return revisions, nil
+}
+
+func (s *memorySource) GetRevision(ctx context.Context, revisionID int64) (*entity.Revision, error) {
+	defer s.rlock(ctx)()
+
+	// Revisions are never deleted, so the ID of a revision is its position.
+	if revisionID < 1 || revisionID > int64(len(s.revisions)) {
+		return nil, fmt.Errorf("revision %d: %w", revisionID, ErrNotFound)
+	}
+	revision := cloneRevision(&s.revisions[revisionID-1])
+
+	return &revision, nil
+}
+
+// cloneRevision copies the events of the revision, the diff is never changed.
+func cloneRevision(revision *entity.Revision) entity.Revision {
+	clone := *revision
+	if revision.Before != nil {
+		before := cloneEvent(revision.Before)
+		clone.Before = &before
+	}
+	if revision.After != nil {
+		after := cloneEvent(revision.After)
+		clone.After = &after
+	}
+	return clone
+}
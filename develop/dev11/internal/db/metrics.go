@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"L2/develop/dev11/internal/metrics"
+	"L2/develop/dev11/internal/trace"
+
+	"go.uber.org/zap"
+)
+
+// queryDuration measures the queries of the SQL source by the source method running them.
+var queryDuration = metrics.Default.Histogram(
+	"calendar_db_query_duration_seconds",
+	"Duration of the database queries by the source method running them.",
+	metrics.DefaultBuckets,
+	"query",
+)
+
+// observeQuery records the duration of the query started at start and logs it if it is slow,
+// with the request ID and the trace context of ctx. The source methods defer it.
+func (s *source) observeQuery(ctx context.Context, query string, start time.Time) {
+	elapsed := time.Since(start)
+	queryDuration.Observe(elapsed.Seconds(), query)
+
+	if s.slowQuery > 0 && elapsed >= s.slowQuery {
+		fields := append([]zap.Field{
+			zap.String("query", query),
+			zap.Duration("duration", elapsed),
+		}, trace.Fields(ctx)...)
+		s.logger.Warn("slow query", fields...)
+	}
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"fmt"
+	"time"
+)
+
+// GetRemindedEvents returns the live events with reminders which may have occurrences within [from, to).
+// Series are returned as masters, not expanded.
+func (s *source) GetRemindedEvents(ctx context.Context, from time.Time, to time.Time) (*entity.Events, error) {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetRemindedEvents", time.Now())
+
+	events := &entity.Events{}
+	err := s.conn(ctx).SelectContext(
+		dbCtx,
+		events,
+		"SELECT * FROM events WHERE reminders <> '' AND (end_at > $1 OR rrule <> '') AND start_at < $2 AND deleted_at IS NULL",
+		from.UTC(), to.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return events, nil
+}
+
+// GetDeliveredReminders returns the keys of the delivered reminders of occurrences starting after the time.
+func (s *source) GetDeliveredReminders(ctx context.Context, startedAfter time.Time) ([]entity.ReminderKey, error) {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetDeliveredReminders", time.Now())
+
+	var keys []entity.ReminderKey
+	err := s.conn(ctx).SelectContext(
+		dbCtx,
+		&keys,
+		"SELECT event_id, start_at, offset_ns FROM reminder_deliveries WHERE start_at > $1",
+		startedAfter.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+
+	for i := range keys {
+		keys[i] = keys[i].Normalize()
+	}
+
+	return keys, nil
+}
+
+// MarkReminderDelivered records the delivery of the reminder, marking it twice is a no-op.
+func (s *source) MarkReminderDelivered(ctx context.Context, key entity.ReminderKey, deliveredAt time.Time) error {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "MarkReminderDelivered", time.Now())
+
+	key = key.Normalize()
+	_, err := s.conn(ctx).ExecContext(
+		dbCtx,
+		`INSERT INTO reminder_deliveries (event_id, start_at, offset_ns, delivered_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`,
+		key.EventID, key.Start, int64(key.Offset), deliveredAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteDeliveredReminders forgets the deliveries of reminders of occurrences starting at or before the time.
+func (s *source) DeleteDeliveredReminders(ctx context.Context, startedBefore time.Time) error {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "DeleteDeliveredReminders", time.Now())
+
+	_, err := s.conn(ctx).ExecContext(dbCtx, "DELETE FROM reminder_deliveries WHERE start_at <= $1", startedBefore.UTC())
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// revisionRow is a row of the audit history, the events and the diff are stored as JSON.
+type revisionRow struct {
+	ID          int64          `db:"id"`
+	Kind        string         `db:"kind"`
+	EventID     uuid.UUID      `db:"event_id"`
+	CalendarID  uuid.UUID      `db:"calendar_id"`
+	ActorID     uuid.UUID      `db:"actor_id"`
+	RequestID   string         `db:"request_id"`
+	Version     int64          `db:"version"`
+	BeforeEvent sql.NullString `db:"before_event"`
+	AfterEvent  sql.NullString `db:"after_event"`
+	Diff        string         `db:"diff"`
+	ChangedAt   time.Time      `db:"changed_at"`
+}
+
+func (r *revisionRow) revision() (entity.Revision, error) {
+	revision := entity.Revision{
+		ID:         r.ID,
+		Kind:       entity.ChangeKind(r.Kind),
+		EventID:    r.EventID,
+		CalendarID: r.CalendarID,
+		ActorID:    r.ActorID,
+		RequestID:  r.RequestID,
+		Version:    r.Version,
+		ChangedAt:  r.ChangedAt.UTC(),
+	}
+	var err error
+	revision.Before, err = nullEvent(r.BeforeEvent)
+	if err != nil {
+		return revision, fmt.Errorf("can't unmarshal event of revision %d: %v", r.ID, err)
+	}
+	revision.After, err = nullEvent(r.AfterEvent)
+	if err != nil {
+		return revision, fmt.Errorf("can't unmarshal event of revision %d: %v", r.ID, err)
+	}
+	err = json.Unmarshal([]byte(r.Diff), &revision.Diff)
+	if err != nil {
+		return revision, fmt.Errorf("can't unmarshal diff of revision %d: %v", r.ID, err)
+	}
+
+	return revision, nil
+}
+
+// nullEvent unmarshals the event, NULL is a nil event.
+func nullEvent(value sql.NullString) (*entity.Event, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	return entity.UnmarshalEvent([]byte(value.String))
+}
+
+// nullJSON marshals the event, a nil event is NULL.
+func nullJSON(event *entity.Event) (sql.NullString, error) {
+	if event == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("can't marshal event: %v", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// AppendRevision appends the revision to the audit history and sets its ID.
+func (s *source) AppendRevision(ctx context.Context, revision *entity.Revision) error {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "AppendRevision", time.Now())
+
+	before, err := nullJSON(revision.Before)
+	if err != nil {
+		return err
+	}
+	after, err := nullJSON(revision.After)
+	if err != nil {
+		return err
+	}
+	diff, err := json.Marshal(revision.Diff)
+	if err != nil {
+		return fmt.Errorf("can't marshal diff: %v", err)
+	}
+
+	err = s.conn(ctx).QueryRowContext(
+		dbCtx,
+		`INSERT INTO event_revisions (kind, event_id, calendar_id, actor_id, request_id, version, before_event, after_event, diff, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+		revision.Kind, revision.EventID, revision.CalendarID, revision.ActorID, revision.RequestID, revision.Version,
+		before, after, string(diff), revision.ChangedAt.UTC(),
+	).Scan(&revision.ID)
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return nil
+}
+
+// GetRevisions returns the audit history of the event, oldest first.
+func (s *source) GetRevisions(ctx context.Context, eventID uuid.UUID) ([]entity.Revision, error) {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetRevisions", time.Now())
+
+	var rows []revisionRow
+	err := s.conn(ctx).SelectContext(dbCtx, &rows, "SELECT * FROM event_revisions WHERE event_id = $1 ORDER BY id", eventID)
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+
+	revisions := make([]entity.Revision, 0, len(rows))
+	for i := range rows {
+		revision, err := rows[i].revision()
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+func (s *source) GetRevision(ctx context.Context, revisionID int64) (*entity.Revision, error) {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetRevision", time.Now())
+
+	var row revisionRow
+	err := s.conn(ctx).GetContext(dbCtx, &row, "SELECT * FROM event_revisions WHERE id = $1", revisionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("revision %d: %w", revisionID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+
+	revision, err := row.revision()
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
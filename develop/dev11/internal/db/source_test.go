@@ -0,0 +1,1093 @@
+package db
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/trace"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	_ "modernc.org/sqlite"
+)
+
+// newSQLiteSource opens an in-memory SQLite database with the schema of the SQLite migrations.
+func newSQLiteSource(t *testing.T) EventSource {
+	t.Helper()
+
+	return NewSource(openSQLite(t), zap.NewNop(), 0)
+}
+
+// openSQLite opens an in-memory SQLite database and applies the SQLite migrations.
+func openSQLite(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	conn, err := sqlx.Connect(DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatalf("can't open sqlite: %v", err)
+	}
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+	migrate(t, conn, "sqlite")
+
+	return conn
+}
+
+// newPostgresSource opens the Postgres database from TEST_POSTGRES_DSN and applies the Postgres migrations
+// to a schema of its own, dropped after the test. The test is skipped if TEST_POSTGRES_DSN isn't set.
+func newPostgresSource(t *testing.T) EventSource {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN isn't set")
+	}
+	conn, err := sqlx.Connect(DriverPostgres, dsn)
+	if err != nil {
+		t.Fatalf("can't open postgres: %v", err)
+	}
+	// A single connection keeps the search path of the schema for every query.
+	conn.SetMaxOpenConns(1)
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := conn.Exec("CREATE SCHEMA " + schema + "; SET search_path TO " + schema + ";"); err != nil {
+		t.Fatalf("can't create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Exec("DROP SCHEMA " + schema + " CASCADE;")
+		conn.Close()
+	})
+	migrate(t, conn, "postgres")
+
+	return NewSource(conn, zap.NewNop(), 0)
+}
+
+// migrate applies the up migrations of the driver to the database.
+func migrate(t *testing.T, conn *sqlx.DB, driver string) {
+	t.Helper()
+
+	files, err := filepath.Glob("../app/migrations/" + driver + "/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("can't find %s migrations: %v", driver, err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		query, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("can't read migration: %v", err)
+		}
+		if _, err := conn.Exec(string(query)); err != nil {
+			t.Fatalf("can't apply migration %s: %v", file, err)
+		}
+	}
+}
+
+func TestEventSources(t *testing.T) {
+	sources := map[string]func(t *testing.T) EventSource{
+		DriverMemory:   func(t *testing.T) EventSource { return NewMemorySource() },
+		DriverSQLite:   newSQLiteSource,
+		DriverPostgres: newPostgresSource,
+	}
+
+	for name, newSource := range sources {
+		t.Run(name, func(t *testing.T) {
+			testEventSource(t, newSource(t))
+		})
+		t.Run(name+"/attendees", func(t *testing.T) {
+			testAttendees(t, newSource(t))
+		})
+		t.Run(name+"/tags", func(t *testing.T) {
+			testTags(t, newSource(t))
+		})
+		t.Run(name+"/batch", func(t *testing.T) {
+			testBatch(t, newSource(t))
+		})
+		t.Run(name+"/transactions", func(t *testing.T) {
+			testTransactions(t, newSource(t))
+		})
+		t.Run(name+"/reminders", func(t *testing.T) {
+			testReminderSource(t, newSource(t).(ReminderSource))
+		})
+		t.Run(name+"/calendars", func(t *testing.T) {
+			testCalendarSource(t, newSource(t).(CalendarSource))
+		})
+		t.Run(name+"/changes", func(t *testing.T) {
+			source := newSource(t)
+			testChangeSource(t, source.(ChangeSource), source.(WebhookSource))
+		})
+		t.Run(name+"/trash", func(t *testing.T) {
+			source := newSource(t)
+			testTrashSource(t, source, source.(TrashSource))
+		})
+		t.Run(name+"/revisions", func(t *testing.T) {
+			testRevisionSource(t, newSource(t).(RevisionSource))
+		})
+	}
+}
+
+// testChangeSource checks the behavior every ChangeSource and WebhookSource implementation must share.
+func testChangeSource(t *testing.T, source ChangeSource, webhooks WebhookSource) {
+	ctx := context.Background()
+	work, home := uuid.New(), uuid.New()
+	changedAt := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+
+	if id, err := source.GetLastChangeID(ctx); err != nil || id != 0 {
+		t.Errorf("GetLastChangeID() of an empty log = %d, %v", id, err)
+	}
+	event := &entity.Event{ID: uuid.New(), Title: "planning", CalendarID: work, TimeZone: "UTC", Version: 1,
+		Start: changedAt, End: changedAt.Add(time.Hour), Reminders: entity.Offsets{15 * time.Minute}}
+	changes := []*entity.Change{
+		entity.NewChange(entity.ChangeCreated, event, uuid.New(), changedAt),
+		entity.NewChange(entity.ChangeCreated, &entity.Event{ID: uuid.New(), CalendarID: home}, uuid.New(), changedAt),
+		entity.NewChange(entity.ChangeDeleted, event, uuid.New(), changedAt.Add(time.Hour)),
+	}
+	for i, change := range changes {
+		if err := source.AppendChange(ctx, change); err != nil {
+			t.Fatalf("AppendChange() error = %v", err)
+		}
+		if i > 0 && change.ID <= changes[i-1].ID {
+			t.Errorf("AppendChange() ID = %d after %d", change.ID, changes[i-1].ID)
+		}
+	}
+
+	got, err := source.GetChanges(ctx, 0, []uuid.UUID{work}, 10)
+	if err != nil {
+		t.Fatalf("GetChanges() error = %v", err)
+	}
+	if len(got) != 2 || got[0].ID != changes[0].ID || got[1].Kind != entity.ChangeDeleted || got[1].Event != nil {
+		t.Fatalf("GetChanges() = %+v", got)
+	}
+	if got[0].Event == nil || got[0].Event.Title != "planning" || !got[0].Event.Start.Equal(changedAt) ||
+		got[0].Event.Reminders.String() != "15m" || !got[0].ChangedAt.Equal(changedAt) {
+		t.Errorf("GetChanges() event = %+v", got[0].Event)
+	}
+	if got, _ := source.GetChanges(ctx, changes[0].ID, []uuid.UUID{work, home}, 1); len(got) != 1 || got[0].ID != changes[1].ID {
+		t.Errorf("GetChanges() after the first change with a limit = %+v", got)
+	}
+	if got, _ := source.GetChanges(ctx, 0, nil, 10); len(got) != 0 {
+		t.Errorf("GetChanges() of no calendars = %+v", got)
+	}
+
+	if err := source.DeleteChanges(ctx, changedAt.Add(time.Minute)); err != nil {
+		t.Fatalf("DeleteChanges() error = %v", err)
+	}
+	if got, _ := source.GetChanges(ctx, 0, []uuid.UUID{work, home}, 10); len(got) != 1 || got[0].ID != changes[2].ID {
+		t.Errorf("GetChanges() after DeleteChanges() = %+v", got)
+	}
+	if id, _ := source.GetLastChangeID(ctx); id != changes[2].ID {
+		t.Errorf("GetLastChangeID() = %d, expected %d", id, changes[2].ID)
+	}
+
+	owner := uuid.New()
+	webhook := &entity.Webhook{ID: uuid.New(), UserID: owner, URL: "https://example.com/hook", Secret: "secret", CreatedAt: changedAt}
+	if err := webhooks.CreateWebhook(ctx, webhook); err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	if err := webhooks.SetWebhookCursor(ctx, webhook.ID, 7); err != nil {
+		t.Fatalf("SetWebhookCursor() error = %v", err)
+	}
+	stored, err := webhooks.GetWebhook(ctx, webhook.ID)
+	if err != nil || stored.LastChangeID != 7 || stored.URL != webhook.URL || !stored.CreatedAt.Equal(changedAt) {
+		t.Errorf("GetWebhook() = %+v, %v", stored, err)
+	}
+	if owned, _ := webhooks.GetUserWebhooks(ctx, owner); len(owned) != 1 {
+		t.Errorf("GetUserWebhooks() = %+v", owned)
+	}
+	if owned, _ := webhooks.GetUserWebhooks(ctx, uuid.New()); len(owned) != 0 {
+		t.Errorf("GetUserWebhooks() of another user = %+v", owned)
+	}
+	if err := webhooks.DeleteWebhook(ctx, webhook.ID); err != nil {
+		t.Fatalf("DeleteWebhook() error = %v", err)
+	}
+	if err := webhooks.DeleteWebhook(ctx, webhook.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteWebhook() of a deleted webhook error = %v, expected ErrNotFound", err)
+	}
+	if all, _ := webhooks.GetWebhooks(ctx); len(all) != 0 {
+		t.Errorf("GetWebhooks() after delete = %+v", all)
+	}
+}
+
+// testRevisionSource checks the behavior every RevisionSource implementation must share.
+func testRevisionSource(t *testing.T, source RevisionSource) {
+	ctx := context.Background()
+	actor := uuid.New()
+	changedAt := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+
+	created := &entity.Event{ID: uuid.New(), Title: "planning", CalendarID: uuid.New(), TimeZone: "UTC", Version: 1,
+		Start: changedAt, End: changedAt.Add(time.Hour)}
+	updated := *created
+	updated.Title, updated.Version = "review", 2
+	var revisions []*entity.Revision
+	for _, change := range []struct {
+		kind          entity.ChangeKind
+		before, after *entity.Event
+	}{
+		{entity.ChangeCreated, nil, created},
+		{entity.ChangeUpdated, created, &updated},
+		{entity.ChangeCreated, nil, &entity.Event{ID: uuid.New(), Title: "other"}},
+		{entity.ChangeDeleted, &updated, nil},
+	} {
+		revision, err := entity.NewRevision(change.kind, change.before, change.after, actor, "req-1", changedAt)
+		if err != nil {
+			t.Fatalf("NewRevision() error = %v", err)
+		}
+		if err := source.AppendRevision(ctx, revision); err != nil {
+			t.Fatalf("AppendRevision() error = %v", err)
+		}
+		if n := len(revisions); n > 0 && revision.ID <= revisions[n-1].ID {
+			t.Errorf("AppendRevision() ID = %d after %d", revision.ID, revisions[n-1].ID)
+		}
+		revisions = append(revisions, revision)
+	}
+
+	got, err := source.GetRevisions(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetRevisions() error = %v", err)
+	}
+	if len(got) != 3 || got[0].ID != revisions[0].ID || got[1].ID != revisions[1].ID || got[2].ID != revisions[3].ID {
+		t.Fatalf("GetRevisions() = %+v", got)
+	}
+	if got[0].Before != nil || got[0].After == nil || !got[0].After.Start.Equal(changedAt) || got[2].After != nil {
+		t.Errorf("GetRevisions() events = %+v", got)
+	}
+	diff := got[1].Diff["title"]
+	if len(got[1].Diff) != 1 || string(diff.Before) != `"planning"` || string(diff.After) != `"review"` {
+		t.Errorf("GetRevisions() diff = %v", got[1].Diff)
+	}
+	if got[1].ActorID != actor || got[1].RequestID != "req-1" || got[1].Version != 2 || !got[1].ChangedAt.Equal(changedAt) {
+		t.Errorf("GetRevisions() revision = %+v", got[1])
+	}
+
+	revision, err := source.GetRevision(ctx, revisions[3].ID)
+	if err != nil || revision.Kind != entity.ChangeDeleted || revision.Before.Title != "review" {
+		t.Errorf("GetRevision() = %+v, %v", revision, err)
+	}
+	if _, err := source.GetRevision(ctx, revisions[3].ID+1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetRevision() of a missing revision error = %v, expected ErrNotFound", err)
+	}
+}
+
+// testCalendarSource checks the behavior every CalendarSource implementation must share.
+func testCalendarSource(t *testing.T, source CalendarSource) {
+	ctx := context.Background()
+	owner, reader := uuid.New(), uuid.New()
+
+	work := &entity.Calendar{ID: uuid.New(), OwnerID: owner, Name: "work"}
+	for _, calendar := range []*entity.Calendar{entity.NewDefaultCalendar(owner), work, entity.NewDefaultCalendar(reader)} {
+		if err := source.CreateCalendar(ctx, calendar); err != nil {
+			t.Fatalf("CreateCalendar() error = %v", err)
+		}
+	}
+	if _, err := source.GetCalendar(ctx, uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetCalendar() of unknown calendar error = %v, expected ErrNotFound", err)
+	}
+	if err := source.CreateCalendar(ctx, entity.NewDefaultCalendar(owner)); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("CreateCalendar() with a taken ID error = %v, expected ErrAlreadyExists", err)
+	}
+
+	share := &entity.Share{CalendarID: work.ID, UserID: reader, Role: entity.RoleWrite}
+	if err := source.SetCalendarShare(ctx, share); err != nil {
+		t.Fatalf("SetCalendarShare() error = %v", err)
+	}
+	share.Role = entity.RoleRead
+	if err := source.SetCalendarShare(ctx, share); err != nil {
+		t.Fatalf("SetCalendarShare() of an existing share error = %v", err)
+	}
+	shares, err := source.GetCalendarShares(ctx, work.ID)
+	if err != nil || len(shares) != 1 || shares[0] != *share {
+		t.Errorf("GetCalendarShares() = %+v, %v", shares, err)
+	}
+
+	roles := map[uuid.UUID]entity.Role{owner: entity.RoleAdmin, reader: entity.RoleRead, uuid.New(): ""}
+	for userID, expected := range roles {
+		role, err := source.GetCalendarRole(ctx, work.ID, userID)
+		if err != nil || role != expected {
+			t.Errorf("GetCalendarRole() = %q, %v, expected %q", role, err, expected)
+		}
+	}
+
+	calendars, err := source.GetUserCalendars(ctx, reader)
+	if err != nil || len(calendars) != 2 || calendars[0].Name != "Default" || calendars[1] != (entity.Calendar{
+		ID: work.ID, OwnerID: owner, Name: "work", Role: entity.RoleRead,
+	}) {
+		t.Errorf("GetUserCalendars() = %+v, %v", calendars, err)
+	}
+
+	work.Name = "office"
+	if err := source.UpdateCalendar(ctx, work); err != nil {
+		t.Fatalf("UpdateCalendar() error = %v", err)
+	}
+	if err := source.DeleteCalendarShare(ctx, work.ID, reader); err != nil {
+		t.Fatalf("DeleteCalendarShare() error = %v", err)
+	}
+	calendars, err = source.GetUserCalendars(ctx, owner)
+	if err != nil || len(calendars) != 2 || calendars[1].Name != "office" {
+		t.Errorf("GetUserCalendars() of owner = %+v, %v", calendars, err)
+	}
+
+	// Deleting a calendar deletes its events.
+	events := source.(EventSource)
+	event := &entity.Event{
+		ID:         uuid.New(),
+		Title:      "meeting",
+		UserID:     owner,
+		CalendarID: work.ID,
+		Start:      time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC),
+		End:        time.Date(2024, time.March, 5, 11, 0, 0, 0, time.UTC),
+		TimeZone:   "UTC",
+	}
+	if err := events.CreateEvent(ctx, event); err != nil {
+		t.Fatalf("CreateEvent() error = %v", err)
+	}
+	if err := source.DeleteCalendar(ctx, work.ID); err != nil {
+		t.Fatalf("DeleteCalendar() error = %v", err)
+	}
+	if _, err := events.GetEvent(ctx, event.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetEvent() of deleted calendar error = %v, expected ErrNotFound", err)
+	}
+	calendars, err = source.GetUserCalendars(ctx, owner)
+	if err != nil || len(calendars) != 1 {
+		t.Errorf("GetUserCalendars() after delete = %+v, %v", calendars, err)
+	}
+}
+
+// testReminderSource checks the behavior every ReminderSource implementation must share.
+func testReminderSource(t *testing.T, source ReminderSource) {
+	ctx := context.Background()
+	events := source.(EventSource)
+	start := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+
+	reminded := &entity.Event{
+		ID:        uuid.New(),
+		Title:     "reminded",
+		UserID:    uuid.New(),
+		Start:     start,
+		End:       start.Add(time.Hour),
+		TimeZone:  "UTC",
+		Reminders: entity.Offsets{10 * time.Minute, time.Hour},
+	}
+	silent := *reminded
+	silent.ID = uuid.New()
+	silent.Title = "silent"
+	silent.Reminders = nil
+	for _, event := range []*entity.Event{reminded, &silent} {
+		if err := events.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("CreateEvent() error = %v", err)
+		}
+	}
+
+	found, err := source.GetRemindedEvents(ctx, start.Add(-time.Hour), start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetRemindedEvents() error = %v", err)
+	}
+	assertTitles(t, "reminded", found, "reminded")
+	if (*found)[0].Reminders.String() != "10m,1h" {
+		t.Errorf("reminders = %q, expected 10m,1h", (*found)[0].Reminders)
+	}
+
+	key := entity.ReminderKey{EventID: reminded.ID, Start: start, Offset: time.Hour}
+	for i := 0; i < 2; i++ {
+		if err := source.MarkReminderDelivered(ctx, key, start.Add(-time.Hour)); err != nil {
+			t.Fatalf("MarkReminderDelivered() #%d error = %v", i+1, err)
+		}
+	}
+	keys, err := source.GetDeliveredReminders(ctx, start.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetDeliveredReminders() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0] != key.Normalize() {
+		t.Errorf("GetDeliveredReminders() = %v, expected %v", keys, key)
+	}
+
+	if err := source.DeleteDeliveredReminders(ctx, start); err != nil {
+		t.Fatalf("DeleteDeliveredReminders() error = %v", err)
+	}
+	keys, err = source.GetDeliveredReminders(ctx, start.Add(-time.Hour))
+	if err != nil || len(keys) != 0 {
+		t.Errorf("GetDeliveredReminders() after delete = %v, %v", keys, err)
+	}
+}
+
+// testEventSource checks the behavior every EventSource implementation must share.
+func testEventSource(t *testing.T, source EventSource) {
+	ctx := context.Background()
+	userID := uuid.New()
+	// The events of the user are in the default calendar of the user.
+	calendarIDs := []uuid.UUID{userID}
+	loc, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+
+	single := &entity.Event{
+		ID:         uuid.New(),
+		Title:      "dentist",
+		UserID:     userID,
+		CalendarID: userID,
+		Start:      time.Date(2024, time.January, 10, 23, 30, 0, 0, loc),
+		End:        time.Date(2024, time.January, 11, 0, 30, 0, 0, loc),
+		TimeZone:   "Europe/Moscow",
+	}
+	series := &entity.Event{
+		ID:         uuid.New(),
+		Title:      "stand-up",
+		UserID:     userID,
+		CalendarID: userID,
+		Start:      time.Date(2024, time.January, 1, 10, 0, 0, 0, loc),
+		End:        time.Date(2024, time.January, 1, 10, 15, 0, 0, loc),
+		TimeZone:   "Europe/Moscow",
+		RRule:      "FREQ=WEEKLY;BYDAY=MO,WE",
+		ExDates:    entity.Dates{time.Date(2024, time.January, 3, 0, 0, 0, 0, time.UTC)},
+	}
+	other := &entity.Event{
+		ID:         uuid.New(),
+		Title:      "someone else's",
+		UserID:     uuid.New(),
+		CalendarID: uuid.New(),
+		Start:      time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC),
+		End:        time.Date(2024, time.January, 10, 13, 0, 0, 0, time.UTC),
+		TimeZone:   "UTC",
+	}
+
+	for _, event := range []*entity.Event{single, series, other} {
+		if err := source.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("CreateEvent() error = %v", err)
+		}
+	}
+
+	stored, err := source.GetEvent(ctx, series.ID)
+	if err != nil {
+		t.Fatalf("GetEvent() error = %v", err)
+	}
+	if stored.Title != series.Title || !stored.Start.Equal(series.Start) || stored.ExDates.String() != "2024-01-03" {
+		t.Errorf("GetEvent() = %+v", stored)
+	}
+	if _, err := source.GetEvent(ctx, uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetEvent() of unknown event error = %v, expected ErrNotFound", err)
+	}
+	if err := source.CreateEvent(ctx, &entity.Event{ID: single.ID, Title: "copy", Start: single.Start, End: single.End}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("CreateEvent() with a taken ID error = %v, expected ErrAlreadyExists", err)
+	}
+
+	// The day is taken in the zone of the date: the dentist overlaps 2024-01-10 in Moscow only.
+	day, err := source.GetEventForDay(ctx, calendarIDs, time.Date(2024, time.January, 10, 0, 0, 0, 0, loc))
+	if err != nil {
+		t.Fatalf("GetEventForDay() error = %v", err)
+	}
+	assertTitles(t, "day", day, "stand-up", "dentist")
+
+	week, err := source.GetEventForWeek(ctx, calendarIDs, time.Date(2024, time.January, 1, 0, 0, 0, 0, loc))
+	if err != nil {
+		t.Fatalf("GetEventForWeek() error = %v", err)
+	}
+	assertTitles(t, "week", week, "stand-up")
+
+	month, err := source.GetEventForMonth(ctx, calendarIDs, time.Date(2024, time.January, 15, 0, 0, 0, 0, loc))
+	if err != nil {
+		t.Fatalf("GetEventForMonth() error = %v", err)
+	}
+	// Mondays and Wednesdays of January 2024 without the 3rd, plus the dentist.
+	if len(*month) != 9+1 {
+		t.Errorf("month = %d events, expected 10", len(*month))
+	}
+
+	if series.Version != 1 || stored.Version != 1 {
+		t.Errorf("version after create = %d, stored %d, expected 1", series.Version, stored.Version)
+	}
+
+	// Searching a window expands series and pages through the occurrences.
+	query := &entity.EventQuery{
+		CalendarIDs: calendarIDs,
+		From:        time.Date(2024, time.January, 1, 0, 0, 0, 0, loc),
+		To:          time.Date(2024, time.February, 1, 0, 0, 0, 0, loc),
+		Text:        "Stand-Up",
+		Sort:        entity.SortStartDesc,
+		Limit:       5,
+	}
+	page, err := source.SearchEvents(ctx, query)
+	if err != nil {
+		t.Fatalf("SearchEvents() error = %v", err)
+	}
+	if len(page.Events) != 5 || page.NextCursor == "" || page.Events[0].Start.Day() != 31 {
+		t.Errorf("SearchEvents() first page = %d events, next %q", len(page.Events), page.NextCursor)
+	}
+	query.After, err = entity.ParseCursor(page.NextCursor)
+	if err != nil {
+		t.Fatalf("ParseCursor() error = %v", err)
+	}
+	page, err = source.SearchEvents(ctx, query)
+	if err != nil || len(page.Events) != 4 || page.NextCursor != "" || page.Events[3].Start.Day() != 1 {
+		t.Errorf("SearchEvents() last page = %+v, %v", page, err)
+	}
+	query.After, query.Text, query.Title = nil, "", "ENTI"
+	page, err = source.SearchEvents(ctx, query)
+	if err != nil {
+		t.Fatalf("SearchEvents() error = %v", err)
+	}
+	assertTitles(t, "title search", &page.Events, "dentist")
+
+	series.Title = "daily"
+	series.ExDates = nil
+	if err := source.UpdateEvent(ctx, series); err != nil {
+		t.Fatalf("UpdateEvent() error = %v", err)
+	}
+	stored, err = source.GetEvent(ctx, series.ID)
+	if err != nil || stored.Title != "daily" || len(stored.ExDates) != 0 || stored.Version != 2 || series.Version != 2 {
+		t.Errorf("GetEvent() after update = %+v, %v", stored, err)
+	}
+
+	// Writes based on a stale version are refused.
+	stale := *series
+	stale.Version = 1
+	stale.Title = "stale"
+	if err := source.UpdateEvent(ctx, &stale); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("UpdateEvent() of a stale version error = %v, expected ErrVersionConflict", err)
+	}
+	if err := source.DeleteEvent(ctx, series.ID, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("DeleteEvent() of a stale version error = %v, expected ErrVersionConflict", err)
+	}
+	missing := *series
+	missing.ID = uuid.New()
+	if err := source.UpdateEvent(ctx, &missing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateEvent() of unknown event error = %v, expected ErrNotFound", err)
+	}
+
+	recurrenceID := time.Date(2024, time.January, 8, 0, 0, 0, 0, time.UTC)
+	override := &entity.Event{
+		ID:           series.OverrideID(recurrenceID),
+		Title:        "moved",
+		UserID:       userID,
+		CalendarID:   userID,
+		Start:        time.Date(2024, time.January, 8, 12, 0, 0, 0, loc),
+		End:          time.Date(2024, time.January, 8, 12, 15, 0, 0, loc),
+		TimeZone:     "Europe/Moscow",
+		SeriesID:     &series.ID,
+		RecurrenceID: &recurrenceID,
+	}
+	if err := source.CreateEvent(ctx, override); err != nil {
+		t.Fatalf("CreateEvent() of override error = %v", err)
+	}
+
+	all, err := source.GetCalendarEvents(ctx, calendarIDs)
+	if err != nil {
+		t.Fatalf("GetCalendarEvents() error = %v", err)
+	}
+	if len(*all) != 3 {
+		t.Errorf("GetCalendarEvents() = %d events, expected 3", len(*all))
+	}
+
+	// Deleting a series deletes its overrides.
+	if err := source.DeleteEvent(ctx, series.ID, series.Version); err != nil {
+		t.Fatalf("DeleteEvent() error = %v", err)
+	}
+	all, err = source.GetCalendarEvents(ctx, calendarIDs)
+	if err != nil {
+		t.Fatalf("GetCalendarEvents() error = %v", err)
+	}
+	assertTitles(t, "after delete", all, "dentist")
+	if err := source.DeleteEvent(ctx, series.ID, 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteEvent() of deleted event error = %v, expected ErrNotFound", err)
+	}
+}
+
+// testAttendees checks that the attendees are stored with the events and find the events they are invited to.
+func testAttendees(t *testing.T, source EventSource) {
+	ctx := context.Background()
+	organizer, attendee := uuid.New(), uuid.New()
+	start := time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+
+	series := &entity.Event{
+		ID:         uuid.New(),
+		Title:      "planning",
+		UserID:     organizer,
+		CalendarID: organizer,
+		Start:      start,
+		End:        start.Add(time.Hour),
+		TimeZone:   "UTC",
+		RRule:      "FREQ=WEEKLY",
+		Attendees: entity.Attendees{
+			{UserID: &attendee, Status: entity.StatusAccepted},
+			{Email: "guest@example.com", Status: entity.StatusNeedsAction},
+		},
+	}
+	external := &entity.Event{
+		ID:         uuid.New(),
+		Title:      "guests only",
+		UserID:     organizer,
+		CalendarID: organizer,
+		Start:      start,
+		End:        start.Add(time.Hour),
+		TimeZone:   "UTC",
+		Attendees:  entity.Attendees{{Email: "guest@example.com", Status: entity.StatusNeedsAction}},
+	}
+	later := &entity.Event{
+		ID:         uuid.New(),
+		Title:      "retro",
+		UserID:     organizer,
+		CalendarID: organizer,
+		Start:      start.Add(2 * time.Hour),
+		End:        start.Add(3 * time.Hour),
+		TimeZone:   "UTC",
+	}
+	for _, event := range []*entity.Event{series, external, later} {
+		if err := source.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("CreateEvent() error = %v", err)
+		}
+	}
+
+	stored, err := source.GetEvent(ctx, series.ID)
+	if err != nil {
+		t.Fatalf("GetEvent() error = %v", err)
+	}
+	if len(stored.Attendees) != 2 || stored.Attendees[0].Status != entity.StatusAccepted || *stored.Attendees[0].UserID != attendee ||
+		stored.Attendees[1].Email != "guest@example.com" {
+		t.Errorf("stored attendees = %+v", stored.Attendees)
+	}
+
+	// The attendee is invited to the later event too.
+	later.Attendees = entity.Attendees{{UserID: &attendee, Status: entity.StatusNeedsAction}}
+	if err := source.UpdateEvent(ctx, later); err != nil {
+		t.Fatalf("UpdateEvent() error = %v", err)
+	}
+
+	invited, err := source.GetInvitedEvents(ctx, attendee, start.AddDate(0, 0, 7), start.AddDate(0, 0, 8))
+	if err != nil {
+		t.Fatalf("GetInvitedEvents() error = %v", err)
+	}
+	assertTitles(t, "invited next week", invited, "planning")
+	invited, err = source.GetInvitedEvents(ctx, attendee, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("GetInvitedEvents() error = %v", err)
+	}
+	assertTitles(t, "invited", invited, "planning", "retro")
+	invited, err = source.GetInvitedEvents(ctx, organizer, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("GetInvitedEvents() error = %v", err)
+	}
+	assertTitles(t, "invited organizer", invited)
+
+	if err := source.DeleteEvent(ctx, series.ID, 0); err != nil {
+		t.Fatalf("DeleteEvent() error = %v", err)
+	}
+	invited, err = source.GetInvitedEvents(ctx, attendee, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("GetInvitedEvents() error = %v", err)
+	}
+	assertTitles(t, "invited after delete", invited, "retro")
+}
+
+// testTags checks that the details and the tags of events are stored, filtered on and counted.
+func testTags(t *testing.T, source EventSource) {
+	ctx := context.Background()
+	work, home := uuid.New(), uuid.New()
+	start := time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+
+	duty := &entity.Event{
+		ID:          uuid.New(),
+		Title:       "duty",
+		UserID:      work,
+		CalendarID:  work,
+		Start:       start,
+		End:         start.Add(time.Hour),
+		TimeZone:    "UTC",
+		RRule:       "FREQ=DAILY",
+		Description: "Pager rotation",
+		Place:       "Office",
+		Color:       "#ff0000",
+		Tags:        entity.Tags{"on-call", "ops"},
+	}
+	standup := &entity.Event{
+		ID: uuid.New(), Title: "standup", UserID: work, CalendarID: work, TimeZone: "UTC",
+		Start: start.Add(time.Hour), End: start.Add(2 * time.Hour), Tags: entity.Tags{"meeting"},
+	}
+	dinner := &entity.Event{
+		ID: uuid.New(), Title: "dinner", UserID: home, CalendarID: home, TimeZone: "UTC",
+		Start: start.Add(8 * time.Hour), End: start.Add(9 * time.Hour), Tags: entity.Tags{"meeting"},
+	}
+	for _, event := range []*entity.Event{duty, standup, dinner} {
+		if err := source.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("CreateEvent() error = %v", err)
+		}
+	}
+
+	stored, err := source.GetEvent(ctx, duty.ID)
+	if err != nil {
+		t.Fatalf("GetEvent() error = %v", err)
+	}
+	if stored.Description != "Pager rotation" || stored.Place != "Office" || stored.Color != "#ff0000" ||
+		stored.Tags.String() != "on-call,ops" {
+		t.Errorf("GetEvent() = %+v, expected the details and the tags", stored)
+	}
+
+	// The occurrences carry the tags of their series.
+	events, err := source.GetEventsInWindow(ctx, []uuid.UUID{work}, start.AddDate(0, 0, 1), start.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatalf("GetEventsInWindow() error = %v", err)
+	}
+	assertTitles(t, "window", events, "duty")
+	if (*events)[0].Tags.String() != "on-call,ops" {
+		t.Errorf("occurrence tags = %v, expected the tags of the series", (*events)[0].Tags)
+	}
+
+	query := &entity.EventQuery{
+		CalendarIDs: []uuid.UUID{work, home}, From: start, To: start.AddDate(0, 0, 1),
+		Tags: []string{"meeting"}, Sort: entity.SortStart, Limit: entity.DefaultSearchLimit,
+	}
+	page, err := source.SearchEvents(ctx, query)
+	if err != nil {
+		t.Fatalf("SearchEvents() error = %v", err)
+	}
+	assertTitles(t, "meetings", &page.Events, "standup", "dinner")
+	query.Tags = []string{"on-call", "meeting"}
+	page, err = source.SearchEvents(ctx, query)
+	if err != nil {
+		t.Fatalf("SearchEvents() error = %v", err)
+	}
+	assertTitles(t, "on-call meetings", &page.Events)
+
+	// An update replaces the tags.
+	standup.Tags = entity.Tags{"meeting", "on-call"}
+	if err := source.UpdateEvent(ctx, standup); err != nil {
+		t.Fatalf("UpdateEvent() error = %v", err)
+	}
+	page, err = source.SearchEvents(ctx, query)
+	if err != nil {
+		t.Fatalf("SearchEvents() error = %v", err)
+	}
+	assertTitles(t, "on-call meetings after update", &page.Events, "standup")
+
+	counts, err := source.GetTagCounts(ctx, []uuid.UUID{work})
+	if err != nil {
+		t.Fatalf("GetTagCounts() error = %v", err)
+	}
+	expected := []entity.TagCount{{Name: "meeting", Count: 1}, {Name: "on-call", Count: 2}, {Name: "ops", Count: 1}}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("GetTagCounts() = %+v, expected %+v", counts, expected)
+	}
+
+	// Deleted events aren't counted.
+	if err := source.DeleteEvent(ctx, duty.ID, 0); err != nil {
+		t.Fatalf("DeleteEvent() error = %v", err)
+	}
+	counts, err = source.GetTagCounts(ctx, []uuid.UUID{work, home})
+	if err != nil {
+		t.Fatalf("GetTagCounts() error = %v", err)
+	}
+	expected = []entity.TagCount{{Name: "meeting", Count: 2}, {Name: "on-call", Count: 1}}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("GetTagCounts() after delete = %+v, expected %+v", counts, expected)
+	}
+}
+
+// testBatch checks that a batch is applied at once and that a failed operation rolls back the ones before it.
+func testBatch(t *testing.T, source EventSource) {
+	ctx := context.Background()
+	userID := uuid.New()
+	calendarIDs := []uuid.UUID{userID}
+	start := time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+	newEvent := func(title string, hour int) *entity.Event {
+		return &entity.Event{ID: uuid.New(), Title: title, UserID: userID, CalendarID: userID, TimeZone: "UTC",
+			Start: start.Add(time.Duration(hour) * time.Hour), End: start.Add(time.Duration(hour+1) * time.Hour)}
+	}
+
+	kept, removed := newEvent("standup", 0), newEvent("lunch", 3)
+	for _, event := range []*entity.Event{kept, removed} {
+		if err := source.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("CreateEvent() error = %v", err)
+		}
+	}
+
+	added := newEvent("planning", 1)
+	changed := *kept
+	changed.Title = "daily"
+	operations := []entity.BatchOperation{
+		{Op: entity.BatchCreate, Event: added},
+		{Op: entity.BatchUpdate, Event: &changed},
+		{Op: entity.BatchDelete, ID: removed.ID, Version: removed.Version},
+	}
+	if err := source.Batch(ctx, operations); err != nil {
+		t.Fatalf("Batch() error = %v", err)
+	}
+	if added.Version != 1 || changed.Version != 2 {
+		t.Errorf("versions after Batch() = %d, %d", added.Version, changed.Version)
+	}
+	events, err := source.GetEventForDay(ctx, calendarIDs, start)
+	if err != nil {
+		t.Fatalf("GetEventForDay() error = %v", err)
+	}
+	assertTitles(t, "after batch", events, "daily", "planning")
+
+	// The stale update fails the batch, so the event created before it is rolled back.
+	rolledBack := newEvent("retro", 5)
+	stale := *kept
+	stale.Title = "stale"
+	err = source.Batch(ctx, []entity.BatchOperation{
+		{Op: entity.BatchCreate, Event: rolledBack},
+		{Op: entity.BatchDelete, ID: added.ID},
+		{Op: entity.BatchUpdate, Event: &stale},
+	})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("Batch() with a stale update error = %v, expected ErrVersionConflict", err)
+	}
+	if _, err := source.GetEvent(ctx, rolledBack.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetEvent() of the rolled back event error = %v, expected ErrNotFound", err)
+	}
+	events, err = source.GetEventForDay(ctx, calendarIDs, start)
+	if err != nil {
+		t.Fatalf("GetEventForDay() error = %v", err)
+	}
+	assertTitles(t, "after failed batch", events, "daily", "planning")
+}
+
+// testTransactions checks that the writes within a transaction are committed together
+// and that an error or a panic of the function rolls all of them back, including the audit history.
+func testTransactions(t *testing.T, source EventSource) {
+	ctx := context.Background()
+	userID := uuid.New()
+	start := time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+	newEvent := func(title string) *entity.Event {
+		return &entity.Event{ID: uuid.New(), Title: title, UserID: userID, CalendarID: userID, TimeZone: "UTC",
+			Start: start, End: start.Add(time.Hour)}
+	}
+	write := func(ctx context.Context, event *entity.Event) error {
+		if err := source.CreateEvent(ctx, event); err != nil {
+			return err
+		}
+		revision, err := entity.NewRevision(entity.ChangeCreated, nil, event, userID, "req-1", start)
+		if err != nil {
+			return err
+		}
+		return source.(RevisionSource).AppendRevision(ctx, revision)
+	}
+	assertStored := func(name string, event *entity.Event, stored bool) {
+		t.Helper()
+		_, err := source.GetEvent(ctx, event.ID)
+		if stored != (err == nil) {
+			t.Errorf("GetEvent() of %s error = %v, expected stored = %v", name, err, stored)
+		}
+		revisions, err := source.(RevisionSource).GetRevisions(ctx, event.ID)
+		if err != nil || stored != (len(revisions) == 1) {
+			t.Errorf("GetRevisions() of %s = %d revisions, %v, expected stored = %v", name, len(revisions), err, stored)
+		}
+	}
+
+	// Nested calls join the outer transaction and the reads within it see its writes.
+	committed, nested := newEvent("committed"), newEvent("nested")
+	nested.Start = start.Add(30 * time.Minute)
+	err := source.WithinTx(ctx, func(ctx context.Context) error {
+		if err := write(ctx, committed); err != nil {
+			return err
+		}
+		if _, err := source.GetEvent(ctx, committed.ID); err != nil {
+			return err
+		}
+		return source.WithinTx(ctx, func(ctx context.Context) error {
+			return write(ctx, nested)
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() error = %v", err)
+	}
+	assertStored("committed event", committed, true)
+	assertStored("nested event", nested, true)
+
+	// The error of the function is returned as is and rolls back every write, the failed nested call too.
+	failure := errors.New("failure")
+	rolledBack, deleted, moved := newEvent("rolled back"), *committed, *nested
+	err = source.WithinTx(ctx, func(ctx context.Context) error {
+		if err := write(ctx, rolledBack); err != nil {
+			return err
+		}
+		if err := source.DeleteEvent(ctx, deleted.ID, 0); err != nil {
+			return err
+		}
+		moved.Start, moved.End = moved.Start.AddDate(0, 0, 1), moved.End.AddDate(0, 0, 1)
+		if err := source.UpdateEvent(ctx, &moved); err != nil {
+			return err
+		}
+		return source.WithinTx(ctx, func(ctx context.Context) error {
+			return failure
+		})
+	})
+	if err != failure {
+		t.Fatalf("WithinTx() error = %v, expected the error of the function", err)
+	}
+	assertStored("rolled back event", rolledBack, false)
+	assertStored("event deleted in a rolled back transaction", &deleted, true)
+	window, err := source.GetEventsInWindow(ctx, []uuid.UUID{userID}, start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetEventsInWindow() error = %v", err)
+	}
+	assertTitles(t, "window after the rolled back transaction", window, "committed", "nested")
+
+	// A panic rolls back too and the source stays usable.
+	panicked := newEvent("panicked")
+	func() {
+		defer func() {
+			if p := recover(); p == nil {
+				t.Errorf("WithinTx() didn't panic")
+			}
+		}()
+		_ = source.WithinTx(ctx, func(ctx context.Context) error {
+			if err := write(ctx, panicked); err != nil {
+				return err
+			}
+			panic("failure")
+		})
+	}()
+	assertStored("event written before a panic", panicked, false)
+
+	if err := source.UpdateEvent(ctx, committed); err != nil {
+		t.Errorf("UpdateEvent() after the rolled back transactions error = %v", err)
+	}
+}
+
+// testTrashSource checks that deleted events leave the queries of live events until they are restored.
+func testTrashSource(t *testing.T, source EventSource, trash TrashSource) {
+	ctx := context.Background()
+	userID := uuid.New()
+	calendarIDs := []uuid.UUID{userID}
+	day := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	newEvent := func(title string, hour int) *entity.Event {
+		return &entity.Event{
+			ID:         uuid.New(),
+			Title:      title,
+			UserID:     userID,
+			CalendarID: userID,
+			Start:      day.Add(time.Duration(hour) * time.Hour),
+			End:        day.Add(time.Duration(hour+1) * time.Hour),
+			TimeZone:   "UTC",
+		}
+	}
+	single := newEvent("dentist", 9)
+	series := newEvent("stand-up", 10)
+	series.RRule = "FREQ=DAILY"
+	series.Reminders = entity.Offsets{time.Minute}
+	recurrenceID := day.AddDate(0, 0, 1)
+	override := newEvent("late stand-up", 35)
+	override.SeriesID = &series.ID
+	override.RecurrenceID = &recurrenceID
+	for _, event := range []*entity.Event{single, series, override} {
+		if err := source.CreateEvent(ctx, event); err != nil {
+			t.Fatalf("CreateEvent(%s) error = %v", event.Title, err)
+		}
+	}
+
+	if err := source.DeleteEvent(ctx, single.ID, 0); err != nil {
+		t.Fatalf("DeleteEvent() error = %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if err := source.DeleteEvent(ctx, series.ID, 0); err != nil {
+		t.Fatalf("DeleteEvent() error = %v", err)
+	}
+
+	// Deleted events are missing from every query of live events.
+	if _, err := source.GetEvent(ctx, single.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetEvent() of deleted event error = %v, expected ErrNotFound", err)
+	}
+	if err := source.UpdateEvent(ctx, single); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateEvent() of deleted event error = %v, expected ErrNotFound", err)
+	}
+	window, err := source.GetEventsInWindow(ctx, calendarIDs, day, day.AddDate(0, 0, 3))
+	if err != nil {
+		t.Fatalf("GetEventsInWindow() error = %v", err)
+	}
+	assertTitles(t, "window after delete", window)
+	all, err := source.GetCalendarEvents(ctx, calendarIDs)
+	if err != nil {
+		t.Fatalf("GetCalendarEvents() error = %v", err)
+	}
+	assertTitles(t, "calendar after delete", all)
+	if reminded, err := source.(ReminderSource).GetRemindedEvents(ctx, day, day.AddDate(0, 0, 3)); err != nil || len(*reminded) != 0 {
+		t.Errorf("GetRemindedEvents() after delete = %v, %v, expected none", reminded, err)
+	}
+
+	// The override went to the trash with its series, so it isn't listed on its own.
+	deleted, err := trash.GetDeletedEvents(ctx, calendarIDs)
+	if err != nil {
+		t.Fatalf("GetDeletedEvents() error = %v", err)
+	}
+	assertTitles(t, "trash", deleted, "stand-up", "dentist")
+	if (*deleted)[0].DeletedAt == nil {
+		t.Errorf("deleted event has no deletion time")
+	}
+	if _, err := trash.GetDeletedEvent(ctx, override.ID); err != nil {
+		t.Errorf("GetDeletedEvent() of override error = %v", err)
+	}
+
+	if err := trash.RestoreEvent(ctx, series.ID); err != nil {
+		t.Fatalf("RestoreEvent() error = %v", err)
+	}
+	if err := trash.RestoreEvent(ctx, series.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RestoreEvent() of live event error = %v, expected ErrNotFound", err)
+	}
+	all, err = source.GetCalendarEvents(ctx, calendarIDs)
+	if err != nil {
+		t.Fatalf("GetCalendarEvents() error = %v", err)
+	}
+	assertTitles(t, "calendar after restore", all, "stand-up", "late stand-up")
+	if (*all)[0].DeletedAt != nil {
+		t.Errorf("restored event has deletion time %v", (*all)[0].DeletedAt)
+	}
+
+	// Purging deletes the events deleted before the time for good.
+	if err := trash.PurgeDeletedEvents(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("PurgeDeletedEvents() error = %v", err)
+	}
+	if _, err := trash.GetDeletedEvent(ctx, single.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetDeletedEvent() of purged event error = %v, expected ErrNotFound", err)
+	}
+	if _, err := source.GetEvent(ctx, series.ID); err != nil {
+		t.Errorf("GetEvent() of restored event after purge error = %v", err)
+	}
+}
+
+func assertTitles(t *testing.T, name string, events *entity.Events, titles ...string) {
+	t.Helper()
+
+	if len(*events) != len(titles) {
+		t.Errorf("%s = %d events, expected %v", name, len(*events), titles)
+		return
+	}
+	for i, event := range *events {
+		if event.Title != titles[i] {
+			t.Errorf("%s event %d = %q, expected %q", name, i, event.Title, titles[i])
+		}
+	}
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	source := NewSource(openSQLite(t), zap.New(core), time.Nanosecond)
+
+	parent := trace.New()
+	ctx := trace.ContextWithRequestID(context.Background(), "req-1")
+	ctx = trace.ContextWithTraceparent(ctx, parent)
+	if _, err := source.GetEvent(ctx, uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetEvent() error = %v, expected ErrNotFound", err)
+	}
+
+	entries := logs.FilterMessage("slow query").All()
+	if len(entries) != 1 {
+		t.Fatalf("slow query log entries = %d, expected 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["query"] != "GetEvent" || fields["request_id"] != "req-1" || fields["trace_id"] != parent.String()[3:35] {
+		t.Errorf("slow query log fields = %v", fields)
+	}
+
+	source = NewSource(openSQLite(t), zap.New(core), 0)
+	source.GetEvent(ctx, uuid.New())
+	if logs.Len() != 1 {
+		t.Errorf("slow query log entries with the log disabled = %d, expected 1", logs.Len())
+	}
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// saveTags replaces the tags of the event, creating the tags which don't exist yet.
+// The caller runs it within a transaction together with the write of the event.
+func (s *source) saveTags(ctx context.Context, eventID uuid.UUID, tags entity.Tags) error {
+	_, err := s.conn(ctx).ExecContext(ctx, "DELETE FROM event_tags WHERE event_id = $1", eventID)
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+
+	for _, tag := range tags {
+		_, err = s.conn(ctx).ExecContext(ctx, "INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING", tag)
+		if err != nil {
+			return fmt.Errorf("can't exec query: %v", err)
+		}
+		_, err = s.conn(ctx).ExecContext(
+			ctx, "INSERT INTO event_tags (event_id, tag_id) SELECT $1, id FROM tags WHERE name = $2", eventID, tag,
+		)
+		if err != nil {
+			return fmt.Errorf("can't exec query: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// loadTags sets the tags of the events with one query. Occurrences share the tags of their series.
+func (s *source) loadTags(ctx context.Context, events []entity.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	seen := make(map[uuid.UUID]bool, len(events))
+	var ids []uuid.UUID
+	for _, event := range events {
+		if !seen[event.ID] {
+			seen[event.ID] = true
+			ids = append(ids, event.ID)
+		}
+	}
+
+	args, in := appendIDs(nil, ids)
+	rows, err := s.conn(ctx).QueryxContext(
+		ctx,
+		"SELECT et.event_id, t.name FROM event_tags et JOIN tags t ON t.id = et.tag_id WHERE et.event_id IN ("+in+") ORDER BY t.name",
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("can't exec query: %v", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[uuid.UUID]entity.Tags, len(ids))
+	for rows.Next() {
+		var eventID uuid.UUID
+		var name string
+		if err := rows.Scan(&eventID, &name); err != nil {
+			return fmt.Errorf("can't scan tag: %v", err)
+		}
+		tags[eventID] = append(tags[eventID], name)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("can't read tags: %v", err)
+	}
+
+	for i := range events {
+		events[i].Tags = tags[events[i].ID]
+	}
+
+	return nil
+}
+
+// tagCondition returns the condition of a query of events matching the events carrying the tag,
+// the tag is appended to the arguments.
+func tagCondition(args []any, tag string) ([]any, string) {
+	args = append(args, tag)
+	return args, fmt.Sprintf(
+		"id IN (SELECT et.event_id FROM event_tags et JOIN tags t ON t.id = et.tag_id WHERE t.name = $%d)", len(args),
+	)
+}
+
+// GetTagCounts returns the tags of the live events of the calendars with the number of events carrying them,
+// ordered by name. A series counts once, however many occurrences it has.
+func (s *source) GetTagCounts(ctx context.Context, calendarIDs []uuid.UUID) ([]entity.TagCount, error) {
+	counts := []entity.TagCount{}
+	if len(calendarIDs) == 0 {
+		return counts, nil
+	}
+
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetTagCounts", time.Now())
+
+	args, in := appendIDs(nil, calendarIDs)
+	err := s.conn(ctx).SelectContext(
+		dbCtx,
+		&counts,
+		`SELECT t.name, COUNT(*) AS count FROM event_tags et JOIN tags t ON t.id = et.tag_id JOIN events e ON e.id = et.event_id
+		WHERE e.deleted_at IS NULL AND e.calendar_id IN (`+in+`) GROUP BY t.name ORDER BY t.name`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+
+	return counts, nil
+}
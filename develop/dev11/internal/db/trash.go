@@ -0,0 +1,118 @@
+package db
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GetDeletedEvents returns the deleted events of the calendars, the last deleted first.
+// Overrides deleted together with their series are restored with it, so they aren't listed.
+func (s *source) GetDeletedEvents(ctx context.Context, calendarIDs []uuid.UUID) (*entity.Events, error) {
+	events := &entity.Events{}
+	if len(calendarIDs) == 0 {
+		return events, nil
+	}
+
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetDeletedEvents", time.Now())
+
+	args, in := appendIDs(nil, calendarIDs)
+	err := s.conn(ctx).SelectContext(
+		dbCtx,
+		events,
+		`SELECT * FROM events e WHERE deleted_at IS NOT NULL AND calendar_id IN (`+in+`)
+		AND NOT EXISTS (SELECT 1 FROM events m WHERE m.id = e.series_id AND m.deleted_at = e.deleted_at)
+		ORDER BY deleted_at DESC, start_at`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+	if err := s.loadTags(dbCtx, *events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetDeletedEvent returns the event if it is in the trash.
+func (s *source) GetDeletedEvent(ctx context.Context, eventID uuid.UUID) (*entity.Event, error) {
+	dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+	defer dbCancel()
+	defer s.observeQuery(ctx, "GetDeletedEvent", time.Now())
+
+	var event entity.Event
+	err := s.conn(ctx).GetContext(dbCtx, &event, "SELECT * FROM events WHERE id = $1 AND deleted_at IS NOT NULL", eventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("deleted event %s: %w", eventID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't exec query: %v", err)
+	}
+	events := []entity.Event{event}
+	if err := s.loadTags(dbCtx, events); err != nil {
+		return nil, err
+	}
+
+	return &events[0], nil
+}
+
+// RestoreEvent takes the event out of the trash together with the overrides deleted with it.
+func (s *source) RestoreEvent(ctx context.Context, eventID uuid.UUID) error {
+	defer s.observeQuery(ctx, "RestoreEvent", time.Now())
+
+	return s.WithinTx(ctx, func(ctx context.Context) error {
+		dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+		defer dbCancel()
+
+		var deletedAt time.Time
+		err := s.conn(ctx).GetContext(dbCtx, &deletedAt, "SELECT deleted_at FROM events WHERE id = $1 AND deleted_at IS NOT NULL", eventID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("deleted event %s: %w", eventID, ErrNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("can't exec query: %v", err)
+		}
+
+		for _, query := range []string{
+			"UPDATE events SET deleted_at = NULL WHERE series_id = $1 AND deleted_at = $2",
+			"UPDATE events SET deleted_at = NULL WHERE id = $1 AND deleted_at = $2",
+		} {
+			_, err := s.conn(ctx).ExecContext(dbCtx, query, eventID, deletedAt)
+			if err != nil {
+				return fmt.Errorf("can't exec query: %v", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// PurgeDeletedEvents deletes the events which were moved to the trash before the time for good, with their tags.
+func (s *source) PurgeDeletedEvents(ctx context.Context, deletedBefore time.Time) error {
+	defer s.observeQuery(ctx, "PurgeDeletedEvents", time.Now())
+
+	return s.WithinTx(ctx, func(ctx context.Context) error {
+		dbCtx, dbCancel := context.WithTimeout(ctx, QueryTimeout)
+		defer dbCancel()
+
+		for _, query := range []string{
+			"DELETE FROM event_tags WHERE event_id IN (SELECT id FROM events WHERE deleted_at < $1)",
+			"DELETE FROM events WHERE deleted_at < $1",
+		} {
+			_, err := s.conn(ctx).ExecContext(dbCtx, query, deletedBefore.UTC())
+			if err != nil {
+				return fmt.Errorf("can't exec query: %v", err)
+			}
+		}
+
+		return nil
+	})
+}
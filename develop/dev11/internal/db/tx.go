@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// queryer runs the queries of the SQL source, both *sqlx.DB and *sqlx.Tx implement it.
+type queryer interface {
+	sqlx.ExtContext
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+}
+
+// txKey is the context key of the transaction of the SQL source.
+type txKey struct{}
+
+// sourceTx is the transaction carried in the context together with the database it belongs to.
+type sourceTx struct {
+	db *sqlx.DB
+	tx *sqlx.Tx
+}
+
+// TxFromContext returns the transaction of the SQL source the context is running in, if any.
+func TxFromContext(ctx context.Context) (*sqlx.Tx, bool) {
+	t, ok := ctx.Value(txKey{}).(*sourceTx)
+	if !ok {
+		return nil, false
+	}
+	return t.tx, true
+}
+
+// conn returns the transaction the context is running in and the database outside of transactions.
+func (s *source) conn(ctx context.Context) queryer {
+	if t, ok := ctx.Value(txKey{}).(*sourceTx); ok && t.db == s.db {
+		return t.tx
+	}
+	return s.db
+}
+
+// WithinTx runs fn within one transaction: every query of the source made with the context passed to fn
+// belongs to it. The transaction is committed if fn returns nil and rolled back if fn fails or panics.
+// Within a transaction fn joins it, so that the outermost call decides. The error of fn is returned as is.
+func (s *source) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if t, ok := ctx.Value(txKey{}).(*sourceTx); ok && t.db == s.db {
+		return fn(ctx)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("can't begin transaction: %v", err)
+	}
+	// Rolling back a committed transaction does nothing.
+	defer tx.Rollback()
+
+	err = fn(context.WithValue(ctx, txKey{}, &sourceTx{db: s.db, tx: tx}))
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("can't commit transaction: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// AttendeeStatus is the participation status of an attendee, the PARTSTAT of iCalendar.
+type AttendeeStatus string
+
+const (
+	StatusNeedsAction AttendeeStatus = "needs-action"
+	StatusAccepted    AttendeeStatus = "accepted"
+	StatusDeclined    AttendeeStatus = "declined"
+	StatusTentative   AttendeeStatus = "tentative"
+)
+
+// ParseAttendeeStatus parses a status, an empty one is needs-action.
+func ParseAttendeeStatus(s string) (AttendeeStatus, error) {
+	switch status := AttendeeStatus(strings.ToLower(s)); status {
+	case "":
+		return StatusNeedsAction, nil
+	case StatusNeedsAction, StatusAccepted, StatusDeclined, StatusTentative:
+		return status, nil
+	default:
+		return "", fmt.Errorf("unknown attendee status %q", s)
+	}
+}
+
+// IsResponse reports whether the status answers an invitation.
+func (s AttendeeStatus) IsResponse() bool {
+	return s == StatusAccepted || s == StatusDeclined || s == StatusTentative
+}
+
+// Attendee is a participant invited to an event by its organizer, the creator of the event.
+// An attendee is either a user of the calendar or an external attendee known by the email address only.
+type Attendee struct {
+	UserID *uuid.UUID     `json:"user_id,omitempty"`
+	Email  string         `json:"email,omitempty"`
+	Status AttendeeStatus `json:"status,omitempty"`
+}
+
+// IsExternal reports whether the attendee isn't a user of the calendar.
+func (a *Attendee) IsExternal() bool {
+	return a.UserID == nil
+}
+
+// key identifies the attendee among the attendees of an event.
+func (a *Attendee) key() string {
+	if a.UserID != nil {
+		return a.UserID.String()
+	}
+	return a.Email
+}
+
+// Attendees is the list of attendees of an event. It is stored as JSON.
+type Attendees []Attendee
+
+// ParseAttendees parses a comma-separated list of user IDs and email addresses of attendees,
+// every attendee needs action.
+func ParseAttendees(s string) (Attendees, error) {
+	attendees := Attendees{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		attendee := Attendee{Status: StatusNeedsAction}
+		if userID, err := uuid.Parse(part); err == nil {
+			attendee.UserID = &userID
+		} else {
+			attendee.Email = part
+		}
+		attendees = append(attendees, attendee)
+	}
+
+	return attendees, attendees.normalize()
+}
+
+// normalize checks the attendees and brings their emails and statuses to the stored form.
+func (a Attendees) normalize() error {
+	seen := make(map[string]bool, len(a))
+	for i := range a {
+		attendee := &a[i]
+		switch {
+		case attendee.UserID != nil && attendee.Email != "":
+			return fmt.Errorf("attendee %s has both user_id and email", attendee.UserID)
+		case attendee.UserID == nil:
+			address, err := mail.ParseAddress(attendee.Email)
+			if err != nil {
+				return fmt.Errorf("invalid attendee email %q: %w", attendee.Email, err)
+			}
+			attendee.Email = strings.ToLower(address.Address)
+		}
+
+		status, err := ParseAttendeeStatus(string(attendee.Status))
+		if err != nil {
+			return err
+		}
+		attendee.Status = status
+
+		if seen[attendee.key()] {
+			return fmt.Errorf("duplicate attendee %s", attendee.key())
+		}
+		seen[attendee.key()] = true
+	}
+
+	return nil
+}
+
+// Validate checks that every attendee is either a user or an email address and is listed once.
+func (a Attendees) Validate() error {
+	return a.Clone().normalize()
+}
+
+// Find returns the attendee who is the user.
+func (a Attendees) Find(userID uuid.UUID) (*Attendee, bool) {
+	for i := range a {
+		if a[i].UserID != nil && *a[i].UserID == userID {
+			return &a[i], true
+		}
+	}
+	return nil, false
+}
+
+// Invites reports whether the user is an attendee.
+func (a Attendees) Invites(userID uuid.UUID) bool {
+	_, ok := a.Find(userID)
+	return ok
+}
+
+// Merge returns the attendees with the statuses of the stored ones, only attendees themselves change
+// their statuses. New attendees need action, and so does everyone if reset is set, because the event
+// was rescheduled.
+func (a Attendees) Merge(stored Attendees, reset bool) Attendees {
+	statuses := make(map[string]AttendeeStatus, len(stored))
+	for i := range stored {
+		statuses[stored[i].key()] = stored[i].Status
+	}
+
+	merged := a.Clone()
+	for i := range merged {
+		status, ok := statuses[merged[i].key()]
+		if !ok || reset {
+			status = StatusNeedsAction
+		}
+		merged[i].Status = status
+	}
+	return merged
+}
+
+// Added returns the attendees who aren't among the stored ones.
+func (a Attendees) Added(stored Attendees) Attendees {
+	known := make(map[string]bool, len(stored))
+	for i := range stored {
+		known[stored[i].key()] = true
+	}
+
+	var added Attendees
+	for i := range a {
+		if !known[a[i].key()] {
+			added = append(added, a[i])
+		}
+	}
+	return added.Clone()
+}
+
+// Clone returns a deep copy of the attendees.
+func (a Attendees) Clone() Attendees {
+	if a == nil {
+		return nil
+	}
+	clone := make(Attendees, len(a))
+	for i, attendee := range a {
+		if attendee.UserID != nil {
+			userID := *attendee.UserID
+			attendee.UserID = &userID
+		}
+		clone[i] = attendee
+	}
+	return clone
+}
+
+// Value implements driver.Valuer. Attendees are stored as the JSON of encoding/json, so a user
+// is always stored as "user_id":"<id>" and the events of an attendee can be found by a text match.
+func (a Attendees) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal([]Attendee(a))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner.
+func (a *Attendees) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("can't scan %T into attendees", src)
+	}
+
+	if len(data) == 0 {
+		*a = nil
+		return nil
+	}
+	var attendees []Attendee
+	if err := json.Unmarshal(data, &attendees); err != nil {
+		return fmt.Errorf("can't unmarshal attendees: %w", err)
+	}
+	*a = attendees
+	return nil
+}
+
+// Invitation invites the attendees to the event of the organizer.
+type Invitation struct {
+	Event     Event
+	Attendees Attendees
+}
+
+// Response is the answer of an attendee to the invitation to the event, the organizer is notified of it.
+type Response struct {
+	Event    Event
+	Attendee Attendee
+}
@@ -0,0 +1,92 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// BatchOp is the kind of an operation of a batch.
+type BatchOp string
+
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchDelete BatchOp = "delete"
+)
+
+// BatchOperation is a change of an event applied together with the other operations of its batch.
+// Create and update carry the event, an update is based on Event.Version like a single update.
+// Delete carries the ID of the event and the version it is based on, zero for any.
+type BatchOperation struct {
+	Op      BatchOp
+	Event   *Event
+	ID      uuid.UUID
+	Version int64
+}
+
+// EventID returns the ID of the event the operation changes.
+func (o *BatchOperation) EventID() uuid.UUID {
+	if o.Event != nil {
+		return o.Event.ID
+	}
+	return o.ID
+}
+
+// ParseJSONBatch parses a batch {"operations": [...]} of operations like {"op": "create", "event": {...}},
+// {"op": "update", "event": {...}, "version": 3} and {"op": "delete", "id": "...", "version": 3}.
+// Events are parsed with ParseJSONEvent, the ID of an update may be given by either id or the event.
+func ParseJSONBatch(data []byte) ([]BatchOperation, error) {
+	var batch struct {
+		Operations []struct {
+			Op      BatchOp         `json:"op"`
+			ID      uuid.UUID       `json:"id"`
+			Version int64           `json:"version"`
+			Event   json.RawMessage `json:"event"`
+		} `json:"operations"`
+	}
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, err
+	}
+	if len(batch.Operations) == 0 {
+		return nil, fmt.Errorf("empty operations")
+	}
+
+	operations := make([]BatchOperation, 0, len(batch.Operations))
+	for n, raw := range batch.Operations {
+		operation := BatchOperation{Op: raw.Op, ID: raw.ID, Version: raw.Version}
+		switch raw.Op {
+		case BatchCreate, BatchUpdate:
+			if len(raw.Event) == 0 {
+				return nil, fmt.Errorf("operation %d: empty event", n)
+			}
+			event, err := ParseJSONEvent(raw.Event)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", n, err)
+			}
+			if raw.Op == BatchUpdate {
+				switch {
+				case event.ID == uuid.Nil:
+					event.ID = raw.ID
+				case raw.ID != uuid.Nil && raw.ID != event.ID:
+					return nil, fmt.Errorf("operation %d: id doesn't match the event", n)
+				}
+				if event.ID == uuid.Nil {
+					return nil, fmt.Errorf("operation %d: empty id", n)
+				}
+				event.Version = raw.Version
+			}
+			operation.Event = event
+		case BatchDelete:
+			if raw.ID == uuid.Nil {
+				return nil, fmt.Errorf("operation %d: empty id", n)
+			}
+		default:
+			return nil, fmt.Errorf("operation %d: unknown op %q", n, raw.Op)
+		}
+		operations = append(operations, operation)
+	}
+
+	return operations, nil
+}
@@ -0,0 +1,76 @@
+package entity
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Role is the access level of a user to a calendar. Every role includes the ones before it.
+type Role string
+
+const (
+	// RoleRead allows reading the events of the calendar.
+	RoleRead Role = "read"
+	// RoleWrite allows creating, changing and deleting the events of the calendar.
+	RoleWrite Role = "write"
+	// RoleAdmin allows renaming, deleting and sharing the calendar. The owner is an admin.
+	RoleAdmin Role = "admin"
+)
+
+var roleRanks = map[Role]int{RoleRead: 1, RoleWrite: 2, RoleAdmin: 3}
+
+// ParseRole parses read, write or admin.
+func ParseRole(value string) (Role, error) {
+	role := Role(value)
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("unknown role %q", value)
+	}
+	return role, nil
+}
+
+// Allows reports whether the role includes the required one. An empty role allows nothing.
+func (r Role) Allows(required Role) bool {
+	return roleRanks[r] > 0 && roleRanks[r] >= roleRanks[required]
+}
+
+// Calendar is a collection of events owned by a user and shared with others.
+// The default calendar of a user has the ID of the user.
+type Calendar struct {
+	ID      uuid.UUID `json:"id" db:"id"`
+	OwnerID uuid.UUID `json:"owner_id" db:"owner_id"`
+	Name    string    `json:"name" db:"name"`
+	// Role is the role of the user the calendar is listed for.
+	Role Role `json:"role,omitempty" db:"role"`
+}
+
+// DefaultCalendarName is the name of the calendar created for every user.
+const DefaultCalendarName = "Default"
+
+// NewDefaultCalendar returns the default calendar of the user.
+func NewDefaultCalendar(userID uuid.UUID) *Calendar {
+	return &Calendar{ID: userID, OwnerID: userID, Name: DefaultCalendarName}
+}
+
+// IsDefault reports whether the calendar is the default calendar of its owner.
+func (c *Calendar) IsDefault() bool {
+	return c.ID == c.OwnerID
+}
+
+type Calendars []Calendar
+
+// IDs returns the IDs of the calendars.
+func (c Calendars) IDs() []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(c))
+	for _, calendar := range c {
+		ids = append(ids, calendar.ID)
+	}
+	return ids
+}
+
+// Share grants a user a role in a calendar.
+type Share struct {
+	CalendarID uuid.UUID `json:"calendar_id" db:"calendar_id"`
+	UserID     uuid.UUID `json:"user_id" db:"user_id"`
+	Role       Role      `json:"role" db:"role"`
+}
@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChangeKind is what happened to an event.
+type ChangeKind string
+
+const (
+	ChangeCreated ChangeKind = "created"
+	ChangeUpdated ChangeKind = "updated"
+	ChangeDeleted ChangeKind = "deleted"
+)
+
+// Change is an entry of the change log of events. IDs grow with every change,
+// so a subscriber resumes the log after the last change it has seen.
+type Change struct {
+	ID         int64      `json:"id"`
+	Kind       ChangeKind `json:"kind"`
+	EventID    uuid.UUID  `json:"event_id"`
+	CalendarID uuid.UUID  `json:"calendar_id"`
+	// UserID is the user who made the change.
+	UserID  uuid.UUID `json:"user_id"`
+	Version int64     `json:"version"`
+	// Event is the event after the change, nil for a deleted event.
+	Event     *Event    `json:"event,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// NewChange returns the change of the event made by the user.
+func NewChange(kind ChangeKind, event *Event, userID uuid.UUID, changedAt time.Time) *Change {
+	change := &Change{
+		Kind:       kind,
+		EventID:    event.ID,
+		CalendarID: event.CalendarID,
+		UserID:     userID,
+		Version:    event.Version,
+		ChangedAt:  changedAt.UTC(),
+	}
+	if kind != ChangeDeleted {
+		snapshot := *event
+		change.Event = &snapshot
+	}
+	return change
+}
+
+// Webhook is a URL the changes of the events a user can read are posted to,
+// signed with the secret of the webhook.
+type Webhook struct {
+	ID     uuid.UUID `json:"id" db:"id"`
+	UserID uuid.UUID `json:"user_id" db:"user_id"`
+	URL    string    `json:"url" db:"url"`
+	// Secret signs the deliveries, it is only shown when the webhook is created.
+	Secret string `json:"secret,omitempty" db:"secret"`
+	// LastChangeID is the last change delivered to the webhook.
+	LastChangeID int64     `json:"-" db:"last_change_id"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// MinWebhookSecretLength is the shortest secret a webhook can be signed with.
+const MinWebhookSecretLength = 16
+
+// Validate checks that the webhook posts to an absolute HTTP or HTTPS URL with a long enough secret.
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if len(w.Secret) < MinWebhookSecretLength {
+		return fmt.Errorf("secret must be at least %d bytes long", MinWebhookSecretLength)
+	}
+	return nil
+}
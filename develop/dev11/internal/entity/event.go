@@ -4,7 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -12,8 +16,214 @@ import (
 type Event struct {
 	ID     uuid.UUID `json:"id,omitempty" db:"id"`
 	Title  string    `json:"title,omitempty" db:"title"`
-	Date   time.Time `json:"date,omitempty" db:"date"`
 	UserID uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	// CalendarID is the calendar the event belongs to, the default calendar of the creator if not given.
+	CalendarID uuid.UUID `json:"calendar_id,omitempty" db:"calendar_id"`
+
+	// UID is the iCalendar UID of an imported event whose UID isn't a UUID.
+	UID string `json:"uid,omitempty" db:"uid"`
+
+	// Start and End bound the event, End is exclusive.
+	Start time.Time `json:"start" db:"start_at"`
+	End   time.Time `json:"end" db:"end_at"`
+	// AllDay events span whole days in their time zone.
+	AllDay bool `json:"all_day" db:"all_day"`
+	// TimeZone is the IANA time zone the event was scheduled in.
+	TimeZone string `json:"time_zone,omitempty" db:"time_zone"`
+
+	// RRule is the recurrence rule of a series master, empty for single events.
+	RRule string `json:"rrule,omitempty" db:"rrule"`
+	// ExDates are the dates excluded from the series, either deleted or overridden.
+	ExDates Dates `json:"exdate,omitempty" db:"exdate"`
+	// SeriesID is the ID of the series master for an overridden occurrence.
+	SeriesID *uuid.UUID `json:"series_id,omitempty" db:"series_id"`
+	// RecurrenceID is the original date of an occurrence of a series.
+	RecurrenceID *time.Time `json:"recurrence_id,omitempty" db:"recurrence_id"`
+
+	// Description is the free text of the event.
+	Description string `json:"description,omitempty" db:"description"`
+	// Place is where the event takes place, it is named location outside of the code,
+	// since Location returns the time zone of the event.
+	Place string `json:"location,omitempty" db:"location"`
+	// Color is the color the event is shown with, #rrggbb or empty for the color of its calendar.
+	Color string `json:"color,omitempty" db:"color"`
+	// Tags label the event, they are stored apart from it and loaded with it.
+	Tags Tags `json:"tags,omitempty" db:"-"`
+
+	// Reminders are the offsets before the start of every occurrence to send notifications at.
+	Reminders Offsets `json:"reminders,omitempty" db:"reminders"`
+
+	// Attendees are the participants the creator of the event, its organizer, invited.
+	Attendees Attendees `json:"attendees,omitempty" db:"attendees"`
+
+	// Version is incremented on every change of the event, it is the entity tag of the event.
+	Version int64 `json:"version" db:"version"`
+
+	// DeletedAt is when the event was moved to the trash, nil for live events.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+
+	// missing are the details left out of the form the event was parsed from.
+	missing missingDetails
+}
+
+// missingDetails tells which of the text details of an event a form left out.
+type missingDetails struct {
+	description, place, color bool
+}
+
+// KeepMissingDetails sets the description, the location and the color left out of the form
+// the event was parsed from to the ones of the stored event, like the other optional fields of an update.
+func (e *Event) KeepMissingDetails(stored *Event) {
+	if e.missing.description {
+		e.Description = stored.Description
+	}
+	if e.missing.place {
+		e.Place = stored.Place
+	}
+	if e.missing.color {
+		e.Color = stored.Color
+	}
+	e.missing = missingDetails{}
+}
+
+// Location returns the time zone of the event, UTC if it is unknown.
+func (e *Event) Location() *time.Location {
+	loc, err := time.LoadLocation(e.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Duration returns the length of the event.
+func (e *Event) Duration() time.Duration {
+	return e.End.Sub(e.Start)
+}
+
+// Overlaps reports whether the event intersects [from, to).
+func (e *Event) Overlaps(from time.Time, to time.Time) bool {
+	return e.Start.Before(to) && e.End.After(from)
+}
+
+// IsRecurring reports whether the event is a series master.
+func (e *Event) IsRecurring() bool {
+	return e.RRule != ""
+}
+
+// IsOccurrence reports whether the event is a single occurrence of a series.
+func (e *Event) IsOccurrence() bool {
+	return e.RecurrenceID != nil
+}
+
+// Occurrences expands a series master into its occurrences overlapping [from, to).
+// The rule is applied in the time zone of the event, so occurrences keep their wall clock
+// time across DST changes. Every occurrence keeps the ID of the master and gets
+// its date as RecurrenceID. A single event is returned as is if it overlaps the window.
+func (e *Event) Occurrences(from time.Time, to time.Time) (Events, error) {
+	events := Events{}
+
+	if !e.IsRecurring() {
+		if e.Overlaps(from, to) {
+			events.Add(*e)
+		}
+		return events, nil
+	}
+
+	rule, err := ParseRRule(e.RRule, e.Location())
+	if err != nil {
+		return nil, fmt.Errorf("invalid rrule of event %s: %w", e.ID, err)
+	}
+
+	duration := e.Duration()
+	start := e.Start.In(e.Location())
+	for _, occurrenceStart := range rule.Occurrences(start, from.Add(-duration), to, e.ExDates) {
+		occurrence := *e
+		occurrence.Start = occurrenceStart
+		occurrence.End = occurrenceStart.Add(duration)
+		if !occurrence.Overlaps(from, to) {
+			continue
+		}
+		recurrenceID := time.Date(occurrenceStart.Year(), occurrenceStart.Month(), occurrenceStart.Day(), 0, 0, 0, 0, time.UTC)
+		occurrence.RecurrenceID = &recurrenceID
+		events.Add(occurrence)
+	}
+
+	return events, nil
+}
+
+// OccurrenceOn returns the occurrence of a series master whose recurrence date is date.
+func (e *Event) OccurrenceOn(date time.Time) (*Event, bool) {
+	loc := e.Location()
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+
+	occurrences, err := e.Occurrences(day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, false
+	}
+	for _, occurrence := range occurrences {
+		if occurrence.RecurrenceID != nil && occurrence.RecurrenceID.Format(dateLayout) == date.Format(dateLayout) {
+			return &occurrence, true
+		}
+	}
+
+	return nil, false
+}
+
+// OverrideID returns the ID of the event overriding the occurrence of the series on date.
+// The ID is derived from the series, so an occurrence can be overridden only once.
+func (e *Event) OverrideID(date time.Time) uuid.UUID {
+	return uuid.NewSHA1(e.ID, []byte(date.Format(dateLayout)))
+}
+
+// Validate checks the invariants every stored event keeps, whatever it was parsed from.
+func (e *Event) Validate() error {
+	if e.Title == "" {
+		return fmt.Errorf("empty title")
+	}
+	if !e.End.After(e.Start) {
+		return fmt.Errorf("end must be after start")
+	}
+	if _, err := time.LoadLocation(e.TimeZone); err != nil {
+		return fmt.Errorf("invalid time_zone: %w", err)
+	}
+	if e.IsRecurring() {
+		if _, err := ParseRRule(e.RRule, e.Location()); err != nil {
+			return err
+		}
+	}
+	if err := e.Attendees.Validate(); err != nil {
+		return err
+	}
+	if err := validateDetails(e.Description, e.Place, e.Color); err != nil {
+		return err
+	}
+	if err := e.Tags.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Limits of the details of an event.
+const (
+	MaxDescriptionLength = 8192
+	MaxLocationLength    = 256
+)
+
+// colorPattern is the form of a color, a hex RGB triplet.
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// validateDetails checks the lengths of the description and the location and the form of the color.
+func validateDetails(description string, location string, color string) error {
+	switch {
+	case utf8.RuneCountInString(description) > MaxDescriptionLength:
+		return fmt.Errorf("description is longer than %d characters", MaxDescriptionLength)
+	case utf8.RuneCountInString(location) > MaxLocationLength:
+		return fmt.Errorf("location is longer than %d characters", MaxLocationLength)
+	case color != "" && !colorPattern.MatchString(color):
+		return fmt.Errorf("invalid color %q: must be #rrggbb", color)
+	}
+	return nil
 }
 
 func UnmarshalEvent(data []byte) (*Event, error) {
@@ -24,33 +234,265 @@ func UnmarshalEvent(data []byte) (*Event, error) {
 	return u, nil
 }
 
-func ParseFormEvent(form url.Values) (*Event, error) {
-	id, err := uuid.Parse(form.Get("id"))
+// ParseJSONEvent parses an event in the JSON form returned by the API, so a fetched event can be sent back
+// changed. Start and end are RFC 3339 times, the end is exclusive for all-day events too.
+// The creator, the series, the UID and the version are assigned by the server and ignored.
+func ParseJSONEvent(data []byte) (*Event, error) {
+	event, err := UnmarshalEvent(data)
 	if err != nil {
 		return nil, err
 	}
+	event.UserID = uuid.Nil
+	event.UID = ""
+	event.SeriesID = nil
+	event.Version = 0
+
+	if event.Title == "" {
+		return nil, fmt.Errorf("empty title")
+	}
 
-	date, err := time.Parse("2006-01-02", form.Get("date"))
+	loc, err := time.LoadLocation(event.TimeZone)
 	if err != nil {
+		return nil, fmt.Errorf("invalid time_zone: %w", err)
+	}
+	event.TimeZone = loc.String()
+
+	switch {
+	case event.Start.IsZero():
+		return nil, fmt.Errorf("empty start")
+	case event.End.IsZero():
+		return nil, fmt.Errorf("empty end")
+	}
+	event.Start = event.Start.In(loc)
+	event.End = event.End.In(loc)
+	if event.AllDay {
+		event.Start = time.Date(event.Start.Year(), event.Start.Month(), event.Start.Day(), 0, 0, 0, 0, loc)
+		event.End = time.Date(event.End.Year(), event.End.Month(), event.End.Day(), 0, 0, 0, 0, loc)
+	}
+	if !event.End.After(event.Start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+
+	if event.RRule != "" {
+		rule, err := ParseRRule(event.RRule, loc)
+		if err != nil {
+			return nil, err
+		}
+		event.RRule = rule.String()
+	}
+
+	if event.RecurrenceID != nil {
+		recurrenceID := time.Date(event.RecurrenceID.Year(), event.RecurrenceID.Month(), event.RecurrenceID.Day(), 0, 0, 0, 0, time.UTC)
+		event.RecurrenceID = &recurrenceID
+	}
+
+	if err := event.Attendees.normalize(); err != nil {
+		return nil, err
+	}
+
+	event.Place = strings.TrimSpace(event.Place)
+	event.Color = strings.ToLower(event.Color)
+	if err := validateDetails(event.Description, event.Place, event.Color); err != nil {
 		return nil, err
 	}
+	if err := event.Tags.normalize(); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// ParseFormEvent parses an event of a form. A missing id is left empty to be assigned on create.
+func ParseFormEvent(form url.Values) (*Event, error) {
+	var id uuid.UUID
+	var err error
+	if form.Get("id") != "" {
+		id, err = uuid.Parse(form.Get("id"))
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	title := form.Get("title")
 	if title == "" {
 		return nil, fmt.Errorf("empty title")
 	}
 
-	userID, err := uuid.Parse(form.Get("user_id"))
+	// The creator is the authenticated caller, a user_id of the form is ignored.
+	event := &Event{
+		ID:    id,
+		Title: title,
+	}
+
+	if form.Get("calendar_id") != "" {
+		event.CalendarID, err = uuid.Parse(form.Get("calendar_id"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid calendar_id: %w", err)
+		}
+	}
+
+	err = parseFormSchedule(form, event)
+	if err != nil {
+		return nil, err
+	}
+
+	if rrule := form.Get("rrule"); rrule != "" {
+		rule, err := ParseRRule(rrule, event.Location())
+		if err != nil {
+			return nil, err
+		}
+		event.RRule = rule.String()
+	}
+
+	// A missing exdate keeps the stored exclusions on update, an empty one clears them.
+	if _, ok := form["exdate"]; ok {
+		event.ExDates, err = ParseDates(form.Get("exdate"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid exdate: %w", err)
+		}
+	}
+
+	// Like exdate, missing reminders are kept on update.
+	if _, ok := form["reminders"]; ok {
+		event.Reminders, err = ParseOffsets(form.Get("reminders"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid reminders: %w", err)
+		}
+	}
+
+	// Like exdate, missing attendees are kept on update.
+	if _, ok := form["attendees"]; ok {
+		event.Attendees, err = ParseAttendees(form.Get("attendees"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid attendees: %w", err)
+		}
+	}
+
+	// Like exdate, missing details are kept on update, empty ones clear them.
+	event.Description = form.Get("description")
+	event.Place = strings.TrimSpace(form.Get("location"))
+	event.Color = strings.ToLower(form.Get("color"))
+	event.missing = missingDetails{
+		description: !form.Has("description"),
+		place:       !form.Has("location"),
+		color:       !form.Has("color"),
+	}
+	err = validateDetails(event.Description, event.Place, event.Color)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Event{
-		ID:     id,
-		Title:  title,
-		Date:   date,
-		UserID: userID,
-	}, nil
+	// Like exdate, missing tags are kept on update.
+	if _, ok := form["tags"]; ok {
+		event.Tags, err = ParseTags(form.Get("tags"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tags: %w", err)
+		}
+	}
+
+	if form.Get("recurrence_id") != "" {
+		recurrenceID, err := time.Parse("2006-01-02", form.Get("recurrence_id"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid recurrence_id: %w", err)
+		}
+		event.RecurrenceID = &recurrenceID
+	}
+
+	return event, nil
+}
+
+// parseFormSchedule fills the start, end and time zone of the event.
+// Either a date for an all-day event or a start with an end or a duration must be given.
+// Local times are interpreted in the tz zone, UTC by default.
+func parseFormSchedule(form url.Values, event *Event) error {
+	loc, err := time.LoadLocation(form.Get("tz"))
+	if err != nil {
+		return fmt.Errorf("invalid tz: %w", err)
+	}
+	event.TimeZone = loc.String()
+
+	allDay := form.Get("all_day")
+	event.AllDay = allDay == "true" || allDay == "1"
+
+	switch {
+	case form.Get("start") != "":
+		event.Start, err = ParseLocalTime(form.Get("start"), loc)
+		if err != nil {
+			return fmt.Errorf("invalid start: %w", err)
+		}
+	case form.Get("date") != "":
+		event.Start, err = time.ParseInLocation(dateLayout, form.Get("date"), loc)
+		if err != nil {
+			return fmt.Errorf("invalid date: %w", err)
+		}
+		event.AllDay = true
+	default:
+		return fmt.Errorf("empty start")
+	}
+
+	if event.AllDay {
+		event.Start = time.Date(event.Start.Year(), event.Start.Month(), event.Start.Day(), 0, 0, 0, 0, loc)
+	}
+
+	switch {
+	case form.Get("end") != "":
+		event.End, err = ParseLocalTime(form.Get("end"), loc)
+		if err != nil {
+			return fmt.Errorf("invalid end: %w", err)
+		}
+		if event.AllDay {
+			// The end date of an all-day event is inclusive in forms.
+			event.End = time.Date(event.End.Year(), event.End.Month(), event.End.Day()+1, 0, 0, 0, 0, loc)
+		}
+	case form.Get("duration") != "":
+		duration, err := time.ParseDuration(form.Get("duration"))
+		if err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+		event.End = event.Start.Add(duration)
+	case event.AllDay:
+		event.End = event.Start.AddDate(0, 0, 1)
+	default:
+		return fmt.Errorf("empty end or duration")
+	}
+
+	if !event.End.After(event.Start) {
+		return fmt.Errorf("end must be after start")
+	}
+
+	return nil
+}
+
+// ParseLocalTime parses RFC 3339 times as is and times without an offset in loc.
+func ParseLocalTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", dateLayout} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time format %q", value)
+}
+
+// DayWindow returns the bounds of the day of date, computed in the location of date.
+func DayWindow(date time.Time) (time.Time, time.Time) {
+	// Вычисляем начало и конец указанного дня
+	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	return startOfDay, startOfDay.AddDate(0, 0, 1)
+}
+
+// WeekWindow returns the bounds of the week starting at date.
+func WeekWindow(date time.Time) (time.Time, time.Time) {
+	return date, date.AddDate(0, 0, 7)
+}
+
+// MonthWindow returns the bounds of the month of date, computed in the location of date.
+func MonthWindow(date time.Time) (time.Time, time.Time) {
+	// Вычисляем начало и конец месяца
+	startOfMonth := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+	return startOfMonth, startOfMonth.AddDate(0, 1, 0)
 }
 
 type Events []Event
@@ -69,3 +511,24 @@ func (e *Events) ToJSON() ([]byte, error) {
 func (e *Events) Add(event Event) {
 	*e = append(*e, event)
 }
+
+// WithTags returns the events carrying every one of the tags, all of them if there are no tags.
+func (e *Events) WithTags(tags []string) *Events {
+	if len(tags) == 0 {
+		return e
+	}
+	filtered := Events{}
+	for _, event := range *e {
+		if event.Tags.HasAll(tags) {
+			filtered = append(filtered, event)
+		}
+	}
+	return &filtered
+}
+
+// Sort orders the events by start time.
+func (e *Events) Sort() {
+	sort.SliceStable(*e, func(i, j int) bool {
+		return (*e)[i].Start.Before((*e)[j].Start)
+	})
+}
@@ -0,0 +1,216 @@
+package entity
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseFormEventSchedule(t *testing.T) {
+	base := url.Values{
+		"id":      {"3f1b1c4e-8a8e-4a7e-9a39-0f0a1f6a2b11"},
+		"user_id": {"9c9d3a4e-1f2b-4c5d-8e9f-0a1b2c3d4e5f"},
+		"title":   {"stand-up"},
+	}
+
+	tests := []struct {
+		name          string
+		params        url.Values
+		expectedStart string
+		expectedEnd   string
+		allDay        bool
+		wantErr       bool
+	}{
+		{
+			name:          "date is all-day",
+			params:        url.Values{"date": {"2024-03-10"}},
+			expectedStart: "2024-03-10T00:00:00Z",
+			expectedEnd:   "2024-03-11T00:00:00Z",
+			allDay:        true,
+		},
+		{
+			name:          "local start with duration",
+			params:        url.Values{"start": {"2024-03-10T10:00"}, "duration": {"30m"}, "tz": {"Europe/Moscow"}},
+			expectedStart: "2024-03-10T10:00:00+03:00",
+			expectedEnd:   "2024-03-10T10:30:00+03:00",
+		},
+		{
+			name:          "all-day end is inclusive",
+			params:        url.Values{"start": {"2024-03-10"}, "end": {"2024-03-12"}, "all_day": {"true"}},
+			expectedStart: "2024-03-10T00:00:00Z",
+			expectedEnd:   "2024-03-13T00:00:00Z",
+			allDay:        true,
+		},
+		{
+			name:    "end before start",
+			params:  url.Values{"start": {"2024-03-10T10:00"}, "end": {"2024-03-10T09:00"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown zone",
+			params:  url.Values{"date": {"2024-03-10"}, "tz": {"Mars/Olympus"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			for k, v := range base {
+				form[k] = v
+			}
+			for k, v := range tt.params {
+				form[k] = v
+			}
+
+			event, err := ParseFormEvent(form)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ParseFormEvent() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFormEvent() error = %v", err)
+			}
+
+			if got := event.Start.Format(time.RFC3339); got != tt.expectedStart {
+				t.Errorf("start = %s, expected %s", got, tt.expectedStart)
+			}
+			if got := event.End.Format(time.RFC3339); got != tt.expectedEnd {
+				t.Errorf("end = %s, expected %s", got, tt.expectedEnd)
+			}
+			if event.AllDay != tt.allDay {
+				t.Errorf("all_day = %v, expected %v", event.AllDay, tt.allDay)
+			}
+		})
+	}
+}
+
+func TestParseFormEventDetails(t *testing.T) {
+	form := url.Values{
+		"title":       {"duty"},
+		"date":        {"2024-03-10"},
+		"description": {"Pager rotation"},
+		"location":    {" Office "},
+		"color":       {"#FF8800"},
+		"tags":        {"On-Call, ops,on-call"},
+	}
+	event, err := ParseFormEvent(form)
+	if err != nil {
+		t.Fatalf("ParseFormEvent() error = %v", err)
+	}
+	if event.Description != "Pager rotation" || event.Place != "Office" || event.Color != "#ff8800" ||
+		event.Tags.String() != "on-call,ops" {
+		t.Errorf("ParseFormEvent() = %+v, expected the details with normalized tags", event)
+	}
+
+	// Missing tags are kept on update, empty ones clear them.
+	delete(form, "tags")
+	if event, err := ParseFormEvent(form); err != nil || event.Tags != nil {
+		t.Errorf("ParseFormEvent() without tags = %v, %v, expected nil tags", event, err)
+	}
+	form.Set("tags", "")
+	if event, err := ParseFormEvent(form); err != nil || event.Tags == nil || len(event.Tags) != 0 {
+		t.Errorf("ParseFormEvent() with empty tags = %v, %v, expected no tags", event, err)
+	}
+
+	// So are the missing description, location and color, while empty ones clear them.
+	stored := *event
+	stored.Description, stored.Place, stored.Color = "Pager rotation", "Office", "#ff8800"
+	partial := url.Values{"title": {"duty"}, "date": {"2024-03-10"}, "color": {""}}
+	event, err = ParseFormEvent(partial)
+	if err != nil {
+		t.Fatalf("ParseFormEvent() error = %v", err)
+	}
+	event.KeepMissingDetails(&stored)
+	if event.Description != "Pager rotation" || event.Place != "Office" || event.Color != "" {
+		t.Errorf("KeepMissingDetails() = %q, %q, %q, expected the stored description and location and no color",
+			event.Description, event.Place, event.Color)
+	}
+
+	invalid := map[string]string{
+		"color":    "red",
+		"tags":     "team meeting",
+		"location": strings.Repeat("x", MaxLocationLength+1),
+	}
+	for key, value := range invalid {
+		broken := url.Values{}
+		for k, v := range form {
+			broken[k] = v
+		}
+		broken.Set(key, value)
+		if _, err := ParseFormEvent(broken); err == nil {
+			t.Errorf("ParseFormEvent() with invalid %s error = nil", key)
+		}
+	}
+}
+
+func TestEventOccurrencesKeepWallClockAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+
+	start := time.Date(2024, time.March, 29, 9, 0, 0, 0, loc)
+	event := Event{
+		Start:    start.UTC(),
+		End:      start.Add(time.Hour).UTC(),
+		TimeZone: "Europe/Berlin",
+		RRule:    "FREQ=DAILY;COUNT=3",
+	}
+
+	occurrences, err := event.Occurrences(start.AddDate(0, 0, -1), start.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("Occurrences() error = %v", err)
+	}
+	if len(occurrences) != 3 {
+		t.Fatalf("Occurrences() = %d, expected 3", len(occurrences))
+	}
+	for _, occurrence := range occurrences {
+		if occurrence.Start.In(loc).Hour() != 9 {
+			t.Errorf("occurrence %s doesn't start at 09:00", occurrence.Start)
+		}
+	}
+	if occurrences[2].RecurrenceID.Format(dateLayout) != "2024-03-31" {
+		t.Errorf("recurrence_id = %s, expected 2024-03-31", occurrences[2].RecurrenceID.Format(dateLayout))
+	}
+}
+
+func TestParseJSONEvent(t *testing.T) {
+	event, err := ParseJSONEvent([]byte(`{
+		"title": "holiday",
+		"user_id": "9c9d3a4e-1f2b-4c5d-8e9f-0a1b2c3d4e5f",
+		"start": "2024-03-10T15:00:00Z",
+		"end": "2024-03-11T00:00:00Z",
+		"all_day": true,
+		"time_zone": "Europe/Moscow",
+		"rrule": "freq=yearly",
+		"reminders": ["1h"],
+		"version": 7
+	}`))
+	if err != nil {
+		t.Fatalf("ParseJSONEvent() error = %v", err)
+	}
+	if event.Start.Format(time.RFC3339) != "2024-03-10T00:00:00+03:00" || event.End.Format(time.RFC3339) != "2024-03-11T00:00:00+03:00" {
+		t.Errorf("all-day event spans %s - %s", event.Start.Format(time.RFC3339), event.End.Format(time.RFC3339))
+	}
+	if event.RRule != "FREQ=YEARLY" || len(event.Reminders) != 1 || event.UserID != uuid.Nil || event.Version != 0 {
+		t.Errorf("ParseJSONEvent() = %+v", event)
+	}
+
+	for _, body := range []string{
+		`{"start": "2024-03-10T10:00:00Z", "end": "2024-03-10T11:00:00Z"}`,
+		`{"title": "x", "start": "2024-03-10T10:00:00Z"}`,
+		`{"title": "x", "start": "2024-03-10T10:00:00Z", "end": "2024-03-10T09:00:00Z"}`,
+		`{"title": "x", "start": "2024-03-10T10:00:00Z", "end": "2024-03-10T11:00:00Z", "time_zone": "Mars/Olympus"}`,
+		`{"title": "x", "start": "2024-03-10T10:00:00Z", "end": "2024-03-10T11:00:00Z", "reminders": ["soon"]}`,
+	} {
+		if _, err := ParseJSONEvent([]byte(body)); err == nil {
+			t.Errorf("ParseJSONEvent(%s) expected an error", body)
+		}
+	}
+}
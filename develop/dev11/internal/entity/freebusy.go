@@ -0,0 +1,87 @@
+package entity
+
+import (
+	"sort"
+	"time"
+)
+
+// Interval is a half-open time interval [Start, End).
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// FreeBusy is the busy time of a user within a window and the free slots left in it.
+type FreeBusy struct {
+	From time.Time  `json:"from"`
+	To   time.Time  `json:"to"`
+	Busy []Interval `json:"busy"`
+	Free []Interval `json:"free"`
+}
+
+// NewFreeBusy merges the events into busy intervals clipped to [from, to) and returns
+// the gaps between them lasting at least minFree as free slots.
+// All-day events don't make the user busy, like transparent events of calendar clients.
+// Times are returned in the location of from.
+func NewFreeBusy(events Events, from time.Time, to time.Time, minFree time.Duration) *FreeBusy {
+	loc := from.Location()
+	fb := &FreeBusy{
+		From: from,
+		To:   to.In(loc),
+		Busy: []Interval{},
+		Free: []Interval{},
+	}
+
+	var intervals []Interval
+	for _, event := range events {
+		if event.AllDay || !event.Overlaps(from, to) {
+			continue
+		}
+		intervals = append(intervals, Interval{Start: maxTime(event.Start, from), End: minTime(event.End, to)})
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Start.Before(intervals[j].Start) })
+
+	for _, interval := range intervals {
+		last := len(fb.Busy) - 1
+		if last >= 0 && !interval.Start.After(fb.Busy[last].End) {
+			fb.Busy[last].End = maxTime(fb.Busy[last].End, interval.End)
+			continue
+		}
+		fb.Busy = append(fb.Busy, interval)
+	}
+
+	free := from
+	for _, busy := range fb.Busy {
+		fb.addFree(free, busy.Start, minFree)
+		free = busy.End
+	}
+	fb.addFree(free, to, minFree)
+
+	for i := range fb.Busy {
+		fb.Busy[i].Start = fb.Busy[i].Start.In(loc)
+		fb.Busy[i].End = fb.Busy[i].End.In(loc)
+	}
+
+	return fb
+}
+
+func (fb *FreeBusy) addFree(start time.Time, end time.Time, minFree time.Duration) {
+	if !end.After(start) || end.Sub(start) < minFree {
+		return
+	}
+	fb.Free = append(fb.Free, Interval{Start: start.In(fb.From.Location()), End: end.In(fb.From.Location())})
+}
+
+func minTime(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
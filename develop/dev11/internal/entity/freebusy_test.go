@@ -0,0 +1,56 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewFreeBusy(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, time.March, 5, hour, minute, 0, 0, time.UTC)
+	}
+	event := func(start, end time.Time) Event {
+		return Event{Start: start, End: end}
+	}
+
+	events := Events{
+		event(at(7, 0), at(9, 30)),  // clipped to the window
+		event(at(10, 0), at(11, 0)), // merged with the next two
+		event(at(10, 30), at(12, 0)),
+		event(at(12, 0), at(12, 30)),
+		event(at(14, 0), at(14, 20)),
+		{Start: at(0, 0), End: at(0, 0).AddDate(0, 0, 1), AllDay: true},
+	}
+
+	fb := NewFreeBusy(events, at(9, 0), at(18, 0), 45*time.Minute)
+
+	expectedBusy := []Interval{{at(9, 0), at(9, 30)}, {at(10, 0), at(12, 30)}, {at(14, 0), at(14, 20)}}
+	// The 30 minutes between 9:30 and 10:00 are shorter than requested.
+	expectedFree := []Interval{{at(12, 30), at(14, 0)}, {at(14, 20), at(18, 0)}}
+
+	assertIntervals(t, "busy", fb.Busy, expectedBusy)
+	assertIntervals(t, "free", fb.Free, expectedFree)
+}
+
+func TestNewFreeBusyEmpty(t *testing.T) {
+	from := time.Date(2024, time.March, 5, 9, 0, 0, 0, time.UTC)
+	to := from.Add(8 * time.Hour)
+
+	fb := NewFreeBusy(nil, from, to, 0)
+
+	assertIntervals(t, "busy", fb.Busy, []Interval{})
+	assertIntervals(t, "free", fb.Free, []Interval{{from, to}})
+}
+
+func assertIntervals(t *testing.T, name string, actual []Interval, expected []Interval) {
+	t.Helper()
+
+	if len(actual) != len(expected) {
+		t.Fatalf("%s = %v, expected %v", name, actual, expected)
+	}
+	for i := range expected {
+		if !actual[i].Start.Equal(expected[i].Start) || !actual[i].End.Equal(expected[i].End) {
+			t.Errorf("%s[%d] = %v, expected %v", name, i, actual[i], expected[i])
+		}
+	}
+}
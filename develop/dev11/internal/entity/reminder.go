@@ -0,0 +1,179 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxReminderOffset is the longest time a reminder may fire before its event.
+const MaxReminderOffset = 7 * 24 * time.Hour
+
+// Offsets is a list of reminder offsets before the start of an event, like 15m or 1h.
+type Offsets []time.Duration
+
+// ParseOffsets parses a comma-separated list of offsets in time.ParseDuration format.
+// The result is sorted and has no duplicates.
+func ParseOffsets(s string) (Offsets, error) {
+	offsets := Offsets{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		offset, err := time.ParseDuration(part)
+		if err != nil {
+			return nil, err
+		}
+		if offset < 0 || offset > MaxReminderOffset {
+			return nil, fmt.Errorf("offset %s out of range [0, %s]", part, formatOffset(MaxReminderOffset))
+		}
+		offsets = append(offsets, offset)
+	}
+
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	unique := offsets[:0]
+	for i, offset := range offsets {
+		if i == 0 || offset != offsets[i-1] {
+			unique = append(unique, offset)
+		}
+	}
+	return unique, nil
+}
+
+// String formats the list as comma-separated offsets.
+func (o Offsets) String() string {
+	parts := make([]string, 0, len(o))
+	for _, offset := range o {
+		parts = append(parts, formatOffset(offset))
+	}
+	return strings.Join(parts, ",")
+}
+
+// MarshalJSON encodes the list as an array of offsets like "1h30m".
+func (o Offsets) MarshalJSON() ([]byte, error) {
+	parts := make([]string, 0, len(o))
+	for _, offset := range o {
+		parts = append(parts, formatOffset(offset))
+	}
+	return json.Marshal(parts)
+}
+
+// UnmarshalJSON decodes an array of offsets.
+func (o *Offsets) UnmarshalJSON(data []byte) error {
+	var parts []string
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+
+	offsets, err := ParseOffsets(strings.Join(parts, ","))
+	if err != nil {
+		return err
+	}
+	*o = offsets
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (o Offsets) Value() (driver.Value, error) {
+	return o.String(), nil
+}
+
+// Scan implements sql.Scanner.
+func (o *Offsets) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("can't scan %T into offsets", src)
+	}
+
+	offsets, err := ParseOffsets(s)
+	if err != nil {
+		return err
+	}
+	*o = offsets
+	return nil
+}
+
+// formatOffset formats the offset without zero trailing units: 1h instead of 1h0m0s.
+func formatOffset(offset time.Duration) string {
+	s := offset.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// Reminder is a notification due before an occurrence of an event.
+type Reminder struct {
+	// Event is the occurrence the reminder is about.
+	Event Event `json:"event"`
+	// Offset is the time before the start of the event the reminder fires at.
+	Offset time.Duration `json:"offset"`
+}
+
+// FireAt returns the time the reminder is due.
+func (r *Reminder) FireAt() time.Time {
+	return r.Event.Start.Add(-r.Offset)
+}
+
+// Key identifies the reminder of an occurrence, so that it is delivered once.
+// Moving the occurrence or changing the offset makes a new reminder.
+func (r *Reminder) Key() ReminderKey {
+	return ReminderKey{
+		EventID: r.Event.ID,
+		Start:   r.Event.Start,
+		Offset:  r.Offset,
+	}.Normalize()
+}
+
+// ReminderKey identifies a delivered reminder.
+type ReminderKey struct {
+	EventID uuid.UUID     `db:"event_id"`
+	Start   time.Time     `db:"start_at"`
+	Offset  time.Duration `db:"offset_ns"`
+}
+
+// Normalize returns the key with the start in UTC at the microsecond precision of databases,
+// so that keys read back from a database are equal to the computed ones.
+func (k ReminderKey) Normalize() ReminderKey {
+	k.Start = k.Start.UTC().Truncate(time.Microsecond)
+	return k
+}
+
+// DueReminders returns the reminders of the occurrences of the event firing within (from, to].
+func (e *Event) DueReminders(from time.Time, to time.Time) ([]Reminder, error) {
+	if len(e.Reminders) == 0 {
+		return nil, nil
+	}
+
+	occurrences, err := e.Occurrences(from, to.Add(MaxReminderOffset))
+	if err != nil {
+		return nil, err
+	}
+
+	var reminders []Reminder
+	for _, occurrence := range occurrences {
+		for _, offset := range e.Reminders {
+			reminder := Reminder{Event: occurrence, Offset: offset}
+			if fireAt := reminder.FireAt(); fireAt.After(from) && !fireAt.After(to) {
+				reminders = append(reminders, reminder)
+			}
+		}
+	}
+
+	return reminders, nil
+}
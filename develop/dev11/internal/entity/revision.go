@@ -0,0 +1,133 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Revision is an entry of the audit history of an event: who changed it, when, within which request and how.
+// IDs grow with every revision of any event.
+type Revision struct {
+	ID         int64      `json:"id"`
+	Kind       ChangeKind `json:"kind"`
+	EventID    uuid.UUID  `json:"event_id"`
+	CalendarID uuid.UUID  `json:"calendar_id"`
+	// ActorID is the user who made the change.
+	ActorID uuid.UUID `json:"actor_id"`
+	// RequestID is the ID of the API request which made the change, if it is known.
+	RequestID string `json:"request_id,omitempty"`
+	// Version is the version of the event after the change, the deleted version for a deletion.
+	Version int64 `json:"version"`
+	// Before is the event before the change, nil for a created event.
+	Before *Event `json:"before"`
+	// After is the event after the change, nil for a deleted event.
+	After *Event `json:"after"`
+	// Diff maps the JSON fields which changed to their values before and after the change.
+	Diff      Diff      `json:"diff"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// Diff maps the changed JSON fields of an event to their values, null for a missing value.
+type Diff map[string]FieldDiff
+
+// FieldDiff is a changed field of an event.
+type FieldDiff struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// NewRevision returns the revision of the change of the event made by the actor. Before and after are copied,
+// so the events may be changed afterwards.
+func NewRevision(
+	kind ChangeKind,
+	before *Event,
+	after *Event,
+	actorID uuid.UUID,
+	requestID string,
+	changedAt time.Time,
+) (*Revision, error) {
+	event := after
+	if event == nil {
+		event = before
+	}
+	if event == nil {
+		return nil, fmt.Errorf("revision without event")
+	}
+
+	revision := &Revision{
+		Kind:       kind,
+		EventID:    event.ID,
+		CalendarID: event.CalendarID,
+		ActorID:    actorID,
+		RequestID:  requestID,
+		Version:    event.Version,
+		ChangedAt:  changedAt.UTC(),
+	}
+
+	var err error
+	var beforeFields, afterFields map[string]json.RawMessage
+	revision.Before, beforeFields, err = snapshot(before)
+	if err != nil {
+		return nil, err
+	}
+	revision.After, afterFields, err = snapshot(after)
+	if err != nil {
+		return nil, err
+	}
+	revision.Diff = newDiff(beforeFields, afterFields)
+
+	return revision, nil
+}
+
+// snapshot returns a deep copy of the event and its JSON fields.
+func snapshot(event *Event) (*Event, map[string]json.RawMessage, error) {
+	if event == nil {
+		return nil, nil, nil
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't marshal event: %v", err)
+	}
+	copied, err := UnmarshalEvent(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't unmarshal event: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't unmarshal event: %v", err)
+	}
+
+	return copied, fields, nil
+}
+
+// newDiff returns the fields whose values differ between before and after.
+// The version changes with every change, so it isn't a part of the diff.
+func newDiff(before map[string]json.RawMessage, after map[string]json.RawMessage) Diff {
+	diff := Diff{}
+	for name, value := range before {
+		if !bytes.Equal(value, after[name]) {
+			diff[name] = FieldDiff{Before: value, After: orNull(after[name])}
+		}
+	}
+	for name, value := range after {
+		if _, ok := before[name]; !ok {
+			diff[name] = FieldDiff{Before: orNull(nil), After: value}
+		}
+	}
+	delete(diff, "version")
+
+	return diff
+}
+
+func orNull(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return value
+}
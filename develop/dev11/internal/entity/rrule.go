@@ -0,0 +1,365 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the FREQ part of a recurrence rule.
+type Frequency string
+
+const (
+	FreqDaily   Frequency = "DAILY"
+	FreqWeekly  Frequency = "WEEKLY"
+	FreqMonthly Frequency = "MONTHLY"
+	FreqYearly  Frequency = "YEARLY"
+)
+
+// maxRecurrencePeriods limits how many periods are walked while expanding a rule,
+// so that a rule without COUNT and UNTIL can't loop forever.
+const maxRecurrencePeriods = 100000
+
+const dateLayout = "2006-01-02"
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// RRule is a subset of the RFC 5545 recurrence rule:
+// FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL and BYDAY without ordinals.
+type RRule struct {
+	Freq     Frequency
+	Interval int
+	Count    int
+	Until    time.Time
+	ByDay    []time.Weekday
+}
+
+// ParseRRule parses a recurrence rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE".
+// An optional "RRULE:" prefix is accepted. A floating or date-only UNTIL is taken in loc,
+// the time zone of the event.
+func ParseRRule(s string, loc *time.Location) (*RRule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("empty rrule")
+	}
+
+	r := &RRule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rrule part %q", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			freq := Frequency(strings.ToUpper(value))
+			switch freq {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				r.Freq = freq
+			default:
+				return nil, fmt.Errorf("unsupported rrule frequency %q", value)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("invalid rrule interval %q", value)
+			}
+			r.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid rrule count %q", value)
+			}
+			r.Count = count
+		case "UNTIL":
+			until, err := parseRRuleTime(value, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rrule until %q: %w", value, err)
+			}
+			r.Until = until
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[strings.ToUpper(code)]
+				if !ok {
+					return nil, fmt.Errorf("unsupported rrule byday %q", code)
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "WKST":
+			// Weeks always start on Monday.
+		default:
+			return nil, fmt.Errorf("unsupported rrule part %q", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("rrule without freq")
+	}
+	if r.Count != 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("rrule can't have both count and until")
+	}
+
+	return r, nil
+}
+
+// String formats the rule back into its RFC 5545 representation.
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			for code, weekday := range weekdayCodes {
+				if weekday == day {
+					codes = append(codes, code)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences returns the start times of the occurrences of a series starting at start
+// that fall into [from, to). Occurrences whose date is listed in exdates are skipped,
+// but still count towards COUNT as RFC 5545 requires.
+func (r *RRule) Occurrences(start time.Time, from time.Time, to time.Time, exdates Dates) []time.Time {
+	var result []time.Time
+	emitted := 0
+
+	for period := 0; period < maxRecurrencePeriods; period++ {
+		candidates := r.periodCandidates(start, period*r.Interval)
+		if len(candidates) == 0 && r.periodStart(start, period*r.Interval).After(to) {
+			break
+		}
+
+		for _, candidate := range candidates {
+			if candidate.Before(start) {
+				continue
+			}
+			if !r.Until.IsZero() && candidate.After(r.Until) {
+				return result
+			}
+			if !candidate.Before(to) {
+				return result
+			}
+
+			emitted++
+			if !candidate.Before(from) && !exdates.Contains(candidate) {
+				result = append(result, candidate)
+			}
+			if r.Count > 0 && emitted >= r.Count {
+				return result
+			}
+		}
+	}
+
+	return result
+}
+
+// periodStart returns the first day of the period shifted by offset units of the rule frequency.
+func (r *RRule) periodStart(start time.Time, offset int) time.Time {
+	switch r.Freq {
+	case FreqDaily:
+		return start.AddDate(0, 0, offset)
+	case FreqWeekly:
+		weekday := (int(start.Weekday()) + 6) % 7
+		return start.AddDate(0, 0, 7*offset-weekday)
+	case FreqMonthly:
+		return time.Date(start.Year(), start.Month()+time.Month(offset), 1,
+			start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	default:
+		return time.Date(start.Year()+offset, time.January, 1,
+			start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	}
+}
+
+// periodCandidates returns the ordered occurrence candidates of a single period.
+func (r *RRule) periodCandidates(start time.Time, offset int) []time.Time {
+	if len(r.ByDay) == 0 {
+		var candidate time.Time
+		switch r.Freq {
+		case FreqDaily:
+			candidate = start.AddDate(0, 0, offset)
+		case FreqWeekly:
+			candidate = start.AddDate(0, 0, 7*offset)
+		case FreqMonthly:
+			candidate = time.Date(start.Year(), start.Month()+time.Month(offset), start.Day(),
+				start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+			if candidate.Day() != start.Day() {
+				// The month is too short, RFC 5545 says such occurrences are skipped.
+				return nil
+			}
+		default:
+			candidate = time.Date(start.Year()+offset, start.Month(), start.Day(),
+				start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+			if candidate.Day() != start.Day() {
+				return nil
+			}
+		}
+		return []time.Time{candidate}
+	}
+
+	periodStart := r.periodStart(start, offset)
+	var periodEnd time.Time
+	switch r.Freq {
+	case FreqDaily:
+		periodEnd = periodStart.AddDate(0, 0, 1)
+	case FreqWeekly:
+		periodEnd = periodStart.AddDate(0, 0, 7)
+	case FreqMonthly:
+		periodEnd = periodStart.AddDate(0, 1, 0)
+	default:
+		periodEnd = periodStart.AddDate(1, 0, 0)
+	}
+
+	var candidates []time.Time
+	for day := periodStart; day.Before(periodEnd); day = day.AddDate(0, 0, 1) {
+		for _, weekday := range r.ByDay {
+			if day.Weekday() == weekday {
+				candidates = append(candidates, day)
+				break
+			}
+		}
+	}
+	return candidates
+}
+
+// parseRRuleTime parses an UNTIL value in either date or date-time form.
+// Values without the UTC designator are local times of loc.
+func parseRRuleTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"20060102T150405", "20060102", dateLayout} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			if layout == "20060102" || layout == dateLayout {
+				// A date-only UNTIL includes the whole day.
+				t = t.Add(24*time.Hour - time.Nanosecond)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time format")
+}
+
+// Dates is a list of calendar dates, stored in the database as a comma-separated string.
+type Dates []time.Time
+
+// ParseDates parses a comma-separated list of dates in 2006-01-02 format.
+func ParseDates(s string) (Dates, error) {
+	dates := Dates{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		date, err := time.Parse(dateLayout, part)
+		if err != nil {
+			return nil, err
+		}
+		dates = append(dates, date)
+	}
+	return dates, nil
+}
+
+// Contains reports whether the list contains the calendar date of t.
+func (d Dates) Contains(t time.Time) bool {
+	for _, date := range d {
+		if date.Format(dateLayout) == t.Format(dateLayout) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add appends the date of t to the list unless it is already there.
+func (d *Dates) Add(t time.Time) {
+	if d.Contains(t) {
+		return
+	}
+	*d = append(*d, time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC))
+	sort.Slice(*d, func(i, j int) bool { return (*d)[i].Before((*d)[j]) })
+}
+
+// String formats the list as comma-separated dates.
+func (d Dates) String() string {
+	parts := make([]string, 0, len(d))
+	for _, date := range d {
+		parts = append(parts, date.Format(dateLayout))
+	}
+	return strings.Join(parts, ",")
+}
+
+// MarshalJSON encodes the list as an array of 2006-01-02 strings.
+func (d Dates) MarshalJSON() ([]byte, error) {
+	parts := make([]string, 0, len(d))
+	for _, date := range d {
+		parts = append(parts, date.Format(dateLayout))
+	}
+	return json.Marshal(parts)
+}
+
+// UnmarshalJSON decodes an array of 2006-01-02 strings.
+func (d *Dates) UnmarshalJSON(data []byte) error {
+	var parts []string
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+
+	dates, err := ParseDates(strings.Join(parts, ","))
+	if err != nil {
+		return err
+	}
+	*d = dates
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (d Dates) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan implements sql.Scanner.
+func (d *Dates) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("can't scan %T into dates", src)
+	}
+
+	dates, err := ParseDates(s)
+	if err != nil {
+		return err
+	}
+	*d = dates
+	return nil
+}
@@ -0,0 +1,148 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestRRuleOccurrences(t *testing.T) {
+	tests := []struct {
+		name     string
+		rrule    string
+		start    string
+		from     string
+		to       string
+		exdates  string
+		expected []string
+	}{
+		{
+			name:     "daily with count",
+			rrule:    "FREQ=DAILY;COUNT=3",
+			start:    "2024-01-01",
+			from:     "2024-01-01",
+			to:       "2024-02-01",
+			expected: []string{"2024-01-01", "2024-01-02", "2024-01-03"},
+		},
+		{
+			name:     "weekly in window",
+			rrule:    "FREQ=WEEKLY",
+			start:    "2024-01-01",
+			from:     "2024-01-10",
+			to:       "2024-01-23",
+			expected: []string{"2024-01-15", "2024-01-22"},
+		},
+		{
+			name:     "weekly byday with interval",
+			rrule:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE",
+			start:    "2024-01-03",
+			from:     "2024-01-01",
+			to:       "2024-01-31",
+			expected: []string{"2024-01-03", "2024-01-15", "2024-01-17", "2024-01-29"},
+		},
+		{
+			name:     "monthly skips short months",
+			rrule:    "FREQ=MONTHLY;UNTIL=20240601",
+			start:    "2024-01-31",
+			from:     "2024-01-01",
+			to:       "2025-01-01",
+			expected: []string{"2024-01-31", "2024-03-31", "2024-05-31"},
+		},
+		{
+			name:     "yearly",
+			rrule:    "FREQ=YEARLY;COUNT=2",
+			start:    "2024-03-08",
+			from:     "2020-01-01",
+			to:       "2030-01-01",
+			expected: []string{"2024-03-08", "2025-03-08"},
+		},
+		{
+			name:     "exdate counts towards count",
+			rrule:    "FREQ=DAILY;COUNT=3",
+			start:    "2024-01-01",
+			from:     "2024-01-01",
+			to:       "2024-02-01",
+			exdates:  "2024-01-02",
+			expected: []string{"2024-01-01", "2024-01-03"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rrule, time.UTC)
+			if err != nil {
+				t.Fatalf("ParseRRule() error = %v", err)
+			}
+			exdates, err := ParseDates(tt.exdates)
+			if err != nil {
+				t.Fatalf("ParseDates() error = %v", err)
+			}
+
+			occurrences := rule.Occurrences(date(tt.start), date(tt.from), date(tt.to), exdates)
+			if len(occurrences) != len(tt.expected) {
+				t.Fatalf("Occurrences() = %v, expected %v", occurrences, tt.expected)
+			}
+			for i, occurrence := range occurrences {
+				if occurrence.Format(dateLayout) != tt.expected[i] {
+					t.Errorf("occurrence %d = %s, expected %s", i, occurrence.Format(dateLayout), tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRRuleLocalUntil(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+
+	// 20:00 in New York is already the next day in UTC, a local UNTIL still includes it.
+	start := time.Date(2024, time.January, 1, 20, 0, 0, 0, loc)
+	for _, rrule := range []string{
+		"FREQ=DAILY;UNTIL=20240103",
+		"FREQ=DAILY;UNTIL=20240103T200000",
+		"FREQ=DAILY;UNTIL=20240104T010000Z",
+	} {
+		rule, err := ParseRRule(rrule, loc)
+		if err != nil {
+			t.Fatalf("ParseRRule(%q) error = %v", rrule, err)
+		}
+		occurrences := rule.Occurrences(start, start, start.AddDate(0, 0, 7), nil)
+		if len(occurrences) != 3 || !occurrences[2].Equal(start.AddDate(0, 0, 2)) {
+			t.Errorf("Occurrences() of %q = %v, expected 3 until 2024-01-03 20:00 local", rrule, occurrences)
+		}
+	}
+}
+
+func TestParseRRuleErrors(t *testing.T) {
+	for _, rrule := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=DAILY;COUNT=2;UNTIL=20240101",
+	} {
+		if _, err := ParseRRule(rrule, time.UTC); err == nil {
+			t.Errorf("ParseRRule(%q) expected error", rrule)
+		}
+	}
+}
+
+func TestRRuleString(t *testing.T) {
+	rule, err := ParseRRule("RRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=5;BYDAY=MO,FR", time.UTC)
+	if err != nil {
+		t.Fatalf("ParseRRule() error = %v", err)
+	}
+	if s := rule.String(); s != "FREQ=WEEKLY;INTERVAL=2;COUNT=5;BYDAY=MO,FR" {
+		t.Errorf("String() = %s", s)
+	}
+}
@@ -0,0 +1,270 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// MaxSearchWindow limits the interval searched for events, since series are expanded within it.
+const MaxSearchWindow = 366 * 24 * time.Hour
+
+// Page sizes of a search.
+const (
+	DefaultSearchLimit = 50
+	MaxSearchLimit     = 200
+)
+
+// SearchSort is the order of found events. A leading minus sorts in descending order.
+type SearchSort string
+
+const (
+	SortStart     SearchSort = "start"
+	SortStartDesc SearchSort = "-start"
+	SortTitle     SearchSort = "title"
+	SortTitleDesc SearchSort = "-title"
+)
+
+// EventQuery is a search for the occurrences of the events of calendars overlapping [From, To).
+type EventQuery struct {
+	// CalendarIDs are the calendars to search, every calendar visible to the caller if empty.
+	CalendarIDs []uuid.UUID
+	From        time.Time
+	To          time.Time
+	// Title is a case-insensitive substring of the title.
+	Title string
+	// Text is a full-text query: every word of it must be a word of the title.
+	Text string
+	// Tags are the tags every found event must carry.
+	Tags  []string
+	Sort  SearchSort
+	Limit int
+	// After is the position of the last event of the previous page.
+	After *Cursor
+}
+
+// Cursor is a position in the results of a search, the sort key of the last returned event.
+// Occurrences of a series share the ID, so the start is a part of the key for every sort.
+type Cursor struct {
+	Sort  SearchSort `json:"s"`
+	Title string     `json:"n,omitempty"`
+	Start time.Time  `json:"t"`
+	ID    uuid.UUID  `json:"i"`
+}
+
+// EventPage is a page of search results. NextCursor is empty on the last page.
+type EventPage struct {
+	Events     Events `json:"events"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ParseEventQuery parses the query parameters of a search:
+// calendar_id and tag, which may be repeated, from, to, tz, title, q, sort, limit and cursor.
+func ParseEventQuery(values url.Values) (*EventQuery, error) {
+	var calendarIDs []uuid.UUID
+	for _, value := range values["calendar_id"] {
+		calendarID, err := uuid.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid calendar_id: %w", err)
+		}
+		calendarIDs = append(calendarIDs, calendarID)
+	}
+
+	tags, err := ParseTagFilter(values)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := time.LoadLocation(values.Get("tz"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid tz: %w", err)
+	}
+	from, err := ParseLocalTime(values.Get("from"), loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from: %w", err)
+	}
+	to, err := ParseLocalTime(values.Get("to"), loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to: %w", err)
+	}
+
+	query := &EventQuery{
+		CalendarIDs: calendarIDs,
+		From:        from,
+		To:          to,
+		Title:       strings.TrimSpace(values.Get("title")),
+		Text:        strings.TrimSpace(values.Get("q")),
+		Tags:        tags,
+		Sort:        SortStart,
+		Limit:       DefaultSearchLimit,
+	}
+	if values.Get("sort") != "" {
+		query.Sort = SearchSort(values.Get("sort"))
+	}
+	if values.Get("limit") != "" {
+		query.Limit, err = strconv.Atoi(values.Get("limit"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit: %w", err)
+		}
+	}
+	if values.Get("cursor") != "" {
+		query.After, err = ParseCursor(values.Get("cursor"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = query.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+// Validate checks the window, the sort, the limit and that the cursor belongs to the same sort.
+func (q *EventQuery) Validate() error {
+	switch {
+	case !q.To.After(q.From):
+		return fmt.Errorf("to must be after from")
+	case q.To.Sub(q.From) > MaxSearchWindow:
+		return fmt.Errorf("window must not exceed %d days", MaxSearchWindow/(24*time.Hour))
+	case q.Limit < 1 || q.Limit > MaxSearchLimit:
+		return fmt.Errorf("limit must be between 1 and %d", MaxSearchLimit)
+	}
+
+	switch q.Sort {
+	case SortStart, SortStartDesc, SortTitle, SortTitleDesc:
+	default:
+		return fmt.Errorf("unknown sort %q", q.Sort)
+	}
+	if q.After != nil && q.After.Sort != q.Sort {
+		return fmt.Errorf("cursor belongs to sort %q", q.After.Sort)
+	}
+
+	return nil
+}
+
+// ParseTagFilter parses the tag query parameters filtering events, which may be repeated.
+// An event matches the filter if it carries every one of the tags.
+func ParseTagFilter(values url.Values) ([]string, error) {
+	var tags []string
+	for _, value := range values["tag"] {
+		tag, err := ParseTag(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// Matches reports whether the title of the event matches the title and text filters of the query
+// and the event carries the tags of the query.
+func (q *EventQuery) Matches(event *Event) bool {
+	if !event.Tags.HasAll(q.Tags) {
+		return false
+	}
+
+	title := strings.ToLower(event.Title)
+	if q.Title != "" && !strings.Contains(title, strings.ToLower(q.Title)) {
+		return false
+	}
+
+	if q.Text != "" {
+		words := map[string]bool{}
+		for _, word := range splitWords(title) {
+			words[word] = true
+		}
+		for _, word := range splitWords(strings.ToLower(q.Text)) {
+			if !words[word] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Page sorts the found occurrences and returns those following the cursor, at most Limit of them.
+func (q *EventQuery) Page(events Events) *EventPage {
+	page := &EventPage{Events: Events{}}
+	for _, event := range events {
+		if q.After == nil || q.compare(q.cursor(&event), *q.After) > 0 {
+			page.Events = append(page.Events, event)
+		}
+	}
+	sort.Slice(page.Events, func(i, j int) bool {
+		return q.compare(q.cursor(&page.Events[i]), q.cursor(&page.Events[j])) < 0
+	})
+
+	if len(page.Events) > q.Limit {
+		page.Events = page.Events[:q.Limit]
+		page.NextCursor = q.cursor(&page.Events[q.Limit-1]).String()
+	}
+
+	return page
+}
+
+// cursor returns the position of the event in the results.
+func (q *EventQuery) cursor(event *Event) Cursor {
+	c := Cursor{Sort: q.Sort, Start: event.Start, ID: event.ID}
+	if q.Sort == SortTitle || q.Sort == SortTitleDesc {
+		c.Title = event.Title
+	}
+	return c
+}
+
+// compare orders two positions in the sort of the query.
+func (q *EventQuery) compare(a Cursor, b Cursor) int {
+	result := strings.Compare(a.Title, b.Title)
+	if result == 0 {
+		result = a.Start.Compare(b.Start)
+	}
+	if result == 0 {
+		result = bytes.Compare(a.ID[:], b.ID[:])
+	}
+
+	if strings.HasPrefix(string(q.Sort), "-") {
+		return -result
+	}
+	return result
+}
+
+// String encodes the cursor as an opaque URL-safe token.
+func (c Cursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor decodes a token returned as the next cursor of a page.
+func ParseCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	var c Cursor
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	return &c, nil
+}
+
+// splitWords splits text into words of letters and digits.
+func splitWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,126 @@
+package entity
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestEventQueryPages(t *testing.T) {
+	at := func(day int) time.Time {
+		return time.Date(2024, time.March, day, 10, 0, 0, 0, time.UTC)
+	}
+	// Occurrences of a series share the ID.
+	seriesID := uuid.New()
+	events := Events{
+		{ID: uuid.New(), Title: "b", Start: at(3)},
+		{ID: seriesID, Title: "a", Start: at(2)},
+		{ID: seriesID, Title: "a", Start: at(4)},
+		{ID: uuid.New(), Title: "c", Start: at(1)},
+		{ID: uuid.New(), Title: "a", Start: at(5)},
+	}
+
+	tests := []struct {
+		sort     SearchSort
+		expected []time.Time
+	}{
+		{SortStart, []time.Time{at(1), at(2), at(3), at(4), at(5)}},
+		{SortStartDesc, []time.Time{at(5), at(4), at(3), at(2), at(1)}},
+		{SortTitle, []time.Time{at(2), at(4), at(5), at(3), at(1)}},
+		{SortTitleDesc, []time.Time{at(1), at(3), at(5), at(4), at(2)}},
+	}
+	for _, tt := range tests {
+		query := &EventQuery{Sort: tt.sort, Limit: 2}
+		var found []time.Time
+		for pages := 0; ; pages++ {
+			if pages > len(events) {
+				t.Fatalf("%s: pagination doesn't end", tt.sort)
+			}
+			page := query.Page(events)
+			for _, event := range page.Events {
+				found = append(found, event.Start)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor, err := ParseCursor(page.NextCursor)
+			if err != nil {
+				t.Fatalf("%s: ParseCursor() error = %v", tt.sort, err)
+			}
+			query.After = cursor
+		}
+
+		if len(found) != len(tt.expected) {
+			t.Errorf("%s: found %v, expected %v", tt.sort, found, tt.expected)
+			continue
+		}
+		for i := range found {
+			if !found[i].Equal(tt.expected[i]) {
+				t.Errorf("%s: found %v, expected %v", tt.sort, found, tt.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestEventQueryMatches(t *testing.T) {
+	event := &Event{Title: "Планирование спринта, Q2"}
+
+	tests := []struct {
+		title    string
+		text     string
+		expected bool
+	}{
+		{"", "", true},
+		{"план", "", true},
+		{"СПРИНТ", "", true},
+		{"review", "", false},
+		{"", "спринта q2", true},
+		{"", "план", false},
+		{"план", "q2", true},
+	}
+	for _, tt := range tests {
+		query := &EventQuery{Title: tt.title, Text: tt.text}
+		if got := query.Matches(event); got != tt.expected {
+			t.Errorf("Matches(title=%q, q=%q) = %v, expected %v", tt.title, tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestParseEventQuery(t *testing.T) {
+	values := url.Values{
+		"calendar_id": {uuid.New().String()},
+		"from":        {"2024-03-01"},
+		"to":          {"2024-04-01"},
+		"tz":          {"Europe/Moscow"},
+		"tag":         {"On-Call", "ops"},
+	}
+	query, err := ParseEventQuery(values)
+	if err != nil {
+		t.Fatalf("ParseEventQuery() error = %v", err)
+	}
+	if query.Sort != SortStart || query.Limit != DefaultSearchLimit || query.From.Format(time.RFC3339) != "2024-03-01T00:00:00+03:00" ||
+		len(query.Tags) != 2 || query.Tags[0] != "on-call" {
+		t.Errorf("ParseEventQuery() = %+v", query)
+	}
+
+	invalid := map[string]string{
+		"to":     "2024-02-01",
+		"sort":   "end",
+		"limit":  "1000",
+		"cursor": Cursor{Sort: SortTitle}.String(),
+		"tag":    "on call",
+	}
+	for key, value := range invalid {
+		broken := url.Values{}
+		for k, v := range values {
+			broken[k] = v
+		}
+		broken.Set(key, value)
+		if _, err := ParseEventQuery(broken); err == nil {
+			t.Errorf("ParseEventQuery() with %s=%s error = nil", key, value)
+		}
+	}
+}
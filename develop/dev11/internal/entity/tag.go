@@ -0,0 +1,109 @@
+package entity
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Limits of the tags of an event.
+const (
+	MaxTags      = 20
+	MaxTagLength = 32
+)
+
+// tagPattern is the form of a stored tag: lowercase letters, digits, dashes and underscores.
+var tagPattern = regexp.MustCompile(`^[\p{Ll}\p{Lo}\p{N}_-]+$`)
+
+// Tags are the labels of an event, like on-call or meeting, shared by the events of every user.
+// Stored tags are lowercase, unique and sorted.
+type Tags []string
+
+// ParseTags parses a comma-separated list of tags.
+func ParseTags(s string) (Tags, error) {
+	tags := Tags{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			tags = append(tags, part)
+		}
+	}
+
+	return tags, tags.normalize()
+}
+
+// ParseTag parses a single tag of a filter.
+func ParseTag(s string) (string, error) {
+	tags := Tags{strings.TrimSpace(s)}
+	if err := tags.normalize(); err != nil {
+		return "", err
+	}
+	return tags[0], nil
+}
+
+// normalize checks the tags, brings them to lowercase and sorts them. Duplicates are dropped.
+func (t *Tags) normalize() error {
+	if len(*t) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(*t))
+	tags := make(Tags, 0, len(*t))
+	for _, tag := range *t {
+		tag = strings.ToLower(tag)
+		switch {
+		case len([]rune(tag)) > MaxTagLength:
+			return fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+		case !tagPattern.MatchString(tag):
+			return fmt.Errorf("invalid tag %q: only letters, digits, dashes and underscores are allowed", tag)
+		case seen[tag]:
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > MaxTags {
+		return fmt.Errorf("more than %d tags", MaxTags)
+	}
+	sort.Strings(tags)
+	*t = tags
+
+	return nil
+}
+
+// Validate checks that every tag is valid and that there aren't too many of them.
+func (t Tags) Validate() error {
+	clone := append(Tags(nil), t...)
+	return clone.normalize()
+}
+
+// HasAll reports whether every one of the tags is among the tags.
+func (t Tags) HasAll(tags []string) bool {
+	for _, tag := range tags {
+		if !t.Has(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// Has reports whether the tag is among the tags.
+func (t Tags) Has(tag string) bool {
+	for _, name := range t {
+		if name == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the tags as a comma-separated list.
+func (t Tags) String() string {
+	return strings.Join(t, ",")
+}
+
+// TagCount is a tag of the events of a user with the number of live events carrying it.
+type TagCount struct {
+	Name  string `json:"name" db:"name"`
+	Count int    `json:"count" db:"count"`
+}
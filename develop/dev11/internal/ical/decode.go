@@ -0,0 +1,398 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"L2/develop/dev11/internal/entity"
+
+	"github.com/google/uuid"
+)
+
+// uidNamespace is the namespace of event IDs derived from UIDs that aren't UUIDs.
+var uidNamespace = uuid.MustParse("5b7c0f0e-3a5e-4f4c-9d6e-2f0d7a1c8b93")
+
+// Item is a single VEVENT of a decoded calendar.
+// Either Event or Err is set.
+type Item struct {
+	UID   string
+	Event *entity.Event
+	Err   error
+}
+
+// property is a parsed content line.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Decode parses the VEVENTs of a VCALENDAR. Errors in a single VEVENT are reported in its Item,
+// the returned error means that the calendar itself can't be read.
+// The UserID of the decoded events is left empty.
+func Decode(r io.Reader) ([]Item, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, fmt.Errorf("can't read calendar: %w", err)
+	}
+
+	var (
+		items      []Item
+		inCalendar bool
+		component  []string
+		vevent     []property
+		inEvent    bool
+	)
+
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+		prop, err := parseProperty(line)
+		if err != nil {
+			if inEvent {
+				vevent = append(vevent, property{name: "X-INVALID", value: fmt.Sprintf("line %d: %v", n+1, err)})
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+
+		switch prop.name {
+		case "BEGIN":
+			value := strings.ToUpper(prop.value)
+			if value == "VCALENDAR" {
+				inCalendar = true
+			} else if value == "VEVENT" && len(component) == 1 {
+				inEvent = true
+				vevent = nil
+			}
+			component = append(component, value)
+		case "END":
+			if len(component) == 0 || component[len(component)-1] != strings.ToUpper(prop.value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", n+1, prop.value)
+			}
+			component = component[:len(component)-1]
+			if inEvent && strings.ToUpper(prop.value) == "VEVENT" {
+				inEvent = false
+				items = append(items, decodeEvent(vevent))
+			}
+		default:
+			// Properties of nested components belong to them, not to the event,
+			// only the triggers of VALARMs are kept as reminders.
+			if inEvent && len(component) == 2 {
+				vevent = append(vevent, prop)
+			} else if inEvent && len(component) == 3 && component[2] == "VALARM" && prop.name == "TRIGGER" {
+				prop.name = "X-ALARM-TRIGGER"
+				vevent = append(vevent, prop)
+			}
+		}
+	}
+
+	if !inCalendar {
+		return nil, fmt.Errorf("no VCALENDAR found")
+	}
+	if len(component) != 0 {
+		return nil, fmt.Errorf("unterminated %s", component[len(component)-1])
+	}
+
+	return items, nil
+}
+
+// decodeEvent converts the properties of a VEVENT into an event.
+func decodeEvent(props []property) Item {
+	item := Item{}
+	event := &entity.Event{TimeZone: "UTC"}
+
+	var (
+		duration    time.Duration
+		hasDuration bool
+		hasEnd      bool
+		exdates     []property
+		rrule       *property
+		recurrence  *property
+		reminders   []string
+		categories  []string
+	)
+
+	for i := range props {
+		prop := props[i]
+		var err error
+
+		switch prop.name {
+		case "X-INVALID":
+			err = fmt.Errorf("%s", prop.value)
+		case "UID":
+			item.UID = unescapeText(prop.value)
+		case "SUMMARY":
+			event.Title = unescapeText(prop.value)
+		case "DESCRIPTION":
+			event.Description = unescapeText(prop.value)
+		case "LOCATION":
+			event.Place = strings.TrimSpace(unescapeText(prop.value))
+		case "CATEGORIES":
+			// Categories which can't be tags, like ones with spaces, are skipped like unsupported alarms.
+			for _, value := range strings.Split(prop.value, ",") {
+				if tag, err := entity.ParseTag(unescapeText(value)); err == nil {
+					categories = append(categories, tag)
+				}
+			}
+		case "DTSTART":
+			event.Start, event.AllDay, err = parseTime(prop)
+			if err == nil && prop.params["TZID"] != "" {
+				event.TimeZone = prop.params["TZID"]
+			}
+		case "DTEND":
+			event.End, _, err = parseTime(prop)
+			hasEnd = true
+		case "DURATION":
+			duration, err = parseDuration(prop.value)
+			hasDuration = true
+		case "RRULE":
+			rrule = &props[i]
+		case "EXDATE":
+			exdates = append(exdates, prop)
+		case "RECURRENCE-ID":
+			recurrence = &props[i]
+		case "X-ALARM-TRIGGER":
+			if offset, ok := alarmOffset(prop); ok {
+				reminders = append(reminders, entity.Offsets{offset}.String())
+			}
+		}
+
+		if err != nil {
+			item.Err = fmt.Errorf("%s: %w", strings.ToLower(prop.name), err)
+			return item
+		}
+	}
+
+	if item.UID == "" {
+		item.Err = fmt.Errorf("uid: missing")
+		return item
+	}
+	if event.Start.IsZero() {
+		item.Err = fmt.Errorf("dtstart: missing")
+		return item
+	}
+	if event.Title == "" {
+		event.Title = "(no title)"
+	}
+
+	switch {
+	case hasEnd:
+	case hasDuration:
+		event.End = event.Start.Add(duration)
+	case event.AllDay:
+		event.End = event.Start.AddDate(0, 0, 1)
+	}
+	if !event.End.After(event.Start) {
+		item.Err = fmt.Errorf("dtend: must be after dtstart")
+		return item
+	}
+
+	// A floating UNTIL is in the time zone of DTSTART, which may follow the rule.
+	if rrule != nil {
+		rule, err := entity.ParseRRule(rrule.value, event.Location())
+		if err != nil {
+			item.Err = fmt.Errorf("rrule: %w", err)
+			return item
+		}
+		event.RRule = rule.String()
+	}
+
+	event.ExDates = entity.Dates{}
+	for _, prop := range exdates {
+		for _, value := range strings.Split(prop.value, ",") {
+			date, _, err := parseTime(property{name: prop.name, params: prop.params, value: value})
+			if err != nil {
+				item.Err = fmt.Errorf("exdate: %w", err)
+				return item
+			}
+			event.ExDates.Add(date.In(event.Location()))
+		}
+	}
+
+	// Reminders are replaced as a whole, a VEVENT without alarms has none.
+	event.Reminders, _ = entity.ParseOffsets(strings.Join(reminders, ","))
+
+	// So are the tags, a VEVENT without categories has none.
+	tags, err := entity.ParseTags(strings.Join(categories, ","))
+	if err != nil {
+		item.Err = fmt.Errorf("categories: %w", err)
+		return item
+	}
+	event.Tags = tags
+
+	if recurrence != nil {
+		date, _, err := parseTime(*recurrence)
+		if err != nil {
+			item.Err = fmt.Errorf("recurrence-id: %w", err)
+			return item
+		}
+		date = date.In(event.Location())
+		recurrenceID := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		event.RecurrenceID = &recurrenceID
+	}
+
+	// UUID UIDs are ours, any other UID is kept to be exported as is.
+	event.ID = EventID(item.UID)
+	if event.ID.String() != item.UID {
+		event.UID = item.UID
+	}
+
+	item.Event = event
+	return item
+}
+
+// alarmOffset returns the reminder offset of a VALARM TRIGGER. Only triggers relative to the start
+// of the event firing before it, within entity.MaxReminderOffset, can be reminders.
+func alarmOffset(prop property) (time.Duration, bool) {
+	if strings.EqualFold(prop.params["RELATED"], "END") || strings.EqualFold(prop.params["VALUE"], "DATE-TIME") {
+		return 0, false
+	}
+	trigger, err := parseDuration(prop.value)
+	if err != nil || trigger > 0 || -trigger > entity.MaxReminderOffset {
+		return 0, false
+	}
+	return -trigger, true
+}
+
+// EventID returns the ID of the event with the given UID.
+// UUIDs are used as is, other UIDs are mapped to a name-based UUID.
+func EventID(uid string) uuid.UUID {
+	if id, err := uuid.Parse(uid); err == nil && id.String() == uid {
+		return id
+	}
+	return uuid.NewSHA1(uidNamespace, []byte(uid))
+}
+
+// unfold reads the content lines, joining folded continuation lines.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// parseProperty splits a content line into its name, parameters and value.
+func parseProperty(line string) (property, error) {
+	prop := property{params: map[string]string{}}
+
+	// The value starts after the first colon outside of a quoted parameter value.
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return prop, fmt.Errorf("invalid content line %q", line)
+	}
+	prop.value = line[colon+1:]
+
+	parts := strings.Split(line[:colon], ";")
+	prop.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return prop, fmt.Errorf("invalid parameter %q", param)
+		}
+		prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+
+	return prop, nil
+}
+
+// parseTime parses a DATE or DATE-TIME value, reporting whether it is a DATE.
+// Local times are interpreted in the TZID zone, floating times in UTC.
+func parseTime(prop property) (time.Time, bool, error) {
+	value := strings.TrimSpace(prop.value)
+
+	loc := time.UTC
+	if tzid := prop.params["TZID"]; tzid != "" {
+		var err error
+		loc, err = time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown time zone %q", tzid)
+		}
+	}
+
+	if prop.params["VALUE"] == "DATE" || len(value) == len(dateFormat) {
+		t, err := time.ParseInLocation(dateFormat, value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(utcFormat, value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation(dateTimeFormat, value, loc)
+	return t, false, err
+}
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration parses an RFC 5545 DURATION value such as PT1H30M or P1D.
+func parseDuration(value string) (time.Duration, error) {
+	match := durationPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil || value == "P" || value == "PT" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var duration time.Duration
+	for i, unit := range units {
+		if match[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(match[i+2])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		duration += time.Duration(n) * unit
+	}
+	if match[1] == "-" {
+		duration = -duration
+	}
+
+	return duration, nil
+}
+
+// unescapeText reverses escapeText.
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
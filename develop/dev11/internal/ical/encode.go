@@ -0,0 +1,209 @@
+// Package ical provides serialization of events to and from iCalendar (RFC 5545).
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"L2/develop/dev11/internal/entity"
+)
+
+const (
+	prodID = "-//L2//dev11 calendar//EN"
+
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405"
+	utcFormat      = "20060102T150405Z"
+
+	// maxLineLength is the maximum length of a content line in octets, excluding CRLF.
+	maxLineLength = 75
+)
+
+// Encode writes the events as a VCALENDAR with one VEVENT per event.
+// Overridden occurrences are written with the UID of their series and a RECURRENCE-ID.
+func Encode(w io.Writer, events entity.Events) error {
+	uids := make(map[string]string, len(events))
+	for _, event := range events {
+		uids[event.ID.String()] = UID(&event)
+	}
+
+	bw := bufio.NewWriter(w)
+	lw := &lineWriter{w: bw}
+
+	lw.write("BEGIN", "VCALENDAR")
+	lw.write("VERSION", "2.0")
+	lw.write("PRODID", prodID)
+	lw.write("CALSCALE", "GREGORIAN")
+
+	stamp := time.Now().UTC().Format(utcFormat)
+	for _, event := range events {
+		uid := UID(&event)
+		if event.SeriesID != nil {
+			if seriesUID, ok := uids[event.SeriesID.String()]; ok {
+				uid = seriesUID
+			} else {
+				uid = event.SeriesID.String()
+			}
+		}
+
+		lw.writeEvent(&event, uid, stamp, event.Attendees)
+	}
+
+	lw.write("END", "VCALENDAR")
+	if lw.err != nil {
+		return fmt.Errorf("can't write calendar: %w", lw.err)
+	}
+
+	return bw.Flush()
+}
+
+// writeEvent writes the event as a VEVENT with the attendees, the organizer is written when there are any.
+func (lw *lineWriter) writeEvent(event *entity.Event, uid string, stamp string, attendees entity.Attendees) {
+	lw.write("BEGIN", "VEVENT")
+	lw.write("UID", escapeText(uid))
+	lw.write("DTSTAMP", stamp)
+	lw.write("SUMMARY", escapeText(event.Title))
+	if event.Description != "" {
+		lw.write("DESCRIPTION", escapeText(event.Description))
+	}
+	if event.Place != "" {
+		lw.write("LOCATION", escapeText(event.Place))
+	}
+	if len(event.Tags) > 0 {
+		lw.write("CATEGORIES", event.Tags.String())
+	}
+	lw.writeTime("DTSTART", event, event.Start)
+	lw.writeTime("DTEND", event, event.End)
+	if event.RRule != "" {
+		lw.write("RRULE", event.RRule)
+	}
+	for _, exdate := range event.ExDates {
+		lw.writeTime("EXDATE", event, occurrenceStart(event, exdate))
+	}
+	if event.RecurrenceID != nil {
+		lw.writeTime("RECURRENCE-ID", event, occurrenceStart(event, *event.RecurrenceID))
+	}
+	if len(attendees) > 0 {
+		lw.write("ORGANIZER", userAddress(event.UserID))
+	}
+	for i := range attendees {
+		lw.writeAttendee(&attendees[i])
+	}
+	for _, offset := range event.Reminders {
+		lw.write("BEGIN", "VALARM")
+		lw.write("ACTION", "DISPLAY")
+		lw.write("DESCRIPTION", escapeText(event.Title))
+		lw.write("TRIGGER", formatTrigger(offset))
+		lw.write("END", "VALARM")
+	}
+	lw.write("END", "VEVENT")
+}
+
+// UID returns the iCalendar UID of the event: the imported UID if there is one, the event ID otherwise.
+func UID(event *entity.Event) string {
+	if event.UID != "" {
+		return event.UID
+	}
+	return event.ID.String()
+}
+
+// occurrenceStart returns the start of the occurrence of the event on the given date,
+// which is how EXDATE and RECURRENCE-ID identify occurrences.
+func occurrenceStart(event *entity.Event, date time.Time) time.Time {
+	start := event.Start.In(event.Location())
+	return time.Date(date.Year(), date.Month(), date.Day(),
+		start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+}
+
+// formatTrigger formats a reminder offset as a VALARM TRIGGER before the start, like -PT15M or -P1DT12H.
+func formatTrigger(offset time.Duration) string {
+	if offset == 0 {
+		return "PT0S"
+	}
+
+	var b strings.Builder
+	b.WriteString("-P")
+	if days := offset / (24 * time.Hour); days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+		offset -= days * 24 * time.Hour
+	}
+	if offset > 0 {
+		b.WriteString("T")
+		for _, unit := range []struct {
+			d      time.Duration
+			suffix string
+		}{{time.Hour, "H"}, {time.Minute, "M"}, {time.Second, "S"}} {
+			if n := offset / unit.d; n > 0 {
+				fmt.Fprintf(&b, "%d%s", n, unit.suffix)
+				offset -= n * unit.d
+			}
+		}
+	}
+	return b.String()
+}
+
+// lineWriter writes folded content lines and keeps the first error.
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+// write writes a property without parameters.
+func (lw *lineWriter) write(name string, value string) {
+	lw.writeLine(name + ":" + value)
+}
+
+// writeLine writes a content line, folding it at maxLineLength octets.
+func (lw *lineWriter) writeLine(line string) {
+	if lw.err != nil {
+		return
+	}
+
+	var b strings.Builder
+	for len(line) > maxLineLength {
+		cut := maxLineLength
+		if b.Len() > 0 {
+			// Continuation lines start with a space that counts towards the limit.
+			cut--
+		}
+		// Don't split multi-byte UTF-8 sequences.
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+
+	_, lw.err = io.WriteString(lw.w, b.String())
+}
+
+// writeTime writes a date or date-time property in the form matching the event:
+// a DATE for all-day events, a UTC time for UTC events and a local time with TZID otherwise.
+func (lw *lineWriter) writeTime(name string, event *entity.Event, t time.Time) {
+	loc := event.Location()
+	switch {
+	case event.AllDay:
+		lw.writeLine(name + ";VALUE=DATE:" + t.In(loc).Format(dateFormat))
+	case loc == time.UTC:
+		lw.write(name, t.UTC().Format(utcFormat))
+	default:
+		lw.writeLine(name + ";TZID=" + loc.String() + ":" + t.In(loc).Format(dateTimeFormat))
+	}
+}
+
+// escapeText escapes a TEXT value.
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
@@ -0,0 +1,222 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"L2/develop/dev11/internal/entity"
+
+	"github.com/google/uuid"
+)
+
+func TestRoundTrip(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+
+	masterID := uuid.New()
+	recurrenceID := time.Date(2024, time.January, 8, 0, 0, 0, 0, time.UTC)
+	exdates, _ := entity.ParseDates("2024-01-15")
+	events := entity.Events{
+		{
+			ID:          masterID,
+			Title:       "stand-up; daily, short",
+			Start:       time.Date(2024, time.January, 1, 10, 0, 0, 0, loc),
+			End:         time.Date(2024, time.January, 1, 10, 15, 0, 0, loc),
+			TimeZone:    "Europe/Moscow",
+			RRule:       "FREQ=WEEKLY;BYDAY=MO",
+			ExDates:     exdates,
+			Reminders:   entity.Offsets{15 * time.Minute, 26*time.Hour + 30*time.Minute},
+			Description: "Agenda:\nblockers, plans; news",
+			Place:       "Room 4, floor 2",
+			Tags:        entity.Tags{"meeting", "team"},
+		},
+		{
+			ID:           uuid.New(),
+			Title:        "stand-up moved",
+			Start:        time.Date(2024, time.January, 8, 11, 0, 0, 0, loc),
+			End:          time.Date(2024, time.January, 8, 11, 15, 0, 0, loc),
+			TimeZone:     "Europe/Moscow",
+			SeriesID:     &masterID,
+			RecurrenceID: &recurrenceID,
+		},
+		{
+			ID:       uuid.New(),
+			UID:      "040000008200E00074C5B7101A82E008@outlook.com",
+			Title:    "holiday",
+			Start:    time.Date(2024, time.January, 7, 0, 0, 0, 0, time.UTC),
+			End:      time.Date(2024, time.January, 8, 0, 0, 0, 0, time.UTC),
+			AllDay:   true,
+			TimeZone: "UTC",
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, events); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("line isn't folded: %q", line)
+		}
+	}
+
+	items, err := Decode(buf)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(items) != len(events) {
+		t.Fatalf("Decode() = %d items, expected %d", len(items), len(events))
+	}
+
+	for i, item := range items {
+		if item.Err != nil {
+			t.Fatalf("item %d error = %v", i, item.Err)
+		}
+		expected := events[i]
+		got := item.Event
+
+		if got.Title != expected.Title {
+			t.Errorf("item %d title = %q, expected %q", i, got.Title, expected.Title)
+		}
+		if !got.Start.Equal(expected.Start) || !got.End.Equal(expected.End) {
+			t.Errorf("item %d = %s - %s, expected %s - %s", i, got.Start, got.End, expected.Start, expected.End)
+		}
+		if got.TimeZone != expected.TimeZone || got.AllDay != expected.AllDay {
+			t.Errorf("item %d zone = %s/%v, expected %s/%v", i, got.TimeZone, got.AllDay, expected.TimeZone, expected.AllDay)
+		}
+		if got.RRule != expected.RRule {
+			t.Errorf("item %d rrule = %q, expected %q", i, got.RRule, expected.RRule)
+		}
+		if got.ExDates.String() != expected.ExDates.String() {
+			t.Errorf("item %d exdate = %q, expected %q", i, got.ExDates, expected.ExDates)
+		}
+		if got.Reminders.String() != expected.Reminders.String() {
+			t.Errorf("item %d reminders = %q, expected %q", i, got.Reminders, expected.Reminders)
+		}
+		if got.Description != expected.Description || got.Place != expected.Place || got.Tags.String() != expected.Tags.String() {
+			t.Errorf("item %d details = %q/%q/%q, expected %q/%q/%q", i, got.Description, got.Place, got.Tags,
+				expected.Description, expected.Place, expected.Tags)
+		}
+	}
+
+	if items[0].Event.ID != masterID {
+		t.Errorf("series id = %s, expected %s", items[0].Event.ID, masterID)
+	}
+	if items[1].Event.ID != masterID || !items[1].Event.RecurrenceID.Equal(recurrenceID) {
+		t.Errorf("override = %s/%v, expected %s/%s", items[1].Event.ID, items[1].Event.RecurrenceID, masterID, recurrenceID)
+	}
+	if items[2].UID != events[2].UID || UID(items[2].Event) != events[2].UID {
+		t.Errorf("uid = %q, expected %q", items[2].UID, events[2].UID)
+	}
+}
+
+func TestDecodeReportsItemErrors(t *testing.T) {
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:ok@example.com",
+		"SUMMARY:ok",
+		"DTSTART:20240101T100000Z",
+		"DURATION:PT1H",
+		"BEGIN:VALARM",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:bad@example.com",
+		"DTSTART;TZID=Mars/Olympus:20240101T100000",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	items, err := Decode(strings.NewReader(calendar))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("Decode() = %d items, expected 2", len(items))
+	}
+	if items[0].Err != nil || items[0].Event.End.Sub(items[0].Event.Start) != time.Hour || items[0].Event.Reminders.String() != "15m" {
+		t.Errorf("first item = %+v", items[0])
+	}
+	if items[1].Err == nil || items[1].UID != "bad@example.com" {
+		t.Errorf("second item = %+v, expected error", items[1])
+	}
+
+	if _, err := Decode(strings.NewReader("BEGIN:VEVENT\r\nEND:VEVENT")); err == nil {
+		t.Error("Decode() without VCALENDAR expected error")
+	}
+}
+
+func TestDecodeLocalUntil(t *testing.T) {
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+
+	// The floating UNTIL is in the time zone of DTSTART even if the rule comes first.
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:local@example.com",
+		"RRULE:FREQ=DAILY;UNTIL=20240103T200000",
+		"DTSTART;TZID=America/New_York:20240101T200000",
+		"DURATION:PT1H",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	items, err := Decode(strings.NewReader(calendar))
+	if err != nil || len(items) != 1 || items[0].Err != nil {
+		t.Fatalf("Decode() = %+v, %v", items, err)
+	}
+	if rrule := items[0].Event.RRule; rrule != "FREQ=DAILY;UNTIL=20240104T010000Z" {
+		t.Errorf("RRule = %s, expected UNTIL in UTC", rrule)
+	}
+}
+
+func TestEncodeScheduling(t *testing.T) {
+	organizer, attendee := uuid.New(), uuid.New()
+	event := &entity.Event{
+		ID:       uuid.New(),
+		Title:    "planning",
+		UserID:   organizer,
+		Start:    time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC),
+		End:      time.Date(2024, time.March, 5, 11, 0, 0, 0, time.UTC),
+		TimeZone: "UTC",
+		Attendees: entity.Attendees{
+			{UserID: &attendee, Status: entity.StatusAccepted},
+			{Email: "guest@example.com", Status: entity.StatusNeedsAction},
+		},
+	}
+
+	request := &bytes.Buffer{}
+	if err := EncodeRequest(request, event); err != nil {
+		t.Fatalf("EncodeRequest() error = %v", err)
+	}
+	for _, line := range []string{
+		"METHOD:REQUEST",
+		"UID:" + event.ID.String(),
+		"ORGANIZER:urn:uuid:" + organizer.String(),
+		"ATTENDEE;PARTSTAT=ACCEPTED:urn:uuid:" + attendee.String(),
+		"ATTENDEE;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:guest@example.com",
+	} {
+		if !strings.Contains(request.String(), line+"\r\n") {
+			t.Errorf("request has no %q:\n%s", line, request)
+		}
+	}
+
+	reply := &bytes.Buffer{}
+	if err := EncodeReply(reply, event, &event.Attendees[0]); err != nil {
+		t.Fatalf("EncodeReply() error = %v", err)
+	}
+	if !strings.Contains(reply.String(), "METHOD:REPLY\r\n") || strings.Contains(reply.String(), "guest@example.com") ||
+		!strings.Contains(reply.String(), "ATTENDEE;PARTSTAT=ACCEPTED:urn:uuid:"+attendee.String()+"\r\n") {
+		t.Errorf("reply:\n%s", reply)
+	}
+}
@@ -0,0 +1,74 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"L2/develop/dev11/internal/entity"
+
+	"github.com/google/uuid"
+)
+
+// The iTIP (RFC 5546) methods of the scheduling messages.
+const (
+	MethodRequest = "REQUEST"
+	MethodReply   = "REPLY"
+)
+
+// EncodeRequest writes the invitation to the event as an iTIP REQUEST listing every attendee.
+// It is what external attendees get, as they can't answer through the API.
+func EncodeRequest(w io.Writer, event *entity.Event) error {
+	return encodeScheduling(w, MethodRequest, event, event.Attendees)
+}
+
+// EncodeReply writes the response of the attendee to the event as an iTIP REPLY to the organizer.
+func EncodeReply(w io.Writer, event *entity.Event, attendee *entity.Attendee) error {
+	return encodeScheduling(w, MethodReply, event, entity.Attendees{*attendee})
+}
+
+// encodeScheduling writes a VCALENDAR with the method and the event with the attendees.
+func encodeScheduling(w io.Writer, method string, event *entity.Event, attendees entity.Attendees) error {
+	uid := UID(event)
+	if event.SeriesID != nil {
+		uid = event.SeriesID.String()
+	}
+
+	bw := bufio.NewWriter(w)
+	lw := &lineWriter{w: bw}
+
+	lw.write("BEGIN", "VCALENDAR")
+	lw.write("VERSION", "2.0")
+	lw.write("PRODID", prodID)
+	lw.write("CALSCALE", "GREGORIAN")
+	lw.write("METHOD", method)
+	lw.writeEvent(event, uid, time.Now().UTC().Format(utcFormat), attendees)
+	lw.write("END", "VCALENDAR")
+	if lw.err != nil {
+		return fmt.Errorf("can't write calendar: %w", lw.err)
+	}
+
+	return bw.Flush()
+}
+
+// writeAttendee writes the attendee with the status, an attendee who needs action is asked to reply.
+func (lw *lineWriter) writeAttendee(attendee *entity.Attendee) {
+	params := ";PARTSTAT=" + strings.ToUpper(string(attendee.Status))
+	if attendee.Status == entity.StatusNeedsAction {
+		params += ";RSVP=TRUE"
+	}
+
+	address := "mailto:" + attendee.Email
+	if attendee.UserID != nil {
+		address = userAddress(*attendee.UserID)
+	}
+
+	lw.writeLine("ATTENDEE" + params + ":" + address)
+}
+
+// userAddress returns the calendar user address of a user of the calendar, who is known by the ID only.
+func userAddress(userID uuid.UUID) string {
+	return "urn:uuid:" + userID.String()
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+	requests := r.Counter("requests_total", "Requests.", "route", "status")
+	inFlight := r.Gauge("in_flight", "Requests in flight.")
+	duration := r.Histogram("duration_seconds", "Request durations.", []float64{1, 0.1}, "route")
+
+	requests.Inc("/events", "200")
+	requests.Inc("/events", "200")
+	requests.Inc(`/a"b\`, "500")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+	duration.Observe(0.05, "/events")
+	duration.Observe(0.5, "/events")
+	duration.Observe(3, "/events")
+
+	var out strings.Builder
+	err := r.Write(&out)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	expected := `# HELP duration_seconds Request durations.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/events",le="0.1"} 1
+duration_seconds_bucket{route="/events",le="1"} 2
+duration_seconds_bucket{route="/events",le="+Inf"} 3
+duration_seconds_sum{route="/events"} 3.55
+duration_seconds_count{route="/events"} 3
+# HELP in_flight Requests in flight.
+# TYPE in_flight gauge
+in_flight 1
+# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{route="/a\"b\\",status="500"} 1
+requests_total{route="/events",status="200"} 2
+`
+	if out.String() != expected {
+		t.Errorf("Write() =\n%s\nexpected\n%s", out.String(), expected)
+	}
+}
+
+func TestRegistryPanics(t *testing.T) {
+	r := NewRegistry()
+	counter := r.Counter("requests_total", "Requests.", "route")
+
+	for name, f := range map[string]func(){
+		"duplicate": func() { r.Gauge("requests_total", "Requests.") },
+		"labels":    func() { counter.Inc("/events", "200") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s expected a panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
@@ -0,0 +1,147 @@
+package notifier
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/ical"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The types of invitation messages.
+const (
+	typeInvitation = "invitation"
+	typeResponse   = "response"
+)
+
+// InvitationNotifier delivers the invitations to events and the responses of the attendees to the organizers.
+type InvitationNotifier interface {
+	NotifyInvitation(ctx context.Context, invitation *entity.Invitation) error
+	NotifyResponse(ctx context.Context, response *entity.Response) error
+}
+
+// multiInvitationNotifier delivers invitations and responses through several notifiers.
+type multiInvitationNotifier struct {
+	notifiers []InvitationNotifier
+}
+
+// MultiInvitation returns an invitation notifier delivering through all the notifiers, like Multi.
+func MultiInvitation(notifiers ...InvitationNotifier) InvitationNotifier {
+	if len(notifiers) == 1 {
+		return notifiers[0]
+	}
+	return &multiInvitationNotifier{notifiers: notifiers}
+}
+
+func (n *multiInvitationNotifier) NotifyInvitation(ctx context.Context, invitation *entity.Invitation) error {
+	var errs []error
+	for _, notifier := range n.notifiers {
+		if err := notifier.NotifyInvitation(ctx, invitation); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *multiInvitationNotifier) NotifyResponse(ctx context.Context, response *entity.Response) error {
+	var errs []error
+	for _, notifier := range n.notifiers {
+		if err := notifier.NotifyResponse(ctx, response); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// invitationMessage is the notification about an invitation to an event or a response to it.
+// ITIP is the iTIP message of it: a REQUEST for an invitation and a REPLY for a response.
+type invitationMessage struct {
+	Type         string           `json:"type"`
+	EventID      uuid.UUID        `json:"event_id"`
+	OrganizerID  uuid.UUID        `json:"organizer_id"`
+	Title        string           `json:"title"`
+	Start        time.Time        `json:"start"`
+	End          time.Time        `json:"end"`
+	AllDay       bool             `json:"all_day"`
+	RecurrenceID *time.Time       `json:"recurrence_id,omitempty"`
+	Attendees    entity.Attendees `json:"attendees"`
+	ITIP         string           `json:"itip"`
+}
+
+func newInvitationMessage(invitation *entity.Invitation) (invitationMessage, error) {
+	m := newEventMessage(typeInvitation, &invitation.Event, invitation.Attendees)
+
+	itip := &bytes.Buffer{}
+	if err := ical.EncodeRequest(itip, &invitation.Event); err != nil {
+		return m, fmt.Errorf("can't encode invitation: %w", err)
+	}
+	m.ITIP = itip.String()
+
+	return m, nil
+}
+
+func newResponseMessage(response *entity.Response) (invitationMessage, error) {
+	m := newEventMessage(typeResponse, &response.Event, entity.Attendees{response.Attendee})
+
+	itip := &bytes.Buffer{}
+	if err := ical.EncodeReply(itip, &response.Event, &response.Attendee); err != nil {
+		return m, fmt.Errorf("can't encode response: %w", err)
+	}
+	m.ITIP = itip.String()
+
+	return m, nil
+}
+
+func newEventMessage(messageType string, event *entity.Event, attendees entity.Attendees) invitationMessage {
+	return invitationMessage{
+		Type:         messageType,
+		EventID:      event.ID,
+		OrganizerID:  event.UserID,
+		Title:        event.Title,
+		Start:        event.Start.In(event.Location()),
+		End:          event.End.In(event.Location()),
+		AllDay:       event.AllDay,
+		RecurrenceID: event.RecurrenceID,
+		Attendees:    attendees,
+	}
+}
+
+// subject returns a one-line summary of the message.
+func (m *invitationMessage) subject() string {
+	if m.Type == typeResponse {
+		return fmt.Sprintf("%s: %s", statusTitles[m.Attendees[0].Status], m.Title)
+	}
+	return fmt.Sprintf("Invitation: %s", m.Title)
+}
+
+// statusTitles are the subjects of the responses.
+var statusTitles = map[entity.AttendeeStatus]string{
+	entity.StatusAccepted:    "Accepted",
+	entity.StatusDeclined:    "Declined",
+	entity.StatusTentative:   "Tentatively accepted",
+	entity.StatusNeedsAction: "Not answered",
+}
+
+// text returns a human-readable description of the message.
+func (m *invitationMessage) text() string {
+	when := fmt.Sprintf("from %s to %s", m.Start.Format("Monday, 2 January 2006 15:04 MST"), m.End.Format("15:04 MST"))
+	if m.AllDay {
+		when = "on " + m.Start.Format("Monday, 2 January 2006")
+	}
+	if m.Type == typeResponse {
+		return fmt.Sprintf("%s answered %s to %s %s.", attendeeName(&m.Attendees[0]), m.Attendees[0].Status, m.Title, when)
+	}
+	return fmt.Sprintf("You are invited to %s %s.", m.Title, when)
+}
+
+// attendeeName returns the email of an external attendee and the user ID of a user.
+func attendeeName(attendee *entity.Attendee) string {
+	if attendee.UserID != nil {
+		return attendee.UserID.String()
+	}
+	return attendee.Email
+}
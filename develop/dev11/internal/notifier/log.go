@@ -0,0 +1,57 @@
+package notifier
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// logNotifier writes reminders, invitations and responses to the log.
+type logNotifier struct {
+	logger *zap.Logger
+}
+
+// NewLogNotifier creates a notifier writing reminders, invitations and responses to the logger.
+func NewLogNotifier(logger *zap.Logger) *logNotifier {
+	return &logNotifier{
+		logger: logger,
+	}
+}
+
+func (n *logNotifier) Notify(ctx context.Context, reminder *entity.Reminder) error {
+	m := newMessage(reminder)
+	n.logger.Info(m.text(),
+		zap.String("event_id", m.EventID.String()),
+		zap.String("user_id", m.UserID.String()),
+		zap.Time("start", m.Start),
+		zap.String("offset", m.Offset),
+	)
+
+	return nil
+}
+
+func (n *logNotifier) NotifyInvitation(ctx context.Context, invitation *entity.Invitation) error {
+	m := newEventMessage(typeInvitation, &invitation.Event, invitation.Attendees)
+	for i := range m.Attendees {
+		n.logger.Info(m.text(),
+			zap.String("event_id", m.EventID.String()),
+			zap.String("organizer_id", m.OrganizerID.String()),
+			zap.String("attendee", attendeeName(&m.Attendees[i])),
+		)
+	}
+
+	return nil
+}
+
+func (n *logNotifier) NotifyResponse(ctx context.Context, response *entity.Response) error {
+	m := newEventMessage(typeResponse, &response.Event, entity.Attendees{response.Attendee})
+	n.logger.Info(m.text(),
+		zap.String("event_id", m.EventID.String()),
+		zap.String("organizer_id", m.OrganizerID.String()),
+		zap.String("attendee", attendeeName(&response.Attendee)),
+		zap.String("status", string(response.Attendee.Status)),
+	)
+
+	return nil
+}
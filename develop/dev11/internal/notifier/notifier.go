@@ -0,0 +1,84 @@
+// Package notifier provides the channels reminders of events, invitations to them
+// and the responses of the attendees are delivered through.
+package notifier
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notifier delivers a reminder. An error means the reminder wasn't delivered and will be retried.
+type Notifier interface {
+	Notify(ctx context.Context, reminder *entity.Reminder) error
+}
+
+// multiNotifier delivers reminders through several notifiers.
+type multiNotifier struct {
+	notifiers []Notifier
+}
+
+// Multi returns a notifier delivering reminders through all the notifiers.
+// It fails if any of them fails, so a retry may deliver a reminder through some notifiers twice.
+func Multi(notifiers ...Notifier) Notifier {
+	if len(notifiers) == 1 {
+		return notifiers[0]
+	}
+	return &multiNotifier{notifiers: notifiers}
+}
+
+func (n *multiNotifier) Notify(ctx context.Context, reminder *entity.Reminder) error {
+	var errs []error
+	for _, notifier := range n.notifiers {
+		if err := notifier.Notify(ctx, reminder); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// message is the notification about a reminder.
+type message struct {
+	EventID      uuid.UUID  `json:"event_id"`
+	UserID       uuid.UUID  `json:"user_id"`
+	Title        string     `json:"title"`
+	Start        time.Time  `json:"start"`
+	End          time.Time  `json:"end"`
+	AllDay       bool       `json:"all_day"`
+	RecurrenceID *time.Time `json:"recurrence_id,omitempty"`
+	Offset       string     `json:"offset"`
+	FireAt       time.Time  `json:"fire_at"`
+}
+
+func newMessage(reminder *entity.Reminder) message {
+	event := &reminder.Event
+	return message{
+		EventID:      event.ID,
+		UserID:       event.UserID,
+		Title:        event.Title,
+		Start:        event.Start.In(event.Location()),
+		End:          event.End.In(event.Location()),
+		AllDay:       event.AllDay,
+		RecurrenceID: event.RecurrenceID,
+		Offset:       entity.Offsets{reminder.Offset}.String(),
+		FireAt:       reminder.FireAt(),
+	}
+}
+
+// subject returns a one-line summary of the reminder.
+func (m *message) subject() string {
+	return fmt.Sprintf("Reminder: %s", m.Title)
+}
+
+// text returns a human-readable description of the reminder.
+func (m *message) text() string {
+	if m.AllDay {
+		return fmt.Sprintf("%s is on %s.", m.Title, m.Start.Format("Monday, 2 January 2006"))
+	}
+	return fmt.Sprintf("%s starts at %s and ends at %s.",
+		m.Title, m.Start.Format("Monday, 2 January 2006 15:04 MST"), m.End.Format("15:04 MST"))
+}
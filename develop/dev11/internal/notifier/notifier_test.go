@@ -0,0 +1,130 @@
+package notifier
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/notifier/smtptest"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func testReminder(t *testing.T) *entity.Reminder {
+	loc, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+
+	return &entity.Reminder{
+		Event: entity.Event{
+			ID:       uuid.New(),
+			Title:    "Стоматолог",
+			UserID:   uuid.New(),
+			Start:    time.Date(2024, time.March, 5, 7, 0, 0, 0, time.UTC),
+			End:      time.Date(2024, time.March, 5, 8, 0, 0, 0, time.UTC),
+			TimeZone: loc.String(),
+		},
+		Offset: 90 * time.Minute,
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	reminder := testReminder(t)
+
+	var received message
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := json.NewDecoder(req.Body).Decode(&received); err != nil {
+			t.Errorf("can't decode webhook: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	n := NewWebhookNotifier(server.URL, server.Client())
+	if err := n.Notify(context.Background(), reminder); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if received.EventID != reminder.Event.ID || received.Offset != "1h30m" || received.Start.Format(time.RFC3339) != "2024-03-05T10:00:00+03:00" {
+		t.Errorf("webhook = %+v", received)
+	}
+
+	status = http.StatusBadGateway
+	if err := n.Notify(context.Background(), reminder); err == nil {
+		t.Errorf("Notify() error = nil for %d", status)
+	}
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	reminder := testReminder(t)
+	server := smtptest.NewServer()
+	defer server.Close()
+
+	n := NewSMTPNotifier(SMTPConfig{Addr: server.Addr, From: "calendar@localhost", To: "{user_id}@example.com"})
+	if err := n.Notify(context.Background(), reminder); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("messages = %d, expected 1", len(messages))
+	}
+	m := messages[0]
+	if m.From != "calendar@localhost" || len(m.To) != 1 || m.To[0] != reminder.Event.UserID.String()+"@example.com" {
+		t.Errorf("envelope = %s -> %v", m.From, m.To)
+	}
+	if !strings.Contains(m.Data, "Subject: =?utf-8?q?") || !strings.Contains(m.Data, "Стоматолог starts at Tuesday, 5 March 2024 10:00 MSK") {
+		t.Errorf("data = %q", m.Data)
+	}
+
+	server.RejectNext(1)
+	if err := n.Notify(context.Background(), reminder); err == nil {
+		t.Errorf("Notify() error = nil for a rejected message")
+	}
+}
+
+func TestSMTPNotifierInvitations(t *testing.T) {
+	event := testReminder(t).Event
+	attendee := uuid.New()
+	event.Attendees = entity.Attendees{
+		{UserID: &attendee, Status: entity.StatusNeedsAction},
+		{Email: "guest@example.com", Status: entity.StatusNeedsAction},
+	}
+	server := smtptest.NewServer()
+	defer server.Close()
+
+	n := NewSMTPNotifier(SMTPConfig{Addr: server.Addr, From: "calendar@localhost", To: "{user_id}@example.com"})
+	if err := n.NotifyInvitation(context.Background(), &entity.Invitation{Event: event, Attendees: event.Attendees}); err != nil {
+		t.Fatalf("NotifyInvitation() error = %v", err)
+	}
+	response := &entity.Response{Event: event, Attendee: entity.Attendee{Email: "guest@example.com", Status: entity.StatusDeclined}}
+	if err := n.NotifyResponse(context.Background(), response); err != nil {
+		t.Fatalf("NotifyResponse() error = %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 3 {
+		t.Fatalf("messages = %d, expected 3", len(messages))
+	}
+	for i, expected := range []struct {
+		to     string
+		method string
+	}{
+		{attendee.String() + "@example.com", "method=REQUEST"},
+		{"guest@example.com", "method=REQUEST"},
+		{event.UserID.String() + "@example.com", "method=REPLY"},
+	} {
+		m := messages[i]
+		if len(m.To) != 1 || m.To[0] != expected.to || !strings.Contains(m.Data, expected.method) || !strings.Contains(m.Data, "BEGIN:VCALENDAR") {
+			t.Errorf("message %d to %v = %q", i, m.To, m.Data)
+		}
+	}
+	if !strings.Contains(messages[2].Data, "guest@example.com answered declined to Стоматолог") {
+		t.Errorf("response = %q", messages[2].Data)
+	}
+}
@@ -0,0 +1,193 @@
+package notifier
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/ical"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SMTPConfig configures the delivery of reminders, invitations and responses by email.
+type SMTPConfig struct {
+	// Addr is the host:port of the SMTP server.
+	Addr string
+	// Username and Password authenticate with PLAIN auth when Username is set.
+	Username string
+	Password string
+	// From is the sender address.
+	From string
+	// To is the address of a user, {user_id} in it is replaced with the ID of the user:
+	// the owner of the event for reminders, the attendee for invitations and the organizer for responses.
+	// External attendees are invited at their own addresses.
+	To string
+}
+
+// smtpNotifier emails reminders, invitations and responses.
+type smtpNotifier struct {
+	config SMTPConfig
+}
+
+// NewSMTPNotifier creates a notifier emailing reminders, invitations and responses through an SMTP server.
+// STARTTLS is used when the server supports it.
+func NewSMTPNotifier(config SMTPConfig) *smtpNotifier {
+	return &smtpNotifier{
+		config: config,
+	}
+}
+
+func (n *smtpNotifier) Notify(ctx context.Context, reminder *entity.Reminder) error {
+	m := newMessage(reminder)
+	to := n.userAddress(m.UserID)
+
+	return n.send(ctx, to, n.compose(&m, to))
+}
+
+// NotifyInvitation emails every attendee the invitation with the iTIP REQUEST of it.
+func (n *smtpNotifier) NotifyInvitation(ctx context.Context, invitation *entity.Invitation) error {
+	m, err := newInvitationMessage(invitation)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range invitation.Attendees {
+		attendee := &invitation.Attendees[i]
+		to := attendee.Email
+		if attendee.UserID != nil {
+			to = n.userAddress(*attendee.UserID)
+		}
+		if err := n.send(ctx, to, n.composeScheduling(&m, to, ical.MethodRequest)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyResponse emails the organizer the response with the iTIP REPLY of it.
+func (n *smtpNotifier) NotifyResponse(ctx context.Context, response *entity.Response) error {
+	m, err := newResponseMessage(response)
+	if err != nil {
+		return err
+	}
+	to := n.userAddress(m.OrganizerID)
+
+	return n.send(ctx, to, n.composeScheduling(&m, to, ical.MethodReply))
+}
+
+// userAddress returns the email address of the user.
+func (n *smtpNotifier) userAddress(userID uuid.UUID) string {
+	return strings.ReplaceAll(n.config.To, "{user_id}", userID.String())
+}
+
+// send delivers the email to the address.
+func (n *smtpNotifier) send(ctx context.Context, to string, data []byte) error {
+	host, _, err := net.SplitHostPort(n.config.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid smtp address: %v", err)
+	}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", n.config.Addr)
+	if err != nil {
+		return fmt.Errorf("can't connect to smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("can't start smtp session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("can't start tls: %w", err)
+		}
+	}
+	if n.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, host)); err != nil {
+			return fmt.Errorf("can't authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.config.From); err != nil {
+		return fmt.Errorf("smtp MAIL: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("smtp RCPT: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("can't write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("message not accepted: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// compose returns the email of the message.
+func (n *smtpNotifier) compose(m *message, to string) []byte {
+	buf := &bytes.Buffer{}
+	n.writeHeader(buf, to, m.subject())
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(m.text())
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// composeScheduling returns the email of the invitation message: the text with the iTIP message
+// of the method as an alternative, which mail clients show as an invitation or apply as a reply.
+func (n *smtpNotifier) composeScheduling(m *invitationMessage, to string, method string) []byte {
+	buf := &bytes.Buffer{}
+	n.writeHeader(buf, to, m.subject())
+
+	parts := multipart.NewWriter(buf)
+	fmt.Fprintf(buf, "Content-Type: multipart/alternative; boundary=%s\r\n", parts.Boundary())
+	buf.WriteString("\r\n")
+
+	text, _ := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	io.WriteString(text, m.text()+"\r\n")
+	calendar, _ := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/calendar; charset=utf-8; method=" + method},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	io.WriteString(calendar, m.ITIP)
+	parts.Close()
+
+	return buf.Bytes()
+}
+
+// writeHeader writes the header fields every email has.
+func (n *smtpNotifier) writeHeader(buf *bytes.Buffer, to string, subject string) {
+	fmt.Fprintf(buf, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(buf, "To: %s\r\n", to)
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+}
@@ -0,0 +1,180 @@
+// Package smtptest provides a local SMTP server for tests, in the spirit of net/http/httptest.
+package smtptest
+
+import (
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// Message is a message accepted by the server.
+type Message struct {
+	From string
+	To   []string
+	// Data is the message with headers, dot-unstuffed, with CRLF line endings.
+	Data string
+}
+
+// Server is a local SMTP server accepting every message without authentication.
+type Server struct {
+	// Addr is the host:port the server listens on.
+	Addr string
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	messages []Message
+	reject   int
+}
+
+// NewServer starts a server on a loopback port. It panics if it can't listen, like httptest.NewServer.
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("smtptest: can't listen: " + err.Error())
+	}
+
+	s := &Server{
+		Addr:     listener.Addr().String(),
+		listener: listener,
+		conns:    map[net.Conn]struct{}{},
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s
+}
+
+// Messages returns the messages accepted so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
+
+// RejectNext makes the server refuse the next n messages with a temporary failure.
+func (s *Server) RejectNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reject = n
+}
+
+// Close stops the server and closes open connections.
+func (s *Server) Close() {
+	s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// handle runs an SMTP session: HELO/EHLO, MAIL, RCPT, DATA, RSET, NOOP and QUIT.
+func (s *Server) handle(conn net.Conn) {
+	tp := textproto.NewConn(conn)
+	defer tp.Close()
+
+	var message Message
+	tp.PrintfLine("220 smtptest ready")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO":
+			tp.PrintfLine("250-smtptest")
+			tp.PrintfLine("250 8BITMIME")
+		case "HELO":
+			tp.PrintfLine("250 smtptest")
+		case "MAIL":
+			message = Message{From: address(arg)}
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			message.To = append(message.To, address(arg))
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			if message.From == "" || len(message.To) == 0 {
+				tp.PrintfLine("503 MAIL and RCPT first")
+				continue
+			}
+			tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			lines, err := tp.ReadDotLines()
+			if err != nil {
+				return
+			}
+			message.Data = strings.Join(lines, "\r\n")
+			tp.PrintfLine(s.accept(message))
+			message = Message{}
+		case "RSET":
+			message = Message{}
+			tp.PrintfLine("250 OK")
+		case "NOOP":
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+// accept stores the message unless it has to be rejected and returns the reply.
+func (s *Server) accept(message Message) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reject > 0 {
+		s.reject--
+		return "451 Try again later"
+	}
+	s.messages = append(s.messages, message)
+	return "250 OK"
+}
+
+// address extracts the address of a MAIL FROM:<...> or RCPT TO:<...> argument.
+func address(arg string) string {
+	start := strings.Index(arg, "<")
+	end := strings.LastIndex(arg, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return arg[start+1 : end]
+}
@@ -0,0 +1,85 @@
+package notifier
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// webhookNotifier posts reminders, invitations and responses as JSON to a URL.
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting reminders, invitations and responses to the URL.
+// Any response but 2xx is a failed delivery.
+func NewWebhookNotifier(url string, client *http.Client) *webhookNotifier {
+	return &webhookNotifier{
+		url:    url,
+		client: client,
+	}
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, reminder *entity.Reminder) error {
+	body, err := json.Marshal(newMessage(reminder))
+	if err != nil {
+		return fmt.Errorf("can't marshal reminder: %v", err)
+	}
+
+	return n.post(ctx, body)
+}
+
+// NotifyInvitation posts the invitation with the iTIP REQUEST of it.
+func (n *webhookNotifier) NotifyInvitation(ctx context.Context, invitation *entity.Invitation) error {
+	m, err := newInvitationMessage(invitation)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("can't marshal invitation: %v", err)
+	}
+
+	return n.post(ctx, body)
+}
+
+// NotifyResponse posts the response with the iTIP REPLY of it.
+func (n *webhookNotifier) NotifyResponse(ctx context.Context, response *entity.Response) error {
+	m, err := newResponseMessage(response)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("can't marshal response: %v", err)
+	}
+
+	return n.post(ctx, body)
+}
+
+// post posts the JSON body to the URL.
+func (n *webhookNotifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("can't create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("can't post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+
+	return nil
+}
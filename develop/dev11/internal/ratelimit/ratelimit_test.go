@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	l := New(Limit{Rate: 2, Burst: 3})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("Allow() %d within the burst refused", i)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("Allow() over the burst = %v, %v, expected false, 500ms", ok, wait)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Errorf("Allow() of another key refused")
+	}
+
+	now = now.Add(250 * time.Millisecond)
+	if ok, wait := l.Allow("a"); ok || wait != 250*time.Millisecond {
+		t.Errorf("Allow() after a partial refill = %v, %v, expected false, 250ms", ok, wait)
+	}
+	now = now.Add(250 * time.Millisecond)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Errorf("Allow() after a refill refused")
+	}
+
+	now = now.Add(sweepInterval)
+	l.Allow("c")
+	if len(l.buckets) != 1 {
+		t.Errorf("buckets after a sweep = %d, expected 1", len(l.buckets))
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	l := New(Limit{})
+	if l != nil {
+		t.Fatalf("New() of a zero limit = %v, expected nil", l)
+	}
+	if ok, _ := l.Allow("a"); !ok {
+		t.Errorf("Allow() of a nil limiter refused")
+	}
+}
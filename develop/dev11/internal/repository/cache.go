@@ -0,0 +1,444 @@
+package repository
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/metrics"
+	"container/list"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	cacheRequests = metrics.Default.Counter(
+		"calendar_event_cache_requests_total",
+		"Lookups of the event cache by result: hit or miss.",
+		"result",
+	)
+	cacheEvictions = metrics.Default.Counter(
+		"calendar_event_cache_evictions_total",
+		"Entries removed from the event cache by reason: lru, ttl or invalidation.",
+		"reason",
+	)
+	cacheEntries = metrics.Default.Gauge(
+		"calendar_event_cache_entries",
+		"Entries held by the event cache.",
+	)
+)
+
+// The bounds of the time range invalidated by the changes of a whole calendar or series.
+var (
+	beginningOfTime = time.Time{}
+	endOfTime       = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+)
+
+// CacheStats are the counters of an EventCache since it was created. Evictions are the entries removed
+// because the cache is full or they expired, Invalidations the ones removed because of writes.
+type CacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64
+	Invalidations uint64
+	Entries       int
+}
+
+// EventCache is a read-through LRU cache of the window queries of events: the events of the day, week
+// and month of a set of calendars, which are the calendars visible to a user, and the events a user is invited to.
+// Entries are keyed by the calendars or the user and the window and expire after the TTL.
+//
+// The cache is filled and invalidated by the decorators it returns: Events caches the queries of an
+// EventRepository, the writes through Events, Trash and Calendars remove the entries of the calendars,
+// attendees and time ranges they change. Within a transaction of TxManager the cache is bypassed,
+// and the entries are invalidated once more when the transaction ends, so that no entry outlives a write.
+type EventCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	// generation is incremented by every invalidation, a query result read before it is not stored.
+	generation uint64
+	stats      CacheStats
+}
+
+// cacheEntry is the result of a window query, either of the calendars or of the invitations of the user.
+type cacheEntry struct {
+	key         string
+	calendarIDs map[uuid.UUID]bool
+	userID      uuid.UUID
+	from, to    time.Time
+	events      entity.Events
+	expiresAt   time.Time
+}
+
+// invalidation is a change of the events of the calendar with the attendees in [from, to).
+type invalidation struct {
+	calendarID uuid.UUID
+	attendees  entity.Attendees
+	// allInvited invalidates the invitations of every user, when the attendees aren't known.
+	allInvited bool
+	from, to   time.Time
+}
+
+// NewEventCache creates a cache of at most size entries which expire after ttl.
+func NewEventCache(size int, ttl time.Duration) *EventCache {
+	return &EventCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// Stats returns the counters of the cache.
+func (c *EventCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	return stats
+}
+
+// cacheTxKey is the context key of the invalidations of a transaction of TxManager.
+type cacheTxKey struct {
+	cache *EventCache
+}
+
+// txInvalidations are the invalidations made within a transaction, repeated when it ends.
+type txInvalidations struct {
+	mu   sync.Mutex
+	list []invalidation
+}
+
+// query returns the events of the entry from the cache or loads and stores them.
+// The events are copied, so that the callers may change the result.
+func (c *EventCache) query(ctx context.Context, entry *cacheEntry, load func() (*entity.Events, error)) (*entity.Events, error) {
+	// Within a transaction the events may be uncommitted.
+	if ctx.Value(cacheTxKey{c}) != nil {
+		return load()
+	}
+
+	c.mu.Lock()
+	if element, ok := c.entries[entry.key]; ok {
+		cached := element.Value.(*cacheEntry)
+		if c.now().Before(cached.expiresAt) {
+			c.lru.MoveToFront(element)
+			c.stats.Hits++
+			events := append(entity.Events(nil), cached.events...)
+			c.mu.Unlock()
+			cacheRequests.Inc("hit")
+			return &events, nil
+		}
+		c.remove(element, "ttl")
+	}
+	c.stats.Misses++
+	generation := c.generation
+	c.mu.Unlock()
+	cacheRequests.Inc("miss")
+
+	events, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A write since the query started may be missing from its result.
+	if c.generation != generation {
+		return events, nil
+	}
+	if element, ok := c.entries[entry.key]; ok {
+		c.remove(element, "lru")
+	}
+	entry.events = append(entity.Events(nil), *events...)
+	entry.expiresAt = c.now().Add(c.ttl)
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	cacheEntries.Inc()
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back(), "lru")
+	}
+
+	return events, nil
+}
+
+// remove removes the entry of the element. It must be called with the lock held.
+func (c *EventCache) remove(element *list.Element, reason string) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).key)
+	if reason == "invalidation" {
+		c.stats.Invalidations++
+	} else {
+		c.stats.Evictions++
+	}
+	cacheEvictions.Inc(reason)
+	cacheEntries.Dec()
+}
+
+// invalidate removes the entries the changes may affect. Within a transaction the changes
+// are kept to be invalidated again when it ends.
+func (c *EventCache) invalidate(ctx context.Context, changes ...invalidation) {
+	if pending, ok := ctx.Value(cacheTxKey{c}).(*txInvalidations); ok {
+		pending.mu.Lock()
+		pending.list = append(pending.list, changes...)
+		pending.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for element := c.lru.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*cacheEntry)
+		for _, change := range changes {
+			if entry.affectedBy(change) {
+				c.remove(element, "invalidation")
+				break
+			}
+		}
+		element = next
+	}
+}
+
+// affectedBy reports whether the change may alter the result of the query of the entry.
+func (e *cacheEntry) affectedBy(change invalidation) bool {
+	if !e.from.Before(change.to) || !change.from.Before(e.to) {
+		return false
+	}
+	if e.calendarIDs != nil {
+		return e.calendarIDs[change.calendarID]
+	}
+	return change.allInvited || change.attendees.Invites(e.userID)
+}
+
+// savedChanges returns the invalidations of the event saved as event and stored before as stored, if it isn't nil:
+// the event leaves the range and the attendees it had and takes the new ones.
+func savedChanges(stored *entity.Event, event *entity.Event) []invalidation {
+	if stored == nil {
+		return []invalidation{eventChange(event)}
+	}
+	return []invalidation{eventChange(stored), eventChange(event)}
+}
+
+// eventChange returns the invalidation of the event alone, a series changes its occurrences from its start on.
+func eventChange(event *entity.Event) invalidation {
+	change := invalidation{calendarID: event.CalendarID, attendees: event.Attendees, from: event.Start, to: event.End}
+	if event.IsRecurring() {
+		change.to = endOfTime
+	}
+	return change
+}
+
+// seriesChange returns the invalidation of the event together with its overrides, which may be moved anywhere.
+func seriesChange(event *entity.Event) invalidation {
+	if !event.IsRecurring() {
+		return eventChange(event)
+	}
+	return invalidation{calendarID: event.CalendarID, allInvited: true, from: beginningOfTime, to: endOfTime}
+}
+
+// calendarEntry returns the entry of the window query of the calendars.
+func calendarEntry(query string, calendarIDs []uuid.UUID, from time.Time, to time.Time) *cacheEntry {
+	ids := make([]string, 0, len(calendarIDs))
+	set := make(map[uuid.UUID]bool, len(calendarIDs))
+	for _, id := range calendarIDs {
+		ids = append(ids, id.String())
+		set[id] = true
+	}
+	sort.Strings(ids)
+
+	return &cacheEntry{
+		key:         query + "|" + windowKey(from, to) + "|" + strings.Join(ids, ","),
+		calendarIDs: set,
+		from:        from,
+		to:          to,
+	}
+}
+
+// invitedEntry returns the entry of the query of the invitations of the user.
+func invitedEntry(userID uuid.UUID, from time.Time, to time.Time) *cacheEntry {
+	return &cacheEntry{key: "invited|" + windowKey(from, to) + "|" + userID.String(), userID: userID, from: from, to: to}
+}
+
+func windowKey(from time.Time, to time.Time) string {
+	return from.UTC().Format(time.RFC3339Nano) + "|" + to.UTC().Format(time.RFC3339Nano)
+}
+
+// Events returns the decorator of the repository caching its window queries.
+func (c *EventCache) Events(next EventRepository) EventRepository {
+	return &cachedEventRepository{EventRepository: next, cache: c}
+}
+
+// cachedEventRepository caches the window queries of the repository it decorates and invalidates them on writes.
+type cachedEventRepository struct {
+	EventRepository
+	cache *EventCache
+}
+
+func (r *cachedEventRepository) Create(ctx context.Context, event *entity.Event) error {
+	err := r.EventRepository.Create(ctx, event)
+	if err != nil {
+		return err
+	}
+	r.cache.invalidate(ctx, eventChange(event))
+
+	return nil
+}
+
+func (r *cachedEventRepository) Update(ctx context.Context, event *entity.Event) error {
+	// The stored event tells the range and the attendees the update moves the event from.
+	stored, _ := r.EventRepository.Get(ctx, event.ID)
+	err := r.EventRepository.Update(ctx, event)
+	if err != nil {
+		return err
+	}
+	r.cache.invalidate(ctx, savedChanges(stored, event)...)
+
+	return nil
+}
+
+func (r *cachedEventRepository) Delete(ctx context.Context, eventID uuid.UUID, version int64) error {
+	stored, getErr := r.EventRepository.Get(ctx, eventID)
+	err := r.EventRepository.Delete(ctx, eventID, version)
+	if err != nil {
+		return err
+	}
+	// The event deleted meanwhile was invalidated by that deletion.
+	if getErr == nil {
+		r.cache.invalidate(ctx, seriesChange(stored))
+	}
+
+	return nil
+}
+
+func (r *cachedEventRepository) Batch(ctx context.Context, operations []entity.BatchOperation) error {
+	stored := make([]*entity.Event, len(operations))
+	for n, operation := range operations {
+		if operation.Op != entity.BatchCreate {
+			stored[n], _ = r.EventRepository.Get(ctx, operation.EventID())
+		}
+	}
+
+	err := r.EventRepository.Batch(ctx, operations)
+	if err != nil {
+		return err
+	}
+
+	changes := make([]invalidation, 0, len(operations))
+	for n, operation := range operations {
+		switch {
+		case operation.Op == entity.BatchDelete && stored[n] != nil:
+			changes = append(changes, seriesChange(stored[n]))
+		case operation.Op != entity.BatchDelete:
+			changes = append(changes, savedChanges(stored[n], operation.Event)...)
+		}
+	}
+	r.cache.invalidate(ctx, changes...)
+
+	return nil
+}
+
+func (r *cachedEventRepository) GetForDay(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error) {
+	from, to := entity.DayWindow(date)
+	return r.cache.query(ctx, calendarEntry("day", calendarIDs, from, to), func() (*entity.Events, error) {
+		return r.EventRepository.GetForDay(ctx, calendarIDs, date)
+	})
+}
+
+func (r *cachedEventRepository) GetForWeek(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error) {
+	from, to := entity.WeekWindow(date)
+	return r.cache.query(ctx, calendarEntry("week", calendarIDs, from, to), func() (*entity.Events, error) {
+		return r.EventRepository.GetForWeek(ctx, calendarIDs, date)
+	})
+}
+
+func (r *cachedEventRepository) GetForMonth(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error) {
+	from, to := entity.MonthWindow(date)
+	return r.cache.query(ctx, calendarEntry("month", calendarIDs, from, to), func() (*entity.Events, error) {
+		return r.EventRepository.GetForMonth(ctx, calendarIDs, date)
+	})
+}
+
+func (r *cachedEventRepository) GetInvited(ctx context.Context, userID uuid.UUID, from time.Time, to time.Time) (*entity.Events, error) {
+	return r.cache.query(ctx, invitedEntry(userID, from, to), func() (*entity.Events, error) {
+		return r.EventRepository.GetInvited(ctx, userID, from, to)
+	})
+}
+
+// Trash returns the decorator of the repository invalidating the restored events.
+func (c *EventCache) Trash(next TrashRepository) TrashRepository {
+	return &cachedTrashRepository{TrashRepository: next, cache: c}
+}
+
+type cachedTrashRepository struct {
+	TrashRepository
+	cache *EventCache
+}
+
+func (r *cachedTrashRepository) Restore(ctx context.Context, eventID uuid.UUID) error {
+	deleted, getErr := r.TrashRepository.Get(ctx, eventID)
+	err := r.TrashRepository.Restore(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if getErr == nil {
+		r.cache.invalidate(ctx, seriesChange(deleted))
+	}
+
+	return nil
+}
+
+// Calendars returns the decorator of the repository invalidating the events of the deleted calendars.
+func (c *EventCache) Calendars(next CalendarRepository) CalendarRepository {
+	return &cachedCalendarRepository{CalendarRepository: next, cache: c}
+}
+
+type cachedCalendarRepository struct {
+	CalendarRepository
+	cache *EventCache
+}
+
+func (r *cachedCalendarRepository) Delete(ctx context.Context, calendarID uuid.UUID) error {
+	err := r.CalendarRepository.Delete(ctx, calendarID)
+	if err != nil {
+		return err
+	}
+	r.cache.invalidate(ctx, invalidation{calendarID: calendarID, allInvited: true, from: beginningOfTime, to: endOfTime})
+
+	return nil
+}
+
+// TxManager returns the decorator of the transaction manager bypassing the cache within transactions
+// and repeating their invalidations when they end.
+func (c *EventCache) TxManager(next TxManager) TxManager {
+	return &cachedTxManager{next: next, cache: c}
+}
+
+type cachedTxManager struct {
+	next  TxManager
+	cache *EventCache
+}
+
+func (m *cachedTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(cacheTxKey{m.cache}) != nil {
+		return m.next.WithinTx(ctx, fn)
+	}
+
+	pending := &txInvalidations{}
+	err := m.next.WithinTx(context.WithValue(ctx, cacheTxKey{m.cache}, pending), fn)
+	// The entries read by other queries before the commit are removed, with or without an error.
+	if len(pending.list) > 0 {
+		m.cache.invalidate(ctx, pending.list...)
+	}
+
+	return err
+}
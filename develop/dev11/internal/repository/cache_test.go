@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestEventCache(t *testing.T) {
+	ctx := context.Background()
+	source := db.NewMemorySource()
+	cache := NewEventCache(3, time.Minute)
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	events := cache.Events(NewEventRepository(source))
+
+	work, home := uuid.New(), uuid.New()
+	attendee := uuid.New()
+	march := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	april := march.AddDate(0, 1, 0)
+	newEvent := func(title string, calendarID uuid.UUID, start time.Time) *entity.Event {
+		return &entity.Event{ID: uuid.New(), Title: title, UserID: work, CalendarID: calendarID, TimeZone: "UTC",
+			Start: start, End: start.Add(time.Hour)}
+	}
+	month := func(calendarIDs []uuid.UUID, date time.Time) entity.Events {
+		t.Helper()
+		got, err := events.GetForMonth(ctx, calendarIDs, date)
+		if err != nil {
+			t.Fatalf("GetForMonth() error = %v", err)
+		}
+		return *got
+	}
+	assertStats := func(name string, expected CacheStats) {
+		t.Helper()
+		if got := cache.Stats(); got != expected {
+			t.Errorf("Stats() %s = %+v, expected %+v", name, got, expected)
+		}
+	}
+
+	standup := newEvent("standup", work, march)
+	if err := events.Create(ctx, standup); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	calendars := []uuid.UUID{work, home}
+	if got := month(calendars, march); len(got) != 1 {
+		t.Errorf("GetForMonth() = %v, expected the standup", got)
+	}
+	// The calendars are a set and the months are keyed by their window, not the date.
+	got := month([]uuid.UUID{home, work}, march.AddDate(0, 0, 10))
+	if len(got) != 1 {
+		t.Errorf("GetForMonth() cached = %v, expected the standup", got)
+	}
+	assertStats("after a miss and a hit", CacheStats{Hits: 1, Misses: 1, Entries: 1})
+
+	// The result is a copy.
+	got[0].Title = "changed"
+	if got := month(calendars, march); got[0].Title != "standup" {
+		t.Errorf("GetForMonth() after changing the result = %v", got)
+	}
+
+	// Writes elsewhere keep the entry, the ones in its calendars and month remove it.
+	month(calendars, april)
+	if err := events.Create(ctx, newEvent("other month", work, april)); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := events.Create(ctx, newEvent("other calendar", uuid.New(), march)); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	month(calendars, march)
+	assertStats("after writes elsewhere", CacheStats{Hits: 3, Misses: 2, Invalidations: 1, Entries: 1})
+
+	// An event moved to another month leaves the entry of the old month and joins the one of the new month.
+	month(calendars, april)
+	standup.Start, standup.End = april, april.Add(time.Hour)
+	if err := events.Update(ctx, standup); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got := month(calendars, march); len(got) != 0 {
+		t.Errorf("GetForMonth() of March after moving = %v, expected none", got)
+	}
+	if got := month(calendars, april); len(got) != 2 {
+		t.Errorf("GetForMonth() of April after moving = %v, expected two events", got)
+	}
+
+	// The invitations are keyed by the user.
+	from, to := entity.MonthWindow(march)
+	for _, userID := range []uuid.UUID{attendee, work} {
+		if _, err := events.GetInvited(ctx, userID, from, to); err != nil {
+			t.Fatalf("GetInvited() error = %v", err)
+		}
+	}
+	invitation := newEvent("invitation", home, march)
+	invitation.Attendees = entity.Attendees{{UserID: &attendee}}
+	if err := events.Create(ctx, invitation); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	stats := cache.Stats()
+	invited, err := events.GetInvited(ctx, attendee, from, to)
+	if err != nil || len(*invited) != 1 {
+		t.Errorf("GetInvited() of the attendee = %v, %v, expected the invitation", invited, err)
+	}
+	if _, err := events.GetInvited(ctx, work, from, to); err != nil {
+		t.Fatalf("GetInvited() error = %v", err)
+	}
+	if got := cache.Stats(); got.Misses != stats.Misses+1 || got.Hits != stats.Hits+1 {
+		t.Errorf("Stats() after an invitation = %+v, expected a miss of the attendee and a hit of the other user", got)
+	}
+
+	// The least recently used entry is evicted when the cache is full and entries expire after the TTL.
+	stats = cache.Stats()
+	month(calendars, april.AddDate(0, 1, 0))
+	month(calendars, april.AddDate(0, 2, 0))
+	if got := cache.Stats(); got.Evictions != stats.Evictions+2 || got.Entries != 3 {
+		t.Errorf("Stats() after filling = %+v, expected two evictions", got)
+	}
+	now = now.Add(time.Minute)
+	month(calendars, april.AddDate(0, 2, 0))
+	if got := cache.Stats(); got.Misses != stats.Misses+3 || got.Evictions != stats.Evictions+3 {
+		t.Errorf("Stats() after the TTL = %+v, expected an expired entry", got)
+	}
+}
+
+func TestEventCacheTransactions(t *testing.T) {
+	ctx := context.Background()
+	source := db.NewMemorySource()
+	cache := NewEventCache(10, time.Minute)
+	events := cache.Events(NewEventRepository(source))
+	trash := cache.Trash(NewTrashRepository(source))
+	calendars := cache.Calendars(NewCalendarRepository(source))
+	tx := cache.TxManager(NewTxManager(source))
+
+	calendarID := uuid.New()
+	start := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	event := &entity.Event{ID: uuid.New(), Title: "standup", UserID: calendarID, CalendarID: calendarID, TimeZone: "UTC",
+		Start: start, End: start.Add(time.Hour)}
+	if err := events.Create(ctx, event); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	day := func(ctx context.Context) int {
+		t.Helper()
+		got, err := events.GetForDay(ctx, []uuid.UUID{calendarID}, start)
+		if err != nil {
+			t.Fatalf("GetForDay() error = %v", err)
+		}
+		return len(*got)
+	}
+
+	// Within a transaction the cache is bypassed, its writes are invalidated when it ends.
+	day(ctx)
+	failure := errors.New("failure")
+	err := tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := events.Delete(ctx, event.ID, 0); err != nil {
+			return err
+		}
+		if n := day(ctx); n != 0 {
+			t.Errorf("GetForDay() within the transaction = %d events, expected none", n)
+		}
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("WithinTx() error = %v", err)
+	}
+	if n := day(ctx); n != 1 {
+		t.Errorf("GetForDay() after the rollback = %d events, expected the event", n)
+	}
+	if stats := cache.Stats(); stats.Hits != 0 || stats.Misses != 2 {
+		t.Errorf("Stats() after the transaction = %+v, expected two misses", stats)
+	}
+
+	// The restored events and the deleted calendars are invalidated too.
+	if err := events.Delete(ctx, event.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if n := day(ctx); n != 0 {
+		t.Errorf("GetForDay() after Delete() = %d events, expected none", n)
+	}
+	if err := trash.Restore(ctx, event.ID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if n := day(ctx); n != 1 {
+		t.Errorf("GetForDay() after Restore() = %d events, expected the event", n)
+	}
+	if err := calendars.Delete(ctx, calendarID); err != nil {
+		t.Fatalf("Delete() of the calendar error = %v", err)
+	}
+	if n := day(ctx); n != 0 {
+		t.Errorf("GetForDay() after deleting the calendar = %d events, expected none", n)
+	}
+}
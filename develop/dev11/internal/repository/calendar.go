@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type calendarRepository struct {
+	source db.CalendarSource
+}
+
+func NewCalendarRepository(source db.CalendarSource) *calendarRepository {
+	return &calendarRepository{
+		source: source,
+	}
+}
+
+func (r *calendarRepository) Create(ctx context.Context, calendar *entity.Calendar) error {
+	err := r.source.CreateCalendar(ctx, calendar)
+	if err != nil {
+		return fmt.Errorf("error in calendarRepository.Create: %w", err)
+	}
+
+	return nil
+}
+
+func (r *calendarRepository) Update(ctx context.Context, calendar *entity.Calendar) error {
+	err := r.source.UpdateCalendar(ctx, calendar)
+	if err != nil {
+		return fmt.Errorf("error in calendarRepository.Update: %w", err)
+	}
+
+	return nil
+}
+
+func (r *calendarRepository) Delete(ctx context.Context, calendarID uuid.UUID) error {
+	err := r.source.DeleteCalendar(ctx, calendarID)
+	if err != nil {
+		return fmt.Errorf("error in calendarRepository.Delete: %w", err)
+	}
+
+	return nil
+}
+
+func (r *calendarRepository) Get(ctx context.Context, calendarID uuid.UUID) (*entity.Calendar, error) {
+	calendar, err := r.source.GetCalendar(ctx, calendarID)
+	if err != nil {
+		return nil, fmt.Errorf("error in calendarRepository.Get: %w", err)
+	}
+
+	return calendar, nil
+}
+
+func (r *calendarRepository) GetForUser(ctx context.Context, userID uuid.UUID) (entity.Calendars, error) {
+	calendars, err := r.source.GetUserCalendars(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error in calendarRepository.GetForUser: %w", err)
+	}
+
+	return calendars, nil
+}
+
+func (r *calendarRepository) GetRole(ctx context.Context, calendarID uuid.UUID, userID uuid.UUID) (entity.Role, error) {
+	role, err := r.source.GetCalendarRole(ctx, calendarID, userID)
+	if err != nil {
+		return "", fmt.Errorf("error in calendarRepository.GetRole: %w", err)
+	}
+
+	return role, nil
+}
+
+func (r *calendarRepository) GetShares(ctx context.Context, calendarID uuid.UUID) ([]entity.Share, error) {
+	shares, err := r.source.GetCalendarShares(ctx, calendarID)
+	if err != nil {
+		return nil, fmt.Errorf("error in calendarRepository.GetShares: %w", err)
+	}
+
+	return shares, nil
+}
+
+func (r *calendarRepository) SetShare(ctx context.Context, share *entity.Share) error {
+	err := r.source.SetCalendarShare(ctx, share)
+	if err != nil {
+		return fmt.Errorf("error in calendarRepository.SetShare: %w", err)
+	}
+
+	return nil
+}
+
+func (r *calendarRepository) DeleteShare(ctx context.Context, calendarID uuid.UUID, userID uuid.UUID) error {
+	err := r.source.DeleteCalendarShare(ctx, calendarID, userID)
+	if err != nil {
+		return fmt.Errorf("error in calendarRepository.DeleteShare: %w", err)
+	}
+
+	return nil
+}
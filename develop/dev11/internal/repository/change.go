@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type changeRepository struct {
+	source db.ChangeSource
+}
+
+func NewChangeRepository(source db.ChangeSource) *changeRepository {
+	return &changeRepository{
+		source: source,
+	}
+}
+
+func (r *changeRepository) Append(ctx context.Context, change *entity.Change) error {
+	err := r.source.AppendChange(ctx, change)
+	if err != nil {
+		return fmt.Errorf("error in changeRepository.Append: %w", err)
+	}
+
+	return nil
+}
+
+func (r *changeRepository) GetAfter(ctx context.Context, afterID int64, calendarIDs []uuid.UUID, limit int) ([]entity.Change, error) {
+	changes, err := r.source.GetChanges(ctx, afterID, calendarIDs, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error in changeRepository.GetAfter: %w", err)
+	}
+
+	return changes, nil
+}
+
+func (r *changeRepository) GetLastID(ctx context.Context) (int64, error) {
+	id, err := r.source.GetLastChangeID(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error in changeRepository.GetLastID: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r *changeRepository) DeleteBefore(ctx context.Context, changedBefore time.Time) error {
+	err := r.source.DeleteChanges(ctx, changedBefore)
+	if err != nil {
+		return fmt.Errorf("error in changeRepository.DeleteBefore: %w", err)
+	}
+
+	return nil
+}
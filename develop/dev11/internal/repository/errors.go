@@ -0,0 +1,12 @@
+package repository
+
+import "L2/develop/dev11/internal/db"
+
+// ErrNotFound is returned when the requested entity doesn't exist.
+var ErrNotFound = db.ErrNotFound
+
+// ErrVersionConflict is returned when the entity was changed since the version the write is based on.
+var ErrVersionConflict = db.ErrVersionConflict
+
+// ErrAlreadyExists is returned when an entity with the same ID exists already.
+var ErrAlreadyExists = db.ErrAlreadyExists
@@ -38,8 +38,8 @@ func (r *eventRepository) Update(ctx context.Context, event *entity.Event) error
 	return nil
 }
 
-func (r *eventRepository) Delete(ctx context.Context, eventID uuid.UUID) error {
-	err := r.source.DeleteEvent(ctx, eventID)
+func (r *eventRepository) Delete(ctx context.Context, eventID uuid.UUID, version int64) error {
+	err := r.source.DeleteEvent(ctx, eventID, version)
 	if err != nil {
 		return fmt.Errorf("error in eventRepository.Delete: %w", err)
 	}
@@ -47,8 +47,26 @@ func (r *eventRepository) Delete(ctx context.Context, eventID uuid.UUID) error {
 	return nil
 }
 
-func (r *eventRepository) GetForDay(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error) {
-	events, err := r.source.GetEventForDay(ctx, userID, date)
+func (r *eventRepository) Get(ctx context.Context, eventID uuid.UUID) (*entity.Event, error) {
+	event, err := r.source.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventRepository.Get: %w", err)
+	}
+
+	return event, nil
+}
+
+func (r *eventRepository) GetAll(ctx context.Context, calendarIDs []uuid.UUID) (*entity.Events, error) {
+	events, err := r.source.GetCalendarEvents(ctx, calendarIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventRepository.GetAll: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *eventRepository) GetForDay(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error) {
+	events, err := r.source.GetEventForDay(ctx, calendarIDs, date)
 	if err != nil {
 		return nil, fmt.Errorf("error in eventRepository.GetForDay: %w", err)
 	}
@@ -56,8 +74,8 @@ func (r *eventRepository) GetForDay(ctx context.Context, userID uuid.UUID, date
 	return events, nil
 }
 
-func (r *eventRepository) GetForWeek(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error) {
-	events, err := r.source.GetEventForWeek(ctx, userID, date)
+func (r *eventRepository) GetForWeek(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error) {
+	events, err := r.source.GetEventForWeek(ctx, calendarIDs, date)
 	if err != nil {
 		return nil, fmt.Errorf("error in eventRepository.GetForWeek: %w", err)
 	}
@@ -65,11 +83,56 @@ func (r *eventRepository) GetForWeek(ctx context.Context, userID uuid.UUID, date
 	return events, nil
 }
 
-func (r *eventRepository) GetForMonth(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error) {
-	events, err := r.source.GetEventForMonth(ctx, userID, date)
+func (r *eventRepository) GetForMonth(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error) {
+	events, err := r.source.GetEventForMonth(ctx, calendarIDs, date)
 	if err != nil {
 		return nil, fmt.Errorf("error in eventRepository.GetForMonth: %w", err)
 	}
 
 	return events, nil
 }
+
+func (r *eventRepository) GetInWindow(ctx context.Context, calendarIDs []uuid.UUID, from time.Time, to time.Time) (*entity.Events, error) {
+	events, err := r.source.GetEventsInWindow(ctx, calendarIDs, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventRepository.GetInWindow: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *eventRepository) Search(ctx context.Context, query *entity.EventQuery) (*entity.EventPage, error) {
+	page, err := r.source.SearchEvents(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventRepository.Search: %w", err)
+	}
+
+	return page, nil
+}
+
+func (r *eventRepository) GetInvited(ctx context.Context, userID uuid.UUID, from time.Time, to time.Time) (*entity.Events, error) {
+	events, err := r.source.GetInvitedEvents(ctx, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventRepository.GetInvited: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *eventRepository) GetTagCounts(ctx context.Context, calendarIDs []uuid.UUID) ([]entity.TagCount, error) {
+	counts, err := r.source.GetTagCounts(ctx, calendarIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventRepository.GetTagCounts: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (r *eventRepository) Batch(ctx context.Context, operations []entity.BatchOperation) error {
+	err := r.source.Batch(ctx, operations)
+	if err != nil {
+		return fmt.Errorf("error in eventRepository.Batch: %w", err)
+	}
+
+	return nil
+}
@@ -14,8 +14,67 @@ import (
 type EventRepository interface {
 	Create(ctx context.Context, event *entity.Event) error
 	Update(ctx context.Context, event *entity.Event) error
-	Delete(ctx context.Context, eventID uuid.UUID) error
-	GetForDay(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error)
-	GetForWeek(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error)
-	GetForMonth(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error)
+	Delete(ctx context.Context, eventID uuid.UUID, version int64) error
+	Get(ctx context.Context, eventID uuid.UUID) (*entity.Event, error)
+	GetAll(ctx context.Context, calendarIDs []uuid.UUID) (*entity.Events, error)
+	GetForDay(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error)
+	GetForWeek(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error)
+	GetForMonth(ctx context.Context, calendarIDs []uuid.UUID, date time.Time) (*entity.Events, error)
+	GetInWindow(ctx context.Context, calendarIDs []uuid.UUID, from time.Time, to time.Time) (*entity.Events, error)
+	Search(ctx context.Context, query *entity.EventQuery) (*entity.EventPage, error)
+	GetInvited(ctx context.Context, userID uuid.UUID, from time.Time, to time.Time) (*entity.Events, error)
+	GetTagCounts(ctx context.Context, calendarIDs []uuid.UUID) ([]entity.TagCount, error)
+	Batch(ctx context.Context, operations []entity.BatchOperation) error
+}
+
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type ReminderRepository interface {
+	GetRemindedEvents(ctx context.Context, from time.Time, to time.Time) (*entity.Events, error)
+	GetDelivered(ctx context.Context, startedAfter time.Time) ([]entity.ReminderKey, error)
+	MarkDelivered(ctx context.Context, key entity.ReminderKey, deliveredAt time.Time) error
+	DeleteDelivered(ctx context.Context, startedBefore time.Time) error
+}
+
+type CalendarRepository interface {
+	Create(ctx context.Context, calendar *entity.Calendar) error
+	Update(ctx context.Context, calendar *entity.Calendar) error
+	Delete(ctx context.Context, calendarID uuid.UUID) error
+	Get(ctx context.Context, calendarID uuid.UUID) (*entity.Calendar, error)
+	GetForUser(ctx context.Context, userID uuid.UUID) (entity.Calendars, error)
+	GetRole(ctx context.Context, calendarID uuid.UUID, userID uuid.UUID) (entity.Role, error)
+	GetShares(ctx context.Context, calendarID uuid.UUID) ([]entity.Share, error)
+	SetShare(ctx context.Context, share *entity.Share) error
+	DeleteShare(ctx context.Context, calendarID uuid.UUID, userID uuid.UUID) error
+}
+
+type ChangeRepository interface {
+	Append(ctx context.Context, change *entity.Change) error
+	GetAfter(ctx context.Context, afterID int64, calendarIDs []uuid.UUID, limit int) ([]entity.Change, error)
+	GetLastID(ctx context.Context) (int64, error)
+	DeleteBefore(ctx context.Context, changedBefore time.Time) error
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *entity.Webhook) error
+	Delete(ctx context.Context, webhookID uuid.UUID) error
+	Get(ctx context.Context, webhookID uuid.UUID) (*entity.Webhook, error)
+	GetForUser(ctx context.Context, userID uuid.UUID) ([]entity.Webhook, error)
+	GetAll(ctx context.Context) ([]entity.Webhook, error)
+	SetCursor(ctx context.Context, webhookID uuid.UUID, lastChangeID int64) error
+}
+
+type TrashRepository interface {
+	GetAll(ctx context.Context, calendarIDs []uuid.UUID) (*entity.Events, error)
+	Get(ctx context.Context, eventID uuid.UUID) (*entity.Event, error)
+	Restore(ctx context.Context, eventID uuid.UUID) error
+	Purge(ctx context.Context, deletedBefore time.Time) error
+}
+
+type RevisionRepository interface {
+	Append(ctx context.Context, revision *entity.Revision) error
+	GetAll(ctx context.Context, eventID uuid.UUID) ([]entity.Revision, error)
+	Get(ctx context.Context, revisionID int64) (*entity.Revision, error)
 }
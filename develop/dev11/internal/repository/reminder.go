@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"fmt"
+	"time"
+)
+
+type reminderRepository struct {
+	source db.ReminderSource
+}
+
+func NewReminderRepository(source db.ReminderSource) *reminderRepository {
+	return &reminderRepository{
+		source: source,
+	}
+}
+
+func (r *reminderRepository) GetRemindedEvents(ctx context.Context, from time.Time, to time.Time) (*entity.Events, error) {
+	events, err := r.source.GetRemindedEvents(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error in reminderRepository.GetRemindedEvents: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *reminderRepository) GetDelivered(ctx context.Context, startedAfter time.Time) ([]entity.ReminderKey, error) {
+	keys, err := r.source.GetDeliveredReminders(ctx, startedAfter)
+	if err != nil {
+		return nil, fmt.Errorf("error in reminderRepository.GetDelivered: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *reminderRepository) MarkDelivered(ctx context.Context, key entity.ReminderKey, deliveredAt time.Time) error {
+	err := r.source.MarkReminderDelivered(ctx, key, deliveredAt)
+	if err != nil {
+		return fmt.Errorf("error in reminderRepository.MarkDelivered: %w", err)
+	}
+
+	return nil
+}
+
+func (r *reminderRepository) DeleteDelivered(ctx context.Context, startedBefore time.Time) error {
+	err := r.source.DeleteDeliveredReminders(ctx, startedBefore)
+	if err != nil {
+		return fmt.Errorf("error in reminderRepository.DeleteDelivered: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type revisionRepository struct {
+	source db.RevisionSource
+}
+
+func NewRevisionRepository(source db.RevisionSource) *revisionRepository {
+	return &revisionRepository{
+		source: source,
+	}
+}
+
+func (r *revisionRepository) Append(ctx context.Context, revision *entity.Revision) error {
+	err := r.source.AppendRevision(ctx, revision)
+	if err != nil {
+		return fmt.Errorf("error in revisionRepository.Append: %w", err)
+	}
+
+	return nil
+}
+
+func (r *revisionRepository) GetAll(ctx context.Context, eventID uuid.UUID) ([]entity.Revision, error) {
+	revisions, err := r.source.GetRevisions(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("error in revisionRepository.GetAll: %w", err)
+	}
+
+	return revisions, nil
+}
+
+func (r *revisionRepository) Get(ctx context.Context, revisionID int64) (*entity.Revision, error) {
+	revision, err := r.source.GetRevision(ctx, revisionID)
+	if err != nil {
+		return nil, fmt.Errorf("error in revisionRepository.Get: %w", err)
+	}
+
+	return revision, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type trashRepository struct {
+	source db.TrashSource
+}
+
+func NewTrashRepository(source db.TrashSource) *trashRepository {
+	return &trashRepository{
+		source: source,
+	}
+}
+
+func (r *trashRepository) GetAll(ctx context.Context, calendarIDs []uuid.UUID) (*entity.Events, error) {
+	events, err := r.source.GetDeletedEvents(ctx, calendarIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error in trashRepository.GetAll: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *trashRepository) Get(ctx context.Context, eventID uuid.UUID) (*entity.Event, error) {
+	event, err := r.source.GetDeletedEvent(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("error in trashRepository.Get: %w", err)
+	}
+
+	return event, nil
+}
+
+func (r *trashRepository) Restore(ctx context.Context, eventID uuid.UUID) error {
+	err := r.source.RestoreEvent(ctx, eventID)
+	if err != nil {
+		return fmt.Errorf("error in trashRepository.Restore: %w", err)
+	}
+
+	return nil
+}
+
+func (r *trashRepository) Purge(ctx context.Context, deletedBefore time.Time) error {
+	err := r.source.PurgeDeletedEvents(ctx, deletedBefore)
+	if err != nil {
+		return fmt.Errorf("error in trashRepository.Purge: %w", err)
+	}
+
+	return nil
+}
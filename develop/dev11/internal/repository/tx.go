@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"L2/develop/dev11/internal/db"
+	"context"
+	"fmt"
+)
+
+type txManager struct {
+	source db.Transactor
+}
+
+func NewTxManager(source db.Transactor) *txManager {
+	return &txManager{
+		source: source,
+	}
+}
+
+// WithinTx runs fn within one transaction of the source, the repositories of the source called with the context
+// passed to fn take part in it. The error of fn is returned as is, so that it isn't wrapped by every layer twice.
+func (m *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	var fnErr error
+	err := m.source.WithinTx(ctx, func(ctx context.Context) error {
+		fnErr = fn(ctx)
+		return fnErr
+	})
+	if err != nil && err != fnErr {
+		return fmt.Errorf("error in txManager.WithinTx: %w", err)
+	}
+
+	return err
+}
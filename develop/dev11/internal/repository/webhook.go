@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type webhookRepository struct {
+	source db.WebhookSource
+}
+
+func NewWebhookRepository(source db.WebhookSource) *webhookRepository {
+	return &webhookRepository{
+		source: source,
+	}
+}
+
+func (r *webhookRepository) Create(ctx context.Context, webhook *entity.Webhook) error {
+	err := r.source.CreateWebhook(ctx, webhook)
+	if err != nil {
+		return fmt.Errorf("error in webhookRepository.Create: %w", err)
+	}
+
+	return nil
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, webhookID uuid.UUID) error {
+	err := r.source.DeleteWebhook(ctx, webhookID)
+	if err != nil {
+		return fmt.Errorf("error in webhookRepository.Delete: %w", err)
+	}
+
+	return nil
+}
+
+func (r *webhookRepository) Get(ctx context.Context, webhookID uuid.UUID) (*entity.Webhook, error) {
+	webhook, err := r.source.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("error in webhookRepository.Get: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (r *webhookRepository) GetForUser(ctx context.Context, userID uuid.UUID) ([]entity.Webhook, error) {
+	webhooks, err := r.source.GetUserWebhooks(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error in webhookRepository.GetForUser: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (r *webhookRepository) GetAll(ctx context.Context) ([]entity.Webhook, error) {
+	webhooks, err := r.source.GetWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error in webhookRepository.GetAll: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (r *webhookRepository) SetCursor(ctx context.Context, webhookID uuid.UUID, lastChangeID int64) error {
+	err := r.source.SetWebhookCursor(ctx, webhookID, lastChangeID)
+	if err != nil {
+		return fmt.Errorf("error in webhookRepository.SetCursor: %w", err)
+	}
+
+	return nil
+}
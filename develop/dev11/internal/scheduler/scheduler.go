@@ -0,0 +1,174 @@
+// Package scheduler provides the background delivery of reminders of events.
+package scheduler
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/notifier"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Scheduler periodically delivers the due reminders of events through a notifier.
+//
+// Delivery is at-least-once: a reminder is recorded as delivered only after the notifier succeeds,
+// so failed reminders and reminders due while the service was down are delivered on a later check,
+// unless they are older than the lookback.
+type Scheduler struct {
+	repo     repository.ReminderRepository
+	notifier notifier.Notifier
+	interval time.Duration
+	lookback time.Duration
+	logger   *zap.Logger
+	now      func() time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	// abort cancels the delivery in progress when the shutdown runs out of time.
+	abort     chan struct{}
+	abortOnce sync.Once
+	done      chan struct{}
+}
+
+// NewScheduler creates a new scheduler checking for due reminders every interval.
+// Reminders which were due more than lookback ago are dropped.
+func NewScheduler(
+	repo repository.ReminderRepository,
+	notifier notifier.Notifier,
+	interval time.Duration,
+	lookback time.Duration,
+	logger *zap.Logger,
+) *Scheduler {
+	return &Scheduler{
+		repo:     repo,
+		notifier: notifier,
+		interval: interval,
+		lookback: lookback,
+		logger:   logger,
+		now:      time.Now,
+		stop:     make(chan struct{}),
+		abort:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Run delivers reminders until the context is done or the scheduler is shut down.
+func (s *Scheduler) Run(ctx context.Context) error {
+	defer close(s.done)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.abort:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.deliver(ctx)
+		if err != nil {
+			s.logger.Error("can't deliver reminders", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown stops the scheduler and waits for the reminder being delivered. If the context is done first,
+// the delivery is canceled.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stop) })
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		s.abortOnce.Do(func() { close(s.abort) })
+	}
+
+	// The run may have finished together with the context.
+	select {
+	case <-s.done:
+		return nil
+	default:
+		return fmt.Errorf("scheduler shutdown error: %w", ctx.Err())
+	}
+}
+
+// deliver sends the reminders due now which haven't been delivered yet.
+func (s *Scheduler) deliver(ctx context.Context) error {
+	now := s.now()
+	from := now.Add(-s.lookback)
+
+	events, err := s.repo.GetRemindedEvents(ctx, from, now.Add(entity.MaxReminderOffset))
+	if err != nil {
+		return err
+	}
+
+	keys, err := s.repo.GetDelivered(ctx, from)
+	if err != nil {
+		return err
+	}
+	delivered := make(map[entity.ReminderKey]bool, len(keys))
+	for _, key := range keys {
+		delivered[key] = true
+	}
+
+	var due []entity.Reminder
+	for _, event := range *events {
+		reminders, err := event.DueReminders(from, now)
+		if err != nil {
+			s.logger.Warn("can't compute reminders", zap.String("event_id", event.ID.String()), zap.Error(err))
+			continue
+		}
+		for _, reminder := range reminders {
+			if !delivered[reminder.Key()] {
+				due = append(due, reminder)
+			}
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].FireAt().Before(due[j].FireAt()) })
+
+	for i := range due {
+		// The reminders left are delivered after the restart.
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.stop:
+			return nil
+		default:
+		}
+
+		reminder := &due[i]
+		err := s.notifier.Notify(ctx, reminder)
+		if err != nil {
+			s.logger.Warn("can't deliver reminder, will retry",
+				zap.String("event_id", reminder.Event.ID.String()), zap.Error(err))
+			continue
+		}
+
+		err = s.repo.MarkDelivered(ctx, reminder.Key(), now)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Reminders of occurrences starting before the lookback can't be due anymore.
+	return s.repo.DeleteDelivered(ctx, from)
+}
@@ -0,0 +1,161 @@
+package scheduler
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/notifier"
+	"L2/develop/dev11/internal/notifier/smtptest"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestSchedulerDeliversAtLeastOnce(t *testing.T) {
+	ctx := context.Background()
+	server := smtptest.NewServer()
+	defer server.Close()
+
+	source := db.NewMemorySource()
+	event := &entity.Event{
+		ID:        uuid.New(),
+		Title:     "dentist",
+		UserID:    uuid.New(),
+		Start:     time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC),
+		End:       time.Date(2024, time.March, 5, 11, 0, 0, 0, time.UTC),
+		TimeZone:  "UTC",
+		Reminders: entity.Offsets{15 * time.Minute, time.Hour},
+	}
+	if err := source.CreateEvent(ctx, event); err != nil {
+		t.Fatalf("CreateEvent() error = %v", err)
+	}
+
+	newScheduler := func(now time.Time) *Scheduler {
+		smtpNotifier := notifier.NewSMTPNotifier(notifier.SMTPConfig{
+			Addr: server.Addr,
+			From: "calendar@localhost",
+			To:   "{user_id}@localhost",
+		})
+		s := NewScheduler(repository.NewReminderRepository(source), smtpNotifier, time.Minute, time.Hour, zap.NewNop())
+		s.now = func() time.Time { return now }
+		return s
+	}
+
+	// Nothing is due two hours before the event.
+	if err := newScheduler(event.Start.Add(-2 * time.Hour)).deliver(ctx); err != nil {
+		t.Fatalf("deliver() error = %v", err)
+	}
+	if len(server.Messages()) != 0 {
+		t.Fatalf("messages = %d before the reminder is due", len(server.Messages()))
+	}
+
+	// The hour reminder is due, but the server fails: it must be retried.
+	s := newScheduler(event.Start.Add(-50 * time.Minute))
+	server.RejectNext(1)
+	if err := s.deliver(ctx); err != nil {
+		t.Fatalf("deliver() error = %v", err)
+	}
+	if len(server.Messages()) != 0 {
+		t.Fatalf("messages = %d, expected the delivery to fail", len(server.Messages()))
+	}
+	if err := s.deliver(ctx); err != nil {
+		t.Fatalf("deliver() error = %v", err)
+	}
+	messages := server.Messages()
+	if len(messages) != 1 || messages[0].To[0] != event.UserID.String()+"@localhost" ||
+		!strings.Contains(messages[0].Data, "dentist starts at") {
+		t.Fatalf("messages = %+v, expected the hour reminder", messages)
+	}
+
+	// After a restart during which the 15 minute reminder became due, only it is delivered.
+	if err := newScheduler(event.Start.Add(-5 * time.Minute)).deliver(ctx); err != nil {
+		t.Fatalf("deliver() error = %v", err)
+	}
+	if err := newScheduler(event.Start.Add(-4 * time.Minute)).deliver(ctx); err != nil {
+		t.Fatalf("deliver() error = %v", err)
+	}
+	if len(server.Messages()) != 2 {
+		t.Fatalf("messages = %d, expected 2", len(server.Messages()))
+	}
+
+	// Reminders older than the lookback are dropped.
+	if err := newScheduler(event.Start.Add(3 * time.Hour)).deliver(ctx); err != nil {
+		t.Fatalf("deliver() error = %v", err)
+	}
+	if len(server.Messages()) != 2 {
+		t.Fatalf("messages = %d, expected 2", len(server.Messages()))
+	}
+}
+
+func TestSchedulerShutdown(t *testing.T) {
+	s := NewScheduler(
+		repository.NewReminderRepository(db.NewMemorySource()),
+		notifier.NewLogNotifier(zap.NewNop()),
+		time.Hour, time.Hour, zap.NewNop(),
+	)
+
+	errs := make(chan error, 1)
+	go func() { errs <- s.Run(context.Background()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if err := <-errs; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+// blockingNotifier blocks every delivery until its context is done.
+type blockingNotifier struct {
+	started chan struct{}
+	calls   int
+}
+
+func (n *blockingNotifier) Notify(ctx context.Context, reminder *entity.Reminder) error {
+	n.calls++
+	n.started <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestSchedulerShutdownCancelsDelivery(t *testing.T) {
+	source := db.NewMemorySource()
+	start := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	event := &entity.Event{ID: uuid.New(), Title: "dentist", UserID: uuid.New(), Start: start, End: start.Add(time.Hour),
+		TimeZone: "UTC", Reminders: entity.Offsets{15 * time.Minute, time.Hour}}
+	if err := source.CreateEvent(context.Background(), event); err != nil {
+		t.Fatalf("CreateEvent() error = %v", err)
+	}
+
+	blocking := &blockingNotifier{started: make(chan struct{}, 2)}
+	s := NewScheduler(repository.NewReminderRepository(source), blocking, time.Hour, time.Hour, zap.NewNop())
+	s.now = func() time.Time { return start.Add(-10 * time.Minute) }
+
+	errs := make(chan error, 1)
+	go func() { errs <- s.Run(context.Background()) }()
+	<-blocking.started
+
+	// The shutdown runs out of time waiting for the first reminder, which is canceled, the second one isn't sent.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err == nil {
+		t.Fatalf("Shutdown() error = nil, expected the timeout")
+	}
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run() didn't return after the shutdown")
+	}
+	if blocking.calls != 1 {
+		t.Errorf("Notify() calls = %d, expected 1", blocking.calls)
+	}
+}
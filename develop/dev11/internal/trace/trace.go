@@ -0,0 +1,128 @@
+// Package trace carries the request ID and the W3C trace context of a request through context.Context,
+// so that log lines of every layer can be correlated with the request.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Traceparent is the W3C trace context of a request: the trace it belongs to and the span of the caller.
+// See https://www.w3.org/TR/trace-context/#traceparent-header.
+type Traceparent struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+// ParseTraceparent parses a traceparent header of version 00. Later versions are parsed
+// by their first four fields as the specification requires.
+func ParseTraceparent(header string) (Traceparent, error) {
+	var t Traceparent
+
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 {
+		return t, fmt.Errorf("invalid traceparent %q", header)
+	}
+	var version [1]byte
+	err := decodeLower(version[:], parts[0])
+	switch {
+	case err != nil || version[0] == 0xff:
+		return t, fmt.Errorf("invalid traceparent version %q", parts[0])
+	case version[0] == 0 && len(parts) != 4:
+		return t, fmt.Errorf("invalid traceparent %q", header)
+	}
+
+	err = decodeLower(t.TraceID[:], parts[1])
+	if err != nil || t.TraceID == [16]byte{} {
+		return t, fmt.Errorf("invalid trace-id %q", parts[1])
+	}
+	err = decodeLower(t.SpanID[:], parts[2])
+	if err != nil || t.SpanID == [8]byte{} {
+		return t, fmt.Errorf("invalid parent-id %q", parts[2])
+	}
+	var flags [1]byte
+	err = decodeLower(flags[:], parts[3])
+	if err != nil {
+		return t, fmt.Errorf("invalid trace-flags %q", parts[3])
+	}
+	t.Flags = flags[0]
+
+	return t, nil
+}
+
+// decodeLower decodes lowercase hex of exactly len(dst) bytes.
+func decodeLower(dst []byte, s string) error {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return fmt.Errorf("invalid hex %q", s)
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+// New starts a new trace with a random trace ID and span ID, sampled.
+func New() Traceparent {
+	var t Traceparent
+	rand.Read(t.TraceID[:])
+	rand.Read(t.SpanID[:])
+	t.Flags = 1
+	return t
+}
+
+// Child returns the trace context of a span started within t: the same trace with a new span ID.
+func (t Traceparent) Child() Traceparent {
+	child := t
+	rand.Read(child.SpanID[:])
+	return child
+}
+
+// String formats t as a traceparent header of version 00.
+func (t Traceparent) String() string {
+	return fmt.Sprintf("00-%x-%x-%02x", t.TraceID, t.SpanID, t.Flags)
+}
+
+type traceparentKey struct{}
+type requestIDKey struct{}
+
+// ContextWithTraceparent returns a copy of ctx carrying the trace context.
+func ContextWithTraceparent(ctx context.Context, t Traceparent) context.Context {
+	return context.WithValue(ctx, traceparentKey{}, t)
+}
+
+// TraceparentFromContext returns the trace context carried by ctx.
+func TraceparentFromContext(ctx context.Context) (Traceparent, bool) {
+	t, ok := ctx.Value(traceparentKey{}).(Traceparent)
+	return t, ok
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the ID of the request.
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the ID of the request carried by ctx.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey{}).(string)
+	return requestID, ok
+}
+
+// Fields returns the log fields correlating a log line with the request of ctx:
+// request_id, trace_id and span_id. A context without a request gives no fields.
+func Fields(ctx context.Context) []zap.Field {
+	var fields []zap.Field
+	if requestID, ok := RequestIDFromContext(ctx); ok {
+		fields = append(fields, zap.String("request_id", requestID))
+	}
+	if t, ok := TraceparentFromContext(ctx); ok {
+		fields = append(fields,
+			zap.String("trace_id", hex.EncodeToString(t.TraceID[:])),
+			zap.String("span_id", hex.EncodeToString(t.SpanID[:])),
+		)
+	}
+	return fields
+}
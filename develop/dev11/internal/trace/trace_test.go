@@ -0,0 +1,52 @@
+package trace
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	parent, err := ParseTraceparent(header)
+	if err != nil || parent.String() != header {
+		t.Fatalf("ParseTraceparent() = %s, %v", parent, err)
+	}
+
+	child := parent.Child()
+	if child.TraceID != parent.TraceID || child.SpanID == parent.SpanID || child.Flags != parent.Flags {
+		t.Errorf("Child() = %s of %s", child, parent)
+	}
+
+	// A future version may append fields.
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Errorf("ParseTraceparent() of a later version error = %v", err)
+	}
+
+	for _, header := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(header); err == nil {
+			t.Errorf("ParseTraceparent(%q) expected an error", header)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	if fields := Fields(context.Background()); len(fields) != 0 {
+		t.Errorf("Fields() of an empty context = %v", fields)
+	}
+
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ContextWithTraceparent(ContextWithRequestID(context.Background(), "req-1"), parent)
+	fields := Fields(ctx)
+	if len(fields) != 3 || fields[0].String != "req-1" || fields[1].String != "4bf92f3577b34da6a3ce929d0e0e4736" || fields[2].String != "00f067aa0ba902b7" {
+		t.Errorf("Fields() = %v", fields)
+	}
+}
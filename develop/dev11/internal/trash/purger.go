@@ -0,0 +1,90 @@
+// Package trash provides the background purge of deleted events.
+package trash
+
+import (
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Purger periodically deletes the events which have been in the trash longer than the retention for good.
+type Purger struct {
+	repo      repository.TrashRepository
+	interval  time.Duration
+	retention time.Duration
+	logger    *zap.Logger
+	now       func() time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewPurger creates a new purger checking the trash every interval.
+func NewPurger(
+	repo repository.TrashRepository,
+	interval time.Duration,
+	retention time.Duration,
+	logger *zap.Logger,
+) *Purger {
+	return &Purger{
+		repo:      repo,
+		interval:  interval,
+		retention: retention,
+		logger:    logger,
+		now:       time.Now,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Run purges the trash until the context is done or the purger is shut down.
+func (p *Purger) Run(ctx context.Context) error {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		err := p.purge(ctx)
+		if err != nil {
+			p.logger.Error("can't purge trash", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-p.stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown stops the purger and waits for the purge in progress to finish.
+func (p *Purger) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	// The run may have finished together with the context.
+	select {
+	case <-p.done:
+		return nil
+	default:
+		return fmt.Errorf("trash purger shutdown error: %w", ctx.Err())
+	}
+}
+
+// purge deletes the events deleted more than the retention ago.
+func (p *Purger) purge(ctx context.Context) error {
+	return p.repo.Purge(ctx, p.now().Add(-p.retention))
+}
@@ -0,0 +1,53 @@
+package trash
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestPurgerKeepsEventsWithinRetention(t *testing.T) {
+	ctx := context.Background()
+	source := db.NewMemorySource()
+
+	event := &entity.Event{
+		ID:         uuid.New(),
+		Title:      "dentist",
+		CalendarID: uuid.New(),
+		Start:      time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC),
+		End:        time.Date(2024, time.March, 5, 11, 0, 0, 0, time.UTC),
+		TimeZone:   "UTC",
+	}
+	if err := source.CreateEvent(ctx, event); err != nil {
+		t.Fatalf("CreateEvent() error = %v", err)
+	}
+	if err := source.DeleteEvent(ctx, event.ID, 0); err != nil {
+		t.Fatalf("DeleteEvent() error = %v", err)
+	}
+
+	p := NewPurger(repository.NewTrashRepository(source), time.Hour, 24*time.Hour, zap.NewNop())
+
+	// A day hasn't passed yet: the event can still be restored.
+	p.now = func() time.Time { return time.Now().Add(23 * time.Hour) }
+	if err := p.purge(ctx); err != nil {
+		t.Fatalf("purge() error = %v", err)
+	}
+	if _, err := source.GetDeletedEvent(ctx, event.ID); err != nil {
+		t.Fatalf("GetDeletedEvent() within the retention error = %v", err)
+	}
+
+	p.now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+	if err := p.purge(ctx); err != nil {
+		t.Fatalf("purge() error = %v", err)
+	}
+	if _, err := source.GetDeletedEvent(ctx, event.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("GetDeletedEvent() after the retention error = %v, expected ErrNotFound", err)
+	}
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// access checks the roles of the caller in calendars for the interactors.
+type access struct {
+	calendars repository.CalendarRepository
+}
+
+// authorize returns the caller if the caller has at least the required role in the calendar.
+// The default calendar of the caller is created on first use.
+func (a access) authorize(ctx context.Context, calendarID uuid.UUID, required entity.Role) (uuid.UUID, error) {
+	callerID, err := caller(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	role, err := a.calendars.GetRole(ctx, calendarID, callerID)
+	if errors.Is(err, repository.ErrNotFound) && calendarID == callerID {
+		role, err = entity.RoleAdmin, a.ensureDefault(ctx, callerID)
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if !role.Allows(required) {
+		return uuid.Nil, fmt.Errorf("%w: %s access to calendar %s required", ErrForbidden, required, calendarID)
+	}
+
+	return callerID, nil
+}
+
+// visible returns the calendars the caller can see, creating the default calendar of the caller.
+func (a access) visible(ctx context.Context) (entity.Calendars, error) {
+	callerID, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.ensureDefault(ctx, callerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.calendars.GetForUser(ctx, callerID)
+}
+
+// readable returns the given calendars if the caller can read all of them,
+// or every calendar visible to the caller if none are given.
+func (a access) readable(ctx context.Context, calendarIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if len(calendarIDs) == 0 {
+		calendars, err := a.visible(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return calendars.IDs(), nil
+	}
+
+	for _, calendarID := range calendarIDs {
+		_, err := a.authorize(ctx, calendarID, entity.RoleRead)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return calendarIDs, nil
+}
+
+// ensureDefault creates the default calendar of the user unless it exists.
+func (a access) ensureDefault(ctx context.Context, userID uuid.UUID) error {
+	_, err := a.calendars.Get(ctx, userID)
+	if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	err = a.calendars.Create(ctx, entity.NewDefaultCalendar(userID))
+	if err != nil {
+		// A concurrent request may have created it.
+		if _, getErr := a.calendars.Get(ctx, userID); getErr == nil {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// BatchResult is the outcome of an operation of a batch: the events the saved event overlaps
+// when conflicts are flagged or the error of the operation.
+type BatchResult struct {
+	Conflicts entity.Events
+	Err       error
+}
+
+// BatchError is returned when operations of a batch fail, none of the operations is applied then.
+// Results holds the outcome of every operation of the batch.
+type BatchError struct {
+	Results []BatchResult
+}
+
+func (e *BatchError) Error() string {
+	var failures []string
+	for n, result := range e.Results {
+		if result.Err != nil {
+			failures = append(failures, fmt.Sprintf("operation %d: %v", n, result.Err))
+		}
+	}
+	return "batch is not applied: " + strings.Join(failures, "; ")
+}
+
+// Unwrap returns the errors of the failed operations, so that their kinds can be told apart with errors.Is.
+func (e *BatchError) Unwrap() []error {
+	var errs []error
+	for _, result := range e.Results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	return errs
+}
+
+// Batch applies the operations of the caller at once. Every operation is checked like Create, Update
+// and Delete and then all of them are written in one transaction, so either all of them are applied or none.
+// An event is changed by one operation of a batch at most, and occurrences of series are overridden
+// by Update only. Overlaps are checked against the stored events, not the other events of the batch.
+// With dryRun the operations are only checked. If any operation fails, a BatchError is returned.
+func (i *eventInteractor) Batch(ctx context.Context, operations []entity.BatchOperation, dryRun bool) ([]BatchResult, error) {
+	_, err := caller(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Batch: %w", err)
+	}
+
+	results := make([]BatchResult, len(operations))
+	stored := make([]*entity.Event, len(operations))
+	changed := make(map[uuid.UUID]int, len(operations))
+	failed := false
+	for n := range operations {
+		operation := &operations[n]
+		if first, ok := changed[operation.EventID()]; ok {
+			results[n].Err = invalid(fmt.Errorf("event %s is changed by operation %d already", operation.EventID(), first))
+		} else {
+			changed[operation.EventID()] = n
+			stored[n], results[n].Conflicts, results[n].Err = i.prepareOperation(ctx, operation)
+		}
+		failed = failed || results[n].Err != nil
+	}
+	if failed {
+		return nil, fmt.Errorf("error in eventInteractor.Batch: %w", &BatchError{Results: results})
+	}
+	if dryRun {
+		return results, nil
+	}
+
+	// The checks above may be outdated by concurrent writes, then the transaction is rolled back.
+	err = writeChanges(ctx, i.tx, i.changes, i.audit, func(ctx context.Context) ([]eventChange, error) {
+		err := i.repo.Batch(ctx, operations)
+		if err != nil {
+			return nil, err
+		}
+		changes := make([]eventChange, 0, len(operations))
+		for n, operation := range operations {
+			if operation.Op == entity.BatchDelete {
+				changes = append(changes, eventChange{kind: entity.ChangeDeleted, before: stored[n]})
+			} else {
+				changes = append(changes, savedChange(stored[n], operation.Event))
+			}
+		}
+		return changes, nil
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		err = fmt.Errorf("%w: %w", ErrConflict, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Batch: %w", conflict(err))
+	}
+
+	for n, operation := range operations {
+		if operation.Op == entity.BatchDelete {
+			continue
+		}
+		err = i.invite(ctx, stored[n], operation.Event)
+		if err != nil {
+			return nil, fmt.Errorf("error in eventInteractor.Batch: %w", err)
+		}
+	}
+
+	return results, nil
+}
+
+// prepareOperation checks the operation of a batch and completes its event for saving like Create and Update.
+// It returns the stored event the operation changes, nil for a created one, and the events the saved event overlaps.
+func (i *eventInteractor) prepareOperation(ctx context.Context, operation *entity.BatchOperation) (*entity.Event, entity.Events, error) {
+	switch operation.Op {
+	case entity.BatchCreate:
+		// A taken ID would fail the whole transaction, so it is reported beforehand.
+		_, err := i.repo.Get(ctx, operation.Event.ID)
+		if err == nil {
+			return nil, nil, conflict(fmt.Errorf("event %s: %w", operation.Event.ID, repository.ErrAlreadyExists))
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, nil, err
+		}
+		conflicts, err := i.prepareCreate(ctx, operation.Event)
+		return nil, conflicts, err
+	case entity.BatchUpdate:
+		if operation.Event.IsOccurrence() {
+			return nil, nil, invalid(fmt.Errorf("occurrence of event %s can't be overridden in a batch", operation.Event.ID))
+		}
+		stored, err := i.checkUpdate(ctx, operation.Event)
+		if err != nil {
+			return nil, nil, err
+		}
+		conflicts, err := i.prepareUpdate(ctx, stored, operation.Event)
+		return stored, conflicts, err
+	case entity.BatchDelete:
+		stored, err := i.repo.Get(ctx, operation.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		_, err = i.authorize(ctx, stored.CalendarID, entity.RoleWrite)
+		if err != nil {
+			return nil, nil, err
+		}
+		return stored, nil, checkVersion(stored, operation.Version)
+	default:
+		return nil, nil, invalid(fmt.Errorf("unknown op %q", operation.Op))
+	}
+}
@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBatch(t *testing.T) {
+	owner := uuid.New()
+	ctx := ContextWithCaller(context.Background(), owner)
+
+	source := db.NewMemorySource()
+	revisions := repository.NewRevisionRepository(source)
+	events := NewEventInteractor(repository.NewEventRepository(source), repository.NewCalendarRepository(source), ConflictFlag,
+		EventOptions{Audit: NewAudit(revisions), Tx: repository.NewTxManager(source)})
+
+	start := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	newEvent := func(title string, hour int) *entity.Event {
+		return &entity.Event{ID: uuid.New(), Title: title, TimeZone: "UTC",
+			Start: start.Add(time.Duration(hour) * time.Hour), End: start.Add(time.Duration(hour+1) * time.Hour)}
+	}
+	standup, lunch := newEvent("standup", 0), newEvent("lunch", 3)
+	for _, event := range []*entity.Event{standup, lunch} {
+		if _, err := events.Create(ctx, event); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	dayEvents := func() []string {
+		day, err := events.GetForDay(ctx, start)
+		if err != nil {
+			t.Fatalf("GetForDay() error = %v", err)
+		}
+		var titles []string
+		for _, event := range *day {
+			titles = append(titles, event.Title)
+		}
+		return titles
+	}
+
+	overlapping := newEvent("planning", 0)
+	overlapping.Start = start.Add(30 * time.Minute)
+	daily := *standup
+	daily.Title = "daily"
+	batch := func() []entity.BatchOperation {
+		return []entity.BatchOperation{
+			{Op: entity.BatchCreate, Event: overlapping},
+			{Op: entity.BatchUpdate, Event: &daily},
+			{Op: entity.BatchDelete, ID: lunch.ID},
+		}
+	}
+
+	// A dry run reports the conflicts and writes nothing.
+	results, err := events.Batch(ctx, batch(), true)
+	if err != nil {
+		t.Fatalf("Batch() dry run error = %v", err)
+	}
+	if len(results) != 3 || len(results[0].Conflicts) != 1 || results[0].Conflicts[0].ID != standup.ID {
+		t.Errorf("Batch() dry run results = %+v", results)
+	}
+	if got := dayEvents(); len(got) != 2 || got[0] != "standup" || got[1] != "lunch" {
+		t.Errorf("events after dry run = %v", got)
+	}
+
+	// A failed operation refuses the whole batch.
+	operations := append(batch(),
+		entity.BatchOperation{Op: entity.BatchCreate, Event: &entity.Event{ID: uuid.New(), Title: "elsewhere", CalendarID: uuid.New(),
+			TimeZone: "UTC", Start: start, End: start.Add(time.Hour)}},
+		entity.BatchOperation{Op: entity.BatchDelete, ID: standup.ID},
+	)
+	_, err = events.Batch(ctx, operations, false)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || !errors.Is(err, ErrNotFound) || !errors.Is(err, ErrValidation) {
+		t.Fatalf("Batch() error = %v, expected a BatchError with ErrNotFound and ErrValidation", err)
+	}
+	for n, result := range batchErr.Results {
+		if failed := n >= 3; (result.Err != nil) != failed {
+			t.Errorf("Batch() result %d error = %v", n, result.Err)
+		}
+	}
+	if got := dayEvents(); len(got) != 2 || got[0] != "standup" || got[1] != "lunch" {
+		t.Errorf("events after failed batch = %v", got)
+	}
+
+	results, err = events.Batch(ctx, batch(), false)
+	if err != nil {
+		t.Fatalf("Batch() error = %v", err)
+	}
+	if overlapping.Version != 1 || daily.Version != 2 || overlapping.UserID != owner {
+		t.Errorf("events after Batch() = %+v, %+v", overlapping, daily)
+	}
+	if got := dayEvents(); len(got) != 2 || got[0] != "daily" || got[1] != "planning" {
+		t.Errorf("events after batch = %v", got)
+	}
+	// Every operation is in the audit history.
+	for _, eventID := range []uuid.UUID{overlapping.ID, standup.ID, lunch.ID} {
+		list, err := revisions.GetAll(ctx, eventID)
+		if err != nil || len(list) == 0 || list[len(list)-1].ActorID != owner {
+			t.Errorf("revisions of %s = %+v, %v", eventID, list, err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// calendarInteractor manages calendars and their sharing on behalf of the caller of the context.
+// Renaming, deleting and sharing a calendar require the admin role.
+type calendarInteractor struct {
+	access
+}
+
+func NewCalendarInteractor(calendars repository.CalendarRepository) *calendarInteractor {
+	return &calendarInteractor{
+		access: access{calendars: calendars},
+	}
+}
+
+// Create creates a calendar owned by the caller.
+func (i *calendarInteractor) Create(ctx context.Context, calendar *entity.Calendar) error {
+	callerID, err := caller(ctx)
+	if err != nil {
+		return fmt.Errorf("error in calendarInteractor.Create: %w", err)
+	}
+	calendar.OwnerID = callerID
+	calendar.Role = entity.RoleAdmin
+
+	err = i.calendars.Create(ctx, calendar)
+	if err != nil {
+		return fmt.Errorf("error in calendarInteractor.Create: %w", conflict(err))
+	}
+
+	return nil
+}
+
+// Update renames the calendar.
+func (i *calendarInteractor) Update(ctx context.Context, calendar *entity.Calendar) error {
+	_, err := i.authorize(ctx, calendar.ID, entity.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("error in calendarInteractor.Update: %w", err)
+	}
+
+	err = i.calendars.Update(ctx, calendar)
+	if err != nil {
+		return fmt.Errorf("error in calendarInteractor.Update: %w", err)
+	}
+
+	return nil
+}
+
+// Delete deletes the calendar with its events. The default calendar of a user can't be deleted.
+func (i *calendarInteractor) Delete(ctx context.Context, calendarID uuid.UUID) error {
+	_, err := i.authorize(ctx, calendarID, entity.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("error in calendarInteractor.Delete: %w", err)
+	}
+
+	calendar, err := i.calendars.Get(ctx, calendarID)
+	if err != nil {
+		return fmt.Errorf("error in calendarInteractor.Delete: %w", err)
+	}
+	if calendar.IsDefault() {
+		return fmt.Errorf("error in calendarInteractor.Delete: %w: default calendar can't be deleted", ErrForbidden)
+	}
+
+	err = i.calendars.Delete(ctx, calendarID)
+	if err != nil {
+		return fmt.Errorf("error in calendarInteractor.Delete: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the calendars visible to the caller with the role of the caller.
+func (i *calendarInteractor) List(ctx context.Context) (entity.Calendars, error) {
+	calendars, err := i.visible(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error in calendarInteractor.List: %w", err)
+	}
+
+	return calendars, nil
+}
+
+// Share grants the role in the calendar to the user, replacing the role the user had.
+func (i *calendarInteractor) Share(ctx context.Context, share *entity.Share) error {
+	_, err := i.authorize(ctx, share.CalendarID, entity.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("error in calendarInteractor.Share: %w", err)
+	}
+
+	calendar, err := i.calendars.Get(ctx, share.CalendarID)
+	if err != nil {
+		return fmt.Errorf("error in calendarInteractor.Share: %w", err)
+	}
+	if share.UserID == calendar.OwnerID {
+		return fmt.Errorf("error in calendarInteractor.Share: %w: role of the owner can't be changed", ErrForbidden)
+	}
+
+	err = i.calendars.SetShare(ctx, share)
+	if err != nil {
+		return fmt.Errorf("error in calendarInteractor.Share: %w", err)
+	}
+
+	return nil
+}
+
+// Unshare revokes the role of the user in the calendar. Users may leave calendars shared with them.
+func (i *calendarInteractor) Unshare(ctx context.Context, calendarID uuid.UUID, userID uuid.UUID) error {
+	required := entity.RoleAdmin
+	if callerID, ok := CallerFromContext(ctx); ok && callerID == userID {
+		required = entity.RoleRead
+	}
+
+	_, err := i.authorize(ctx, calendarID, required)
+	if err != nil {
+		return fmt.Errorf("error in calendarInteractor.Unshare: %w", err)
+	}
+
+	err = i.calendars.DeleteShare(ctx, calendarID, userID)
+	if err != nil {
+		return fmt.Errorf("error in calendarInteractor.Unshare: %w", err)
+	}
+
+	return nil
+}
+
+// Shares returns the users the calendar is shared with.
+func (i *calendarInteractor) Shares(ctx context.Context, calendarID uuid.UUID) ([]entity.Share, error) {
+	_, err := i.authorize(ctx, calendarID, entity.RoleAdmin)
+	if err != nil {
+		return nil, fmt.Errorf("error in calendarInteractor.Shares: %w", err)
+	}
+
+	shares, err := i.calendars.GetShares(ctx, calendarID)
+	if err != nil {
+		return nil, fmt.Errorf("error in calendarInteractor.Shares: %w", err)
+	}
+
+	return shares, nil
+}
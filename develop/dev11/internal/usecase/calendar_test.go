@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCalendarAccess(t *testing.T) {
+	owner, member, stranger := uuid.New(), uuid.New(), uuid.New()
+	as := func(userID uuid.UUID) context.Context {
+		return ContextWithCaller(context.Background(), userID)
+	}
+	at := func(hour int) time.Time {
+		return time.Date(2024, time.March, 5, hour, 0, 0, 0, time.UTC)
+	}
+	newEvent := func(title string, calendarID uuid.UUID, hour int) *entity.Event {
+		return &entity.Event{
+			ID:         uuid.New(),
+			Title:      title,
+			CalendarID: calendarID,
+			Start:      at(hour),
+			End:        at(hour + 1),
+			TimeZone:   "UTC",
+		}
+	}
+
+	source := db.NewMemorySource()
+	calendarRepo := repository.NewCalendarRepository(source)
+	calendars := NewCalendarInteractor(calendarRepo)
+	events := NewEventInteractor(repository.NewEventRepository(source), calendarRepo, ConflictFlag, EventOptions{})
+
+	work := &entity.Calendar{ID: uuid.New(), Name: "work"}
+	if err := calendars.Create(as(owner), work); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	private := newEvent("private", uuid.Nil, 9)
+	private.Tags = entity.Tags{"personal"}
+	meeting := newEvent("meeting", work.ID, 10)
+	meeting.Tags = entity.Tags{"meeting"}
+	for _, event := range []*entity.Event{private, meeting} {
+		if _, err := events.Create(as(owner), event); err != nil {
+			t.Fatalf("Create(%s) error = %v", event.Title, err)
+		}
+	}
+	if private.CalendarID != owner {
+		t.Errorf("event without a calendar went to %s, expected the default calendar", private.CalendarID)
+	}
+
+	// Nothing is visible before sharing.
+	if _, err := events.Get(as(member), meeting.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Get() before sharing error = %v, expected ErrForbidden", err)
+	}
+	if _, err := events.GetForDay(context.Background(), at(0)); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("GetForDay() without a caller error = %v, expected ErrUnauthenticated", err)
+	}
+
+	err := calendars.Share(as(owner), &entity.Share{CalendarID: work.ID, UserID: member, Role: entity.RoleRead})
+	if err != nil {
+		t.Fatalf("Share() error = %v", err)
+	}
+	own := newEvent("own", uuid.Nil, 12)
+	if _, err := events.Create(as(member), own); err != nil {
+		t.Fatalf("Create() in the default calendar error = %v", err)
+	}
+
+	// The member sees the shared calendar merged with the own one, but can't change it.
+	day, err := events.GetForDay(as(member), at(0))
+	if err != nil || len(*day) != 2 || (*day)[0].Title != "meeting" || (*day)[1].Title != "own" {
+		t.Errorf("GetForDay() of member = %+v, %v", day, err)
+	}
+	if _, err := events.Create(as(member), newEvent("intrusion", work.ID, 14)); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Create() with the read role error = %v, expected ErrForbidden", err)
+	}
+	if err := events.Delete(as(member), meeting.ID, 0); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Delete() with the read role error = %v, expected ErrForbidden", err)
+	}
+	if err := calendars.Share(as(member), &entity.Share{CalendarID: work.ID, UserID: stranger, Role: entity.RoleRead}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Share() with the read role error = %v, expected ErrForbidden", err)
+	}
+
+	// The free/busy time of the owner covers only the calendars shared with the caller.
+	freeBusy, err := events.FreeBusy(as(member), owner, at(0), at(24), 0)
+	if err != nil || len(freeBusy.Busy) != 1 || !freeBusy.Busy[0].Start.Equal(at(10)) {
+		t.Errorf("FreeBusy() of owner for member = %+v, %v", freeBusy, err)
+	}
+	if _, err := events.FreeBusy(as(stranger), owner, at(0), at(24), 0); !errors.Is(err, ErrForbidden) {
+		t.Errorf("FreeBusy() for stranger error = %v, expected ErrForbidden", err)
+	}
+
+	// So do the tags of the owner, the tags are kept by an update without them.
+	update := *meeting
+	update.Tags = nil
+	if _, err := events.Update(as(owner), &update); err != nil {
+		t.Fatalf("Update() without tags error = %v", err)
+	}
+	tags, err := events.GetTags(as(member), owner)
+	if err != nil || len(tags) != 1 || tags[0] != (entity.TagCount{Name: "meeting", Count: 1}) {
+		t.Errorf("GetTags() of owner for member = %+v, %v", tags, err)
+	}
+	if _, err := events.GetTags(as(stranger), owner); !errors.Is(err, ErrForbidden) {
+		t.Errorf("GetTags() for stranger error = %v, expected ErrForbidden", err)
+	}
+	day, err = events.GetForDay(as(member), at(0), "meeting")
+	if err != nil || len(*day) != 1 || (*day)[0].Title != "meeting" {
+		t.Errorf("GetForDay() of member with a tag = %+v, %v", day, err)
+	}
+
+	// With the write role the member may change the events.
+	err = calendars.Share(as(owner), &entity.Share{CalendarID: work.ID, UserID: member, Role: entity.RoleWrite})
+	if err != nil {
+		t.Fatalf("Share() error = %v", err)
+	}
+	if err := events.Delete(as(member), meeting.ID, 0); err != nil {
+		t.Errorf("Delete() with the write role error = %v", err)
+	}
+
+	list, err := calendars.List(as(member))
+	if err != nil || len(list) != 2 || list[1].ID != work.ID || list[1].Role != entity.RoleWrite {
+		t.Errorf("List() = %+v, %v", list, err)
+	}
+	if err := calendars.Delete(as(owner), owner); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Delete() of the default calendar error = %v, expected ErrForbidden", err)
+	}
+	if err := calendars.Unshare(as(member), work.ID, member); err != nil {
+		t.Errorf("Unshare() of oneself error = %v", err)
+	}
+	if err := calendars.Delete(as(owner), work.ID); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+}
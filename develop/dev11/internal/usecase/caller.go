@@ -0,0 +1,37 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUnauthenticated is returned when the context carries no caller.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is returned when the caller's role doesn't allow the operation.
+	ErrForbidden = errors.New("forbidden")
+)
+
+type callerKey struct{}
+
+// ContextWithCaller returns a copy of ctx carrying the ID of the user performing the operation.
+func ContextWithCaller(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, callerKey{}, userID)
+}
+
+// CallerFromContext returns the ID of the user performing the operation.
+func CallerFromContext(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(callerKey{}).(uuid.UUID)
+	return userID, ok && userID != uuid.Nil
+}
+
+// caller returns the caller of the context or ErrUnauthenticated.
+func caller(ctx context.Context) (uuid.UUID, error) {
+	userID, ok := CallerFromContext(ctx)
+	if !ok {
+		return uuid.Nil, ErrUnauthenticated
+	}
+	return userID, nil
+}
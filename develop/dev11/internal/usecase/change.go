@@ -0,0 +1,256 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// subscriptionBuffer is how many changes a subscriber may fall behind before it is dropped.
+	subscriptionBuffer = 256
+	// replayPage is how many changes of the log are read at once when a stream resumes.
+	replayPage = 100
+	// visibilityTTL is how long a stream trusts the calendars the caller could see,
+	// so that sharing and unsharing reach open streams.
+	visibilityTTL = 30 * time.Second
+)
+
+// ChangeFeed is the bus of the changes of events: the interactors record every change in the change log
+// and publish it to the subscribers. A nil ChangeFeed records nothing.
+type ChangeFeed struct {
+	repo repository.ChangeRepository
+	now  func() time.Time
+
+	// appendMu keeps the changes published in the order of their IDs.
+	appendMu sync.Mutex
+
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewChangeFeed creates a change feed recording the changes in the change log of the repository.
+func NewChangeFeed(repo repository.ChangeRepository) *ChangeFeed {
+	return &ChangeFeed{
+		repo:        repo,
+		now:         time.Now,
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+// Subscription receives the changes published after it was made. C is closed when the subscription
+// is closed, when the subscriber falls behind by more than subscriptionBuffer changes and when the feed closes.
+type Subscription struct {
+	C <-chan entity.Change
+
+	ch   chan entity.Change
+	feed *ChangeFeed
+}
+
+// Subscribe subscribes to the changes published from now on.
+func (f *ChangeFeed) Subscribe() *Subscription {
+	ch := make(chan entity.Change, subscriptionBuffer)
+	s := &Subscription{C: ch, ch: ch, feed: f}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		close(ch)
+		return s
+	}
+	f.subscribers[s] = struct{}{}
+	return s
+}
+
+// Close unsubscribes, closing C.
+func (s *Subscription) Close() {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+
+	s.feed.drop(s)
+}
+
+// drop closes the subscription. It must be called with the lock held.
+func (f *ChangeFeed) drop(s *Subscription) {
+	if _, ok := f.subscribers[s]; ok {
+		delete(f.subscribers, s)
+		close(s.ch)
+	}
+}
+
+// Close closes every subscription and refuses new ones, so that the streams end on shutdown.
+func (f *ChangeFeed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	for s := range f.subscribers {
+		f.drop(s)
+	}
+}
+
+// record appends the change of the event made by the caller to the change log and publishes it.
+func (f *ChangeFeed) record(ctx context.Context, kind entity.ChangeKind, event *entity.Event) error {
+	if f == nil {
+		return nil
+	}
+	userID, _ := CallerFromContext(ctx)
+	change := entity.NewChange(kind, event, userID, f.now())
+
+	f.appendMu.Lock()
+	defer f.appendMu.Unlock()
+
+	err := f.repo.Append(ctx, change)
+	if err != nil {
+		return err
+	}
+	f.publish(*change)
+
+	return nil
+}
+
+// publish sends the change to every subscriber, dropping the ones which fell behind.
+func (f *ChangeFeed) publish(change entity.Change) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for s := range f.subscribers {
+		select {
+		case s.ch <- change:
+		default:
+			f.drop(s)
+		}
+	}
+}
+
+// ChangeStream delivers the changes of the events a caller can read on C, oldest first.
+// C is closed when the stream ends: Err then tells why, nil if the context is done or the feed closed.
+type ChangeStream struct {
+	C <-chan entity.Change
+
+	err error
+}
+
+// Err returns the error which ended the stream. It must be called after C is closed.
+func (s *ChangeStream) Err() error {
+	return s.err
+}
+
+// changeInteractor streams the changes of events to their readers.
+type changeInteractor struct {
+	access
+	repo repository.ChangeRepository
+	feed *ChangeFeed
+}
+
+func NewChangeInteractor(
+	repo repository.ChangeRepository,
+	calendars repository.CalendarRepository,
+	feed *ChangeFeed,
+) *changeInteractor {
+	return &changeInteractor{
+		access: access{calendars: calendars},
+		repo:   repo,
+		feed:   feed,
+	}
+}
+
+// Stream streams the changes of the events the caller can read made after the change afterID:
+// first the ones of the change log and then the ones published by the feed, until ctx is done.
+// A zero afterID streams the new changes only.
+func (i *changeInteractor) Stream(ctx context.Context, afterID int64) (*ChangeStream, error) {
+	if i.feed == nil {
+		return nil, fmt.Errorf("error in changeInteractor.Stream: %w: change feed is disabled", ErrBusinessRule)
+	}
+	calendars, err := i.visible(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error in changeInteractor.Stream: %w", err)
+	}
+	// Subscribing before reading the log loses no change in between, the duplicates are skipped by ID.
+	subscription := i.feed.Subscribe()
+
+	ch := make(chan entity.Change)
+	stream := &ChangeStream{C: ch}
+	go func() {
+		defer close(ch)
+		defer subscription.Close()
+
+		stream.err = i.stream(ctx, afterID, calendars, subscription, ch)
+	}()
+
+	return stream, nil
+}
+
+func (i *changeInteractor) stream(
+	ctx context.Context,
+	afterID int64,
+	calendars entity.Calendars,
+	subscription *Subscription,
+	ch chan<- entity.Change,
+) error {
+	send := func(change entity.Change) bool {
+		select {
+		case ch <- change:
+			afterID = change.ID
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for afterID > 0 {
+		changes, err := i.repo.GetAfter(ctx, afterID, calendars.IDs(), replayPage)
+		if err != nil {
+			return fmt.Errorf("error in changeInteractor.Stream: %w", err)
+		}
+		for _, change := range changes {
+			if !send(change) {
+				return nil
+			}
+		}
+		if len(changes) < replayPage {
+			break
+		}
+	}
+
+	readable := visibleSet(calendars)
+	checked := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case change, ok := <-subscription.C:
+			if !ok {
+				return nil
+			}
+			if change.ID <= afterID {
+				continue
+			}
+			if time.Since(checked) > visibilityTTL {
+				calendars, err := i.visible(ctx)
+				if err != nil {
+					return fmt.Errorf("error in changeInteractor.Stream: %w", err)
+				}
+				readable, checked = visibleSet(calendars), time.Now()
+			}
+			if readable[change.CalendarID] && !send(change) {
+				return nil
+			}
+		}
+	}
+}
+
+func visibleSet(calendars entity.Calendars) map[uuid.UUID]bool {
+	set := make(map[uuid.UUID]bool, len(calendars))
+	for _, calendar := range calendars {
+		set[calendar.ID] = true
+	}
+	return set
+}
@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestChangeStream(t *testing.T) {
+	owner, member := uuid.New(), uuid.New()
+	as := func(userID uuid.UUID) context.Context {
+		return ContextWithCaller(context.Background(), userID)
+	}
+
+	source := db.NewMemorySource()
+	calendarRepo := repository.NewCalendarRepository(source)
+	changeRepo := repository.NewChangeRepository(source)
+	feed := NewChangeFeed(changeRepo)
+	events := NewEventInteractor(repository.NewEventRepository(source), calendarRepo, ConflictFlag, EventOptions{Changes: feed})
+	changes := NewChangeInteractor(changeRepo, calendarRepo, feed)
+
+	event := &entity.Event{
+		ID:       uuid.New(),
+		Title:    "standup",
+		Start:    time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC),
+		End:      time.Date(2024, time.March, 5, 11, 0, 0, 0, time.UTC),
+		TimeZone: "UTC",
+	}
+	if _, err := events.Create(as(owner), event); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	event.Title = "daily standup"
+	if _, err := events.Update(as(owner), event); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	// The stream resumes after the first change and stays open for the next ones.
+	stream, err := changes.Stream(as(owner), 1)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	memberStream, err := changes.Stream(as(member), 0)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if err := events.Delete(as(owner), event.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	var got []string
+	for len(got) < 2 {
+		select {
+		case change := <-stream.C:
+			got = append(got, fmt.Sprintf("%d %s by %s", change.ID, change.Kind, change.UserID))
+			if change.Kind == entity.ChangeDeleted && change.Event != nil {
+				t.Errorf("deleted change carries the event %v", change.Event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("streamed %v, timed out waiting for more", got)
+		}
+	}
+	expected := fmt.Sprintf("2 updated by %s,3 deleted by %s", owner, owner)
+	if strings.Join(got, ",") != expected {
+		t.Errorf("streamed %v, expected %s", got, expected)
+	}
+
+	// Closing the feed ends the streams, the member got none of the changes of the owner.
+	feed.Close()
+	for change := range memberStream.C {
+		t.Errorf("member streamed %+v", change)
+	}
+	if _, ok := <-stream.C; ok || stream.Err() != nil {
+		t.Errorf("stream after Close is open or failed: %v", stream.Err())
+	}
+}
+
+func TestChangeFeedDropsSlowSubscribers(t *testing.T) {
+	feed := NewChangeFeed(repository.NewChangeRepository(db.NewMemorySource()))
+	slow, fast := feed.Subscribe(), feed.Subscribe()
+
+	event := &entity.Event{ID: uuid.New(), CalendarID: uuid.New()}
+	for i := 0; i <= subscriptionBuffer; i++ {
+		if err := feed.record(context.Background(), entity.ChangeUpdated, event); err != nil {
+			t.Fatalf("record() error = %v", err)
+		}
+		<-fast.C
+	}
+
+	received := 0
+	for range slow.C {
+		received++
+	}
+	if received != subscriptionBuffer {
+		t.Errorf("slow subscriber received %d changes before it was dropped, expected %d", received, subscriptionBuffer)
+	}
+	fast.Close()
+	if _, ok := <-fast.C; ok {
+		t.Error("subscription is open after Close")
+	}
+}
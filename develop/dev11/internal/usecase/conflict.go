@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConflictMode defines how overlapping events of the same calendar are handled on create and update.
+type ConflictMode string
+
+const (
+	// ConflictFlag saves the event and reports the events it overlaps.
+	ConflictFlag ConflictMode = "flag"
+	// ConflictReject refuses to save an event overlapping other events with a ConflictError.
+	ConflictReject ConflictMode = "reject"
+)
+
+// conflictHorizon limits how far ahead occurrences of an endless series are checked for conflicts.
+const conflictHorizon = 366 * 24 * time.Hour
+
+// ConflictError is returned in the reject mode when the event overlaps other events of its calendar.
+type ConflictError struct {
+	Conflicts entity.Events
+}
+
+func (e *ConflictError) Error() string {
+	titles := make([]string, 0, len(e.Conflicts))
+	for _, event := range e.Conflicts {
+		titles = append(titles, fmt.Sprintf("%q at %s", event.Title, event.Start.Format(time.RFC3339)))
+	}
+	return "event overlaps " + strings.Join(titles, ", ")
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// conflicts returns the occurrences of other events of the calendar overlapping any occurrence of the event.
+// All-day events neither conflict nor are conflicted with, and a series never conflicts with its overrides.
+func (i *eventInteractor) conflicts(ctx context.Context, event *entity.Event) (entity.Events, error) {
+	if event.AllDay {
+		return nil, nil
+	}
+
+	to := event.End
+	if event.IsRecurring() {
+		to = event.Start.Add(conflictHorizon)
+	}
+	candidates, err := event.Occurrences(event.Start, to)
+	if err != nil || len(candidates) == 0 {
+		return nil, err
+	}
+
+	existing, err := i.repo.GetInWindow(ctx, []uuid.UUID{event.CalendarID}, candidates[0].Start, candidates[len(candidates)-1].End)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts entity.Events
+	for _, other := range *existing {
+		if other.AllDay || seriesID(&other) == seriesID(event) {
+			continue
+		}
+		for _, candidate := range candidates {
+			if other.Overlaps(candidate.Start, candidate.End) {
+				conflicts.Add(other)
+				break
+			}
+		}
+	}
+
+	return conflicts, nil
+}
+
+// checkConflicts finds the conflicts of the event and turns them into an error in the reject mode.
+func (i *eventInteractor) checkConflicts(ctx context.Context, event *entity.Event) (entity.Events, error) {
+	conflicts, err := i.conflicts(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 && i.conflictMode == ConflictReject {
+		return nil, &ConflictError{Conflicts: conflicts}
+	}
+
+	return conflicts, nil
+}
+
+// seriesID returns the ID of the series the event belongs to, which is its own ID for masters and single events.
+func seriesID(event *entity.Event) uuid.UUID {
+	if event.SeriesID != nil {
+		return *event.SeriesID
+	}
+	return event.ID
+}
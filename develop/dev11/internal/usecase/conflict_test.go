@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestConflicts(t *testing.T) {
+	userID := uuid.New()
+	ctx := ContextWithCaller(context.Background(), userID)
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, time.March, day, hour, 0, 0, 0, time.UTC)
+	}
+	newEvent := func(title string, start time.Time, rrule string) *entity.Event {
+		return &entity.Event{
+			ID:       uuid.New(),
+			Title:    title,
+			UserID:   userID,
+			Start:    start,
+			End:      start.Add(time.Hour),
+			TimeZone: "UTC",
+			RRule:    rrule,
+		}
+	}
+
+	source := db.NewMemorySource()
+	repo := repository.NewEventRepository(source)
+	calendars := repository.NewCalendarRepository(source)
+	reject := NewEventInteractor(repo, calendars, ConflictReject, EventOptions{})
+	flag := NewEventInteractor(repo, calendars, ConflictFlag, EventOptions{})
+
+	// Mondays at 10:00.
+	series := newEvent("weekly", at(4, 10), "FREQ=WEEKLY")
+	if _, err := reject.Create(ctx, series); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Another user's calendar and all-day events are never in the way.
+	other := newEvent("other user", at(11, 10), "")
+	if _, err := reject.Create(ContextWithCaller(context.Background(), uuid.New()), other); err != nil {
+		t.Errorf("Create(%s) error = %v", other.Title, err)
+	}
+	holiday := newEvent("holiday", at(11, 0), "")
+	holiday.AllDay = true
+	holiday.End = at(12, 0)
+	if _, err := reject.Create(ctx, holiday); err != nil {
+		t.Errorf("Create(%s) error = %v", holiday.Title, err)
+	}
+
+	// A single event on the third Monday overlaps the series.
+	clash := newEvent("clash", at(18, 10), "")
+	_, err := reject.Create(ctx, clash)
+	var conflictErr *ConflictError
+	if !errors.As(err, &conflictErr) || len(conflictErr.Conflicts) != 1 || !conflictErr.Conflicts[0].Start.Equal(at(18, 10)) {
+		t.Fatalf("Create() in reject mode error = %v, expected a conflict with the occurrence on the 18th", err)
+	}
+	if _, err := repo.Get(ctx, clash.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("rejected event was saved: %v", err)
+	}
+
+	conflicts, err := flag.Create(ctx, clash)
+	if err != nil || len(conflicts) != 1 {
+		t.Fatalf("Create() in flag mode = %v, %v, expected one conflict", conflicts, err)
+	}
+
+	// Moving an occurrence of the series away from the clash doesn't conflict with the series itself.
+	recurrenceID := at(18, 0)
+	moved := newEvent("weekly", at(18, 15), "")
+	moved.ID = series.ID
+	moved.RecurrenceID = &recurrenceID
+	if _, err := reject.Update(ctx, moved); err != nil {
+		t.Errorf("Update() of occurrence error = %v", err)
+	}
+
+	// Moving the clash onto the series conflicts with every following Monday.
+	clash.Start, clash.End = at(25, 10), at(25, 11)
+	clash.RRule = "FREQ=WEEKLY;COUNT=3"
+	conflicts, err = flag.Update(ctx, clash)
+	if err != nil || len(conflicts) != 3 {
+		t.Errorf("Update() in flag mode = %d conflicts, %v, expected 3", len(conflicts), err)
+	}
+}
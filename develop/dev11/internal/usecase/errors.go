@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/repository"
+	"errors"
+	"fmt"
+)
+
+// Errors of the interactors, told apart with errors.Is through any wrapping.
+// ErrUnauthenticated and ErrForbidden are the access errors.
+var (
+	// ErrNotFound is returned when the requested event or calendar doesn't exist.
+	ErrNotFound = repository.ErrNotFound
+	// ErrValidation is returned for an event or a query that is invalid by itself.
+	ErrValidation = errors.New("invalid input")
+	// ErrConflict is returned when a write clashes with the stored data: the ID is taken,
+	// the event overlaps other events in the reject mode or was changed concurrently.
+	ErrConflict = errors.New("conflict")
+	// ErrBusinessRule is returned when the stored data doesn't allow the operation,
+	// like overriding an occurrence the series doesn't have.
+	ErrBusinessRule = errors.New("business rule violated")
+)
+
+// invalid marks err as ErrValidation.
+func invalid(err error) error {
+	return fmt.Errorf("%w: %w", ErrValidation, err)
+}
+
+// conflict marks a taken ID of the repository as ErrConflict, other errors are returned as is.
+func conflict(err error) error {
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	}
+	return err
+}
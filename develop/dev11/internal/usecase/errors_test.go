@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestErrorKinds(t *testing.T) {
+	ctx := ContextWithCaller(context.Background(), uuid.New())
+	start := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	newEvent := func() *entity.Event {
+		return &entity.Event{ID: uuid.New(), Title: "planning", Start: start, End: start.Add(time.Hour), TimeZone: "UTC"}
+	}
+
+	source := db.NewMemorySource()
+	events := NewEventInteractor(repository.NewEventRepository(source), repository.NewCalendarRepository(source), ConflictReject, EventOptions{})
+
+	event := newEvent()
+	if _, err := events.Create(ctx, event); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	invalid := newEvent()
+	invalid.End = invalid.Start
+	occurrence := newEvent()
+	occurrence.ID = event.ID
+	occurrence.RecurrenceID = &start
+	overlap := newEvent()
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"invalid event", second(events.Create(ctx, invalid)), ErrValidation},
+		{"taken ID", second(events.Create(ctx, event)), ErrConflict},
+		{"overlap in reject mode", second(events.Create(ctx, overlap)), ErrConflict},
+		{"occurrence of a single event", second(events.Update(ctx, occurrence)), ErrBusinessRule},
+		{"update of unknown event", second(events.Update(ctx, newEvent())), ErrNotFound},
+		{"delete of unknown event", events.Delete(ctx, uuid.New(), 0), ErrNotFound},
+		{"search without window", second(events.Search(ctx, &entity.EventQuery{Sort: entity.SortStart, Limit: 1})), ErrValidation},
+		{"without caller", second(events.GetForDay(context.Background(), start)), ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.kind) {
+			t.Errorf("%s: error = %v, expected %v", tt.name, tt.err, tt.kind)
+		}
+	}
+}
+
+// second returns the error of a call returning a value and an error.
+func second[T any](_ T, err error) error {
+	return err
+}
@@ -4,70 +4,647 @@ import (
 	"L2/develop/dev11/internal/entity"
 	"L2/develop/dev11/internal/repository"
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// eventInteractor manages events on behalf of the caller of the context.
+// Reading events requires the read role in their calendar, changing them the write role.
 type eventInteractor struct {
-	repo repository.EventRepository
+	access
+	repo         repository.EventRepository
+	conflictMode ConflictMode
+	changes      *ChangeFeed
+	audit        *Audit
+	invitations  *Invitations
+	tx           repository.TxManager
 }
 
-func NewEventInteractor(repo repository.EventRepository) *eventInteractor {
-	return &eventInteractor{repo: repo}
+// EventOptions are the optional collaborators of the event interactor.
+type EventOptions struct {
+	// Changes records the changes of events and publishes them to the streams, nil disables both.
+	Changes *ChangeFeed
+	// Audit records the revisions of events, nil records none.
+	Audit *Audit
+	// Invitations invites the attendees added to events, nil invites none.
+	Invitations *Invitations
+	// Tx writes the events together with their revisions within one transaction, nil writes them without transactions.
+	Tx repository.TxManager
 }
 
-func (i *eventInteractor) Create(ctx context.Context, event *entity.Event) error {
-	err := i.repo.Create(ctx, event)
+// NewEventInteractor creates the event interactor.
+func NewEventInteractor(
+	repo repository.EventRepository,
+	calendars repository.CalendarRepository,
+	conflictMode ConflictMode,
+	options EventOptions,
+) *eventInteractor {
+	return &eventInteractor{
+		access:       access{calendars: calendars},
+		repo:         repo,
+		conflictMode: conflictMode,
+		changes:      options.Changes,
+		audit:        options.Audit,
+		invitations:  options.Invitations,
+		tx:           options.Tx,
+	}
+}
+
+// Create saves the event created by the caller and returns the events it overlaps.
+// An event without a calendar goes to the default calendar of the caller.
+// In the reject mode an overlapping event isn't saved and a ConflictError is returned.
+// The caller is the organizer of the event, every attendee is invited.
+func (i *eventInteractor) Create(ctx context.Context, event *entity.Event) (entity.Events, error) {
+	conflicts, err := i.prepareCreate(ctx, event)
 	if err != nil {
-		return fmt.Errorf("error in eventInteractor.Create: %w", err)
+		return nil, fmt.Errorf("error in eventInteractor.Create: %w", err)
 	}
 
-	return nil
+	err = writeChanges(ctx, i.tx, i.changes, i.audit, func(ctx context.Context) ([]eventChange, error) {
+		return []eventChange{savedChange(nil, event)}, i.repo.Create(ctx, event)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Create: %w", conflict(err))
+	}
+	err = i.invite(ctx, nil, event)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Create: %w", err)
+	}
+
+	return conflicts, nil
 }
 
-func (i *eventInteractor) Update(ctx context.Context, event *entity.Event) error {
-	err := i.repo.Update(ctx, event)
+// prepareCreate checks the event created by the caller, completes it for saving and returns the events it overlaps.
+func (i *eventInteractor) prepareCreate(ctx context.Context, event *entity.Event) (entity.Events, error) {
+	err := event.Validate()
+	if err != nil {
+		return nil, invalid(err)
+	}
+	callerID, err := caller(ctx)
 	if err != nil {
-		return fmt.Errorf("error in eventInteractor.Update: %w", err)
+		return nil, err
+	}
+	if event.CalendarID == uuid.Nil {
+		event.CalendarID = callerID
+	}
+	_, err = i.authorize(ctx, event.CalendarID, entity.RoleWrite)
+	if err != nil {
+		return nil, err
+	}
+	event.UserID = callerID
+	event.Attendees = event.Attendees.Merge(nil, true)
+	if event.Attendees.Invites(callerID) {
+		return nil, invalid(fmt.Errorf("organizer can't be an attendee"))
 	}
 
-	return nil
+	return i.checkConflicts(ctx, event)
+}
+
+// Update saves the changes of the event and returns the events it overlaps, like Create.
+// A non-zero event.Version is the version the changes are based on: if the stored event
+// has another one, a VersionConflictError is returned. On success event.Version is the new version.
+// Missing attendees are kept. The statuses are kept too unless the event is rescheduled,
+// then every attendee is invited again like the added ones.
+func (i *eventInteractor) Update(ctx context.Context, event *entity.Event) (entity.Events, error) {
+	stored, err := i.checkUpdate(ctx, event)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Update: %w", err)
+	}
+
+	switch {
+	case event.IsOccurrence() && stored.IsRecurring():
+		conflicts, err := i.overrideOccurrence(ctx, stored, event)
+		if err != nil {
+			return nil, fmt.Errorf("error in eventInteractor.Update: %w", i.versionConflict(ctx, event.ID, err))
+		}
+		event.Version = stored.Version
+		return conflicts, nil
+	case event.IsOccurrence() && stored.SeriesID == nil:
+		return nil, fmt.Errorf("error in eventInteractor.Update: %w: event %s is not recurring", ErrBusinessRule, event.ID)
+	}
+
+	conflicts, err := i.prepareUpdate(ctx, stored, event)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Update: %w", err)
+	}
+
+	err = writeChanges(ctx, i.tx, i.changes, i.audit, func(ctx context.Context) ([]eventChange, error) {
+		return []eventChange{savedChange(stored, event)}, i.repo.Update(ctx, event)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Update: %w", i.versionConflict(ctx, event.ID, err))
+	}
+	err = i.invite(ctx, stored, event)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Update: %w", err)
+	}
+
+	return conflicts, nil
+}
+
+// checkUpdate checks the changes of the event and that the caller can write them and returns the stored event.
+func (i *eventInteractor) checkUpdate(ctx context.Context, event *entity.Event) (*entity.Event, error) {
+	err := event.Validate()
+	if err != nil {
+		return nil, invalid(err)
+	}
+	stored, err := i.repo.Get(ctx, event.ID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = i.authorize(ctx, stored.CalendarID, entity.RoleWrite)
+	if err != nil {
+		return nil, err
+	}
+	err = checkVersion(stored, event.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+// prepareUpdate completes the changes of the stored event for saving and returns the events the event overlaps.
+func (i *eventInteractor) prepareUpdate(ctx context.Context, stored *entity.Event, event *entity.Event) (entity.Events, error) {
+	if stored.SeriesID != nil && event.IsRecurring() {
+		return nil, fmt.Errorf("%w: overridden occurrence %s can't recur", ErrBusinessRule, event.ID)
+	}
+
+	if event.ExDates == nil {
+		event.ExDates = stored.ExDates
+	}
+	if event.Reminders == nil {
+		event.Reminders = stored.Reminders
+	}
+	if event.Tags == nil {
+		event.Tags = stored.Tags
+	}
+	event.KeepMissingDetails(stored)
+	if event.Attendees == nil {
+		event.Attendees = stored.Attendees
+	}
+	event.Attendees = event.Attendees.Merge(stored.Attendees, isRescheduled(stored, event))
+	if event.Attendees.Invites(stored.UserID) {
+		return nil, invalid(fmt.Errorf("organizer can't be an attendee"))
+	}
+	// The creator, the calendar and the series of an event never change.
+	event.UserID = stored.UserID
+	event.CalendarID = stored.CalendarID
+	event.SeriesID = stored.SeriesID
+	// The write fails if the event changes after it was read.
+	event.Version = stored.Version
+
+	return i.checkConflicts(ctx, event)
+}
+
+// isRescheduled reports whether the update moves the event or changes its recurrence.
+func isRescheduled(stored *entity.Event, event *entity.Event) bool {
+	return !stored.Start.Equal(event.Start) || !stored.End.Equal(event.End) || stored.AllDay != event.AllDay ||
+		stored.TimeZone != event.TimeZone || stored.RRule != event.RRule
+}
+
+// overrideOccurrence stores the changes of a single occurrence of the series as a separate event
+// and excludes the original occurrence from the series within one transaction. An existing override is updated.
+func (i *eventInteractor) overrideOccurrence(ctx context.Context, master *entity.Event, changes *entity.Event) (entity.Events, error) {
+	override, conflicts, err := i.prepareOverride(ctx, master, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *master
+	before.ExDates = append(entity.Dates(nil), master.ExDates...)
+	err = writeChanges(ctx, i.tx, i.changes, i.audit, func(ctx context.Context) ([]eventChange, error) {
+		existing, err := i.saveOverride(ctx, override)
+		if err != nil {
+			return nil, err
+		}
+
+		master.ExDates.Add(*override.RecurrenceID)
+		err = i.repo.Update(ctx, master)
+		if err != nil {
+			return nil, err
+		}
+
+		return []eventChange{savedChange(existing, override), savedChange(&before, master)}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = i.invitations.invite(ctx, override, override.Attendees.Added(master.Attendees))
+	if err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+// prepareOverride checks the changes of an occurrence of the series and returns the override storing them
+// and the events the override overlaps.
+func (i *eventInteractor) prepareOverride(
+	ctx context.Context,
+	master *entity.Event,
+	changes *entity.Event,
+) (*entity.Event, entity.Events, error) {
+	recurrenceID := *changes.RecurrenceID
+
+	// The occurrence may already be excluded because it is overridden.
+	series := *master
+	series.ExDates = nil
+	if _, ok := series.OccurrenceOn(recurrenceID); !ok {
+		return nil, nil, fmt.Errorf("%w: series %s has no occurrence on %s", ErrBusinessRule, master.ID, recurrenceID.Format("2006-01-02"))
+	}
+
+	override := *changes
+	override.ID = master.OverrideID(recurrenceID)
+	override.UserID = master.UserID
+	override.CalendarID = master.CalendarID
+	override.SeriesID = &master.ID
+	override.RRule = ""
+	override.ExDates = nil
+	if override.Reminders == nil {
+		override.Reminders = master.Reminders
+	}
+	if override.Tags == nil {
+		override.Tags = master.Tags
+	}
+	override.KeepMissingDetails(master)
+	// The attendees of the series answer for the override too, the attendees added to it are invited.
+	if override.Attendees == nil {
+		override.Attendees = master.Attendees
+	}
+	override.Attendees = override.Attendees.Merge(master.Attendees, false)
+	if override.Attendees.Invites(master.UserID) {
+		return nil, nil, invalid(fmt.Errorf("organizer can't be an attendee"))
+	}
+
+	conflicts, err := i.checkConflicts(ctx, &override)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &override, conflicts, nil
+}
+
+// saveOverride creates the override or updates the existing one and returns the existing override, nil if it is created.
+func (i *eventInteractor) saveOverride(ctx context.Context, override *entity.Event) (*entity.Event, error) {
+	existing, err := i.repo.Get(ctx, override.ID)
+	switch {
+	case err == nil:
+		override.Version = existing.Version
+		return existing, i.repo.Update(ctx, override)
+	case errors.Is(err, repository.ErrNotFound):
+		return nil, i.repo.Create(ctx, override)
+	default:
+		return nil, err
+	}
 }
 
-func (i *eventInteractor) Delete(ctx context.Context, eventID uuid.UUID) error {
-	err := i.repo.Delete(ctx, eventID)
+// Save creates the event or replaces the stored one together with the occurrences of the series overriding it,
+// checking them like Create, Update and Update of an occurrence. Either the series and all of the overrides
+// are saved within one transaction or none of them. It returns the events the series overlaps.
+func (i *eventInteractor) Save(ctx context.Context, event *entity.Event, overrides entity.Events) (entity.Events, error) {
+	if event.IsOccurrence() {
+		return nil, fmt.Errorf("error in eventInteractor.Save: %w", invalid(fmt.Errorf("event %s is an occurrence", event.ID)))
+	}
+
+	stored, err := i.repo.Get(ctx, event.ID)
+	var conflicts entity.Events
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		stored = nil
+		conflicts, err = i.prepareCreate(ctx, event)
+	case err == nil:
+		stored, err = i.checkUpdate(ctx, event)
+		if err == nil {
+			conflicts, err = i.prepareUpdate(ctx, stored, event)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Save: %w", err)
+	}
+
+	// Every override is checked before anything is written.
+	prepared := make(entity.Events, 0, len(overrides))
+	overridden := make(map[uuid.UUID]bool, len(overrides))
+	event.ExDates = append(entity.Dates(nil), event.ExDates...)
+	for n := range overrides {
+		changes := &overrides[n]
+		err = changes.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("error in eventInteractor.Save: %w", invalid(err))
+		}
+		if !changes.IsOccurrence() || !event.IsRecurring() {
+			return nil, fmt.Errorf("error in eventInteractor.Save: %w: event %s is not an occurrence of a series", ErrBusinessRule, changes.ID)
+		}
+		override, _, err := i.prepareOverride(ctx, event, changes)
+		if err != nil {
+			return nil, fmt.Errorf("error in eventInteractor.Save: %w", err)
+		}
+		if overridden[override.ID] {
+			return nil, fmt.Errorf("error in eventInteractor.Save: %w", invalid(fmt.Errorf(
+				"occurrence on %s is overridden twice", override.RecurrenceID.Format("2006-01-02"))))
+		}
+		overridden[override.ID] = true
+		event.ExDates.Add(*override.RecurrenceID)
+		prepared = append(prepared, *override)
+	}
+
+	err = writeChanges(ctx, i.tx, i.changes, i.audit, func(ctx context.Context) ([]eventChange, error) {
+		var err error
+		if stored == nil {
+			err = i.repo.Create(ctx, event)
+		} else {
+			err = i.repo.Update(ctx, event)
+		}
+		if err != nil {
+			return nil, err
+		}
+		changes := []eventChange{savedChange(stored, event)}
+		for n := range prepared {
+			existing, err := i.saveOverride(ctx, &prepared[n])
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, savedChange(existing, &prepared[n]))
+		}
+		return changes, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Save: %w", i.versionConflict(ctx, event.ID, conflict(err)))
+	}
+
+	err = i.invite(ctx, stored, event)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Save: %w", err)
+	}
+	for n := range prepared {
+		err = i.invitations.invite(ctx, &prepared[n], prepared[n].Attendees.Added(event.Attendees))
+		if err != nil {
+			return nil, fmt.Errorf("error in eventInteractor.Save: %w", err)
+		}
+	}
+
+	return conflicts, nil
+}
+
+// Delete deletes the event. A non-zero version makes the deletion conditional like Update.
+func (i *eventInteractor) Delete(ctx context.Context, eventID uuid.UUID, version int64) error {
+	stored, err := i.repo.Get(ctx, eventID)
+	if err != nil {
+		return fmt.Errorf("error in eventInteractor.Delete: %w", err)
+	}
+	_, err = i.authorize(ctx, stored.CalendarID, entity.RoleWrite)
 	if err != nil {
 		return fmt.Errorf("error in eventInteractor.Delete: %w", err)
 	}
 
+	err = writeChanges(ctx, i.tx, i.changes, i.audit, func(ctx context.Context) ([]eventChange, error) {
+		return []eventChange{{kind: entity.ChangeDeleted, before: stored}}, i.repo.Delete(ctx, eventID, version)
+	})
+	if err != nil {
+		return fmt.Errorf("error in eventInteractor.Delete: %w", i.versionConflict(ctx, eventID, err))
+	}
+
 	return nil
 }
 
-func (i *eventInteractor) GetForDay(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error) {
-	events, err := i.repo.GetForDay(ctx, userID, date)
+// invite invites the attendees of the event created or, if stored isn't nil, updated who have to answer:
+// every attendee of a new or rescheduled event and the added attendees otherwise.
+func (i *eventInteractor) invite(ctx context.Context, stored *entity.Event, event *entity.Event) error {
+	invited := event.Attendees
+	if stored != nil && !isRescheduled(stored, event) {
+		invited = event.Attendees.Added(stored.Attendees)
+	}
+
+	return i.invitations.invite(ctx, event, invited)
+}
+
+// Get returns the event if the caller can read its calendar or is an attendee of it.
+func (i *eventInteractor) Get(ctx context.Context, eventID uuid.UUID) (*entity.Event, error) {
+	event, err := i.repo.Get(ctx, eventID)
 	if err != nil {
-		return nil, fmt.Errorf("error in eventInteractor.GetForDay: %w", err)
+		return nil, fmt.Errorf("error in eventInteractor.Get: %w", err)
+	}
+	_, err = i.authorize(ctx, event.CalendarID, entity.RoleRead)
+	if callerID, _ := CallerFromContext(ctx); errors.Is(err, ErrForbidden) && event.Attendees.Invites(callerID) {
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Get: %w", err)
+	}
+
+	return event, nil
+}
+
+// GetAll returns the events of the calendars, every calendar visible to the caller if none are given.
+func (i *eventInteractor) GetAll(ctx context.Context, calendarIDs ...uuid.UUID) (*entity.Events, error) {
+	calendarIDs, err := i.readable(ctx, calendarIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.GetAll: %w", err)
+	}
+
+	events, err := i.repo.GetAll(ctx, calendarIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.GetAll: %w", err)
 	}
 
 	return events, nil
 }
 
-func (i *eventInteractor) GetForWeek(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error) {
-	events, err := i.repo.GetForWeek(ctx, userID, date)
+// GetForDay returns the events of every calendar visible to the caller and the events the caller is invited to
+// which carry every one of the tags.
+func (i *eventInteractor) GetForDay(ctx context.Context, date time.Time, tags ...string) (*entity.Events, error) {
+	calendars, err := i.visible(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.GetForDay: %w", err)
+	}
+
+	events, err := i.repo.GetForDay(ctx, calendars.IDs(), date)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.GetForDay: %w", err)
+	}
+	from, to := entity.DayWindow(date)
+	err = i.addInvitations(ctx, events, calendars, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.GetForDay: %w", err)
+	}
+
+	return events.WithTags(tags), nil
+}
+
+// GetForWeek returns the events of every calendar visible to the caller and the events the caller is invited to
+// which carry every one of the tags.
+func (i *eventInteractor) GetForWeek(ctx context.Context, date time.Time, tags ...string) (*entity.Events, error) {
+	calendars, err := i.visible(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error in eventInteractor.GetForWeek: %w", err)
 	}
 
-	return events, nil
+	events, err := i.repo.GetForWeek(ctx, calendars.IDs(), date)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.GetForWeek: %w", err)
+	}
+	from, to := entity.WeekWindow(date)
+	err = i.addInvitations(ctx, events, calendars, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.GetForWeek: %w", err)
+	}
+
+	return events.WithTags(tags), nil
 }
 
-func (i *eventInteractor) GetForMonth(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error) {
-	events, err := i.repo.GetForMonth(ctx, userID, date)
+// GetForMonth returns the events of every calendar visible to the caller and the events the caller is invited to
+// which carry every one of the tags.
+func (i *eventInteractor) GetForMonth(ctx context.Context, date time.Time, tags ...string) (*entity.Events, error) {
+	calendars, err := i.visible(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error in eventInteractor.GetForMonth: %w", err)
 	}
 
-	return events, nil
+	events, err := i.repo.GetForMonth(ctx, calendars.IDs(), date)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.GetForMonth: %w", err)
+	}
+	from, to := entity.MonthWindow(date)
+	err = i.addInvitations(ctx, events, calendars, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.GetForMonth: %w", err)
+	}
+
+	return events.WithTags(tags), nil
+}
+
+// addInvitations adds the events in [from, to) the caller is invited to from the calendars
+// the caller can't see, the events of the visible ones are already there.
+func (i *eventInteractor) addInvitations(
+	ctx context.Context,
+	events *entity.Events,
+	calendars entity.Calendars,
+	from time.Time,
+	to time.Time,
+) error {
+	callerID, err := caller(ctx)
+	if err != nil {
+		return err
+	}
+	invited, err := i.repo.GetInvited(ctx, callerID, from, to)
+	if err != nil {
+		return err
+	}
+
+	visible := make(map[uuid.UUID]bool, len(calendars))
+	for _, calendar := range calendars {
+		visible[calendar.ID] = true
+	}
+	n := len(*events)
+	for _, event := range *invited {
+		if !visible[event.CalendarID] {
+			events.Add(event)
+		}
+	}
+	if len(*events) > n {
+		events.Sort()
+	}
+
+	return nil
+}
+
+// FreeBusy returns the merged busy intervals of the user within [from, to)
+// and the free slots between them lasting at least minFree.
+// The user is busy with the events of the calendars the user owns and the caller can read.
+func (i *eventInteractor) FreeBusy(ctx context.Context, userID uuid.UUID, from time.Time, to time.Time, minFree time.Duration) (*entity.FreeBusy, error) {
+	calendarIDs, err := i.ownedReadable(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.FreeBusy: %w", err)
+	}
+
+	events, err := i.repo.GetInWindow(ctx, calendarIDs, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.FreeBusy: %w", err)
+	}
+
+	return entity.NewFreeBusy(*events, from, to, minFree), nil
+}
+
+// GetTags returns the tags of the events of the calendars the user owns which the caller can read,
+// with the number of events carrying each of them.
+func (i *eventInteractor) GetTags(ctx context.Context, userID uuid.UUID) ([]entity.TagCount, error) {
+	calendarIDs, err := i.ownedReadable(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.GetTags: %w", err)
+	}
+
+	counts, err := i.repo.GetTagCounts(ctx, calendarIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.GetTags: %w", err)
+	}
+
+	return counts, nil
+}
+
+// Search returns a page of the occurrences of the user's events matching the query.
+func (i *eventInteractor) Search(ctx context.Context, query *entity.EventQuery) (*entity.EventPage, error) {
+	err := query.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Search: %w", invalid(err))
+	}
+	query.CalendarIDs, err = i.readable(ctx, query.CalendarIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Search: %w", err)
+	}
+
+	page, err := i.repo.Search(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error in eventInteractor.Search: %w", err)
+	}
+
+	return page, nil
+}
+
+// ownedReadable returns the calendars the user owns which the caller can read.
+// It fails with ErrForbidden if the caller can read none of them.
+func (i *eventInteractor) ownedReadable(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	callerID, err := caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if callerID == userID {
+		calendars, err := i.visible(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var owned []uuid.UUID
+		for _, calendar := range calendars {
+			if calendar.OwnerID == userID {
+				owned = append(owned, calendar.ID)
+			}
+		}
+		return owned, nil
+	}
+
+	calendars, err := i.calendars.GetForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var readable []uuid.UUID
+	for _, calendar := range calendars {
+		if calendar.OwnerID != userID {
+			continue
+		}
+		role, err := i.calendars.GetRole(ctx, calendar.ID, callerID)
+		if err != nil {
+			return nil, err
+		}
+		if role.Allows(entity.RoleRead) {
+			readable = append(readable, calendar.ID)
+		}
+	}
+	if len(readable) == 0 {
+		return nil, fmt.Errorf("%w: no calendar of user %s is shared with the caller", ErrForbidden, userID)
+	}
+
+	return readable, nil
 }
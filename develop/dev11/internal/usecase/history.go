@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"L2/develop/dev11/internal/trace"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit appends a revision to the history of an event for every change the interactors make,
+// with the caller and the ID of the request. A nil Audit records nothing.
+type Audit struct {
+	repo repository.RevisionRepository
+	now  func() time.Time
+}
+
+// NewAudit creates an audit appending the revisions to the repository.
+func NewAudit(repo repository.RevisionRepository) *Audit {
+	return &Audit{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// record appends the revision of the event made by the caller, before is nil for a created event
+// and after for a deleted one.
+func (a *Audit) record(ctx context.Context, kind entity.ChangeKind, before *entity.Event, after *entity.Event) error {
+	if a == nil {
+		return nil
+	}
+	actorID, _ := CallerFromContext(ctx)
+	requestID, _ := trace.RequestIDFromContext(ctx)
+
+	revision, err := entity.NewRevision(kind, before, after, actorID, requestID, a.now())
+	if err != nil {
+		return err
+	}
+
+	return a.repo.Append(ctx, revision)
+}
+
+// historyInteractor reads the history of events and reverts them on behalf of the caller of the context.
+// Reading the history of an event requires the read role in its calendar, reverting it the write role.
+type historyInteractor struct {
+	access
+	repo   repository.RevisionRepository
+	events EventInteractor
+}
+
+// NewHistoryInteractor creates the history interactor, reverts are saved by events like any other update.
+func NewHistoryInteractor(
+	repo repository.RevisionRepository,
+	calendars repository.CalendarRepository,
+	events EventInteractor,
+) *historyInteractor {
+	return &historyInteractor{
+		access: access{calendars: calendars},
+		repo:   repo,
+		events: events,
+	}
+}
+
+// List returns the revisions of the event, oldest first. The history outlives the event,
+// so it is kept after the event is deleted or purged.
+func (i *historyInteractor) List(ctx context.Context, eventID uuid.UUID) ([]entity.Revision, error) {
+	revisions, err := i.repo.GetAll(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("error in historyInteractor.List: %w", err)
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("error in historyInteractor.List: event %s: %w", eventID, ErrNotFound)
+	}
+	// The calendar of an event never changes.
+	_, err = i.authorize(ctx, revisions[len(revisions)-1].CalendarID, entity.RoleRead)
+	if err != nil {
+		return nil, fmt.Errorf("error in historyInteractor.List: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// Revert updates the event to how it was after the revision and returns it with the events it overlaps.
+// The revert is a change like any other: it gets a new version and a revision of its own.
+// A non-zero version makes it conditional like Update. A deleted event has to be restored first.
+func (i *historyInteractor) Revert(
+	ctx context.Context,
+	eventID uuid.UUID,
+	revisionID int64,
+	version int64,
+) (*entity.Event, entity.Events, error) {
+	revision, err := i.repo.Get(ctx, revisionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error in historyInteractor.Revert: %w", err)
+	}
+	if revision.EventID != eventID {
+		return nil, nil, fmt.Errorf("error in historyInteractor.Revert: revision %d of event %s: %w", revisionID, eventID, ErrNotFound)
+	}
+	_, err = i.authorize(ctx, revision.CalendarID, entity.RoleWrite)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error in historyInteractor.Revert: %w", err)
+	}
+	if revision.After == nil {
+		return nil, nil, fmt.Errorf("error in historyInteractor.Revert: %w: revision %d deleted the event", ErrBusinessRule, revisionID)
+	}
+
+	event := revision.After
+	event.Version = version
+	// Empty tags are omitted from the revision, while missing tags are kept by Update.
+	if event.Tags == nil {
+		event.Tags = entity.Tags{}
+	}
+	conflicts, err := i.events.Update(ctx, event)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error in historyInteractor.Revert: %w", err)
+	}
+
+	return event, conflicts, nil
+}
@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"L2/develop/dev11/internal/trace"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHistory(t *testing.T) {
+	owner, member, stranger := uuid.New(), uuid.New(), uuid.New()
+	as := func(userID uuid.UUID, requestID string) context.Context {
+		return trace.ContextWithRequestID(ContextWithCaller(context.Background(), userID), requestID)
+	}
+
+	source := db.NewMemorySource()
+	calendarRepo := repository.NewCalendarRepository(source)
+	revisions := repository.NewRevisionRepository(source)
+	events := NewEventInteractor(repository.NewEventRepository(source), calendarRepo, ConflictFlag,
+		EventOptions{Audit: NewAudit(revisions), Tx: repository.NewTxManager(source)})
+	calendars := NewCalendarInteractor(calendarRepo)
+	history := NewHistoryInteractor(revisions, calendarRepo, events)
+
+	start := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	event := &entity.Event{ID: uuid.New(), Title: "standup", Start: start, End: start.Add(time.Hour), TimeZone: "UTC"}
+	if _, err := events.Create(as(owner, "create"), event); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := calendars.Share(as(owner, "share"), &entity.Share{CalendarID: owner, UserID: member, Role: entity.RoleWrite}); err != nil {
+		t.Fatalf("Share() error = %v", err)
+	}
+	moved := *event
+	moved.Start, moved.End = start.Add(2*time.Hour), start.Add(3*time.Hour)
+	moved.Tags = entity.Tags{"moved"}
+	if _, err := events.Update(as(member, "move"), &moved); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	// The history answers who moved and tagged the meeting.
+	list, err := history.List(as(owner, "list"), event.ID)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var got []string
+	for _, revision := range list {
+		got = append(got, fmt.Sprintf("%s %s by %s", revision.Kind, revision.RequestID, revision.ActorID))
+		if revision.Kind == entity.ChangeUpdated && (len(revision.Diff) != 3 || !revision.Before.Start.Equal(start)) {
+			t.Errorf("update diff = %v, before = %+v", revision.Diff, revision.Before)
+		}
+	}
+	expected := fmt.Sprintf("created create by %s,updated move by %s", owner, member)
+	if strings.Join(got, ",") != expected {
+		t.Errorf("history = %v, expected %s", got, expected)
+	}
+	if _, err := history.List(as(stranger, "list"), event.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("List() by a stranger error = %v, expected ErrForbidden", err)
+	}
+	if _, err := history.List(as(owner, "list"), uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("List() of an unknown event error = %v, expected ErrNotFound", err)
+	}
+
+	// Reverting to the first revision moves the meeting back, drops the tag and is a revision of its own.
+	if _, _, err := history.Revert(as(owner, "revert"), event.ID, list[0].ID, 1); !errors.As(err, new(*VersionConflictError)) {
+		t.Errorf("Revert() of a stale version error = %v, expected VersionConflictError", err)
+	}
+	reverted, _, err := history.Revert(as(owner, "revert"), event.ID, list[0].ID, 0)
+	if err != nil {
+		t.Fatalf("Revert() error = %v", err)
+	}
+	stored, err := events.Get(as(owner, "get"), event.ID)
+	if err != nil || !stored.Start.Equal(start) || len(stored.Tags) != 0 || stored.Version != 3 || reverted.Version != 3 {
+		t.Errorf("event after Revert() = %+v, %v", stored, err)
+	}
+	if list, _ := history.List(as(owner, "list"), event.ID); len(list) != 3 || list[2].RequestID != "revert" {
+		t.Errorf("history after Revert() = %+v", list)
+	}
+
+	if _, _, err := history.Revert(as(owner, "revert"), uuid.New(), list[0].ID, 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Revert() with a revision of another event error = %v, expected ErrNotFound", err)
+	}
+	if err := events.Delete(as(owner, "delete"), event.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	list, _ = history.List(as(owner, "list"), event.ID)
+	if _, _, err := history.Revert(as(owner, "revert"), event.ID, list[len(list)-1].ID, 0); !errors.Is(err, ErrBusinessRule) {
+		t.Errorf("Revert() to the deletion error = %v, expected ErrBusinessRule", err)
+	}
+}
@@ -12,10 +12,51 @@ import (
 //go:generate mockgen -source=./interfaces.go -destination=usecases_mock.go -package=usecase
 
 type EventInteractor interface {
-	Create(ctx context.Context, event *entity.Event) error
-	Update(ctx context.Context, event *entity.Event) error
-	Delete(ctx context.Context, eventID uuid.UUID) error
-	GetForDay(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error)
-	GetForWeek(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error)
-	GetForMonth(ctx context.Context, userID uuid.UUID, date time.Time) (*entity.Events, error)
+	Create(ctx context.Context, event *entity.Event) (entity.Events, error)
+	Update(ctx context.Context, event *entity.Event) (entity.Events, error)
+	Delete(ctx context.Context, eventID uuid.UUID, version int64) error
+	Save(ctx context.Context, event *entity.Event, overrides entity.Events) (entity.Events, error)
+	Get(ctx context.Context, eventID uuid.UUID) (*entity.Event, error)
+	GetAll(ctx context.Context, calendarIDs ...uuid.UUID) (*entity.Events, error)
+	GetForDay(ctx context.Context, date time.Time, tags ...string) (*entity.Events, error)
+	GetForWeek(ctx context.Context, date time.Time, tags ...string) (*entity.Events, error)
+	GetForMonth(ctx context.Context, date time.Time, tags ...string) (*entity.Events, error)
+	FreeBusy(ctx context.Context, userID uuid.UUID, from time.Time, to time.Time, minFree time.Duration) (*entity.FreeBusy, error)
+	Search(ctx context.Context, query *entity.EventQuery) (*entity.EventPage, error)
+	GetTags(ctx context.Context, userID uuid.UUID) ([]entity.TagCount, error)
+	Batch(ctx context.Context, operations []entity.BatchOperation, dryRun bool) ([]BatchResult, error)
+}
+
+type CalendarInteractor interface {
+	Create(ctx context.Context, calendar *entity.Calendar) error
+	Update(ctx context.Context, calendar *entity.Calendar) error
+	Delete(ctx context.Context, calendarID uuid.UUID) error
+	List(ctx context.Context) (entity.Calendars, error)
+	Share(ctx context.Context, share *entity.Share) error
+	Unshare(ctx context.Context, calendarID uuid.UUID, userID uuid.UUID) error
+	Shares(ctx context.Context, calendarID uuid.UUID) ([]entity.Share, error)
+}
+
+type ChangeInteractor interface {
+	Stream(ctx context.Context, afterID int64) (*ChangeStream, error)
+}
+
+type WebhookInteractor interface {
+	Create(ctx context.Context, webhook *entity.Webhook) error
+	Delete(ctx context.Context, webhookID uuid.UUID) error
+	List(ctx context.Context) ([]entity.Webhook, error)
+}
+
+type TrashInteractor interface {
+	List(ctx context.Context, calendarIDs ...uuid.UUID) (*entity.Events, error)
+	Restore(ctx context.Context, eventID uuid.UUID) (*entity.Event, error)
+}
+
+type HistoryInteractor interface {
+	List(ctx context.Context, eventID uuid.UUID) ([]entity.Revision, error)
+	Revert(ctx context.Context, eventID uuid.UUID, revisionID int64, version int64) (*entity.Event, entity.Events, error)
+}
+
+type InvitationInteractor interface {
+	Respond(ctx context.Context, eventID uuid.UUID, status entity.AttendeeStatus) (*entity.Event, error)
 }
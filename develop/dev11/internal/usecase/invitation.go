@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// respondAttempts is how many times a response is saved when the event changes concurrently.
+const respondAttempts = 3
+
+// InvitationNotifier delivers the invitations to the attendees of events and their responses to the organizers.
+// The notifier package provides the implementations.
+type InvitationNotifier interface {
+	NotifyInvitation(ctx context.Context, invitation *entity.Invitation) error
+	NotifyResponse(ctx context.Context, response *entity.Response) error
+}
+
+// Invitations sends the invitations and the responses of the attendees through the notifier.
+// A nil Invitations sends nothing.
+type Invitations struct {
+	notifier InvitationNotifier
+}
+
+// NewInvitations creates the invitations delivered by the notifier.
+func NewInvitations(notifier InvitationNotifier) *Invitations {
+	return &Invitations{
+		notifier: notifier,
+	}
+}
+
+// invite invites the attendees to the event.
+func (n *Invitations) invite(ctx context.Context, event *entity.Event, attendees entity.Attendees) error {
+	if n == nil || len(attendees) == 0 {
+		return nil
+	}
+	return n.notifier.NotifyInvitation(ctx, &entity.Invitation{Event: *event, Attendees: attendees})
+}
+
+// respond notifies the organizer of the event about the response of the attendee.
+func (n *Invitations) respond(ctx context.Context, event *entity.Event, attendee *entity.Attendee) error {
+	if n == nil {
+		return nil
+	}
+	return n.notifier.NotifyResponse(ctx, &entity.Response{Event: *event, Attendee: *attendee})
+}
+
+// invitationInteractor answers the invitations to events on behalf of the caller of the context.
+// Only an attendee of an event can answer, no role in its calendar is required.
+type invitationInteractor struct {
+	repo        repository.EventRepository
+	changes     *ChangeFeed
+	audit       *Audit
+	invitations *Invitations
+	tx          repository.TxManager
+}
+
+// NewInvitationInteractor creates the invitation interactor, the responses are recorded by changes and audit
+// and delivered to the organizers by invitations. The responses are saved together with their revisions
+// within the transactions of tx, a nil tx saves them without.
+func NewInvitationInteractor(
+	repo repository.EventRepository,
+	changes *ChangeFeed,
+	audit *Audit,
+	invitations *Invitations,
+	tx repository.TxManager,
+) *invitationInteractor {
+	return &invitationInteractor{
+		repo:        repo,
+		changes:     changes,
+		audit:       audit,
+		invitations: invitations,
+		tx:          tx,
+	}
+}
+
+// Respond sets the status of the caller among the attendees of the event, notifies the organizer
+// and returns the event. The response is a change of the event like any other: it gets a new version,
+// a change and a revision. It applies to every occurrence of a series.
+func (i *invitationInteractor) Respond(ctx context.Context, eventID uuid.UUID, status entity.AttendeeStatus) (*entity.Event, error) {
+	callerID, err := caller(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error in invitationInteractor.Respond: %w", err)
+	}
+	if !status.IsResponse() {
+		return nil, fmt.Errorf("error in invitationInteractor.Respond: %w", invalid(fmt.Errorf("status %q is not a response", status)))
+	}
+
+	// The response only changes the status of the caller, so it is saved again on the event changed meanwhile.
+	for attempt := 1; ; attempt++ {
+		stored, err := i.repo.Get(ctx, eventID)
+		if err != nil {
+			return nil, fmt.Errorf("error in invitationInteractor.Respond: %w", err)
+		}
+
+		event := *stored
+		event.Attendees = stored.Attendees.Clone()
+		attendee, ok := event.Attendees.Find(callerID)
+		if !ok {
+			return nil, fmt.Errorf("error in invitationInteractor.Respond: %w: caller is not an attendee of event %s", ErrForbidden, eventID)
+		}
+		attendee.Status = status
+
+		err = writeChanges(ctx, i.tx, i.changes, i.audit, func(ctx context.Context) ([]eventChange, error) {
+			return []eventChange{{kind: entity.ChangeUpdated, before: stored, after: &event}}, i.repo.Update(ctx, &event)
+		})
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if attempt < respondAttempts {
+				continue
+			}
+			err = fmt.Errorf("%w: %w", ErrConflict, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error in invitationInteractor.Respond: %w", err)
+		}
+
+		err = i.invitations.respond(ctx, &event, attendee)
+		if err != nil {
+			return nil, fmt.Errorf("error in invitationInteractor.Respond: %w", err)
+		}
+
+		return &event, nil
+	}
+}
@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// recordingNotifier records the invitations and responses as "invite <attendees>" and "<attendee> <status>".
+type recordingNotifier struct {
+	sent []string
+}
+
+func (n *recordingNotifier) NotifyInvitation(ctx context.Context, invitation *entity.Invitation) error {
+	var attendees []string
+	for _, attendee := range invitation.Attendees {
+		attendees = append(attendees, attendeeKey(&attendee))
+	}
+	n.sent = append(n.sent, "invite "+strings.Join(attendees, ","))
+	return nil
+}
+
+func (n *recordingNotifier) NotifyResponse(ctx context.Context, response *entity.Response) error {
+	n.sent = append(n.sent, fmt.Sprintf("%s %s", attendeeKey(&response.Attendee), response.Attendee.Status))
+	return nil
+}
+
+func attendeeKey(attendee *entity.Attendee) string {
+	if attendee.UserID != nil {
+		return attendee.UserID.String()
+	}
+	return attendee.Email
+}
+
+func TestInvitations(t *testing.T) {
+	organizer, attendee, stranger := uuid.New(), uuid.New(), uuid.New()
+	as := func(userID uuid.UUID) context.Context {
+		return ContextWithCaller(context.Background(), userID)
+	}
+
+	source := db.NewMemorySource()
+	notifier := &recordingNotifier{}
+	invitations := NewInvitations(notifier)
+	eventRepo := repository.NewEventRepository(source)
+	events := NewEventInteractor(eventRepo, repository.NewCalendarRepository(source), ConflictFlag, EventOptions{Invitations: invitations})
+	rsvp := NewInvitationInteractor(eventRepo, nil, nil, invitations, nil)
+
+	start := time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC)
+	event := &entity.Event{
+		ID:       uuid.New(),
+		Title:    "planning",
+		Start:    start,
+		End:      start.Add(time.Hour),
+		TimeZone: "UTC",
+		// The statuses set by the organizer are ignored.
+		Attendees: entity.Attendees{{UserID: &attendee, Status: entity.StatusAccepted}},
+	}
+	if _, err := events.Create(as(organizer), event); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// The attendee sees the event without access to the calendar of the organizer.
+	day, err := events.GetForDay(as(attendee), start)
+	if err != nil || len(*day) != 1 || (*day)[0].ID != event.ID || (*day)[0].Attendees[0].Status != entity.StatusNeedsAction {
+		t.Fatalf("GetForDay() of the attendee = %+v, %v", day, err)
+	}
+	if _, err := events.Get(as(attendee), event.ID); err != nil {
+		t.Errorf("Get() by the attendee error = %v", err)
+	}
+	if _, err := events.Get(as(stranger), event.ID); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Get() by a stranger error = %v, expected ErrForbidden", err)
+	}
+
+	if _, err := rsvp.Respond(as(stranger), event.ID, entity.StatusAccepted); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Respond() by a stranger error = %v, expected ErrForbidden", err)
+	}
+	if _, err := rsvp.Respond(as(attendee), event.ID, entity.StatusNeedsAction); !errors.Is(err, ErrValidation) {
+		t.Errorf("Respond() with needs-action error = %v, expected ErrValidation", err)
+	}
+	responded, err := rsvp.Respond(as(attendee), event.ID, entity.StatusTentative)
+	if err != nil {
+		t.Fatalf("Respond() error = %v", err)
+	}
+	if responded.Version != 2 || responded.Attendees[0].Status != entity.StatusTentative {
+		t.Errorf("event after Respond() = %+v", responded)
+	}
+
+	// Adding an attendee invites only the new one and keeps the answers.
+	changed := *responded
+	changed.Attendees = entity.Attendees{{UserID: &attendee}, {Email: "guest@example.com"}}
+	if _, err := events.Update(as(organizer), &changed); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if changed.Attendees[0].Status != entity.StatusTentative || changed.Attendees[1].Status != entity.StatusNeedsAction {
+		t.Errorf("attendees after Update() = %+v", changed.Attendees)
+	}
+
+	// Rescheduling asks everyone again.
+	moved := changed
+	moved.Attendees = nil
+	moved.Start, moved.End = start.Add(time.Hour), start.Add(2*time.Hour)
+	if _, err := events.Update(as(organizer), &moved); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if moved.Attendees[0].Status != entity.StatusNeedsAction {
+		t.Errorf("attendees after rescheduling = %+v", moved.Attendees)
+	}
+
+	expected := []string{
+		"invite " + attendee.String(),
+		attendee.String() + " tentative",
+		"invite guest@example.com",
+		"invite " + attendee.String() + ",guest@example.com",
+	}
+	if strings.Join(notifier.sent, "; ") != strings.Join(expected, "; ") {
+		t.Errorf("notifications = %q, expected %q", notifier.sent, expected)
+	}
+
+	invalid := &entity.Event{ID: uuid.New(), Title: "self", Start: start, End: start.Add(time.Hour), TimeZone: "UTC",
+		Attendees: entity.Attendees{{UserID: &organizer}}}
+	if _, err := events.Create(as(organizer), invalid); !errors.Is(err, ErrValidation) {
+		t.Errorf("Create() with the organizer as an attendee error = %v, expected ErrValidation", err)
+	}
+}
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// trashInteractor lists and restores the deleted events on behalf of the caller of the context.
+// Listing the trash of a calendar requires the read role in it, restoring its events the write role.
+type trashInteractor struct {
+	access
+	repo    repository.TrashRepository
+	events  repository.EventRepository
+	changes *ChangeFeed
+	audit   *Audit
+	tx      repository.TxManager
+}
+
+// NewTrashInteractor creates the trash interactor, the restored events are recorded by changes and audit.
+// The events are restored together with their revisions within the transactions of tx, a nil tx restores them without.
+func NewTrashInteractor(
+	repo repository.TrashRepository,
+	events repository.EventRepository,
+	calendars repository.CalendarRepository,
+	changes *ChangeFeed,
+	audit *Audit,
+	tx repository.TxManager,
+) *trashInteractor {
+	return &trashInteractor{
+		access:  access{calendars: calendars},
+		repo:    repo,
+		events:  events,
+		changes: changes,
+		audit:   audit,
+		tx:      tx,
+	}
+}
+
+// List returns the deleted events of the calendars, of every calendar visible to the caller if none are given.
+func (i *trashInteractor) List(ctx context.Context, calendarIDs ...uuid.UUID) (*entity.Events, error) {
+	calendarIDs, err := i.readable(ctx, calendarIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error in trashInteractor.List: %w", err)
+	}
+
+	events, err := i.repo.GetAll(ctx, calendarIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error in trashInteractor.List: %w", err)
+	}
+
+	return events, nil
+}
+
+// Restore takes the deleted event out of the trash together with the overrides deleted with it
+// and returns the restored event. An override can't be restored while its series is deleted.
+func (i *trashInteractor) Restore(ctx context.Context, eventID uuid.UUID) (*entity.Event, error) {
+	deleted, err := i.repo.Get(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("error in trashInteractor.Restore: %w", err)
+	}
+	_, err = i.authorize(ctx, deleted.CalendarID, entity.RoleWrite)
+	if err != nil {
+		return nil, fmt.Errorf("error in trashInteractor.Restore: %w", err)
+	}
+	if deleted.SeriesID != nil {
+		_, err = i.events.Get(ctx, *deleted.SeriesID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("error in trashInteractor.Restore: %w: series %s is deleted, restore it first",
+				ErrBusinessRule, *deleted.SeriesID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error in trashInteractor.Restore: %w", err)
+		}
+	}
+
+	var event *entity.Event
+	err = writeChanges(ctx, i.tx, i.changes, i.audit, func(ctx context.Context) ([]eventChange, error) {
+		err := i.repo.Restore(ctx, eventID)
+		if err != nil {
+			return nil, err
+		}
+		event, err = i.events.Get(ctx, eventID)
+		if err != nil {
+			return nil, err
+		}
+		return []eventChange{{kind: entity.ChangeCreated, before: deleted, after: event}}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error in trashInteractor.Restore: %w", err)
+	}
+
+	return event, nil
+}
@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+)
+
+// eventChange is a change of an event written within a transaction, before is nil for a created event
+// and after for a deleted one.
+type eventChange struct {
+	kind   entity.ChangeKind
+	before *entity.Event
+	after  *entity.Event
+}
+
+// savedChange returns the change of the event created or, if stored isn't nil, updated.
+func savedChange(stored *entity.Event, event *entity.Event) eventChange {
+	if stored == nil {
+		return eventChange{kind: entity.ChangeCreated, after: event}
+	}
+	return eventChange{kind: entity.ChangeUpdated, before: stored, after: event}
+}
+
+// withinTx runs fn within one transaction of tx, a nil tx runs fn as is.
+func withinTx(ctx context.Context, tx repository.TxManager, fn func(ctx context.Context) error) error {
+	if tx == nil {
+		return fn(ctx)
+	}
+	return tx.WithinTx(ctx, fn)
+}
+
+// writeChanges runs write and records the revisions of the changes it returns in the audit history
+// within one transaction of tx, so that a failure leaves neither the changes nor their revisions.
+// The changes are recorded in the change log only once the transaction commits,
+// so that the subscribers are never sent a change which is rolled back.
+func writeChanges(
+	ctx context.Context,
+	tx repository.TxManager,
+	feed *ChangeFeed,
+	audit *Audit,
+	write func(ctx context.Context) ([]eventChange, error),
+) error {
+	var changes []eventChange
+	err := withinTx(ctx, tx, func(ctx context.Context) error {
+		var err error
+		changes, err = write(ctx)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			err = audit.record(ctx, change.kind, change.before, change.after)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		event := change.after
+		if event == nil {
+			event = change.before
+		}
+		err = feed.record(ctx, change.kind, event)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
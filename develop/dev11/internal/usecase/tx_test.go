@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// failingRevisions fails to append the revisions while fail is set.
+type failingRevisions struct {
+	repository.RevisionRepository
+	fail bool
+}
+
+var errAppend = errors.New("can't append revision")
+
+func (r *failingRevisions) Append(ctx context.Context, revision *entity.Revision) error {
+	if r.fail {
+		return errAppend
+	}
+	return r.RevisionRepository.Append(ctx, revision)
+}
+
+func TestTransactions(t *testing.T) {
+	owner := uuid.New()
+	ctx := ContextWithCaller(context.Background(), owner)
+
+	source := db.NewMemorySource()
+	changeRepo := repository.NewChangeRepository(source)
+	feed := NewChangeFeed(changeRepo)
+	revisions := &failingRevisions{RevisionRepository: repository.NewRevisionRepository(source)}
+	events := NewEventInteractor(repository.NewEventRepository(source), repository.NewCalendarRepository(source), ConflictFlag,
+		EventOptions{Changes: feed, Audit: NewAudit(revisions), Tx: repository.NewTxManager(source)})
+
+	start := time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+	series := &entity.Event{ID: uuid.New(), Title: "standup", TimeZone: "UTC", RRule: "FREQ=DAILY",
+		Start: start, End: start.Add(time.Hour)}
+	if _, err := events.Create(ctx, series); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	subscription := feed.Subscribe()
+	defer subscription.Close()
+
+	// Every write fails with the audit history, none of them is left behind or published.
+	revisions.fail = true
+	created := &entity.Event{ID: uuid.New(), Title: "lunch", TimeZone: "UTC", Start: start.Add(3 * time.Hour), End: start.Add(4 * time.Hour)}
+	if _, err := events.Create(ctx, created); !errors.Is(err, errAppend) {
+		t.Errorf("Create() error = %v, expected the audit error", err)
+	}
+	if _, err := events.Get(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of the rolled back event error = %v, expected ErrNotFound", err)
+	}
+
+	// The override and the exclusion of the occurrence from the series are rolled back together.
+	recurrenceID := start.AddDate(0, 0, 1)
+	occurrence := *series
+	occurrence.Title, occurrence.RecurrenceID = "planning", &recurrenceID
+	occurrence.Start, occurrence.End = recurrenceID.Add(time.Hour), recurrenceID.Add(2*time.Hour)
+	if _, err := events.Update(ctx, &occurrence); !errors.Is(err, errAppend) {
+		t.Errorf("Update() of an occurrence error = %v, expected the audit error", err)
+	}
+	if _, err := events.Get(ctx, series.OverrideID(recurrenceID)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of the rolled back override error = %v, expected ErrNotFound", err)
+	}
+
+	// A series saved with its overrides is rolled back as a whole.
+	replaced := *series
+	replaced.Title = "daily"
+	if _, err := events.Save(ctx, &replaced, entity.Events{occurrence}); !errors.Is(err, errAppend) {
+		t.Errorf("Save() error = %v, expected the audit error", err)
+	}
+	if _, err := events.Get(ctx, series.OverrideID(recurrenceID)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of the override of the rolled back series error = %v, expected ErrNotFound", err)
+	}
+
+	if err := events.Delete(ctx, series.ID, 0); !errors.Is(err, errAppend) {
+		t.Errorf("Delete() error = %v, expected the audit error", err)
+	}
+	stored, err := events.Get(ctx, series.ID)
+	if err != nil || stored.Version != 1 || len(stored.ExDates) != 0 {
+		t.Errorf("Get() of the series = %+v, %v, expected it unchanged", stored, err)
+	}
+
+	if lastID, err := changeRepo.GetLastID(ctx); err != nil || lastID != 1 {
+		t.Errorf("GetLastID() = %d, %v, expected only the change of the series", lastID, err)
+	}
+	select {
+	case change := <-subscription.C:
+		t.Errorf("change %+v of a rolled back write is published", change)
+	default:
+	}
+
+	// An override of a missing occurrence fails the save before the series is written.
+	revisions.fail = false
+	missing := occurrence
+	missingID := start.AddDate(0, 0, -1)
+	missing.RecurrenceID = &missingID
+	replaced = *series
+	replaced.Title = "daily"
+	if _, err := events.Save(ctx, &replaced, entity.Events{occurrence, missing}); !errors.Is(err, ErrBusinessRule) {
+		t.Errorf("Save() with an override of a missing occurrence error = %v, expected ErrBusinessRule", err)
+	}
+	if stored, err := events.Get(ctx, series.ID); err != nil || stored.Title != series.Title {
+		t.Errorf("Get() of the series = %+v, %v, expected it unchanged", stored, err)
+	}
+
+	if err := events.Delete(ctx, series.ID, 0); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// VersionConflictError is returned when the event was changed since the version a write is based on.
+// Current is the stored event the client has to reconcile its changes with.
+type VersionConflictError struct {
+	Current *entity.Event
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("event %s was changed, current version is %d", e.Current.ID, e.Current.Version)
+}
+
+func (e *VersionConflictError) Unwrap() error {
+	return repository.ErrVersionConflict
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// checkVersion fails with a VersionConflictError unless the expected version is zero or the stored one.
+func checkVersion(stored *entity.Event, version int64) error {
+	if version != 0 && version != stored.Version {
+		return &VersionConflictError{Current: stored}
+	}
+
+	return nil
+}
+
+// versionConflict turns a version conflict of the repository into a VersionConflictError
+// with the current event. Other errors are returned as is.
+func (i *eventInteractor) versionConflict(ctx context.Context, eventID uuid.UUID, err error) error {
+	if !errors.Is(err, repository.ErrVersionConflict) {
+		return err
+	}
+
+	current, getErr := i.repo.Get(ctx, eventID)
+	if getErr != nil {
+		return errors.Join(err, getErr)
+	}
+
+	return &VersionConflictError{Current: current}
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// secretBytes is how many random bytes a generated secret of a webhook has.
+const secretBytes = 16
+
+// webhookInteractor manages the webhooks of the caller of the context.
+type webhookInteractor struct {
+	repo    repository.WebhookRepository
+	changes repository.ChangeRepository
+	now     func() time.Time
+}
+
+func NewWebhookInteractor(repo repository.WebhookRepository, changes repository.ChangeRepository) *webhookInteractor {
+	return &webhookInteractor{
+		repo:    repo,
+		changes: changes,
+		now:     time.Now,
+	}
+}
+
+// Create registers the webhook of the caller. The changes made from now on are posted to it.
+// A webhook without a secret gets a random one, the secret is returned in the webhook only here.
+func (i *webhookInteractor) Create(ctx context.Context, webhook *entity.Webhook) error {
+	callerID, err := caller(ctx)
+	if err != nil {
+		return fmt.Errorf("error in webhookInteractor.Create: %w", err)
+	}
+	if webhook.Secret == "" {
+		webhook.Secret, err = newSecret()
+		if err != nil {
+			return fmt.Errorf("error in webhookInteractor.Create: %w", err)
+		}
+	}
+	err = webhook.Validate()
+	if err != nil {
+		return fmt.Errorf("error in webhookInteractor.Create: %w", invalid(err))
+	}
+
+	lastChangeID, err := i.changes.GetLastID(ctx)
+	if err != nil {
+		return fmt.Errorf("error in webhookInteractor.Create: %w", err)
+	}
+	webhook.ID = uuid.New()
+	webhook.UserID = callerID
+	webhook.LastChangeID = lastChangeID
+	webhook.CreatedAt = i.now().UTC()
+
+	err = i.repo.Create(ctx, webhook)
+	if err != nil {
+		return fmt.Errorf("error in webhookInteractor.Create: %w", conflict(err))
+	}
+
+	return nil
+}
+
+// Delete deletes the webhook of the caller.
+func (i *webhookInteractor) Delete(ctx context.Context, webhookID uuid.UUID) error {
+	callerID, err := caller(ctx)
+	if err != nil {
+		return fmt.Errorf("error in webhookInteractor.Delete: %w", err)
+	}
+	webhook, err := i.repo.Get(ctx, webhookID)
+	if err != nil {
+		return fmt.Errorf("error in webhookInteractor.Delete: %w", err)
+	}
+	if webhook.UserID != callerID {
+		return fmt.Errorf("error in webhookInteractor.Delete: %w: webhook %s belongs to another user", ErrForbidden, webhookID)
+	}
+
+	err = i.repo.Delete(ctx, webhookID)
+	if err != nil {
+		return fmt.Errorf("error in webhookInteractor.Delete: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the webhooks of the caller without their secrets.
+func (i *webhookInteractor) List(ctx context.Context) ([]entity.Webhook, error) {
+	callerID, err := caller(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error in webhookInteractor.List: %w", err)
+	}
+
+	webhooks, err := i.repo.GetForUser(ctx, callerID)
+	if err != nil {
+		return nil, fmt.Errorf("error in webhookInteractor.List: %w", err)
+	}
+	for j := range webhooks {
+		webhooks[j].Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+// newSecret returns a random secret of 32 hex digits.
+func newSecret() (string, error) {
+	secret := make([]byte, secretBytes)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", fmt.Errorf("can't generate secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,313 @@
+// Package webhook provides the background delivery of the changes of events to registered webhooks.
+package webhook
+
+import (
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"L2/develop/dev11/internal/usecase"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// EventHeader is the kind of the delivered change.
+	EventHeader = "X-Calendar-Event"
+	// DeliveryHeader is the ID of the delivered change, the same on every retry.
+	DeliveryHeader = "X-Calendar-Delivery"
+	// SignatureHeader is the signature of the delivery made by Sign.
+	SignatureHeader = "X-Calendar-Signature"
+)
+
+// page is how many changes are read from the change log at once.
+const page = 100
+
+// Config configures the dispatcher.
+type Config struct {
+	// Interval is how often the webhooks are checked for undelivered changes.
+	Interval time.Duration
+	// MaxAttempts is how many times a change is posted before it is dropped.
+	MaxAttempts int
+	// MaxBackoff limits the wait between the attempts, which doubles from Interval on.
+	MaxBackoff time.Duration
+	// Retention is how long the change log is kept, zero keeps it forever.
+	Retention time.Duration
+}
+
+// retry is the state of a webhook which failed to accept a change.
+type retry struct {
+	attempts int
+	next     time.Time
+}
+
+// Dispatcher posts the changes of the events a user can read to the webhooks of the user, in the order of the change log.
+//
+// Delivery is at-least-once: the cursor of a webhook moves past a change only after the webhook accepted it
+// with a 2xx response. A failed change is retried with an exponential backoff and dropped after MaxAttempts,
+// so that a broken webhook doesn't hold back its later changes forever.
+type Dispatcher struct {
+	webhooks  repository.WebhookRepository
+	changes   repository.ChangeRepository
+	calendars repository.CalendarRepository
+	feed      *usecase.ChangeFeed
+	client    *http.Client
+	config    Config
+	logger    *zap.Logger
+	now       func() time.Time
+
+	retries map[uuid.UUID]*retry
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewDispatcher creates a new dispatcher. Besides checking every interval it wakes up
+// on every change published by the feed, a nil feed leaves the interval only.
+func NewDispatcher(
+	webhooks repository.WebhookRepository,
+	changes repository.ChangeRepository,
+	calendars repository.CalendarRepository,
+	feed *usecase.ChangeFeed,
+	client *http.Client,
+	config Config,
+	logger *zap.Logger,
+) *Dispatcher {
+	return &Dispatcher{
+		webhooks:  webhooks,
+		changes:   changes,
+		calendars: calendars,
+		feed:      feed,
+		client:    client,
+		config:    config,
+		logger:    logger,
+		now:       time.Now,
+		retries:   map[uuid.UUID]*retry{},
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Run delivers changes until the context is done or the dispatcher is shut down.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	wake := make(chan struct{}, 1)
+	if d.feed != nil {
+		subscription := d.feed.Subscribe()
+		defer subscription.Close()
+		// The changes only wake the dispatcher up, so they are drained without blocking the feed.
+		go func() {
+			for range subscription.C {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+
+	for {
+		err := d.dispatch(ctx)
+		if err != nil {
+			d.logger.Error("can't deliver webhooks", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-d.stop:
+			return nil
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// Shutdown stops the dispatcher and waits for the delivery in progress to finish.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.stopOnce.Do(func() { close(d.stop) })
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	// The run may have finished together with the context.
+	select {
+	case <-d.done:
+		return nil
+	default:
+		return fmt.Errorf("webhook dispatcher shutdown error: %w", ctx.Err())
+	}
+}
+
+// dispatch delivers the undelivered changes to every webhook which isn't waiting for a retry
+// and purges the change log older than the retention.
+func (d *Dispatcher) dispatch(ctx context.Context) error {
+	webhooks, err := d.webhooks.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	registered := make(map[uuid.UUID]bool, len(webhooks))
+	for i := range webhooks {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		webhook := &webhooks[i]
+		registered[webhook.ID] = true
+		if r, ok := d.retries[webhook.ID]; ok && d.now().Before(r.next) {
+			continue
+		}
+
+		err := d.deliver(ctx, webhook)
+		if err != nil {
+			return err
+		}
+	}
+	// Deleted webhooks aren't retried.
+	for webhookID := range d.retries {
+		if !registered[webhookID] {
+			delete(d.retries, webhookID)
+		}
+	}
+
+	if d.config.Retention <= 0 {
+		return nil
+	}
+	return d.changes.DeleteBefore(ctx, d.now().Add(-d.config.Retention))
+}
+
+// deliver posts the changes after the cursor of the webhook which its user can read, stopping at the first failure.
+func (d *Dispatcher) deliver(ctx context.Context, webhook *entity.Webhook) error {
+	calendars, err := d.calendars.GetForUser(ctx, webhook.UserID)
+	if err != nil {
+		return err
+	}
+
+	for {
+		changes, err := d.changes.GetAfter(ctx, webhook.LastChangeID, calendars.IDs(), page)
+		if err != nil {
+			return err
+		}
+
+		for i := range changes {
+			change := &changes[i]
+			err := d.post(ctx, webhook, change)
+			if err != nil && !d.failed(webhook, change, err) {
+				return nil
+			}
+			delete(d.retries, webhook.ID)
+
+			err = d.webhooks.SetCursor(ctx, webhook.ID, change.ID)
+			if err != nil {
+				return err
+			}
+			webhook.LastChangeID = change.ID
+		}
+
+		if len(changes) < page {
+			return nil
+		}
+	}
+}
+
+// failed records the failed attempt to post the change and schedules the next one.
+// It reports whether the change is dropped because it ran out of attempts.
+func (d *Dispatcher) failed(webhook *entity.Webhook, change *entity.Change, err error) bool {
+	r, ok := d.retries[webhook.ID]
+	if !ok {
+		r = &retry{}
+		d.retries[webhook.ID] = r
+	}
+	r.attempts++
+
+	fields := []zap.Field{
+		zap.String("webhook_id", webhook.ID.String()),
+		zap.Int64("change_id", change.ID),
+		zap.Int("attempts", r.attempts),
+		zap.Error(err),
+	}
+	if r.attempts >= d.config.MaxAttempts {
+		d.logger.Error("can't deliver change to webhook, dropping it", fields...)
+		return true
+	}
+
+	r.next = d.now().Add(d.backoff(r.attempts))
+	d.logger.Warn("can't deliver change to webhook, will retry", fields...)
+	return false
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.config.Interval
+	for i := 1; i < attempts && backoff < d.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > d.config.MaxBackoff {
+		backoff = d.config.MaxBackoff
+	}
+	return backoff
+}
+
+// post posts the change as JSON to the webhook. Any response but 2xx is a failed delivery.
+func (d *Dispatcher) post(ctx context.Context, webhook *entity.Webhook, change *entity.Change) error {
+	body, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("can't marshal change: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("can't create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(change.Kind))
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(change.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, d.now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("can't post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Sign returns the signature of the body posted at the given time: "t=<unix time>,v1=<hex>",
+// where v1 is the HMAC-SHA256 of "<unix time>.<body>" keyed with the secret of the webhook.
+// Receivers recompute it to authenticate the delivery and check t to reject replays.
+func Sign(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"L2/develop/dev11/internal/db"
+	"L2/develop/dev11/internal/entity"
+	"L2/develop/dev11/internal/repository"
+	"L2/develop/dev11/internal/usecase"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// receiver is a webhook endpoint failing the first requests.
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.requests)
+}
+
+type fixture struct {
+	ctx        context.Context
+	source     db.ChangeSource
+	events     usecase.EventInteractor
+	dispatcher *Dispatcher
+	webhook    *entity.Webhook
+	now        time.Time
+}
+
+func newFixture(t *testing.T, url string, config Config) *fixture {
+	t.Helper()
+
+	source := db.NewMemorySource()
+	changes := repository.NewChangeRepository(source)
+	calendars := repository.NewCalendarRepository(source)
+	webhooks := repository.NewWebhookRepository(source)
+	feed := usecase.NewChangeFeed(changes)
+
+	f := &fixture{
+		ctx:    usecase.ContextWithCaller(context.Background(), uuid.New()),
+		source: source,
+		events: usecase.NewEventInteractor(repository.NewEventRepository(source), calendars, usecase.ConflictFlag, usecase.EventOptions{Changes: feed}),
+		// The feed stamps the changes with the current time.
+		now: time.Now(),
+	}
+	f.webhook = &entity.Webhook{URL: url}
+	err := usecase.NewWebhookInteractor(webhooks, changes).Create(f.ctx, f.webhook)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	f.dispatcher = NewDispatcher(webhooks, changes, calendars, feed, http.DefaultClient, config, zap.NewNop())
+	f.dispatcher.now = func() time.Time { return f.now }
+	return f
+}
+
+func (f *fixture) createEvent(t *testing.T, ctx context.Context, title string) *entity.Event {
+	t.Helper()
+
+	event := &entity.Event{
+		ID:       uuid.New(),
+		Title:    title,
+		Start:    f.now.Add(time.Hour),
+		End:      f.now.Add(2 * time.Hour),
+		TimeZone: "UTC",
+	}
+	_, err := f.events.Create(ctx, event)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return event
+}
+
+func (f *fixture) dispatch(t *testing.T) {
+	t.Helper()
+
+	err := f.dispatcher.dispatch(context.Background())
+	if err != nil {
+		t.Fatalf("dispatch() error = %v", err)
+	}
+}
+
+func TestDispatcherRetriesWithBackoff(t *testing.T) {
+	r := &receiver{failures: 1}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	f := newFixture(t, server.URL, Config{Interval: time.Minute, MaxAttempts: 5, MaxBackoff: time.Hour})
+	first := f.createEvent(t, f.ctx, "standup")
+	f.createEvent(t, f.ctx, "review")
+	// The changes of calendars the user can't read aren't delivered.
+	f.createEvent(t, usecase.ContextWithCaller(context.Background(), uuid.New()), "private")
+
+	// The first attempt fails: nothing is delivered until the backoff passes.
+	f.dispatch(t)
+	f.dispatch(t)
+	if r.count() != 1 {
+		t.Fatalf("requests = %d, want 1 before the backoff passes", r.count())
+	}
+
+	f.now = f.now.Add(time.Minute)
+	f.dispatch(t)
+	if r.count() != 3 {
+		t.Fatalf("requests = %d, want 3", r.count())
+	}
+
+	var titles []string
+	for i, req := range r.requests[1:] {
+		body := r.bodies[i+1]
+		var change entity.Change
+		if err := json.Unmarshal(body, &change); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		titles = append(titles, change.Event.Title)
+
+		if req.Header.Get(EventHeader) != string(entity.ChangeCreated) {
+			t.Errorf("%s = %q, want created", EventHeader, req.Header.Get(EventHeader))
+		}
+		if req.Header.Get(DeliveryHeader) != strconv.FormatInt(change.ID, 10) {
+			t.Errorf("%s = %q, want %d", DeliveryHeader, req.Header.Get(DeliveryHeader), change.ID)
+		}
+		if got, want := req.Header.Get(SignatureHeader), Sign(f.webhook.Secret, f.now, body); got != want {
+			t.Errorf("%s = %q, want %q", SignatureHeader, got, want)
+		}
+	}
+	if strings.Join(titles, ",") != "standup,review" {
+		t.Errorf("delivered %v, want standup and review in order", titles)
+	}
+	if !strings.Contains(string(r.bodies[0]), first.ID.String()) {
+		t.Errorf("first attempt = %s, want the change of %s", r.bodies[0], first.ID)
+	}
+
+	// Delivered changes aren't posted again.
+	f.dispatch(t)
+	if r.count() != 3 {
+		t.Errorf("requests = %d after everything was delivered, want 3", r.count())
+	}
+}
+
+func TestDispatcherDropsAfterMaxAttempts(t *testing.T) {
+	r := &receiver{failures: 2}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	f := newFixture(t, server.URL, Config{Interval: time.Minute, MaxAttempts: 2, MaxBackoff: time.Hour, Retention: 24 * time.Hour})
+	f.createEvent(t, f.ctx, "dropped")
+	f.createEvent(t, f.ctx, "delivered")
+
+	f.dispatch(t)
+	f.now = f.now.Add(time.Minute)
+	// The second failure drops the first change and the second one is delivered right away.
+	f.dispatch(t)
+	if r.count() != 3 {
+		t.Fatalf("requests = %d, want 3", r.count())
+	}
+	if !strings.Contains(string(r.bodies[2]), `"title":"delivered"`) {
+		t.Errorf("last delivery = %s, want the second change", r.bodies[2])
+	}
+
+	// The change log older than the retention is purged.
+	f.now = f.now.Add(25 * time.Hour)
+	f.dispatch(t)
+	changes, err := f.source.GetChanges(context.Background(), 0, []uuid.UUID{f.webhook.UserID}, 10)
+	if err != nil {
+		t.Fatalf("GetChanges() error = %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("changes = %d after the retention, want 0", len(changes))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{config: Config{Interval: time.Minute, MaxBackoff: 10 * time.Minute}}
+	for attempts, want := range []time.Duration{1: time.Minute, 2: 2 * time.Minute, 3: 4 * time.Minute, 4: 8 * time.Minute, 5: 10 * time.Minute, 6: 10 * time.Minute} {
+		if attempts == 0 {
+			continue
+		}
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}